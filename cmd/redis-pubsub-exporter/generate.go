@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/generate"
+)
+
+// newGenerateCommands registers the "generate" subcommands and returns their
+// runners keyed by full command name. Runners read cfg after flag parsing.
+func newGenerateCommands(app *kingpin.Application, cfg *config.Config) map[string]func(w io.Writer) error {
+	cmd := app.Command("generate", "Generate configuration for other tools from the exporter's configuration.")
+
+	rules := cmd.Command("rules", "Print a Prometheus rules file with recording rules and alerts for the exporter's metrics.")
+	var slowSubscriberBytes int64
+	rules.Flag("slow-subscriber-bytes", "Client output buffer size above which a subscriber is alerted as slow.").
+		Default("33554432").
+		Int64Var(&slowSubscriberBytes)
+
+	dashboard := cmd.Command("dashboard", "Print Grafana dashboard JSON for the exporter's metrics, known patterns and hash metrics.")
+	var title, uid string
+	dashboard.Flag("title", "Dashboard title.").Default("Redis Pub/Sub").StringVar(&title)
+	dashboard.Flag("uid", "Dashboard UID.").Default("redis-pubsub").StringVar(&uid)
+
+	return map[string]func(w io.Writer) error{
+		rules.FullCommand(): func(w io.Writer) error {
+			out, err := generate.Rules(generate.RulesOptions{
+				Namespace:           collector.Namespace,
+				Patterns:            cfg.KnownPatterns,
+				SlowSubscriberBytes: slowSubscriberBytes,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(out)
+			return err
+		},
+		dashboard.FullCommand(): func(w io.Writer) error {
+			out, err := generate.Dashboard(generate.DashboardOptions{
+				Namespace:   collector.Namespace,
+				Patterns:    cfg.KnownPatterns,
+				HashMetrics: cfg.HashMetrics,
+				Title:       title,
+				UID:         uid,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(append(out, '\n'))
+			return err
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/redis-pubsub-exporter/internal/health"
+)
+
+// grpcWatchInterval is how often Watch re-evaluates the status.
+const grpcWatchInterval = 5 * time.Second
+
+// grpcHealth implements the grpc.health.v1 service on top of the same
+// checks as the HTTP probes: "liveness" mirrors /livez, "readiness" and the
+// empty (overall) service mirror /readyz.
+type grpcHealth struct {
+	healthpb.UnimplementedHealthServer
+	ready    readiness
+	liveness *health.Liveness
+}
+
+func (g *grpcHealth) status(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	var ok bool
+	switch service {
+	case "liveness":
+		ok = len(g.liveness.Problems()) == 0
+	case "", "readiness":
+		ok = g.ready.IsRedisUp()
+	default:
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "unknown service %q", service)
+	}
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING, nil
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING, nil
+}
+
+func (g *grpcHealth) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s, err := g.status(req.GetService())
+	if err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: s}, nil
+}
+
+// Watch sends the current status and then every change, polling every
+// grpcWatchInterval. Unknown services are reported as SERVICE_UNKNOWN as the
+// protocol requires, rather than failing the stream.
+func (g *grpcHealth) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(grpcWatchInterval)
+	defer ticker.Stop()
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		s, _ := g.status(req.GetService())
+		if s != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: s}); err != nil {
+				return err
+			}
+			last = s
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
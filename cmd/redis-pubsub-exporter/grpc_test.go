@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/redis-pubsub-exporter/internal/health"
+)
+
+func TestGRPCHealthCheck(t *testing.T) {
+	l := health.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name     string
+		ready    readiness
+		service  string
+		want     healthpb.HealthCheckResponse_ServingStatus
+		wantCode codes.Code
+	}{
+		{name: "overall ready", ready: fakeReadiness{up: true}, want: healthpb.HealthCheckResponse_SERVING},
+		{name: "overall not ready", ready: fakeReadiness{}, want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{name: "readiness", ready: fakeReadiness{}, service: "readiness", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{name: "liveness ignores redis", ready: fakeReadiness{}, service: "liveness", want: healthpb.HealthCheckResponse_SERVING},
+		{name: "unknown service", ready: fakeReadiness{up: true}, service: "redis", wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &grpcHealth{ready: tt.ready, liveness: l}
+			resp, err := g.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("want code %v, got %v", tt.wantCode, code)
+			}
+			if err == nil && resp.GetStatus() != tt.want {
+				t.Errorf("want %v, got %v", tt.want, resp.GetStatus())
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/health"
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+// readiness is implemented by the single-target collector (via singleTarget)
+// and by the multi-target manager.
+type readiness interface {
+	IsRedisUp() bool
+	Statuses() []targets.Status
+}
+
+// singleTarget reports the status of the one configured Redis.
+type singleTarget struct {
+	*collector.RedisPubSubCollector
+	addr string
+}
+
+func (s singleTarget) Statuses() []targets.Status {
+	return []targets.Status{{Addr: s.addr, Status: s.Status()}}
+}
+
+func (s singleTarget) Histories() []targets.History {
+	return []targets.History{{Addr: s.addr, Scrapes: s.History()}}
+}
+
+// historian is implemented by singleTarget and the multi-target manager.
+type historian interface {
+	Histories() []targets.History
+}
+
+// historyResponse is the JSON body of /api/v1/history.
+type historyResponse struct {
+	Targets []targets.History `json:"targets"`
+}
+
+// historyHandler serves /api/v1/history: summaries of recent scrapes per
+// target, oldest first, for when Prometheus itself is unavailable.
+func historyHandler(h historian) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(historyResponse{Targets: h.Histories()})
+	}
+}
+
+// sdGroup is a target group in the Prometheus HTTP SD format.
+type sdGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// sdMeta prefixes the meta labels of /api/v1/targets; Prometheus drops them
+// after relabelling unless they are copied into real labels.
+const sdMeta = "__meta_redis_pubsub_"
+
+// targetsHandler serves /api/v1/targets: the monitored Redis targets with
+// their labels and scrape status, for a Prometheus http_sd_configs entry.
+func targetsHandler(ready readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		statuses := ready.Statuses()
+		groups := make([]sdGroup, 0, len(statuses))
+		for _, s := range statuses {
+			labels := maps.Clone(s.Labels)
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[sdMeta+"ready"] = strconv.FormatBool(s.Ready)
+			labels[sdMeta+"consecutive_failures"] = strconv.Itoa(s.ConsecutiveFailures)
+			if !s.LastSuccess.IsZero() {
+				labels[sdMeta+"last_success"] = s.LastSuccess.UTC().Format(time.RFC3339)
+			}
+			if s.LastError != "" {
+				labels[sdMeta+"last_error"] = s.LastError
+			}
+			groups = append(groups, sdGroup{Targets: []string{s.Addr}, Labels: labels})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(groups)
+	}
+}
+
+// stuckScrape returns an error if a scrape has been querying Redis for longer
+// than the HTTP write timeout. Scrapes give up after the shorter scrape
+// timeout, so such a scrape is stuck and holds up all later ones.
+func stuckScrape(ready readiness, now time.Time) error {
+	for _, s := range ready.Statuses() {
+		if since := s.ScrapeRunningSince; !since.IsZero() && now.Sub(since) > config.HTTPWriteTimeout {
+			return fmt.Errorf("scrape of %s running for %s", s.Addr, now.Sub(since).Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// redisTargetAddr describes the configured Redis for status output.
+func redisTargetAddr(cfg *config.Config) string {
+	switch {
+	case cfg.ClusterEnabled():
+		return strings.Join(cfg.ClusterAddrs, ",")
+	case cfg.SentinelEnabled():
+		return cfg.SentinelMaster + "@" + strings.Join(cfg.SentinelAddrs, ",")
+	}
+	return cfg.RedisAddr()
+}
+
+// healthResponse is the JSON body of /healthz and /readyz.
+type healthResponse struct {
+	Status  string           `json:"status"`
+	Version string           `json:"version"`
+	Targets []targets.Status `json:"targets"`
+}
+
+// wantsJSON reports whether the client asked for a JSON body with
+// ?format=json or an Accept header.
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// healthHandler serves /healthz: the process is alive, so it always succeeds.
+func healthHandler(ready readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, ready, http.StatusOK, "ok")
+	}
+}
+
+// readyHandler serves /readyz: ready while Redis is considered reachable.
+func readyHandler(ready readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ready.IsRedisUp() {
+			writeHealth(w, r, ready, http.StatusOK, "ok")
+		} else {
+			writeHealth(w, r, ready, http.StatusServiceUnavailable, "redis not reachable")
+		}
+	}
+}
+
+// livenessResponse is the JSON body of /livez.
+type livenessResponse struct {
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// liveHandler serves /livez: it fails only when the exporter itself is
+// broken (a background loop died or a component is deadlocked), never
+// because Redis is down.
+func liveHandler(l *health.Liveness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := livenessResponse{Status: "ok", Problems: l.Problems()}
+		code := http.StatusOK
+		if len(resp.Problems) > 0 {
+			resp.Status = "not live"
+			code = http.StatusServiceUnavailable
+		}
+		if !wantsJSON(r) {
+			w.WriteHeader(code)
+			_, _ = fmt.Fprint(w, resp.Status)
+			for _, p := range resp.Problems {
+				_, _ = fmt.Fprint(w, "\n"+p)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, ready readiness, code int, status string) {
+	if !wantsJSON(r) {
+		w.WriteHeader(code)
+		_, _ = fmt.Fprint(w, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(healthResponse{
+		Status:  status,
+		Version: version,
+		Targets: ready.Statuses(),
+	})
+}
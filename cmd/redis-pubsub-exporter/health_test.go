@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/health"
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+type fakeReadiness struct {
+	up       bool
+	statuses []targets.Status
+}
+
+func (f fakeReadiness) IsRedisUp() bool            { return f.up }
+func (f fakeReadiness) Statuses() []targets.Status { return f.statuses }
+func (f fakeReadiness) Histories() []targets.History {
+	return []targets.History{{Addr: "redis:6379", Scrapes: []collector.Snapshot{{Up: f.up, Channels: 4}}}}
+}
+
+func TestReadyHandler(t *testing.T) {
+	down := fakeReadiness{statuses: []targets.Status{{
+		Addr:   "redis:6379",
+		Status: collector.Status{LastError: "connection refused", ConsecutiveFailures: 2},
+	}}}
+
+	tests := []struct {
+		name     string
+		ready    readiness
+		url      string
+		accept   string
+		wantCode int
+		wantBody string // plain-text body; empty for JSON
+	}{
+		{name: "ready", ready: fakeReadiness{up: true}, url: "/readyz", wantCode: http.StatusOK, wantBody: "ok"},
+		{name: "not ready", ready: down, url: "/readyz", wantCode: http.StatusServiceUnavailable, wantBody: "redis not reachable"},
+		{name: "json query", ready: down, url: "/readyz?format=json", wantCode: http.StatusServiceUnavailable},
+		{name: "json accept", ready: down, url: "/readyz", accept: "application/json", wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			readyHandler(tt.ready)(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("want status %d, got %d", tt.wantCode, rec.Code)
+			}
+			if tt.wantBody != "" {
+				if got := rec.Body.String(); got != tt.wantBody {
+					t.Errorf("want body %q, got %q", tt.wantBody, got)
+				}
+				return
+			}
+
+			var got healthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Status != "redis not reachable" || len(got.Targets) != 1 {
+				t.Fatalf("unexpected response %+v", got)
+			}
+			if tg := got.Targets[0]; tg.Addr != "redis:6379" || tg.LastError != "connection refused" || tg.ConsecutiveFailures != 2 {
+				t.Errorf("unexpected target status %+v", tg)
+			}
+		})
+	}
+}
+
+func TestHealthHandlerAlwaysOK(t *testing.T) {
+	rec := httptest.NewRecorder()
+	healthHandler(fakeReadiness{})(rec, httptest.NewRequest(http.MethodGet, "/healthz?format=json", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("want 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("want JSON content type, got %q", ct)
+	}
+}
+
+func TestLiveHandlerIgnoresRedis(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l := health.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	l.Go(ctx, "targets", func(ctx context.Context) { <-ctx.Done() })
+	l.AddProbe("collector", func() error { return stuckScrape(fakeReadiness{}, time.Now()) })
+
+	rec := httptest.NewRecorder()
+	liveHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("want 200 ok while Redis is down, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStuckScrape(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		since   time.Time
+		wantErr bool
+	}{
+		{name: "idle"},
+		{name: "running", since: now.Add(-5 * time.Second)},
+		{name: "stuck", since: now.Add(-time.Minute), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready := fakeReadiness{statuses: []targets.Status{
+				{Addr: "redis:6379", Status: collector.Status{ScrapeRunningSince: tt.since}},
+			}}
+			if err := stuckScrape(ready, now); (err != nil) != tt.wantErr {
+				t.Errorf("want error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHistoryHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	historyHandler(fakeReadiness{up: true})(rec, httptest.NewRequest(http.MethodGet, "/api/v1/history", nil))
+
+	var got historyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Targets) != 1 || len(got.Targets[0].Scrapes) != 1 || got.Targets[0].Scrapes[0].Channels != 4 {
+		t.Errorf("unexpected history %+v", got)
+	}
+}
+
+func TestTargetsHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		ready readiness
+		want  []sdGroup
+	}{
+		{
+			name:  "no targets",
+			ready: fakeReadiness{},
+			want:  []sdGroup{},
+		},
+		{
+			name: "labels and status",
+			ready: fakeReadiness{statuses: []targets.Status{
+				{
+					Addr:   "redis-orders:6379",
+					Labels: map[string]string{"env": "prod"},
+					Status: collector.Status{Ready: true, LastSuccess: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+				},
+				{
+					Addr:   "redis-users:6379",
+					Status: collector.Status{LastError: "connection refused", ConsecutiveFailures: 3},
+				},
+			}},
+			want: []sdGroup{
+				{Targets: []string{"redis-orders:6379"}, Labels: map[string]string{
+					"env":                       "prod",
+					"__meta_redis_pubsub_ready": "true",
+					"__meta_redis_pubsub_consecutive_failures": "0",
+					"__meta_redis_pubsub_last_success":         "2026-01-02T03:04:05Z",
+				}},
+				{Targets: []string{"redis-users:6379"}, Labels: map[string]string{
+					"__meta_redis_pubsub_ready":                "false",
+					"__meta_redis_pubsub_consecutive_failures": "3",
+					"__meta_redis_pubsub_last_error":           "connection refused",
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			targetsHandler(tt.ready)(rec, httptest.NewRequest(http.MethodGet, "/api/v1/targets", nil))
+
+			var got []sdGroup
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if got == nil {
+				t.Fatal("want a JSON array, got null")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
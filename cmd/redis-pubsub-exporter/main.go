@@ -2,23 +2,43 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
+	"crypto/rand"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/redis-pubsub-exporter/internal/admin"
 	"github.com/redis-pubsub-exporter/internal/collector"
 	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/dial"
+	"github.com/redis-pubsub-exporter/internal/filterfile"
+	"github.com/redis-pubsub-exporter/internal/health"
+	"github.com/redis-pubsub-exporter/internal/leader"
+	"github.com/redis-pubsub-exporter/internal/logging"
+	"github.com/redis-pubsub-exporter/internal/probe"
+	"github.com/redis-pubsub-exporter/internal/sampler"
+	"github.com/redis-pubsub-exporter/internal/sentinel"
+	"github.com/redis-pubsub-exporter/internal/state"
+	"github.com/redis-pubsub-exporter/internal/targets"
+	"github.com/redis-pubsub-exporter/internal/tlsfiles"
+	"github.com/redis-pubsub-exporter/internal/vault"
 )
 
 var (
@@ -29,63 +49,717 @@ var (
 )
 
 func main() {
-	cfg := config.Load()
+	prefix := flagArg(os.Args[1:], "env-prefix")
+	cfg := config.LoadProfile(prefix, flagArg(os.Args[1:], "profile"))
 
 	app := kingpin.New("redis-pubsub-exporter",
 		"Prometheus exporter for Redis Pub/Sub channels, patterns, and client subscriptions.")
 	app.Version(fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date))
 	app.HelpFlag.Short('h')
 
-	app.Flag("redis.host", "Redis server hostname.").
-		Envar("REDIS_HOST").
+	app.Flag("env-prefix", "Prefix of every environment variable name, e.g. RPE_ reads RPE_REDIS_HOST instead of REDIS_HOST.").
+		Default(prefix).
+		StringVar(&prefix)
+
+	app.Flag("profile", "Collection profile whose defaults apply: minimal (server and channel metrics, tight limits), standard or deep (higher limits, change rates). Explicit settings still win.").
+		Envar(prefix+"PROFILE").
+		Default(cfg.Profile).
+		EnumVar(&cfg.Profile, config.Profiles()...)
+
+	app.Flag("mode", "Collection mode: full, or info-only to take the channel, pattern and client counts from INFO without enumerating channels or clients.").
+		Envar(prefix+"MODE").
+		Default(cfg.Mode).
+		EnumVar(&cfg.Mode, collector.ModeFull, collector.ModeInfoOnly)
+
+	app.Flag("strict-config", "Fail on unknown environment variables in the exporter's namespace (the --env-prefix, or REDIS_) instead of warning.").
+		Envar(prefix + "STRICT_CONFIG").
+		Default(strconv.FormatBool(cfg.StrictConfig)).
+		BoolVar(&cfg.StrictConfig)
+
+	app.Flag("redis.host", "Redis server hostname or IP address; IPv6 literals may be bracketed.").
+		Envar(prefix + "REDIS_HOST").
 		Default(cfg.RedisHost).
 		StringVar(&cfg.RedisHost)
 
 	app.Flag("redis.port", "Redis server port.").
-		Envar("REDIS_PORT").
+		Envar(prefix + "REDIS_PORT").
 		Default(strconv.Itoa(cfg.RedisPort)).
 		IntVar(&cfg.RedisPort)
 
-	app.Flag("redis.password", "Redis server password.").
-		Envar("REDIS_PASSWORD").
-		Default("").
+	// Secret flags have no Default: it would show up in --help and replace
+	// a value read from <VAR>_FILE by config.Load.
+	app.Flag("redis.password", "Redis server password (or from the file in REDIS_PASSWORD_FILE).").
+		Envar(prefix + "REDIS_PASSWORD").
 		StringVar(&cfg.RedisPassword)
 
 	app.Flag("redis.db", "Redis database number.").
-		Envar("REDIS_DB").
+		Envar(prefix + "REDIS_DB").
 		Default(strconv.Itoa(cfg.RedisDB)).
 		IntVar(&cfg.RedisDB)
 
 	app.Flag("redis.tls", "Enable TLS for Redis connection.").
-		Envar("REDIS_TLS").
+		Envar(prefix + "REDIS_TLS").
 		Default("false").
 		BoolVar(&cfg.RedisTLS)
 
+	app.Flag("redis.tls-cert-file", "PEM file with the TLS client certificate; reloaded, and connections re-established, when it changes.").
+		Envar(prefix + "REDIS_TLS_CERT_FILE").
+		Default(cfg.RedisTLSCertFile).
+		StringVar(&cfg.RedisTLSCertFile)
+
+	app.Flag("redis.tls-key-file", "PEM file with the key of --redis.tls-cert-file.").
+		Envar(prefix + "REDIS_TLS_KEY_FILE").
+		Default(cfg.RedisTLSKeyFile).
+		StringVar(&cfg.RedisTLSKeyFile)
+
+	app.Flag("redis.tls-ca-file", "PEM file with the CA bundle to verify Redis with instead of the system roots; reloaded when it changes.").
+		Envar(prefix + "REDIS_TLS_CA_FILE").
+		Default(cfg.RedisTLSCAFile).
+		StringVar(&cfg.RedisTLSCAFile)
+
+	app.Flag("redis.client-name", "Name set with CLIENT SETNAME on every Redis connection, shown in CLIENT LIST and the slowlog (empty = unnamed).").
+		Envar(prefix + "REDIS_CLIENT_NAME").
+		Default(cfg.RedisClientName).
+		StringVar(&cfg.RedisClientName)
+
+	app.Flag("redis.protocol", "RESP protocol version requested from Redis: 3, or 2 for proxies such as Twemproxy or Envoy that only speak RESP2.").
+		Envar(prefix + "REDIS_PROTOCOL").
+		Default(strconv.Itoa(cfg.RedisProtocol)).
+		IntVar(&cfg.RedisProtocol)
+
+	var sentinelAddrs string
+	app.Flag("redis.sentinel-addrs", "Comma-separated Sentinel addresses (host:port). Used with --redis.sentinel-master.").
+		Envar(prefix + "REDIS_SENTINEL_ADDRS").
+		Default(strings.Join(cfg.SentinelAddrs, ",")).
+		StringVar(&sentinelAddrs)
+
+	app.Flag("redis.sentinel-master", "Sentinel master name. Enables sentinel mode; --redis.host/--redis.port are ignored.").
+		Envar(prefix + "REDIS_SENTINEL_MASTER").
+		Default(cfg.SentinelMaster).
+		StringVar(&cfg.SentinelMaster)
+
+	app.Flag("redis.sentinel-password", "Password for authenticating to Sentinel.").
+		Envar(prefix + "REDIS_SENTINEL_PASSWORD").
+		StringVar(&cfg.SentinelPassword)
+
+	app.Flag("redis.srv", "DNS SRV name (e.g. _redis._tcp.redis.example.com) looked up on every connect instead of --redis.host/--redis.port.").
+		Envar(prefix + "REDIS_SRV").
+		Default(cfg.RedisSRV).
+		StringVar(&cfg.RedisSRV)
+
+	app.Flag("redis.dns-refresh-interval", "Recycle pooled Redis connections after this long so the target is re-resolved (0 = never).").
+		Envar(prefix + "REDIS_DNS_REFRESH_INTERVAL").
+		Default(cfg.DNSRefreshInterval.String()).
+		DurationVar(&cfg.DNSRefreshInterval)
+
+	app.Flag("redis.conn-max-idle-time", "Close pooled Redis connections idle for this long, before a NAT gateway or load balancer silently drops them (0 = never).").
+		Envar(prefix + "REDIS_CONN_MAX_IDLE_TIME").
+		Default(cfg.RedisConnMaxIdleTime.String()).
+		DurationVar(&cfg.RedisConnMaxIdleTime)
+
+	app.Flag("redis.conn-max-lifetime", "Close pooled Redis connections after this long, however busy (0 = never).").
+		Envar(prefix + "REDIS_CONN_MAX_LIFETIME").
+		Default(cfg.RedisConnMaxLifetime.String()).
+		DurationVar(&cfg.RedisConnMaxLifetime)
+
+	app.Flag("redis.tcp-keepalive", "Interval of TCP keepalive probes on Redis, proxy and SSH bastion connections (0 = off).").
+		Envar(prefix + "REDIS_TCP_KEEPALIVE").
+		Default(cfg.RedisTCPKeepAlive.String()).
+		DurationVar(&cfg.RedisTCPKeepAlive)
+
+	app.Flag("redis.prefer-replica", "Read INFO from a replica; PUBSUB and CLIENT LIST still go to the master.").
+		Envar(prefix + "REDIS_PREFER_REPLICA").
+		Default("false").
+		BoolVar(&cfg.PreferReplica)
+
+	app.Flag("redis.proxy-url", "Dial Redis through this proxy: socks5://[user:pass@]host:port or http://[user:pass@]host:port (HTTP CONNECT).").
+		Envar(prefix + "REDIS_PROXY_URL").
+		Default(cfg.ProxyURL).
+		StringVar(&cfg.ProxyURL)
+
+	app.Flag("redis.ssh.addr", "SSH bastion (host[:port]) to tunnel Redis connections through.").
+		Envar(prefix + "REDIS_SSH_ADDR").
+		Default(cfg.SSHAddr).
+		StringVar(&cfg.SSHAddr)
+
+	app.Flag("redis.ssh.user", "SSH user on the bastion.").
+		Envar(prefix + "REDIS_SSH_USER").
+		Default(cfg.SSHUser).
+		StringVar(&cfg.SSHUser)
+
+	app.Flag("redis.ssh.key-file", "Private key for SSH authentication (passphrase from REDIS_SSH_KEY_PASSPHRASE).").
+		Envar(prefix + "REDIS_SSH_KEY_FILE").
+		Default(cfg.SSHKeyFile).
+		StringVar(&cfg.SSHKeyFile)
+
+	app.Flag("redis.ssh.agent", "Authenticate to the bastion via the SSH agent at SSH_AUTH_SOCK.").
+		Envar(prefix + "REDIS_SSH_AGENT").
+		Default("false").
+		BoolVar(&cfg.SSHAgent)
+
+	app.Flag("redis.ssh.known-hosts", "known_hosts file used to verify the bastion host key.").
+		Envar(prefix + "REDIS_SSH_KNOWN_HOSTS").
+		Default(cfg.SSHKnownHosts).
+		StringVar(&cfg.SSHKnownHosts)
+
+	app.Flag("redis.ssh.insecure-ignore-host-key", "Do not verify the bastion host key.").
+		Envar(prefix + "REDIS_SSH_INSECURE_IGNORE_HOST_KEY").
+		Default("false").
+		BoolVar(&cfg.SSHInsecureIgnoreHostKey)
+
+	var clusterAddrs string
+	app.Flag("redis.cluster-addrs", "Comma-separated Redis Cluster seed nodes (host:port). Enables cluster mode.").
+		Envar(prefix + "REDIS_CLUSTER_ADDRS").
+		Default(strings.Join(cfg.ClusterAddrs, ",")).
+		StringVar(&clusterAddrs)
+
+	app.Flag("targets.file", "YAML/JSON file listing Redis targets (Prometheus file_sd format). Enables multi-target mode.").
+		Envar(prefix + "TARGETS_FILE").
+		Default(cfg.TargetsFile).
+		StringVar(&cfg.TargetsFile)
+
+	app.Flag("targets.consul-service", "Consul service whose healthy instances are scraped. Enables multi-target mode.").
+		Envar(prefix + "TARGETS_CONSUL_SERVICE").
+		Default(cfg.ConsulService).
+		StringVar(&cfg.ConsulService)
+
+	app.Flag("targets.consul-tag", "Only use Consul service instances with this tag.").
+		Envar(prefix + "TARGETS_CONSUL_TAG").
+		Default(cfg.ConsulTag).
+		StringVar(&cfg.ConsulTag)
+
+	app.Flag("targets.consul-addr", "Consul HTTP API address.").
+		Envar(prefix + "CONSUL_HTTP_ADDR").
+		Default(cfg.ConsulAddr).
+		StringVar(&cfg.ConsulAddr)
+
+	app.Flag("targets.refresh-interval", "How often the target list is re-read.").
+		Envar(prefix + "TARGETS_REFRESH_INTERVAL").
+		Default(cfg.TargetsRefreshInterval.String()).
+		DurationVar(&cfg.TargetsRefreshInterval)
+
+	app.Flag("probe.enabled", "Serve /probe?target=host:port, which scrapes the given Redis instance on request (multi-target exporter pattern).").
+		Envar(prefix + "PROBE_ENABLED").
+		Default(strconv.FormatBool(cfg.ProbeEnabled)).
+		BoolVar(&cfg.ProbeEnabled)
+
+	app.Flag("probe.modules-file", "YAML file defining the modules /probe can select with ?module=.").
+		Envar(prefix + "PROBE_MODULES_FILE").
+		Default(cfg.ProbeModulesFile).
+		StringVar(&cfg.ProbeModulesFile)
+
+	var probeAllowedTargets string
+	app.Flag("probe.allowed-targets", "Comma-separated host:port globs of /probe targets that get the global password and client certificate; others are probed without them (default: the configured targets). A request with the admin token may probe any target with them.").
+		Envar(prefix + "PROBE_ALLOWED_TARGETS").
+		Default(strings.Join(cfg.ProbeAllowedTargets, ",")).
+		StringVar(&probeAllowedTargets)
+
+	app.Flag("vault.addr", "Vault address. With --vault.path, the Redis password and TLS material are read from Vault (token from VAULT_TOKEN).").
+		Envar(prefix + "VAULT_ADDR").
+		Default(cfg.VaultAddr).
+		StringVar(&cfg.VaultAddr)
+
+	app.Flag("vault.path", "Vault secret path, e.g. secret/data/redis (KV v2) or secret/redis (KV v1).").
+		Envar(prefix + "VAULT_PATH").
+		Default(cfg.VaultPath).
+		StringVar(&cfg.VaultPath)
+
+	app.Flag("vault.kubernetes-role", "Authenticate to Vault with the Kubernetes auth method using this role instead of a token.").
+		Envar(prefix + "VAULT_KUBERNETES_ROLE").
+		Default(cfg.VaultKubernetesRole).
+		StringVar(&cfg.VaultKubernetesRole)
+
+	app.Flag("vault.kubernetes-mount", "Mount path of the Vault Kubernetes auth method.").
+		Envar(prefix + "VAULT_KUBERNETES_MOUNT").
+		Default(cfg.VaultKubernetesMount).
+		StringVar(&cfg.VaultKubernetesMount)
+
+	app.Flag("vault.password-key", "Secret key holding the Redis password.").
+		Envar(prefix + "VAULT_PASSWORD_KEY").
+		Default(cfg.VaultPasswordKey).
+		StringVar(&cfg.VaultPasswordKey)
+
+	app.Flag("vault.refresh-interval", "How often the secret is re-read when it has no lease.").
+		Envar(prefix + "VAULT_REFRESH_INTERVAL").
+		Default(cfg.VaultRefreshInterval.String()).
+		DurationVar(&cfg.VaultRefreshInterval)
+
 	app.Flag("web.listen-address", "Address to listen on for metrics (e.g. :9123 or 0.0.0.0:9123).").
-		Envar("EXPORTER_LISTEN_ADDRESS").
+		Envar(prefix + "EXPORTER_LISTEN_ADDRESS").
 		Default(cfg.ListenAddress).
 		StringVar(&cfg.ListenAddress)
 
+	app.Flag("web.grpc-health-address", "Address to serve the gRPC health checking protocol (grpc.health.v1) on, e.g. :9124 (empty = disabled).").
+		Envar(prefix + "GRPC_HEALTH_ADDRESS").
+		Default(cfg.GRPCHealthAddress).
+		StringVar(&cfg.GRPCHealthAddress)
+
 	app.Flag("max-channels", "Maximum number of channels to track (high cardinality guard).").
-		Envar("MAX_CHANNELS").
+		Envar(prefix + "MAX_CHANNELS").
 		Default(strconv.Itoa(cfg.MaxChannels)).
 		IntVar(&cfg.MaxChannels)
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	app.Flag("max-clients", "Maximum number of clients with per-client subscription metrics (0 = unlimited).").
+		Envar(prefix + "MAX_CLIENTS").
+		Default(strconv.Itoa(cfg.MaxClients)).
+		IntVar(&cfg.MaxClients)
+
+	app.Flag("clients.include-name", "Regular expression; only clients whose name matches get per-client series (default: all).").
+		Envar(prefix + "CLIENT_INCLUDE_NAME").
+		Default(cfg.ClientIncludeName).
+		StringVar(&cfg.ClientIncludeName)
+
+	app.Flag("clients.exclude-name", "Regular expression; clients whose name matches get no per-client series.").
+		Envar(prefix + "CLIENT_EXCLUDE_NAME").
+		Default(cfg.ClientExcludeName).
+		StringVar(&cfg.ClientExcludeName)
+
+	app.Flag("clients.aggregate-by", "Per-client series: none (one per connection) or ip (summed per source IP, for clients that never set a name).").
+		Envar(prefix+"CLIENT_AGGREGATE_BY").
+		Default(cfg.ClientAggregate).
+		EnumVar(&cfg.ClientAggregate, collector.ClientAggregateNone, collector.ClientAggregateIP)
+
+	app.Flag("patterns.max", "Maximum number of patterns queried per scrape, configured ones first, then auto-discovered prefixes with the most channels (0 = unlimited).").
+		Envar(prefix + "MAX_PATTERNS").
+		Default(strconv.Itoa(cfg.MaxPatterns)).
+		IntVar(&cfg.MaxPatterns)
+
+	app.Flag("max-series", "Last-ditch cap on Redis-derived series per scrape; excess series are dropped and exporter_series_limited set (0 = unlimited).").
+		Envar(prefix + "MAX_SERIES").
+		Default(strconv.Itoa(cfg.MaxSeries)).
+		IntVar(&cfg.MaxSeries)
+
+	var channelInclude, channelExclude, criticalChannels, scrapeDisable string
+	app.Flag("channels.include", "Comma-separated globs; only matching channels are tracked (default: all).").
+		Envar(prefix + "CHANNEL_INCLUDE").
+		Default(strings.Join(cfg.ChannelInclude, ",")).
+		StringVar(&channelInclude)
+
+	app.Flag("channels.exclude", "Comma-separated globs of channels never to track.").
+		Envar(prefix + "CHANNEL_EXCLUDE").
+		Default(strings.Join(cfg.ChannelExclude, ",")).
+		StringVar(&channelExclude)
+
+	app.Flag("channels.critical", "Comma-separated channels whose subscribers are checked on every scrape, reporting a fall to zero since the previous scrape.").
+		Envar(prefix + "CRITICAL_CHANNELS").
+		Default(strings.Join(cfg.CriticalChannels, ",")).
+		StringVar(&criticalChannels)
+
+	app.Flag("channels.system", "Keyspace notification channels (__keyspace@*, __keyevent@*): include (mixed in with application channels), separate (exported as system_channel_* metrics) or exclude.").
+		Envar(prefix+"SYSTEM_CHANNELS").
+		Default(cfg.SystemChannels).
+		EnumVar(&cfg.SystemChannels, collector.SystemChannelsInclude, collector.SystemChannelsSeparate, collector.SystemChannelsExclude)
+
+	app.Flag("channels.filter-file", "YAML file with include/exclude channel globs, reloaded on change; replaces --channels.include/--channels.exclude.").
+		Envar(prefix + "CHANNEL_FILTER_FILE").
+		Default(cfg.ChannelFilterFile).
+		StringVar(&cfg.ChannelFilterFile)
+
+	app.Flag("web.admin-token", "Bearer token enabling the runtime admin API under /api/v1/ and /debug/config (empty = disabled).").
+		Envar(prefix + "ADMIN_TOKEN").
+		StringVar(&cfg.AdminToken)
+
+	app.Flag("scrape.min-interval", "Serve scrapes from cache if Redis was queried less than this long ago (0 = always query).").
+		Envar(prefix + "SCRAPE_MIN_INTERVAL").
+		Default(cfg.ScrapeMinInterval.String()).
+		DurationVar(&cfg.ScrapeMinInterval)
+
+	app.Flag("scrape.timeout", "Maximum time one scrape may spend querying Redis; must be shorter than the HTTP write timeout ("+config.HTTPWriteTimeout.String()+").").
+		Envar(prefix + "SCRAPE_TIMEOUT").
+		Default(cfg.ScrapeTimeout.String()).
+		DurationVar(&cfg.ScrapeTimeout)
+
+	app.Flag("scrape.max-commands", "Redis commands one scrape may issue before per-item metrics are skipped; totals are still reported (0 = unlimited).").
+		Envar(prefix + "SCRAPE_MAX_COMMANDS").
+		Default(strconv.Itoa(cfg.ScrapeMaxCommands)).
+		IntVar(&cfg.ScrapeMaxCommands)
+
+	app.Flag("scrape.max-duration", "Time one scrape may spend before per-item metrics are skipped; totals are still reported (0 = unlimited).").
+		Envar(prefix + "SCRAPE_MAX_DURATION").
+		Default(cfg.ScrapeMaxDuration.String()).
+		DurationVar(&cfg.ScrapeMaxDuration)
+
+	app.Flag("scrape.disable", "Comma-separated scrape subsystems to skip: server, cluster, channels, clients, rates, hash_metrics, patterns.").
+		Envar(prefix + "SCRAPE_DISABLE").
+		Default(strings.Join(cfg.ScrapeDisable, ",")).
+		StringVar(&scrapeDisable)
+
+	app.Flag("scrape.failure-backoff", "After a failed scrape, don't query Redis for this long (doubling per consecutive failure); scrapes meanwhile report redis_up 0 (0 = disabled).").
+		Envar(prefix + "SCRAPE_FAILURE_BACKOFF").
+		Default(cfg.ScrapeFailureBackoff.String()).
+		DurationVar(&cfg.ScrapeFailureBackoff)
+
+	app.Flag("scrape.failure-backoff-max", "Upper bound for --scrape.failure-backoff.").
+		Envar(prefix + "SCRAPE_FAILURE_BACKOFF_MAX").
+		Default(cfg.ScrapeFailureBackoffMax.String()).
+		DurationVar(&cfg.ScrapeFailureBackoffMax)
+
+	app.Flag("scrape.auth-failure-backoff", "After Redis rejects the credentials (NOAUTH, WRONGPASS), don't query it, not even for health checks, for this long (0 = use --scrape.failure-backoff).").
+		Envar(prefix + "SCRAPE_AUTH_FAILURE_BACKOFF").
+		Default(cfg.ScrapeAuthBackoff.String()).
+		DurationVar(&cfg.ScrapeAuthBackoff)
+
+	app.Flag("web.ready-failure-threshold", "Report not ready on /readyz after this many consecutive failed scrapes.").
+		Envar(prefix + "READY_FAILURE_THRESHOLD").
+		Default(strconv.Itoa(cfg.ReadyFailureThreshold)).
+		IntVar(&cfg.ReadyFailureThreshold)
+
+	app.Flag("web.ready-max-staleness", "Report not ready on /readyz when the last successful scrape is older than this (0 = disabled).").
+		Envar(prefix + "READY_MAX_STALENESS").
+		Default(cfg.ReadyMaxStaleness.String()).
+		DurationVar(&cfg.ReadyMaxStaleness)
+
+	app.Flag("redis.health-check-interval", "PING Redis in the background this often to keep /readyz current between scrapes (0 = disabled).").
+		Envar(prefix + "REDIS_HEALTH_CHECK_INTERVAL").
+		Default(cfg.HealthCheckInterval.String()).
+		DurationVar(&cfg.HealthCheckInterval)
+
+	app.Flag("log.level", "Minimum level logged: debug, info, warn or error.").
+		Envar(prefix+"LOG_LEVEL").
+		Default(cfg.LogLevel).
+		EnumVar(&cfg.LogLevel, "debug", "info", "warn", "error")
+
+	app.Flag("log.diff-min-delta", "At debug level, report channels whose subscriber count changed by at least this much since the previous scrape.").
+		Envar(prefix + "LOG_DIFF_MIN_DELTA").
+		Default(strconv.Itoa(cfg.LogDiffMinDelta)).
+		IntVar(&cfg.LogDiffMinDelta)
+
+	app.Flag("log.dedup-interval", "Log identical warnings and errors at most once per interval, with a repeat count (0 = log every occurrence).").
+		Envar(prefix + "LOG_DEDUP_INTERVAL").
+		Default(cfg.LogDedupInterval.String()).
+		DurationVar(&cfg.LogDedupInterval)
+
+	app.Flag("web.access-log", "Log every HTTP request with method, path, status, duration and remote address.").
+		Envar(prefix + "ACCESS_LOG").
+		Default(strconv.FormatBool(cfg.AccessLog)).
+		BoolVar(&cfg.AccessLog)
+
+	app.Flag("web.metrics-error-handling", "How /metrics handles a metric that fails to gather: continue (serve the rest and log), http-error (respond 500) or panic.").
+		Envar(prefix+"METRICS_ERROR_HANDLING").
+		Default(cfg.MetricsErrorHandling).
+		EnumVar(&cfg.MetricsErrorHandling, "continue", "http-error", "panic")
+
+	app.Flag("web.disable-compression", "Never gzip /metrics responses, even when the client accepts it.").
+		Envar(prefix + "METRICS_DISABLE_COMPRESSION").
+		Default(strconv.FormatBool(cfg.MetricsDisableCompression)).
+		BoolVar(&cfg.MetricsDisableCompression)
+
+	app.Flag("heartbeat.key", "Redis key written after every successful scrape, e.g. exporter:heartbeat:{instance} ({instance} = hostname; empty = disabled).").
+		Envar(prefix + "HEARTBEAT_KEY").
+		Default(cfg.HeartbeatKey).
+		StringVar(&cfg.HeartbeatKey)
+
+	app.Flag("heartbeat.ttl", "Expiry of the heartbeat key.").
+		Envar(prefix + "HEARTBEAT_TTL").
+		Default(cfg.HeartbeatTTL.String()).
+		DurationVar(&cfg.HeartbeatTTL)
+
+	app.Flag("ha.lock-key", "Redis key exporter replicas compete for; only the holder runs subsystems that subscribe or publish (empty = disabled).").
+		Envar(prefix + "HA_LOCK_KEY").
+		Default(cfg.HALockKey).
+		StringVar(&cfg.HALockKey)
+
+	app.Flag("ha.lock-ttl", "Expiry of the leader lock; a standby replica takes over at most this long after the leader dies.").
+		Envar(prefix + "HA_LOCK_TTL").
+		Default(cfg.HALockTTL.String()).
+		DurationVar(&cfg.HALockTTL)
+
+	app.Flag("state.file", "Persist cumulative counters to this file so they survive restarts.").
+		Envar(prefix + "STATE_FILE").
+		Default(cfg.StateFile).
+		StringVar(&cfg.StateFile)
+
+	app.Flag("state.redis-key", "Persist cumulative counters to this Redis key instead of a file.").
+		Envar(prefix + "STATE_REDIS_KEY").
+		Default(cfg.StateRedisKey).
+		StringVar(&cfg.StateRedisKey)
+
+	app.Flag("state.save-interval", "How often persisted counters are saved; they are also saved on shutdown.").
+		Envar(prefix + "STATE_SAVE_INTERVAL").
+		Default(cfg.StateSaveInterval.String()).
+		DurationVar(&cfg.StateSaveInterval)
+
+	app.Flag("web.history-size", "Number of recent scrape summaries served at /api/v1/history behind --web.admin-token (0 = disabled).").
+		Envar(prefix + "HISTORY_SIZE").
+		Default(strconv.Itoa(cfg.HistorySize)).
+		IntVar(&cfg.HistorySize)
+
+	app.Flag("rates.window", "Export channel creation, subscriber change and client churn rates averaged over this window (0 = disabled).").
+		Envar(prefix + "RATE_WINDOW").
+		Default(cfg.RateWindow.String()).
+		DurationVar(&cfg.RateWindow)
+
+	app.Flag("orphans.min-age", "Count channels without subscribers for at least this long in orphan_channels_persistent (0 = disabled).").
+		Envar(prefix + "ORPHAN_MIN_AGE").
+		Default(cfg.OrphanMinAge.String()).
+		DurationVar(&cfg.OrphanMinAge)
+
+	app.Flag("patterns.cache-ttl", "Reuse each pattern's matching channel count for this long instead of querying it on every scrape (0 = disabled).").
+		Envar(prefix + "PATTERN_CACHE_TTL").
+		Default(cfg.PatternCacheTTL.String()).
+		DurationVar(&cfg.PatternCacheTTL)
+
+	app.Flag("patterns.discovery-min-channels", "Only query an auto-discovered prefix once it covers at least this many channels.").
+		Envar(prefix + "PATTERN_DISCOVERY_MIN_CHANNELS").
+		Default(strconv.Itoa(cfg.PatternDiscoveryMinChannels)).
+		IntVar(&cfg.PatternDiscoveryMinChannels)
+
+	app.Flag("patterns.discovery-min-scrapes", "Only query an auto-discovered prefix once it was seen in this many scrapes; it is dropped after as many scrapes without.").
+		Envar(prefix + "PATTERN_DISCOVERY_MIN_SCRAPES").
+		Default(strconv.Itoa(cfg.PatternDiscoveryMinScrapes)).
+		IntVar(&cfg.PatternDiscoveryMinScrapes)
+
+	app.Flag("sampler.enabled", "Subscribe to --sampler.patterns and count published messages per channel (leader only with --ha.lock-key).").
+		Envar(prefix + "SAMPLER_ENABLED").
+		Default(strconv.FormatBool(cfg.SamplerEnabled)).
+		BoolVar(&cfg.SamplerEnabled)
+
+	var samplerPatterns string
+	app.Flag("sampler.patterns", "Comma-separated patterns the sampler subscribes to.").
+		Envar(prefix + "SAMPLER_PATTERNS").
+		Default(strings.Join(cfg.SamplerPatterns, ",")).
+		StringVar(&samplerPatterns)
+
+	app.Flag("sampler.channel-counters", "Export a sampled message counter per channel; per-pattern counters for KNOWN_PATTERNS are always exported.").
+		Envar(prefix + "SAMPLER_CHANNEL_COUNTERS").
+		Default(strconv.FormatBool(cfg.SamplerChannelCounters)).
+		BoolVar(&cfg.SamplerChannelCounters)
+
+	app.Flag("sampler.max-channels", "Maximum number of channels the sampler tracks individually (0 = unlimited).").
+		Envar(prefix + "SAMPLER_MAX_CHANNELS").
+		Default(strconv.Itoa(cfg.SamplerMaxChannels)).
+		IntVar(&cfg.SamplerMaxChannels)
+
+	app.Flag("sampler.max-rate", "Maximum number of messages the sampler processes per second; the rest are dropped (0 = unlimited).").
+		Envar(prefix + "SAMPLER_MAX_MESSAGES_PER_SECOND").
+		Default(strconv.Itoa(cfg.SamplerMaxRate)).
+		IntVar(&cfg.SamplerMaxRate)
+
+	app.Flag("sampler.classify-payloads", "Count sampled messages per known pattern and payload class (json, text, binary).").
+		Envar(prefix + "SAMPLER_CLASSIFY_PAYLOADS").
+		Default(strconv.FormatBool(cfg.SamplerClassifyPayloads)).
+		BoolVar(&cfg.SamplerClassifyPayloads)
+
+	app.Flag("sampler.payload-type-field", "JSON field whose string value sampled messages are also counted by, e.g. event_type (implies --sampler.classify-payloads).").
+		Envar(prefix + "SAMPLER_PAYLOAD_TYPE_FIELD").
+		Default(cfg.SamplerPayloadTypeField).
+		StringVar(&cfg.SamplerPayloadTypeField)
+
+	app.Flag("sampler.mode", "How the sampler observes messages: psubscribe, or monitor (DANGEROUS: runs MONITOR, which slows Redis down considerably while active).").
+		Envar(prefix+"SAMPLER_MODE").
+		Default(cfg.SamplerMode).
+		EnumVar(&cfg.SamplerMode, sampler.ModePSubscribe, sampler.ModeMonitor)
+
+	app.Flag("sampler.monitor-duration", "How long each MONITOR slice runs in monitor mode.").
+		Envar(prefix + "SAMPLER_MONITOR_DURATION").
+		Default(cfg.SamplerMonitorDuration.String()).
+		DurationVar(&cfg.SamplerMonitorDuration)
+
+	app.Flag("sampler.monitor-interval", "How often a MONITOR slice starts in monitor mode.").
+		Envar(prefix + "SAMPLER_MONITOR_INTERVAL").
+		Default(cfg.SamplerMonitorInterval.String()).
+		DurationVar(&cfg.SamplerMonitorInterval)
+
+	app.Flag("sampler.silent-window", "Report channels with subscribers but no message for this long as silent (0 = disabled).").
+		Envar(prefix + "SILENT_CHANNEL_WINDOW").
+		Default(cfg.SilentWindow.String()).
+		DurationVar(&cfg.SilentWindow)
+
+	var printConfig bool
+	app.Flag("print-config", "Print the effective value of every setting and whether it came from a flag, an environment variable, the profile or the default, then exit.").
+		BoolVar(&printConfig)
+
+	var snapshotPath string
+	app.Flag("dump-snapshot", "Write the unfiltered Redis state (INFO, channels, clients) as JSON to this file and exit, for offline analysis with the replay command.").
+		PlaceHolder("path.json").
+		StringVar(&snapshotPath)
+
+	// Benchmarking the emit path and cardinality guards without a large Redis
+	var fakeRedis bool
+	var fakeChannels, fakeClients int
+	app.Flag("selftest.fake-redis", "Serve metrics from a synthetic in-memory Redis instead of connecting to one.").
+		Hidden().
+		BoolVar(&fakeRedis)
+	app.Flag("selftest.channels", "Number of channels of the fake Redis.").
+		Hidden().
+		Default("100000").
+		IntVar(&fakeChannels)
+	app.Flag("selftest.clients", "Number of subscribed clients of the fake Redis.").
+		Hidden().
+		Default("1000").
+		IntVar(&fakeClients)
+
+	app.Command("serve", "Run the exporter (default).").Default()
+	replayCmd := app.Command("replay", "Serve metrics from a snapshot written by --dump-snapshot instead of a live Redis, applying the configured limits and filters.")
+	replayFile := replayCmd.Arg("file", "Snapshot JSON file.").Required().ExistingFile()
+	generate := newGenerateCommands(app, cfg)
+
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	cfg.SentinelAddrs = config.SplitList(sentinelAddrs)
+	cfg.ClusterAddrs = config.SplitList(clusterAddrs)
+	cfg.ChannelInclude = config.SplitList(channelInclude)
+	cfg.ChannelExclude = config.SplitList(channelExclude)
+	cfg.CriticalChannels = config.SplitList(criticalChannels)
+	cfg.ProbeAllowedTargets = config.SplitList(probeAllowedTargets)
+	cfg.ScrapeDisable = config.SplitList(scrapeDisable)
+	cfg.SamplerPatterns = config.SplitList(samplerPatterns)
+
+	if run, ok := generate[command]; ok {
+		if err := run(os.Stdout); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	settings := settingsOf(app, os.Args[1:], cfg)
+	if printConfig {
+		if err := printSettings(os.Stdout, settings); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := cfg.Validate(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.LogLevel)) // validated by kingpin
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	if cfg.LogDedupInterval > 0 {
+		handler = logging.NewDedupHandler(handler, cfg.LogDedupInterval)
+	}
+	logger := slog.New(handler)
 
 	logger.Info("starting Redis PubSub Exporter",
 		"version", version,
+		"profile", cfg.Profile,
+		"mode", cfg.Mode,
 		"redis", cfg.RedisAddr(),
 		"redis_tls", cfg.RedisTLS,
 		"listen", cfg.ListenAddress,
 		"max_channels", cfg.MaxChannels,
 		"known_patterns", cfg.KnownPatterns,
 		"hash_metrics", len(cfg.HashMetrics),
+		"scrape_min_interval", cfg.ScrapeMinInterval,
 	)
 
+	if err := cfg.Validate(); err != nil {
+		logger.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := cfg.CheckEnv(); err != nil {
+		if cfg.StrictConfig {
+			logger.Error("unknown configuration", "error", err)
+			os.Exit(1)
+		}
+		logger.Warn("ignoring unknown configuration", "error", err)
+	}
+	for _, d := range cfg.Deprecations() {
+		logger.Warn("deprecated configuration option in use, it will be removed in a future release",
+			"option", d.Option, "replacement", d.Replacement)
+	}
+	if command == replayCmd.FullCommand() {
+		snap, err := collector.ReadSnapshot(*replayFile)
+		if err != nil {
+			logger.Error("failed to read snapshot", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("replaying snapshot",
+			"path", *replayFile,
+			"taken", snap.Taken,
+			"channels", len(snap.Channels),
+			"clients", len(snap.Clients),
+		)
+		if err := serveSnapshot(cfg, snap, logger); err != nil {
+			logger.Error("replay failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if fakeRedis {
+		logger.Warn("serving metrics of a fake Redis for benchmarking",
+			"channels", fakeChannels, "clients", fakeClients)
+		if err := serveSnapshot(cfg, collector.FakeSnapshot(fakeChannels, fakeClients), logger); err != nil {
+			logger.Error("selftest failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if strings.Contains(cfg.HeartbeatKey, "{instance}") {
+		host, err := os.Hostname()
+		if err != nil {
+			logger.Error("cannot resolve {instance} in heartbeat key", "error", err)
+			os.Exit(1)
+		}
+		cfg.HeartbeatKey = strings.ReplaceAll(cfg.HeartbeatKey, "{instance}", host)
+	}
+
+	if cfg.ClusterEnabled() && hashMetricsUseDB(cfg.HashMetrics) {
+		logger.Error("hash metrics with db= are not supported in cluster mode")
+		os.Exit(1)
+	}
+	if cfg.SentinelEnabled() && cfg.ClusterEnabled() {
+		logger.Error("sentinel mode and cluster mode are mutually exclusive")
+		os.Exit(1)
+	}
+	if cfg.MultiTargetEnabled() && (cfg.SentinelEnabled() || cfg.ClusterEnabled() || cfg.RedisSRV != "") {
+		logger.Error("multi-target mode only supports standalone targets")
+		os.Exit(1)
+	}
+	if cfg.StateFile != "" && cfg.StateRedisKey != "" {
+		logger.Error("state file and state Redis key are mutually exclusive")
+		os.Exit(1)
+	}
+	if cfg.MultiTargetEnabled() && (cfg.StateFile != "" || cfg.StateRedisKey != "") {
+		logger.Warn("counter persistence is not supported in multi-target mode, ignoring")
+		cfg.StateFile, cfg.StateRedisKey = "", ""
+	}
+	if cfg.SamplerEnabled && cfg.SamplerMode == sampler.ModeMonitor {
+		if cfg.ClusterEnabled() {
+			logger.Error("sampler monitor mode is not supported in cluster mode")
+			os.Exit(1)
+		}
+	}
+	if cfg.MultiTargetEnabled() && cfg.SamplerEnabled {
+		logger.Warn("the message sampler is not supported in multi-target mode, ignoring")
+		cfg.SamplerEnabled = false
+	}
+	if cfg.MultiTargetEnabled() && cfg.HALockKey != "" {
+		logger.Warn("leader election is not supported in multi-target mode, ignoring lock key", "key", cfg.HALockKey)
+		cfg.HALockKey = ""
+	}
+	if cfg.MultiTargetEnabled() {
+		logger.Info("multi-target mode enabled",
+			"targets_file", cfg.TargetsFile,
+			"consul_service", cfg.ConsulService,
+			"refresh_interval", cfg.TargetsRefreshInterval,
+		)
+	}
+	if cfg.RedisSRV != "" && (cfg.SentinelEnabled() || cfg.ClusterEnabled()) {
+		logger.Error("--redis.srv is only supported in standalone mode")
+		os.Exit(1)
+	}
+	if cfg.RedisSRV != "" {
+		logger.Info("resolving redis target via SRV", "srv", cfg.RedisSRV)
+	}
+	if cfg.ClusterEnabled() {
+		logger.Info("cluster mode enabled", "seeds", cfg.ClusterAddrs)
+	}
+	if cfg.SentinelEnabled() {
+		if len(cfg.SentinelAddrs) == 0 {
+			logger.Error("sentinel mode requires --redis.sentinel-addrs")
+			os.Exit(1)
+		}
+		logger.Info("sentinel mode enabled",
+			"master", cfg.SentinelMaster,
+			"sentinels", cfg.SentinelAddrs,
+		)
+	}
+
 	for _, hm := range cfg.HashMetrics {
 		logger.Info("hash metric configured",
 			"redis_key", hm.RedisKey,
@@ -94,26 +768,305 @@ func main() {
 		)
 	}
 
-	// Redis client
-	opts := &redis.Options{
-		Addr:         cfg.RedisAddr(),
-		Password:     cfg.RedisPassword,
-		DB:           cfg.RedisDB,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
-		PoolSize:     5,
+	// Background tasks are stopped on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Background loops and lock probes behind /livez
+	liveness := health.New(logger)
+
+	// Credentials from Vault, re-read before their lease expires
+	var env connEnv
+	if cfg.VaultEnabled() {
+		vc := vault.New(vault.Config{
+			Addr:            cfg.VaultAddr,
+			Path:            cfg.VaultPath,
+			Token:           cfg.VaultToken,
+			KubernetesRole:  cfg.VaultKubernetesRole,
+			KubernetesMount: cfg.VaultKubernetesMount,
+			PasswordKey:     cfg.VaultPasswordKey,
+			RefreshInterval: cfg.VaultRefreshInterval,
+		}, logger)
+		if err := vc.Load(ctx); err != nil {
+			logger.Error("failed to load redis credentials from vault", "error", err)
+			os.Exit(1)
+		}
+		liveness.Go(ctx, "vault", vc.Run)
+		env.creds = vc
+	}
+
+	// TLS material from files, re-read when cert-manager or a Secret update
+	// rotates it; connections made with the old material are closed
+	if cfg.RedisTLSCertFile != "" || cfg.RedisTLSCAFile != "" {
+		files := tlsfiles.New(tlsfiles.Files{
+			Cert: cfg.RedisTLSCertFile,
+			Key:  cfg.RedisTLSKeyFile,
+			CA:   cfg.RedisTLSCAFile,
+		}, logger)
+		if _, err := files.Load(); err != nil {
+			logger.Error("failed to load redis TLS files", "error", err)
+			os.Exit(1)
+		}
+		env.tlsFiles, env.conns = files, dial.NewTracker()
+		liveness.Go(ctx, "tls-files", func(ctx context.Context) {
+			files.Run(ctx, func() {
+				logger.Info("redis TLS material changed, reconnecting", "connections", env.conns.CloseAll())
+			})
+		})
+	}
+
+	// Proxy and/or SSH tunnel to a bastion host (the bastion is reached via
+	// the proxy when both are set)
+	if cfg.ProxyURL != "" {
+		proxyDial, err := dial.Proxy(cfg.ProxyURL, 5*time.Second, cfg.RedisTCPKeepAlive)
+		if err != nil {
+			logger.Error("invalid proxy configuration", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("dialing redis via proxy", "proxy", redactURL(cfg.ProxyURL))
+		env.base = proxyDial
+	}
+	if cfg.SSHAddr != "" {
+		tunnel, err := dial.NewSSHTunnel(dial.SSHConfig{
+			Addr:                  cfg.SSHAddr,
+			User:                  cfg.SSHUser,
+			KeyFile:               cfg.SSHKeyFile,
+			KeyPassphrase:         cfg.SSHKeyPassphrase,
+			UseAgent:              cfg.SSHAgent,
+			KnownHostsFile:        cfg.SSHKnownHosts,
+			InsecureIgnoreHostKey: cfg.SSHInsecureIgnoreHostKey,
+			KeepAlive:             cfg.RedisTCPKeepAlive,
+			Dial:                  env.base,
+		})
+		if err != nil {
+			logger.Error("invalid ssh tunnel configuration", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("tunnelling redis connections via ssh", "bastion", cfg.SSHAddr, "user", cfg.SSHUser)
+		env.base = tunnel.DialContext
+		defer func() { _ = tunnel.Close() }()
+	}
+
+	if snapshotPath != "" {
+		if err := dumpSnapshot(cfg, env, snapshotPath, logger); err != nil {
+			logger.Error("failed to write snapshot", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
-	if cfg.RedisTLS {
-		opts.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
+
+	// Metrics sources: the default registry plus the Redis collector(s),
+	// gathered per request so a cancelled scrape aborts its Redis commands.
+	var scrape func(ctx context.Context) prometheus.Gatherer
+	var ready readiness
+	var history historian
+	var takeSnapshot func(ctx context.Context) (*collector.ServerSnapshot, error)
+
+	// Limits shared by all collectors and adjustable via the admin API
+	limits := collector.NewLimitStore(collector.Limits{
+		MaxChannels:    cfg.MaxChannels,
+		MaxClients:     cfg.MaxClients,
+		MaxPatterns:    cfg.MaxPatterns,
+		MaxSeries:      cfg.MaxSeries,
+		ChannelInclude: cfg.ChannelInclude,
+		ChannelExclude: cfg.ChannelExclude,
+	})
+	if cfg.ChannelFilterFile != "" {
+		filters := filterfile.New(cfg.ChannelFilterFile, limits, logger)
+		if err := filters.Load(); err != nil {
+			logger.Error("failed to load channel filter file", "error", err)
+			os.Exit(1)
+		}
+		liveness.Go(ctx, "filter-file", filters.Run)
+	}
+	var closers []func()
+
+	if cfg.MultiTargetEnabled() {
+		mgr := targets.NewManager(targetSource(cfg), newTargetFactory(cfg, env, limits, logger), cfg.TargetsRefreshInterval, logger)
+		liveness.Go(ctx, "targets", mgr.Run)
+		scrape = func(ctx context.Context) prometheus.Gatherer {
+			return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return mgr.GatherContext(ctx)
+			})
+		}
+		ready = mgr
+		history = mgr
+	} else {
+		// Redis client
+		opts := redisOptions(cfg, env, logger)
+		rdb := redis.NewUniversalClient(opts)
+		protocol := addProtocolHook(rdb, cfg.RedisProtocol)
+		tlsCerts := addTLSCertHook(rdb, cfg.RedisTLS)
+		closers = append(closers, func() {
+			if err := rdb.Close(); err != nil {
+				logger.Error("redis close error", "error", err)
+			}
+		})
+
+		// Optional replica for INFO reads
+		var infoClient collector.RedisQuerier
+		if cfg.PreferReplica {
+			if replica := newReplicaClient(cfg, opts, env, rdb, logger); replica != nil {
+				infoClient = collector.NewQuerier(replica)
+				closers = append(closers, func() {
+					if err := replica.Close(); err != nil {
+						logger.Error("redis replica close error", "error", err)
+					}
+				})
+			}
+		}
+
+		// Clients for hash metrics, one small pool per database
+		var dbClient func(int) collector.KeyReader
+		if len(cfg.HashMetrics) > 0 {
+			dbs := newDBClients(opts, cfg.HashMetricsPoolSize)
+			dbClient = dbs.Get
+			closers = append(closers, func() {
+				if err := dbs.Close(); err != nil {
+					logger.Error("redis db client close error", "error", err)
+				}
+			})
+		}
+
+		// Leader election between replicas
+		var elector *leader.Elector
+		if cfg.HALockKey != "" {
+			elector = leader.New(rdb, cfg.HALockKey, replicaID(), cfg.HALockTTL, logger)
+			prometheus.MustRegister(elector)
+			liveness.Go(ctx, "leader", elector.Run)
+			logger.Info("leader election enabled", "key", cfg.HALockKey, "ttl", cfg.HALockTTL)
+		}
+
+		// Message sampler; subscribes on the leader only
+		var activity collector.ActivitySource
+		if cfg.SamplerEnabled {
+			subscriber := newSubscriberClient(opts)
+			closers = append(closers, func() {
+				if err := subscriber.Close(); err != nil {
+					logger.Error("redis sampler client close error", "error", err)
+				}
+			})
+			s := sampler.New(subscriber, sampler.Options{
+				Mode:             cfg.SamplerMode,
+				NewMonitor:       newMonitorClient(cfg, opts),
+				MonitorDuration:  cfg.SamplerMonitorDuration,
+				MonitorInterval:  cfg.SamplerMonitorInterval,
+				Subscribe:        cfg.SamplerPatterns,
+				Patterns:         cfg.KnownPatterns,
+				ChannelCounters:  cfg.SamplerChannelCounters,
+				MaxChannels:      cfg.SamplerMaxChannels,
+				MaxRate:          cfg.SamplerMaxRate,
+				ClassifyPayloads: cfg.SamplerClassifyPayloads,
+				TypeField:        cfg.SamplerPayloadTypeField,
+			}, logger)
+			prometheus.MustRegister(s)
+			liveness.Go(ctx, "sampler", whileLeading(elector, s.Run))
+			activity = s
 		}
+
+		// Create and register collector
+		clientInclude, clientExclude := cfg.ClientNameFilters()
+		coll := collector.New(collector.NewQuerier(rdb), collector.Options{
+			Limits:         limits,
+			KnownPatterns:  cfg.KnownPatterns,
+			MinSubscribers: cfg.PatternMinSubscribers,
+			HealthWeights:  cfg.HealthScoreWeights,
+			SystemChannels: cfg.SystemChannels,
+			Mode:           cfg.Mode,
+			HashMetrics:    cfg.HashMetrics,
+			ClusterMode:    cfg.ClusterEnabled(),
+			InfoClient:     infoClient,
+			DBClient:       dbClient,
+			DB:             cfg.RedisDB,
+			MinInterval:    cfg.ScrapeMinInterval,
+			Timeout:        cfg.ScrapeTimeout,
+			DiffMinDelta:   cfg.LogDiffMinDelta,
+			Protocol:       protocol,
+			TLSCerts:       tlsCerts,
+			MaxCommands:    cfg.ScrapeMaxCommands,
+			MaxDuration:    cfg.ScrapeMaxDuration,
+
+			CriticalChannels: cfg.CriticalChannels,
+
+			ClientIncludeName: clientInclude,
+			ClientExcludeName: clientExclude,
+			ClientAggregate:   cfg.ClientAggregate,
+
+			DisabledSubsystems: cfg.ScrapeDisable,
+
+			FailureBackoff:    cfg.ScrapeFailureBackoff,
+			FailureBackoffMax: cfg.ScrapeFailureBackoffMax,
+
+			AuthFailureBackoff: cfg.ScrapeAuthBackoff,
+
+			ReadyFailureThreshold: cfg.ReadyFailureThreshold,
+			ReadyMaxStaleness:     cfg.ReadyMaxStaleness,
+
+			HeartbeatKey: cfg.HeartbeatKey,
+			HeartbeatTTL: cfg.HeartbeatTTL,
+
+			HistorySize: cfg.HistorySize,
+			RateWindow:  cfg.RateWindow,
+
+			OrphanMinAge:    cfg.OrphanMinAge,
+			PatternCacheTTL: cfg.PatternCacheTTL,
+
+			DiscoveryMinChannels: cfg.PatternDiscoveryMinChannels,
+			DiscoveryMinScrapes:  cfg.PatternDiscoveryMinScrapes,
+
+			Activity:     activity,
+			SilentWindow: cfg.SilentWindow,
+		}, logger)
+		logger.Debug("scrape subsystems", "enabled", coll.Subsystems())
+		scrape = func(ctx context.Context) prometheus.Gatherer {
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(collector.WithContext(ctx, coll))
+			return reg
+		}
+		if cfg.HealthCheckInterval > 0 {
+			liveness.Go(ctx, "health-check", func(ctx context.Context) {
+				coll.RunHealthCheck(ctx, cfg.HealthCheckInterval)
+			})
+		}
+		single := singleTarget{RedisPubSubCollector: coll, addr: redisTargetAddr(cfg)}
+		ready, history = single, single
+		takeSnapshot = func(ctx context.Context) (*collector.ServerSnapshot, error) {
+			return collector.TakeSnapshot(ctx, collector.NewQuerier(rdb), cfg.ClusterEnabled())
+		}
+
+		// Counter persistence
+		if backend := stateBackend(cfg, rdb); backend != nil {
+			var saved collector.Counters
+			if ok, err := state.Load(ctx, backend, &saved); err != nil {
+				logger.Warn("failed to load saved counters, starting from zero", "error", err)
+			} else if ok {
+				coll.RestoreCounters(saved)
+				logger.Info("restored saved counters", "scrape_errors", saved.ScrapeErrors)
+			}
+			counters := func() any { return coll.Counters() }
+			liveness.Go(ctx, "state", func(ctx context.Context) {
+				state.Run(ctx, backend, cfg.StateSaveInterval, counters, logger)
+			})
+			// Save once more on shutdown, before the Redis client is closed.
+			closers = append([]func(){func() {
+				saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := state.Save(saveCtx, backend, counters()); err != nil {
+					logger.Error("failed to save counters", "error", err)
+				}
+			}}, closers...)
+		}
+
 	}
-	rdb := redis.NewClient(opts)
 
-	// Create and register collector
-	coll := collector.New(rdb, cfg.MaxChannels, cfg.KnownPatterns, cfg.HashMetrics, logger)
-	prometheus.MustRegister(coll)
+	liveness.AddProbe("collector", func() error { return stuckScrape(ready, time.Now()) })
+
+	// Sentinel events
+	if cfg.SentinelEnabled() {
+		watcher := sentinel.NewWatcher(cfg.SentinelAddrs, cfg.SentinelPassword, logger)
+		prometheus.MustRegister(watcher)
+		liveness.Go(ctx, "sentinel", watcher.Run)
+	}
 
 	// Exporter build info
 	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -124,24 +1077,66 @@ func main() {
 	buildInfo.WithLabelValues(version, commit, date).Set(1)
 	prometheus.MustRegister(buildInfo)
 
+	// Deprecated options still in use, to find them across a fleet before
+	// they are removed
+	deprecatedConfig := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "redis_pubsub",
+		Name:      "exporter_deprecated_config_used",
+		Help:      "Set to 1 for each deprecated configuration option in use.",
+	}, []string{"option"})
+	for _, d := range cfg.Deprecations() {
+		deprecatedConfig.WithLabelValues(d.Option).Set(1)
+	}
+	prometheus.MustRegister(deprecatedConfig)
+
 	// HTTP server
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
-
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = fmt.Fprint(w, "ok")
-	})
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		metricsHandler(scrape, promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(logger.Handler(), slog.LevelError),
+			ErrorHandling: metricsErrorHandling(cfg.MetricsErrorHandling),
+			// Negotiated via Accept-Encoding; identity is always available.
+			DisableCompression:  cfg.MetricsDisableCompression,
+			OfferedCompressions: []promhttp.Compression{promhttp.Gzip},
+		}),
+	))
 
-	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
-		if coll.IsRedisUp() {
-			w.WriteHeader(http.StatusOK)
-			_, _ = fmt.Fprint(w, "ok")
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = fmt.Fprint(w, "redis not reachable")
+	mux.HandleFunc("/healthz", healthHandler(ready))
+	mux.HandleFunc("/readyz", readyHandler(ready))
+	mux.HandleFunc("/livez", liveHandler(liveness))
+	mux.HandleFunc("GET /api/v1/targets", targetsHandler(ready))
+	if cfg.ProbeEnabled {
+		modules := map[string]probe.Module{}
+		if cfg.ProbeModulesFile != "" {
+			var err error
+			if modules, err = probe.LoadModules(cfg.ProbeModulesFile); err != nil {
+				logger.Error("failed to load probe modules", "error", err)
+				os.Exit(1)
+			}
 		}
-	})
+		// Targets not trusted with the credentials get neither the password
+		// nor TLS material from files or Vault.
+		untrusted := newTargetScraper(withoutCredentials(cfg), connEnv{base: env.base, conns: env.conns}, limits, logger)
+		mux.Handle("GET /probe", probeHandler(newTargetScraper(cfg, env, limits, logger), untrusted, probeTrust(cfg, ready), modules, promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(logger.Handler(), slog.LevelError),
+			ErrorHandling: metricsErrorHandling(cfg.MetricsErrorHandling),
+		}))
+		logger.Info("probe endpoint enabled", "path", "/probe", "modules", slices.Sorted(maps.Keys(modules)))
+	}
+	if cfg.AdminToken != "" {
+		adminAPI := admin.New(limits, cfg.AdminToken, logger)
+		if takeSnapshot != nil {
+			adminAPI.HandleSnapshot(takeSnapshot)
+		}
+		if cfg.HistorySize > 0 {
+			adminAPI.Handle("GET /api/v1/history", historyHandler(history))
+		}
+		adminAPI.Handle("GET /debug/config", configHandler(settings))
+		mux.Handle("/api/v1/", adminAPI)
+		mux.Handle("GET /debug/config", adminAPI)
+		logger.Info("runtime admin API enabled", "path", "/api/v1/")
+	}
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -152,16 +1147,23 @@ func main() {
 <p>Version: %s</p>
 <p><a href="/metrics">Metrics</a></p>
 <p><a href="/healthz">Health</a></p>
+<p><a href="/livez">Live</a></p>
 <p><a href="/readyz">Ready</a></p>
+<p><a href="/api/v1/targets">Targets</a></p>
 </body>
 </html>`, version)
 	})
 
+	var httpHandler http.Handler = mux
+	if cfg.AccessLog {
+		httpHandler = logging.AccessLog(mux, logger)
+	}
+
 	srv := &http.Server{
 		Addr:         cfg.ListenAddress,
-		Handler:      mux,
+		Handler:      httpHandler,
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: config.HTTPWriteTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 
@@ -172,6 +1174,21 @@ func main() {
 		errCh <- srv.ListenAndServe()
 	}()
 
+	var grpcSrv *grpc.Server
+	if cfg.GRPCHealthAddress != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCHealthAddress)
+		if err != nil {
+			logger.Error("failed to listen for gRPC health checks", "addr", cfg.GRPCHealthAddress, "error", err)
+			os.Exit(1)
+		}
+		grpcSrv = grpc.NewServer()
+		healthpb.RegisterHealthServer(grpcSrv, &grpcHealth{ready: ready, liveness: liveness})
+		go func() {
+			logger.Info("serving gRPC health checks", "addr", cfg.GRPCHealthAddress)
+			errCh <- grpcSrv.Serve(lis)
+		}()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 
@@ -182,15 +1199,88 @@ func main() {
 		logger.Error("server error", "error", err)
 	}
 
+	cancel()
+
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("shutdown error", "error", err)
 	}
-	if err := rdb.Close(); err != nil {
-		logger.Error("redis close error", "error", err)
+	if grpcSrv != nil {
+		// Stop rather than GracefulStop: Watch streams never end on their own.
+		grpcSrv.Stop()
+	}
+	for _, closeFn := range closers {
+		closeFn()
 	}
 
 	logger.Info("exporter stopped")
 }
+
+// replicaID identifies this process as the leader lock holder. The random
+// suffix keeps it unique when replicas share a hostname.
+func replicaID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host + "-" + rand.Text()[:8]
+}
+
+// stateBackend returns where counters are persisted, or nil if disabled.
+func stateBackend(cfg *config.Config, rdb redis.UniversalClient) state.Backend {
+	switch {
+	case cfg.StateFile != "":
+		return state.File(cfg.StateFile)
+	case cfg.StateRedisKey != "":
+		return state.RedisKey{Client: rdb, Key: cfg.StateRedisKey}
+	}
+	return nil
+}
+
+// whileLeading runs fn only while this replica holds the leader lock, or
+// always if leader election is disabled.
+func whileLeading(elector *leader.Elector, fn func(ctx context.Context)) func(ctx context.Context) {
+	if elector == nil {
+		return fn
+	}
+	return func(ctx context.Context) { elector.RunWhileLeading(ctx, fn) }
+}
+
+// metricsErrorHandling maps --web.metrics-error-handling to promhttp.
+func metricsErrorHandling(mode string) promhttp.HandlerErrorHandling {
+	switch mode {
+	case "http-error":
+		return promhttp.HTTPErrorOnError
+	case "panic":
+		return promhttp.PanicOnError
+	}
+	return promhttp.ContinueOnError
+}
+
+// metricsHandler serves the default registry plus the gatherer scrape
+// returns for each request, bound to that request's context.
+func metricsHandler(scrape func(ctx context.Context) prometheus.Gatherer, opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, scrape(r.Context())}
+		promhttp.HandlerFor(gatherers, opts).ServeHTTP(w, r)
+	})
+}
+
+// flagArg returns the value of the flag --name in args. It is needed for
+// --env-prefix and --profile before the flags are defined, since the
+// environment variables and defaults of the others depend on them.
+func flagArg(args []string, name string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case strings.HasPrefix(arg, "--"+name+"="):
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		case arg == "--"+name && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/redis-pubsub-exporter/internal/admin"
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/probe"
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+// probeHandler serves /probe?target=host:port&module=name&alias=name. Each
+// request scrapes the target once through a collector of its own, running
+// the module's collectors with its settings, and returns only that target's
+// metrics. They carry a target label and, if given, an alias label. Targets
+// the request isn't trusted with are scraped through newUntrusted, which
+// holds no global credentials.
+func probeHandler(newScraper, newUntrusted scraperFactory, trusted func(r *http.Request, addr string) bool,
+	modules map[string]probe.Module, opts promhttp.HandlerOpts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		addr, err := targets.NormalizeAddr(q.Get("target"))
+		if err != nil {
+			http.Error(w, "target parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var module probe.Module
+		if name := q.Get("module"); name != "" {
+			m, ok := modules[name]
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+				return
+			}
+			module = m
+		}
+
+		scrape := newScraper
+		if !trusted(r, addr) {
+			scrape = newUntrusted
+		}
+		coll, closeFn, err := scrape(targets.Target{Addr: addr, Overrides: module.Redis}, module.Collectors)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer closeFn()
+
+		labels := prometheus.Labels{"target": addr}
+		if alias := q.Get("alias"); alias != "" {
+			labels["alias"] = alias
+		}
+		reg := prometheus.NewRegistry()
+		prometheus.WrapRegistererWith(labels, reg).MustRegister(collector.WithContext(r.Context(), coll))
+		promhttp.HandlerFor(reg, opts).ServeHTTP(w, r)
+	}
+}
+
+// probeTrust reports whether a probe of addr may use the global password and
+// client certificate: addr matches cfg.ProbeAllowedTargets (by default, it
+// is one of the configured targets) or the request carries the admin token.
+func probeTrust(cfg *config.Config, configured readiness) func(r *http.Request, addr string) bool {
+	return func(r *http.Request, addr string) bool {
+		if cfg.AdminToken != "" && admin.Authorized(r, cfg.AdminToken) {
+			return true
+		}
+		if len(cfg.ProbeAllowedTargets) == 0 {
+			return slices.ContainsFunc(configured.Statuses(), func(s targets.Status) bool { return s.Addr == addr })
+		}
+		return slices.ContainsFunc(cfg.ProbeAllowedTargets, func(glob string) bool { return collector.MatchGlob(glob, addr) })
+	}
+}
+
+// withoutCredentials returns a copy of cfg without passwords, for probes of
+// targets that aren't trusted with them.
+func withoutCredentials(cfg *config.Config) *config.Config {
+	c := *cfg
+	c.RedisPassword, c.SentinelPassword = "", ""
+	c.RedisTLSCertFile, c.RedisTLSKeyFile = "", ""
+	return &c
+}
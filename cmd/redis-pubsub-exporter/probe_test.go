@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/probe"
+)
+
+func TestProbeHandler(t *testing.T) {
+	// A closed port, so probes report the target as down without waiting.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	cfg := config.LoadPrefix("PROBE_TEST_")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limits := collector.NewLimitStore(collector.Limits{MaxChannels: cfg.MaxChannels})
+	modules := map[string]probe.Module{"minimal": {Collectors: []string{"server", "channels"}}}
+	scraper := newTargetScraper(cfg, connEnv{}, limits, logger)
+	trusted := func(*http.Request, string) bool { return true }
+	handler := probeHandler(scraper, scraper, trusted, modules, promhttp.HandlerOpts{})
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "target with alias",
+			query:    "?target=" + addr + "&alias=payments-redis",
+			wantCode: http.StatusOK,
+			wantBody: `redis_pubsub_exporter_redis_up{alias="payments-redis",target="` + addr + `"} 0`,
+		},
+		{
+			name:     "module",
+			query:    "?target=" + addr + "&module=minimal",
+			wantCode: http.StatusOK,
+			wantBody: `redis_pubsub_exporter_redis_up{target="` + addr + `"} 0`,
+		},
+		{name: "missing target", query: "", wantCode: http.StatusBadRequest, wantBody: "empty target address"},
+		{name: "unknown module", query: "?target=" + addr + "&module=full", wantCode: http.StatusBadRequest, wantBody: `unknown module "full"`},
+		{name: "invalid port", query: "?target=redis:http", wantCode: http.StatusBadRequest, wantBody: "invalid port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/probe"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("want status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("want body containing %q, got:\n%s", tt.wantBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestProbeCredentials(t *testing.T) {
+	// A server that records what clients send before hanging up.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	received := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 4096)
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, _ := conn.Read(buf)
+			received <- string(buf[:n])
+			_ = conn.Close()
+		}
+	}()
+	addr := ln.Addr().String()
+
+	cfg := config.LoadPrefix("PROBE_TEST_")
+	cfg.RedisPassword = "hunter2"
+	cfg.AdminToken = "s3cret"
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limits := collector.NewLimitStore(collector.Limits{MaxChannels: cfg.MaxChannels})
+
+	tests := []struct {
+		name         string
+		configured   bool
+		allowed      []string
+		token        string
+		afterProbe   bool // an untrusted probe went through the handler first
+		wantPassword bool
+	}{
+		{name: "not a configured target"},
+		{name: "configured target", configured: true, wantPassword: true},
+		{name: "allowed", allowed: []string{"127.0.0.1:*"}, wantPassword: true},
+		{name: "not allowed", allowed: []string{"redis-*:6379"}},
+		{name: "admin token", token: "s3cret", wantPassword: true},
+		{name: "wrong token", token: "guess"},
+		{name: "admin token after untrusted probe", token: "s3cret", afterProbe: true, wantPassword: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.ProbeAllowedTargets = tt.allowed
+			configured := singleTarget{collector.New(nil, collector.Options{}, logger), cfg.RedisAddr()}
+			if tt.configured {
+				configured.addr = addr
+			}
+			handler := probeHandler(newTargetScraper(cfg, connEnv{}, limits, logger),
+				newTargetScraper(withoutCredentials(cfg), connEnv{}, limits, logger),
+				probeTrust(cfg, configured), nil, promhttp.HandlerOpts{})
+			if tt.afterProbe {
+				handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe?target="+addr, nil))
+				for len(received) > 0 {
+					<-received
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/probe?target="+addr, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			handler(httptest.NewRecorder(), req)
+
+			var sent strings.Builder
+			for len(received) > 0 {
+				sent.WriteString(<-received)
+			}
+			if sent.Len() == 0 {
+				t.Fatal("probe did not connect")
+			}
+			if got := strings.Contains(sent.String(), "hunter2"); got != tt.wantPassword {
+				t.Errorf("want password sent %v, got %v:\n%q", tt.wantPassword, got, sent.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/dial"
+	"github.com/redis-pubsub-exporter/internal/sampler"
+	"github.com/redis-pubsub-exporter/internal/targets"
+	"github.com/redis-pubsub-exporter/internal/tlsfiles"
+)
+
+// credentialSource supplies Redis credentials that may rotate at runtime,
+// such as *vault.Client.
+type credentialSource interface {
+	Password() string
+	TLSConfig(base *tls.Config) *tls.Config
+}
+
+// connEnv holds runtime dependencies shared by all Redis connections.
+type connEnv struct {
+	creds credentialSource // optional; password and TLS material are read on every new connection
+	base  dial.Func        // optional transport such as an SSH tunnel; nil dials directly
+
+	tlsFiles *tlsfiles.Source // optional; TLS material from files, applied before creds
+	conns    *dial.Tracker    // optional; open connections, closed when tlsFiles change
+}
+
+// dialer returns a dial.Dialer on top of the configured transport. Tunnelled
+// transports resolve host names on the remote side.
+func (e connEnv) dialer(timeout, keepAlive time.Duration, tlsConfig *tls.Config, logger *slog.Logger) *dial.Dialer {
+	d := &dial.Dialer{
+		Base:      dial.Direct(timeout, keepAlive),
+		TLSConfig: tlsConfig,
+		Logger:    logger,
+	}
+	if e.base != nil {
+		d.Base = e.base
+		d.Resolver = dial.PassthroughResolver{}
+	}
+	if tlsConfig != nil && (e.creds != nil || e.tlsFiles != nil) {
+		d.TLSConfigFunc = func() *tls.Config {
+			cfg := tlsConfig
+			if e.tlsFiles != nil {
+				cfg = e.tlsFiles.TLSConfig(cfg)
+			}
+			if e.creds != nil {
+				cfg = e.creds.TLSConfig(cfg)
+			}
+			return cfg
+		}
+	}
+	d.Tracker = e.conns
+	return d
+}
+
+// redisOptions builds client options for the configured connection mode
+// (standalone, sentinel or cluster).
+func redisOptions(cfg *config.Config, env connEnv, logger *slog.Logger) *redis.UniversalOptions {
+	opts := &redis.UniversalOptions{
+		Addrs:        []string{cfg.RedisAddr()},
+		Password:     cfg.RedisPassword,
+		DB:           cfg.RedisDB,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		PoolSize:     5,
+		Protocol:     cfg.RedisProtocol,
+
+		// CLIENT SETINFO (Redis 7.2+) reports lib-name
+		// go-redis(redis-pubsub-exporter_<version>,<go version>) and the
+		// go-redis version as lib-ver; older servers ignore it.
+		ClientName:     cfg.RedisClientName,
+		IdentitySuffix: "redis-pubsub-exporter_" + version,
+	}
+	if cfg.SentinelEnabled() {
+		opts.Addrs = cfg.SentinelAddrs
+		opts.MasterName = cfg.SentinelMaster
+		opts.SentinelPassword = cfg.SentinelPassword
+	}
+	if cfg.ClusterEnabled() {
+		opts.Addrs = cfg.ClusterAddrs
+		opts.IsClusterMode = true
+	}
+	if cfg.RedisTLS {
+		opts.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	// Resolve the target on every dial (and perform TLS there, since go-redis
+	// skips its own TLS handling when a custom dialer is set).
+	d := env.dialer(opts.DialTimeout, cfg.RedisTCPKeepAlive, opts.TLSConfig, logger)
+	d.SRV = cfg.RedisSRV
+	opts.Dialer = d.DialContext
+
+	// Connections are recycled after whichever lifetime is shorter; go-redis
+	// reads a zero idle time as its 30m default and -1 as never.
+	opts.ConnMaxLifetime = cfg.RedisConnMaxLifetime
+	if r := cfg.DNSRefreshInterval; r > 0 && (opts.ConnMaxLifetime == 0 || r < opts.ConnMaxLifetime) {
+		opts.ConnMaxLifetime = r
+	}
+	opts.ConnMaxIdleTime = cfg.RedisConnMaxIdleTime
+	if opts.ConnMaxIdleTime == 0 {
+		opts.ConnMaxIdleTime = -1
+	}
+
+	if env.creds != nil {
+		opts.Password = ""
+		opts.CredentialsProvider = func() (string, string) {
+			return "", env.creds.Password()
+		}
+	}
+	return opts
+}
+
+// addProtocolHook adds a ProtocolHook to rdb, or in cluster mode to every
+// node client, since connections are set up there.
+func addProtocolHook(rdb redis.UniversalClient, protocol int) *collector.ProtocolHook {
+	hook := collector.NewProtocolHook(protocol)
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		cluster.OnNewNode(func(node *redis.Client) { node.AddHook(hook) })
+	} else {
+		rdb.AddHook(hook)
+	}
+	return hook
+}
+
+// addTLSCertHook adds a TLSCertHook to rdb, or in cluster mode to every node
+// client, if TLS is enabled; otherwise it returns nil.
+func addTLSCertHook(rdb redis.UniversalClient, enabled bool) *collector.TLSCertHook {
+	if !enabled {
+		return nil
+	}
+	hook := collector.NewTLSCertHook()
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		cluster.OnNewNode(func(node *redis.Client) { node.AddHook(hook) })
+	} else {
+		rdb.AddHook(hook)
+	}
+	return hook
+}
+
+// newReplicaClient returns a client connected to a replica of the configured
+// master, used for INFO reads with --redis.prefer-replica. In sentinel mode
+// Sentinel picks the replica; in standalone mode the first online replica from
+// INFO replication is used. Returns nil when no replica is available.
+func newReplicaClient(cfg *config.Config, opts *redis.UniversalOptions, env connEnv, master redis.UniversalClient, logger *slog.Logger) redis.UniversalClient {
+	switch {
+	case cfg.ClusterEnabled():
+		logger.Warn("--redis.prefer-replica is not supported in cluster mode, reading INFO from masters")
+		return nil
+
+	case cfg.SentinelEnabled():
+		fo := opts.Failover()
+		fo.ReplicaOnly = true
+		fo.PoolSize = 2
+		logger.Info("reading INFO from sentinel-selected replica", "master", cfg.SentinelMaster)
+		return redis.NewFailoverClient(fo)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := collector.NewQuerier(master).InfoMap(ctx, "replication").Result()
+	if err != nil {
+		logger.Warn("replica discovery failed, reading INFO from master", "error", err)
+		return nil
+	}
+	var section map[string]string
+	for name, s := range info {
+		if strings.EqualFold(name, "replication") {
+			section = s
+		}
+	}
+	replicas := onlineReplicas(section)
+	if len(replicas) == 0 {
+		logger.Warn("no online replica found, reading INFO from master")
+		return nil
+	}
+
+	so := opts.Simple()
+	so.Addr = replicas[0]
+	so.PoolSize = 2
+	// The replica address is explicit; don't let an SRV lookup redirect it.
+	so.Dialer = env.dialer(opts.DialTimeout, cfg.RedisTCPKeepAlive, opts.TLSConfig, logger).DialContext
+	logger.Info("reading INFO from replica", "replica", so.Addr)
+	return redis.NewClient(so)
+}
+
+// dbClients lazily opens a small connection pool per database for key-based
+// metrics, including the configured REDIS_DB, so key reads never share
+// connections with the pub/sub scrape.
+type dbClients struct {
+	opts     *redis.UniversalOptions
+	poolSize int
+
+	mu      sync.Mutex
+	clients map[int]redis.UniversalClient
+}
+
+func newDBClients(opts *redis.UniversalOptions, poolSize int) *dbClients {
+	return &dbClients{opts: opts, poolSize: max(poolSize, 1), clients: make(map[int]redis.UniversalClient)}
+}
+
+// Get returns a reader bound to db.
+func (d *dbClients) Get(db int) collector.KeyReader {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok := d.clients[db]; ok {
+		return c
+	}
+	o := *d.opts
+	o.DB = db
+	o.PoolSize = d.poolSize
+	o.MinIdleConns = 0
+	c := redis.NewUniversalClient(&o)
+	d.clients[db] = c
+	return c
+}
+
+// Close closes the per-database clients.
+func (d *dbClients) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []error
+	for db, c := range d.clients {
+		errs = append(errs, c.Close())
+		delete(d.clients, db)
+	}
+	return errors.Join(errs...)
+}
+
+// newMonitorClient returns a factory for the sampler's MONITOR clients: one
+// dedicated connection each, without a read timeout since MONITOR output can
+// pause for any length of time.
+func newMonitorClient(cfg *config.Config, opts *redis.UniversalOptions) func() sampler.Monitorer {
+	return func() sampler.Monitorer {
+		o := *opts
+		o.PoolSize = 1
+		o.MinIdleConns = 0
+		o.ReadTimeout = -1
+		if cfg.SentinelEnabled() {
+			return redis.NewFailoverClient(o.Failover())
+		}
+		return redis.NewClient(o.Simple())
+	}
+}
+
+// newSubscriberClient returns the sampler's client. A subscription holds its
+// connection for as long as it lasts, so it gets a client of its own instead
+// of a connection from the scrape pool. The read timeout is kept: go-redis
+// waits for messages without one but still applies it to the handshake, so
+// an unresponsive server can't hang the sampler.
+func newSubscriberClient(opts *redis.UniversalOptions) redis.UniversalClient {
+	o := *opts
+	o.PoolSize = 1
+	o.MinIdleConns = 0
+	return redis.NewUniversalClient(&o)
+}
+
+// hashMetricsUseDB reports whether any hash metric reads from a specific db.
+func hashMetricsUseDB(defs []config.HashMetricDef) bool {
+	for _, d := range defs {
+		if d.DB != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// onlineReplicas extracts the addresses of online replicas from the INFO
+// replication section, ordered by replica index. Entries look like:
+//
+//	slave0:ip=10.0.0.2,port=6379,state=online,offset=1234,lag=0
+func onlineReplicas(section map[string]string) []string {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		if strings.HasPrefix(k, "slave") && k != "slave_read_only" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var addrs []string
+	for _, k := range keys {
+		fields := make(map[string]string)
+		for _, pair := range strings.Split(section[k], ",") {
+			if idx := strings.IndexByte(pair, '='); idx >= 0 {
+				fields[pair[:idx]] = pair[idx+1:]
+			}
+		}
+		if fields["state"] != "online" || fields["ip"] == "" || fields["port"] == "" {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(fields["ip"], fields["port"]))
+	}
+	return addrs
+}
+
+// targetSource returns the configured multi-target discovery source.
+func targetSource(cfg *config.Config) targets.Source {
+	if cfg.TargetsFile != "" {
+		return &targets.FileSource{Path: cfg.TargetsFile}
+	}
+	return &targets.ConsulSource{
+		Addr:    cfg.ConsulAddr,
+		Service: cfg.ConsulService,
+		Tag:     cfg.ConsulTag,
+		Token:   cfg.ConsulToken,
+	}
+}
+
+// newTargetFactory returns a targets.Factory that creates a standalone Redis
+// client and collector for each discovered target. All targets share limits.
+func newTargetFactory(cfg *config.Config, env connEnv, limits *collector.LimitStore, logger *slog.Logger) targets.Factory {
+	newScraper := newTargetScraper(cfg, env, limits, logger)
+	return func(t targets.Target) (targets.Scraper, func(), error) {
+		coll, closeFn, err := newScraper(t, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stop := func() {}
+		if cfg.HealthCheckInterval > 0 {
+			var ctx context.Context
+			ctx, stop = context.WithCancel(context.Background())
+			go coll.RunHealthCheck(ctx, cfg.HealthCheckInterval)
+		}
+		return coll, func() {
+			stop()
+			closeFn()
+		}, nil
+	}
+}
+
+// scraperFactory creates a collector for a target, running only the named
+// subsystems if any are given. The returned func closes its Redis clients.
+type scraperFactory func(t targets.Target, collectors []string) (*collector.RedisPubSubCollector, func(), error)
+
+// newTargetScraper returns a scraperFactory for standalone Redis targets,
+// with the target's overrides applied on top of cfg.
+func newTargetScraper(cfg *config.Config, env connEnv, limits *collector.LimitStore, logger *slog.Logger) scraperFactory {
+	clientInclude, clientExclude := cfg.ClientNameFilters()
+	return func(t targets.Target, collectors []string) (*collector.RedisPubSubCollector, func(), error) {
+		host, portStr, err := net.SplitHostPort(t.Addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid port in %q: %w", t.Addr, err)
+		}
+
+		tcfg := *cfg
+		tcfg.RedisHost = host
+		tcfg.RedisPort = port
+		if err := applyOverrides(&tcfg, t.Overrides); err != nil {
+			return nil, nil, fmt.Errorf("target %s: %w", t.Addr, err)
+		}
+
+		// A per-target password replaces shared (e.g. Vault) credentials.
+		tenv := env
+		if t.Overrides.Password != "" || t.Overrides.PasswordFile != "" {
+			tenv.creds = nil
+		}
+
+		disabled := tcfg.ScrapeDisable
+		if len(collectors) > 0 {
+			disabled = nil
+		}
+
+		targetLogger := logger.With("target", t.Addr)
+		opts := redisOptions(&tcfg, tenv, targetLogger)
+		rdb := redis.NewUniversalClient(opts)
+		protocol := addProtocolHook(rdb, tcfg.RedisProtocol)
+		tlsCerts := addTLSCertHook(rdb, tcfg.RedisTLS)
+		dbs := newDBClients(opts, tcfg.HashMetricsPoolSize)
+		coll := collector.New(collector.NewQuerier(rdb), collector.Options{
+			Limits:         limits,
+			KnownPatterns:  tcfg.KnownPatterns,
+			MinSubscribers: tcfg.PatternMinSubscribers,
+			HealthWeights:  tcfg.HealthScoreWeights,
+			SystemChannels: tcfg.SystemChannels,
+			Mode:           tcfg.Mode,
+			HashMetrics:    tcfg.HashMetrics,
+			DBClient:       dbs.Get,
+			DB:             tcfg.RedisDB,
+			MinInterval:    tcfg.ScrapeMinInterval,
+			Timeout:        tcfg.ScrapeTimeout,
+			DiffMinDelta:   tcfg.LogDiffMinDelta,
+			Protocol:       protocol,
+			TLSCerts:       tlsCerts,
+			MaxCommands:    tcfg.ScrapeMaxCommands,
+			MaxDuration:    tcfg.ScrapeMaxDuration,
+
+			CriticalChannels: tcfg.CriticalChannels,
+
+			ClientIncludeName: clientInclude,
+			ClientExcludeName: clientExclude,
+			ClientAggregate:   tcfg.ClientAggregate,
+
+			EnabledSubsystems:  collectors,
+			DisabledSubsystems: disabled,
+
+			FailureBackoff:    tcfg.ScrapeFailureBackoff,
+			FailureBackoffMax: tcfg.ScrapeFailureBackoffMax,
+
+			AuthFailureBackoff: tcfg.ScrapeAuthBackoff,
+
+			ReadyFailureThreshold: tcfg.ReadyFailureThreshold,
+			ReadyMaxStaleness:     tcfg.ReadyMaxStaleness,
+
+			HeartbeatKey: tcfg.HeartbeatKey,
+			HeartbeatTTL: tcfg.HeartbeatTTL,
+
+			HistorySize: tcfg.HistorySize,
+			RateWindow:  tcfg.RateWindow,
+
+			OrphanMinAge:    tcfg.OrphanMinAge,
+			PatternCacheTTL: tcfg.PatternCacheTTL,
+
+			DiscoveryMinChannels: tcfg.PatternDiscoveryMinChannels,
+			DiscoveryMinScrapes:  tcfg.PatternDiscoveryMinScrapes,
+		}, targetLogger)
+
+		return coll, func() {
+			if err := errors.Join(dbs.Close(), rdb.Close()); err != nil {
+				targetLogger.Error("redis close error", "error", err)
+			}
+		}, nil
+	}
+}
+
+// applyOverrides applies per-target settings from the targets file on top of
+// the global configuration.
+func applyOverrides(cfg *config.Config, o targets.Overrides) error {
+	if o.PasswordFile != "" {
+		raw, err := os.ReadFile(o.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("read password file: %w", err)
+		}
+		cfg.RedisPassword = strings.TrimSpace(string(raw))
+	}
+	if o.Password != "" {
+		cfg.RedisPassword = o.Password
+	}
+	if o.TLS != nil {
+		cfg.RedisTLS = *o.TLS
+	}
+	if o.DB != nil {
+		cfg.RedisDB = *o.DB
+	}
+	if len(o.KnownPatterns) > 0 {
+		cfg.KnownPatterns = o.KnownPatterns
+	}
+	return nil
+}
+
+// redactURL hides the password in a URL for logging.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<invalid url>"
+	}
+	return u.Redacted()
+}
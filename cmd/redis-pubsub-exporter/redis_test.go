@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+func TestOnlineReplicas(t *testing.T) {
+	section := map[string]string{
+		"role":             "master",
+		"connected_slaves": "3",
+		"slave_read_only":  "1",
+		"slave0":           "ip=10.0.0.2,port=6379,state=wait_bgsave,offset=0,lag=0",
+		"slave1":           "ip=10.0.0.3,port=6380,state=online,offset=1234,lag=0",
+		"slave2":           "ip=::1,port=6381,state=online,offset=1234,lag=1",
+	}
+
+	got := onlineReplicas(section)
+
+	want := []string{"10.0.0.3:6380", "[::1]:6381"}
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("replica %d: want %q, got %q", i, want[i], got[i])
+		}
+	}
+
+	if got := onlineReplicas(nil); len(got) != 0 {
+		t.Errorf("nil section: want no replicas, got %v", got)
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(pwFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tls, db := true, 3
+
+	tests := []struct {
+		name    string
+		o       targets.Overrides
+		want    config.Config
+		wantErr bool
+	}{
+		{
+			name: "no overrides inherits global",
+			want: config.Config{RedisPassword: "global", RedisDB: 0, KnownPatterns: []string{"global.*"}},
+		},
+		{
+			name: "all overrides",
+			o:    targets.Overrides{PasswordFile: pwFile, TLS: &tls, DB: &db, KnownPatterns: []string{"orders.*"}},
+			want: config.Config{RedisPassword: "from-file", RedisTLS: true, RedisDB: 3, KnownPatterns: []string{"orders.*"}},
+		},
+		{
+			name: "inline password wins over file",
+			o:    targets.Overrides{Password: "inline", PasswordFile: pwFile},
+			want: config.Config{RedisPassword: "inline", KnownPatterns: []string{"global.*"}},
+		},
+		{
+			name:    "missing password file",
+			o:       targets.Overrides{PasswordFile: "/nonexistent"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{RedisPassword: "global", KnownPatterns: []string{"global.*"}}
+			err := applyOverrides(&cfg, tt.o)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.RedisPassword != tt.want.RedisPassword || cfg.RedisTLS != tt.want.RedisTLS || cfg.RedisDB != tt.want.RedisDB {
+				t.Errorf("want password=%q tls=%v db=%d, got password=%q tls=%v db=%d",
+					tt.want.RedisPassword, tt.want.RedisTLS, tt.want.RedisDB, cfg.RedisPassword, cfg.RedisTLS, cfg.RedisDB)
+			}
+			if !slices.Equal(cfg.KnownPatterns, tt.want.KnownPatterns) {
+				t.Errorf("known patterns: want %v, got %v", tt.want.KnownPatterns, cfg.KnownPatterns)
+			}
+		})
+	}
+}
+
+func TestRedisOptionsConnLifetime(t *testing.T) {
+	tests := []struct {
+		name                    string
+		idle, lifetime, refresh time.Duration
+		wantIdle, wantLifetime  time.Duration
+	}{
+		{name: "idle only", idle: 4 * time.Minute, wantIdle: 4 * time.Minute},
+		{name: "idle off", wantIdle: -1},
+		{name: "lifetime", idle: time.Minute, lifetime: time.Hour, wantIdle: time.Minute, wantLifetime: time.Hour},
+		{name: "dns refresh shorter", lifetime: time.Hour, refresh: 5 * time.Minute, wantIdle: -1, wantLifetime: 5 * time.Minute},
+		{name: "dns refresh only", refresh: 5 * time.Minute, wantIdle: -1, wantLifetime: 5 * time.Minute},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				RedisHost:            "localhost",
+				RedisPort:            6379,
+				RedisConnMaxIdleTime: tt.idle,
+				RedisConnMaxLifetime: tt.lifetime,
+				DNSRefreshInterval:   tt.refresh,
+			}
+			opts := redisOptions(cfg, connEnv{}, logger)
+			if opts.ConnMaxIdleTime != tt.wantIdle || opts.ConnMaxLifetime != tt.wantLifetime {
+				t.Errorf("want idle=%s lifetime=%s, got idle=%s lifetime=%s",
+					tt.wantIdle, tt.wantLifetime, opts.ConnMaxIdleTime, opts.ConnMaxLifetime)
+			}
+		})
+	}
+}
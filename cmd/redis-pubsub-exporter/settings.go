@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// secretFlags hold credentials; their values are never shown.
+var secretFlags = map[string]bool{
+	"redis.password":          true,
+	"redis.sentinel-password": true,
+	"web.admin-token":         true,
+}
+
+// setting is the effective value of a flag and where it came from.
+type setting struct {
+	Flag   string `json:"flag"`
+	Value  string `json:"value"`
+	Source string `json:"source"`         // config.SourceDefault, SourceProfile, SourceEnv or SourceFlag
+	From   string `json:"from,omitempty"` // the profile, or the variable that was set
+}
+
+// settingsOf reports every flag of app after parsing args, in definition
+// order. A flag given on the command line wins over its environment
+// variable, which wins over whatever config.Load read (another variable
+// such as EXPORTER_PORT, or a profile default), which wins over the
+// built-in default.
+func settingsOf(app *kingpin.Application, args []string, cfg *config.Config) []setting {
+	onCommandLine := make(map[string]bool)
+	if ctx, err := app.ParseContext(args); err == nil {
+		for _, el := range ctx.Elements {
+			if f, ok := el.Clause.(*kingpin.FlagClause); ok {
+				onCommandLine[f.Model().Name] = true
+			}
+		}
+	}
+
+	var out []setting
+	for _, f := range app.Model().Flags {
+		if f.Hidden || f.Name == "help" || f.Name == "version" {
+			continue
+		}
+		s := setting{Flag: f.Name, Value: f.Value.String()}
+		switch {
+		case onCommandLine[f.Name]:
+			s.Source = config.SourceFlag
+		case f.Envar != "" && os.Getenv(f.Envar) != "":
+			s.Source, s.From = config.SourceEnv, f.Envar
+		case f.Envar != "":
+			s.Source, s.From = cfg.Origin(f.Envar)
+		default:
+			s.Source = config.SourceDefault
+		}
+		switch {
+		case s.Value == "":
+		case secretFlags[f.Name]:
+			s.Value = "<redacted>"
+		case f.Name == "redis.proxy-url":
+			s.Value = redactURL(s.Value)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// printSettings writes settings as a table for --print-config.
+func printSettings(w io.Writer, settings []setting) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, s := range settings {
+		source := s.Source
+		if s.From != "" {
+			source += " " + s.From
+		}
+		_, _ = fmt.Fprintf(tw, "--%s\t%q\t%s\n", s.Flag, s.Value, source)
+	}
+	return tw.Flush()
+}
+
+// configHandler serves /debug/config: the effective settings and their
+// sources as JSON.
+func configHandler(settings []setting) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(settings)
+	}
+}
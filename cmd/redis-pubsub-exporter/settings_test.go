@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+func TestSettingsOf(t *testing.T) {
+	t.Setenv("EXPORTER_PORT", "9999")
+	t.Setenv("REDIS_HOST", "from-env")
+	t.Setenv("REDIS_PASSWORD", "hunter2")
+
+	cfg := config.Load()
+	app := kingpin.New("test", "")
+	app.Flag("web.listen-address", "").Envar("EXPORTER_LISTEN_ADDRESS").Default(cfg.ListenAddress).StringVar(&cfg.ListenAddress)
+	app.Flag("redis.host", "").Envar("REDIS_HOST").Default(cfg.RedisHost).StringVar(&cfg.RedisHost)
+	app.Flag("redis.db", "").Envar("REDIS_DB").Default("0").IntVar(&cfg.RedisDB)
+	app.Flag("redis.password", "").Envar("REDIS_PASSWORD").Default(cfg.RedisPassword).StringVar(&cfg.RedisPassword)
+	app.Flag("max-channels", "").Envar("MAX_CHANNELS").Default("500").IntVar(&cfg.MaxChannels)
+	args := []string{"--max-channels=10"}
+	if _, err := app.Parse(args); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := []setting{
+		{Flag: "web.listen-address", Value: ":9999", Source: config.SourceEnv, From: "EXPORTER_PORT"},
+		{Flag: "redis.host", Value: "from-env", Source: config.SourceEnv, From: "REDIS_HOST"},
+		{Flag: "redis.db", Value: "0", Source: config.SourceDefault},
+		{Flag: "redis.password", Value: "<redacted>", Source: config.SourceEnv, From: "REDIS_PASSWORD"},
+		{Flag: "max-channels", Value: "10", Source: config.SourceFlag},
+	}
+	got := settingsOf(app, args, cfg)
+	if len(got) != len(want) {
+		t.Fatalf("want %d settings, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("want %+v, got %+v", want[i], got[i])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := printSettings(&out, got); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "hunter2") || !strings.Contains(out.String(), "env EXPORTER_PORT") {
+		t.Errorf("unexpected --print-config output:\n%s", out.String())
+	}
+
+	rec := httptest.NewRecorder()
+	configHandler(got)(rec, httptest.NewRequest("GET", "/debug/config", nil))
+	var served []setting
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatalf("decode /debug/config: %v", err)
+	}
+	if len(served) != len(want) || served[0] != want[0] {
+		t.Errorf("/debug/config: want %+v, got %+v", want, served)
+	}
+}
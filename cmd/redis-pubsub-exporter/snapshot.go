@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// snapshotTimeout bounds taking a snapshot for --dump-snapshot; it enumerates
+// every channel and client, so it may take longer than a scrape.
+const snapshotTimeout = time.Minute
+
+// dumpSnapshot connects to the configured Redis and writes a snapshot of its
+// unfiltered state to path as JSON.
+func dumpSnapshot(cfg *config.Config, env connEnv, path string, logger *slog.Logger) error {
+	rdb := redis.NewUniversalClient(redisOptions(cfg, env, logger))
+	defer func() { _ = rdb.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+	s, err := collector.TakeSnapshot(ctx, collector.NewQuerier(rdb), cfg.ClusterEnabled())
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Client addresses and channel names may be sensitive
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	logger.Info("snapshot written",
+		"path", path,
+		"channels", len(s.Channels),
+		"clients", len(s.Clients),
+		"unsupported", s.Unsupported,
+	)
+	return nil
+}
+
+// serveSnapshot serves /metrics from snap, applying the limits, filters and
+// patterns of cfg as if it were a live server, until SIGINT or SIGTERM. It
+// backs the replay command and --selftest.fake-redis.
+func serveSnapshot(cfg *config.Config, snap *collector.ServerSnapshot, logger *slog.Logger) error {
+	clientInclude, clientExclude := cfg.ClientNameFilters()
+	coll := collector.New(collector.NewSnapshotQuerier(snap), collector.Options{
+		Limits: collector.NewLimitStore(collector.Limits{
+			MaxChannels:    cfg.MaxChannels,
+			MaxClients:     cfg.MaxClients,
+			MaxPatterns:    cfg.MaxPatterns,
+			MaxSeries:      cfg.MaxSeries,
+			ChannelInclude: cfg.ChannelInclude,
+			ChannelExclude: cfg.ChannelExclude,
+		}),
+		KnownPatterns:  cfg.KnownPatterns,
+		MinSubscribers: cfg.PatternMinSubscribers,
+		HealthWeights:  cfg.HealthScoreWeights,
+		SystemChannels: cfg.SystemChannels,
+		Mode:           cfg.Mode,
+		ClusterMode:    snap.ClusterInfo != "",
+		Timeout:        cfg.ScrapeTimeout,
+		MaxCommands:    cfg.ScrapeMaxCommands,
+
+		CriticalChannels: cfg.CriticalChannels,
+
+		ClientIncludeName: clientInclude,
+		ClientExcludeName: clientExclude,
+		ClientAggregate:   cfg.ClientAggregate,
+
+		DisabledSubsystems: cfg.ScrapeDisable,
+
+		DiscoveryMinChannels: cfg.PatternDiscoveryMinChannels,
+		DiscoveryMinScrapes:  cfg.PatternDiscoveryMinScrapes,
+	}, logger)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(coll)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}))
+	srv := &http.Server{
+		Addr:         cfg.ListenAddress,
+		Handler:      mux,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: config.HTTPWriteTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "addr", cfg.ListenAddress)
+		errCh <- srv.ListenAndServe()
+	}()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case <-sigCh:
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("serve: %w", err)
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
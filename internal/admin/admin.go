@@ -0,0 +1,183 @@
+// Package admin implements the authenticated runtime admin API, used to
+// adjust collector limits during incidents without a restart.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+// Handler serves the admin API under /api/v1/. Every request must carry
+// "Authorization: Bearer <token>".
+//
+//	GET    /api/v1/config                  current limits
+//	DELETE /api/v1/config                  drop runtime overrides
+//	PUT    /api/v1/config/max-channels     body: number
+//	PUT    /api/v1/config/max-clients      body: number
+//	PUT    /api/v1/config/channel-include  body: array of globs
+//	PUT    /api/v1/config/channel-exclude  body: array of globs
+//	GET    /api/v1/patterns                runtime pattern changes
+//	POST   /api/v1/patterns                body: {"pattern": "orders.*"}
+//	DELETE /api/v1/patterns                body: {"pattern": "orders.*"}
+//	GET    /api/v1/snapshot                raw scrape state (see HandleSnapshot)
+//	GET    /api/v1/history                 recent scrape summaries (see Handle)
+//
+// Overrides apply to every collector sharing the LimitStore and last until
+// they are reset or the configuration is reloaded.
+type Handler struct {
+	limits *collector.LimitStore
+	token  string
+	logger *slog.Logger
+	mux    *http.ServeMux
+}
+
+// configResponse is returned by every /api/v1/config endpoint.
+type configResponse struct {
+	collector.Limits
+	Overridden bool `json:"overridden"`
+}
+
+// New returns the admin API handler. token must not be empty.
+func New(limits *collector.LimitStore, token string, logger *slog.Logger) *Handler {
+	h := &Handler{limits: limits, token: token, logger: logger, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /api/v1/config", func(w http.ResponseWriter, _ *http.Request) {
+		h.writeConfig(w, h.limits.Get())
+	})
+	h.mux.HandleFunc("DELETE /api/v1/config", func(w http.ResponseWriter, _ *http.Request) {
+		h.logger.Info("admin: runtime limits reset")
+		h.writeConfig(w, h.limits.Reset())
+	})
+	h.mux.HandleFunc("PUT /api/v1/config/max-channels", h.putInt("max-channels", 1, func(l *collector.Limits, n int) { l.MaxChannels = n }))
+	h.mux.HandleFunc("PUT /api/v1/config/max-clients", h.putInt("max-clients", 0, func(l *collector.Limits, n int) { l.MaxClients = n }))
+	h.mux.HandleFunc("PUT /api/v1/config/max-patterns", h.putInt("max-patterns", 0, func(l *collector.Limits, n int) { l.MaxPatterns = n }))
+	h.mux.HandleFunc("PUT /api/v1/config/max-series", h.putInt("max-series", 0, func(l *collector.Limits, n int) { l.MaxSeries = n }))
+	h.mux.HandleFunc("PUT /api/v1/config/channel-include", h.putList("channel-include", func(l *collector.Limits, v []string) { l.ChannelInclude = v }))
+	h.mux.HandleFunc("PUT /api/v1/config/channel-exclude", h.putList("channel-exclude", func(l *collector.Limits, v []string) { l.ChannelExclude = v }))
+	h.mux.HandleFunc("GET /api/v1/patterns", func(w http.ResponseWriter, _ *http.Request) {
+		h.writePatterns(w, h.limits.Get())
+	})
+	h.mux.HandleFunc("POST /api/v1/patterns", h.patternOp("added", (*collector.Limits).AddPattern))
+	h.mux.HandleFunc("DELETE /api/v1/patterns", h.patternOp("removed", (*collector.Limits).RemovePattern))
+	return h
+}
+
+// HandleSnapshot serves GET /api/v1/snapshot from take: the unfiltered
+// Redis state as JSON, for replaying offline. It is only behind the admin
+// token because it contains client addresses and every channel name.
+func (h *Handler) HandleSnapshot(take func(ctx context.Context) (*collector.ServerSnapshot, error)) {
+	h.mux.HandleFunc("GET /api/v1/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		s, err := take(r.Context())
+		if err != nil {
+			h.logger.Error("admin: snapshot failed", "error", err)
+			http.Error(w, "snapshot failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		h.logger.Info("admin: snapshot taken", "channels", len(s.Channels), "clients", len(s.Clients))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s)
+	})
+}
+
+// Handle serves handler at pattern, such as "GET /api/v1/history", behind
+// the admin token.
+func (h *Handler) Handle(pattern string, handler http.Handler) {
+	h.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="redis-pubsub-exporter"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	return Authorized(r, h.token)
+}
+
+// Authorized reports whether r carries "Authorization: Bearer <token>".
+// token must not be empty.
+func Authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// putInt sets a limit to a number of at least minimum; 0 means unlimited for
+// the limits that allow it.
+func (h *Handler) putInt(name string, minimum int, set func(*collector.Limits, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			http.Error(w, fmt.Sprintf("%s: want a number: %v", name, err), http.StatusBadRequest)
+			return
+		}
+		if n < minimum {
+			http.Error(w, fmt.Sprintf("%s: must be at least %d", name, minimum), http.StatusBadRequest)
+			return
+		}
+		h.logger.Info("admin: runtime limit changed", "limit", name, "value", n)
+		h.writeConfig(w, h.limits.Update(func(l *collector.Limits) { set(l, n) }))
+	}
+}
+
+func (h *Handler) putList(name string, set func(*collector.Limits, []string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var v []string
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			http.Error(w, fmt.Sprintf("%s: want an array of strings: %v", name, err), http.StatusBadRequest)
+			return
+		}
+		h.logger.Info("admin: runtime filter changed", "filter", name, "value", v)
+		h.writeConfig(w, h.limits.Update(func(l *collector.Limits) { set(l, v) }))
+	}
+}
+
+// patternRequest is the body of POST and DELETE /api/v1/patterns.
+type patternRequest struct {
+	Pattern string `json:"pattern"`
+}
+
+// patternsResponse is returned by the /api/v1/patterns endpoints.
+type patternsResponse struct {
+	Patterns        []string `json:"patterns"`
+	IgnoredPatterns []string `json:"ignored_patterns"`
+}
+
+func (h *Handler) patternOp(verb string, apply func(*collector.Limits, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req patternRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("want {\"pattern\": ...}: %v", err), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Pattern) == "" {
+			http.Error(w, "pattern must not be empty", http.StatusBadRequest)
+			return
+		}
+		h.logger.Info("admin: pattern "+verb, "pattern", req.Pattern)
+		h.writePatterns(w, h.limits.Update(func(l *collector.Limits) { apply(l, req.Pattern) }))
+	}
+}
+
+func (h *Handler) writePatterns(w http.ResponseWriter, l collector.Limits) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(patternsResponse{
+		Patterns:        l.Patterns,
+		IgnoredPatterns: l.IgnoredPatterns,
+	})
+}
+
+func (h *Handler) writeConfig(w http.ResponseWriter, l collector.Limits) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(configResponse{Limits: l, Overridden: h.limits.Overridden()})
+}
@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+func TestHandler(t *testing.T) {
+	store := collector.NewLimitStore(collector.Limits{MaxChannels: 500})
+	h := New(store, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		token    string
+		wantCode int
+		want     collector.Limits
+	}{
+		{name: "no token", method: http.MethodGet, path: "/api/v1/config", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/api/v1/config", token: "nope", wantCode: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, path: "/api/v1/config", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 500}},
+		{name: "raise max channels", method: http.MethodPut, path: "/api/v1/config/max-channels", body: "5000", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 5000}},
+		{name: "set max clients", method: http.MethodPut, path: "/api/v1/config/max-clients", body: "50", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 5000, MaxClients: 50}},
+		{name: "exclude channels", method: http.MethodPut, path: "/api/v1/config/channel-exclude", body: `["debug.*"]`, token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 5000, MaxClients: 50, ChannelExclude: []string{"debug.*"}}},
+		{name: "set max patterns", method: http.MethodPut, path: "/api/v1/config/max-patterns", body: "20", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 5000, MaxClients: 50, MaxPatterns: 20, ChannelExclude: []string{"debug.*"}}},
+		{name: "set max series", method: http.MethodPut, path: "/api/v1/config/max-series", body: "10000", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 5000, MaxClients: 50, MaxPatterns: 20, MaxSeries: 10000, ChannelExclude: []string{"debug.*"}}},
+		{name: "zero max channels", method: http.MethodPut, path: "/api/v1/config/max-channels", body: "0", token: "s3cret", wantCode: http.StatusBadRequest},
+		{name: "negative limit", method: http.MethodPut, path: "/api/v1/config/max-clients", body: "-1", token: "s3cret", wantCode: http.StatusBadRequest},
+		{name: "invalid body", method: http.MethodPut, path: "/api/v1/config/channel-include", body: `"orders.*"`, token: "s3cret", wantCode: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodPost, path: "/api/v1/config/max-channels", body: "1", token: "s3cret", wantCode: http.StatusMethodNotAllowed},
+		{name: "reset", method: http.MethodDelete, path: "/api/v1/config", token: "s3cret", wantCode: http.StatusOK,
+			want: collector.Limits{MaxChannels: 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("want status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got configResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.MaxChannels != tt.want.MaxChannels || got.MaxClients != tt.want.MaxClients || got.MaxPatterns != tt.want.MaxPatterns ||
+				!slices.Equal(got.ChannelExclude, tt.want.ChannelExclude) {
+				t.Errorf("want %+v, got %+v", tt.want, got.Limits)
+			}
+			if store.Get().MaxChannels != tt.want.MaxChannels {
+				t.Errorf("store not updated: %+v", store.Get())
+			}
+		})
+	}
+}
+
+func TestHandlerPatterns(t *testing.T) {
+	store := collector.NewLimitStore(collector.Limits{MaxChannels: 500})
+	h := New(store, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		wantCode    int
+		wantPattern []string
+		wantIgnored []string
+	}{
+		{name: "add", method: http.MethodPost, body: `{"pattern": "orders.*"}`, wantCode: http.StatusOK, wantPattern: []string{"orders.*"}},
+		{name: "add twice", method: http.MethodPost, body: `{"pattern": "orders.*"}`, wantCode: http.StatusOK, wantPattern: []string{"orders.*"}},
+		{name: "remove runtime pattern", method: http.MethodDelete, body: `{"pattern": "orders.*"}`, wantCode: http.StatusOK},
+		{name: "remove known pattern", method: http.MethodDelete, body: `{"pattern": "users.*"}`, wantCode: http.StatusOK, wantIgnored: []string{"users.*"}},
+		{name: "re-add ignored pattern", method: http.MethodPost, body: `{"pattern": "users.*"}`, wantCode: http.StatusOK, wantPattern: []string{"users.*"}},
+		{name: "list", method: http.MethodGet, wantCode: http.StatusOK, wantPattern: []string{"users.*"}},
+		{name: "empty pattern", method: http.MethodPost, body: `{"pattern": " "}`, wantCode: http.StatusBadRequest},
+		{name: "invalid body", method: http.MethodPost, body: `orders.*`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/patterns", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer s3cret")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("want status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got patternsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !slices.Equal(got.Patterns, tt.wantPattern) || !slices.Equal(got.IgnoredPatterns, tt.wantIgnored) {
+				t.Errorf("want %v / ignored %v, got %+v", tt.wantPattern, tt.wantIgnored, got)
+			}
+		})
+	}
+}
+
+func TestHandlerSnapshot(t *testing.T) {
+	store := collector.NewLimitStore(collector.Limits{})
+	h := New(store, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h.HandleSnapshot(func(context.Context) (*collector.ServerSnapshot, error) {
+		return &collector.ServerSnapshot{Version: collector.SnapshotVersion, Channels: map[string]int64{"orders.1": 2}}, nil
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{name: "no token", wantCode: http.StatusUnauthorized},
+		{name: "snapshot", token: "s3cret", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/snapshot", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("want status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got collector.ServerSnapshot
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Channels["orders.1"] != 2 {
+				t.Errorf("want orders.1 with 2 subscribers, got %+v", got.Channels)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	h := New(collector.NewLimitStore(collector.Limits{}), "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h.Handle("GET /api/v1/history", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "[]")
+	}))
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{name: "no token", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", token: "nope", wantCode: http.StatusUnauthorized},
+		{name: "history", token: "s3cret", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/history", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("want status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
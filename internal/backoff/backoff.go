@@ -0,0 +1,36 @@
+// Package backoff computes the delays of reconnect loops, such as those of
+// the message sampler and the Sentinel watcher.
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is an exponential backoff with jitter. The zero value is not
+// usable; set Min and Max.
+type Backoff struct {
+	Min time.Duration // delay after the first failure
+	Max time.Duration // upper bound of the delay
+
+	attempt int
+}
+
+// Next returns the delay before the next attempt: Min doubled per call since
+// the last Reset and capped at Max, of which a random half is dropped. The
+// jitter keeps exporters that lost Redis in the same failover from all
+// reconnecting at the same moment.
+func (b *Backoff) Next() time.Duration {
+	d := b.Max
+	if b.attempt < 32 && b.Min<<b.attempt < b.Max {
+		d = b.Min << b.attempt
+		b.attempt++
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Reset starts over at Min, e.g. after a connection was established.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
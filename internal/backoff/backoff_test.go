@@ -0,0 +1,20 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 5 * time.Second}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := b.Next(); got < want/2 || got > want {
+			t.Errorf("attempt %d: want a delay between %v and %v, got %v", i, want/2, want, got)
+		}
+	}
+
+	b.Reset()
+	if got := b.Next(); got < time.Second/2 || got > time.Second {
+		t.Errorf("after reset: want a delay between 500ms and 1s, got %v", got)
+	}
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BenchmarkCollect drives Collect end to end against FakeSnapshot, with
+// every channel exported and with the default MaxChannels guard truncating
+// them. Compare runs with benchstat to catch per-channel overhead:
+//
+//	go test -run x -bench Collect -count 10 ./internal/collector/ > new.txt
+func BenchmarkCollect(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, channels := range []int{1_000, 10_000, 100_000} {
+		snap := FakeSnapshot(channels, channels/100)
+		for _, bm := range []struct {
+			name        string
+			maxChannels int
+		}{
+			{name: "all", maxChannels: channels},
+			{name: "limited", maxChannels: 500},
+		} {
+			b.Run(strconv.Itoa(channels)+"/"+bm.name, func(b *testing.B) {
+				c := New(NewSnapshotQuerier(snap), Options{
+					MaxChannels:   bm.maxChannels,
+					KnownPatterns: []string{"orders.*", "users.*"},
+				}, logger)
+				b.ReportAllocs()
+				for b.Loop() {
+					drain(c)
+				}
+			})
+		}
+	}
+}
+
+// drain runs one collection and discards the metrics.
+func drain(c prometheus.Collector) {
+	ch := make(chan prometheus.Metric, 1024)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+	for range ch {
+	}
+}
@@ -0,0 +1,27 @@
+package collector
+
+import "time"
+
+// OverBudget reports whether the scrape has used up its command or time
+// budget (Options.MaxCommands, Options.MaxDuration). Subsystems check it
+// before optional per-item queries and skip them once it is exhausted, so
+// totals are still reported but the scrape's load on Redis stays bounded.
+func (s *ScrapeState) OverBudget() bool {
+	if s.exceeded {
+		return true
+	}
+	if s.maxCommands > 0 && s.count.commands >= s.maxCommands ||
+		s.maxDuration > 0 && time.Since(s.start) >= s.maxDuration {
+		s.exceeded = true
+	}
+	return s.exceeded
+}
+
+// commandsLeft returns how many commands the budget still allows, or -1 if
+// it is unlimited.
+func (s *ScrapeState) commandsLeft() int {
+	if s.maxCommands <= 0 {
+		return -1
+	}
+	return max(s.maxCommands-s.count.commands, 0)
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCollectBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxCommands  int
+		maxDuration  time.Duration
+		wantExceeded float64
+		wantPresent  []string
+		wantAbsent   []string
+	}{
+		{
+			name:        "unlimited",
+			wantPresent: []string{"redis_pubsub_clients_total{}", "redis_pubsub_pattern_subscriber_count{pattern=users.*}"},
+		},
+		{
+			// PING, INFO, PUBSUB CHANNELS and NUMSUB use it up.
+			name:         "commands exhausted before CLIENT LIST",
+			maxCommands:  4,
+			wantExceeded: 1,
+			wantPresent: []string{
+				"redis_pubsub_channels_total{}",
+				"redis_pubsub_channel_subscriber_count{channel=orders.created}",
+				"redis_pubsub_patterns_total{}",
+			},
+			wantAbsent: []string{"redis_pubsub_clients_total{}", "redis_pubsub_pattern_subscriber_count{pattern=orders.*}"},
+		},
+		{
+			// One command left for the two patterns; known ones go first.
+			name:         "patterns cut to the remaining commands",
+			maxCommands:  7,
+			wantExceeded: 1,
+			wantPresent:  []string{"redis_pubsub_clients_total{}", "redis_pubsub_pattern_subscriber_count{pattern=orders.*}"},
+			wantAbsent:   []string{"redis_pubsub_pattern_subscriber_count{pattern=users.*}"},
+		},
+		{
+			name:         "duration exhausted",
+			maxDuration:  time.Nanosecond,
+			wantExceeded: 1,
+			wantPresent:  []string{"redis_pubsub_channels_total{}", "redis_pubsub_patterns_total{}"},
+			wantAbsent: []string{
+				"redis_pubsub_channel_subscriber_count{channel=orders.created}",
+				"redis_pubsub_orphan_channels_total{}",
+				"redis_pubsub_clients_total{}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{
+				channels:   map[string]int64{"orders.created": 1, "users.login": 2},
+				clientList: "id=1 addr=10.0.0.1:1 name=users sub=1 psub=0",
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			c := New(q, Options{
+				MaxChannels:   100,
+				KnownPatterns: []string{"orders.*"},
+				MaxCommands:   tt.maxCommands,
+				MaxDuration:   tt.maxDuration,
+			}, logger)
+
+			got := collect(t, c)
+			if got["redis_pubsub_exporter_redis_up{}"] != 1 {
+				t.Fatal("a scrape over budget is still successful")
+			}
+			if v := got["redis_pubsub_exporter_scrape_budget_exceeded{}"]; v != tt.wantExceeded {
+				t.Errorf("want budget_exceeded %v, got %v", tt.wantExceeded, v)
+			}
+			for _, key := range tt.wantPresent {
+				if _, ok := got[key]; !ok {
+					t.Errorf("%s should be emitted", key)
+				}
+			}
+			for _, key := range tt.wantAbsent {
+				if _, ok := got[key]; ok {
+					t.Errorf("%s should be skipped", key)
+				}
+			}
+		})
+	}
+}
@@ -7,14 +7,29 @@ import (
 
 // PubSubClient represents a Redis client with pub/sub subscriptions.
 type PubSubClient struct {
+	ID   int64  // client id, unique for the lifetime of the server
 	Addr string // client address (ip:port)
 	Name string // client name (from CLIENT SETNAME)
 	Sub  int    // number of channel subscriptions (SUBSCRIBE)
 	PSub int    // number of pattern subscriptions (PSUBSCRIBE)
+	OMem int64  // output buffer memory; messages the subscriber has not read yet
+	Cmd  string // last command the client ran
+
+	// Redis 7+ fields; zero values when the server does not report them.
+	LAddr  string // local (server-side) address the client connected to
+	Resp   int    // RESP protocol version negotiated via HELLO (2 or 3)
+	TotMem int64  // total memory consumed by the client in its various buffers
+	Redir  int64  // client id of the tracking redirection target, -1 if none
+	SSub   int    // number of shard channel subscriptions (SSUBSCRIBE)
+
+	// Redis 7.2+ fields: bytes read from and written to the connection.
+	NetIn  int64
+	NetOut int64
 }
 
 // ParseClientList parses the output of Redis CLIENT LIST command
-// and returns only clients that have active pub/sub subscriptions.
+// and returns only clients that have active pub/sub subscriptions,
+// sharded ones included.
 //
 // CLIENT LIST returns lines like:
 //
@@ -39,8 +54,9 @@ func ParseClientList(raw string) []PubSubClient {
 
 		sub := parseIntField(fields, "sub")
 		psub := parseIntField(fields, "psub")
+		ssub := parseIntField(fields, "ssub")
 
-		if sub == 0 && psub == 0 {
+		if sub == 0 && psub == 0 && ssub == 0 {
 			continue
 		}
 
@@ -55,16 +71,47 @@ func ParseClientList(raw string) []PubSubClient {
 		}
 
 		clients = append(clients, PubSubClient{
-			Addr: addr,
-			Name: name,
-			Sub:  sub,
-			PSub: psub,
+			ID:     parseInt64Field(fields, "id"),
+			Addr:   addr,
+			Name:   name,
+			Sub:    sub,
+			PSub:   psub,
+			OMem:   parseInt64Field(fields, "omem"),
+			Cmd:    fields["cmd"],
+			LAddr:  fields["laddr"],
+			Resp:   parseIntField(fields, "resp"),
+			TotMem: parseInt64Field(fields, "tot-mem"),
+			Redir:  parseInt64Field(fields, "redir"),
+			SSub:   ssub,
+			NetIn:  parseInt64Field(fields, "tot-net-in"),
+			NetOut: parseInt64Field(fields, "tot-net-out"),
 		})
 	}
 
 	return clients
 }
 
+// CountClientFlags counts the clients in CLIENT LIST output carrying each
+// flag, e.g. "S" (replica), "M" (master), "O" (MONITOR), "b" (blocked), "P"
+// (pub/sub) or "N" (none of them). Unlike ParseClientList it includes all
+// clients.
+func CountClientFlags(raw string) map[string]int {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		for _, pair := range strings.Fields(line) {
+			flags, ok := strings.CutPrefix(pair, "flags=")
+			if !ok {
+				continue
+			}
+			for _, f := range flags {
+				counts[string(f)]++
+			}
+			break
+		}
+	}
+	return counts
+}
+
 func parseIntField(fields map[string]string, key string) int {
 	v, ok := fields[key]
 	if !ok {
@@ -76,3 +123,15 @@ func parseIntField(fields map[string]string, key string) int {
 	}
 	return i
 }
+
+func parseInt64Field(fields map[string]string, key string) int64 {
+	v, ok := fields[key]
+	if !ok {
+		return 0
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
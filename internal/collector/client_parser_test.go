@@ -1,7 +1,9 @@
 package collector
 
 import (
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -107,6 +109,54 @@ func TestParseClientList(t *testing.T) {
 				assertClient(t, clients[0], "heavy", "10.0.0.1:1234", 9999, 500)
 			},
 		},
+		{
+			name:  "redis 7 fields are parsed",
+			input: "id=7 addr=10.0.0.1:5000 laddr=10.0.0.100:6379 fd=8 name=resp3-app age=1 idle=0 flags=P db=0 sub=1 psub=1 ssub=0 multi=-1 watch=0 qbuf=0 qbuf-free=0 argv-mem=0 multi-mem=0 rbs=1024 rbp=0 obl=0 oll=0 omem=0 tot-mem=22426 events=r cmd=psubscribe user=default redir=-1 resp=3 lib-name=go-redis lib-ver=9.18.0",
+			want:  1,
+			checks: func(t *testing.T, clients []PubSubClient) {
+				t.Helper()
+				c := clients[0]
+				assertClient(t, c, "resp3-app", "10.0.0.1:5000", 1, 1)
+				if c.LAddr != "10.0.0.100:6379" {
+					t.Errorf("laddr: want %q, got %q", "10.0.0.100:6379", c.LAddr)
+				}
+				if c.Resp != 3 {
+					t.Errorf("resp: want 3, got %d", c.Resp)
+				}
+				if c.TotMem != 22426 {
+					t.Errorf("tot-mem: want 22426, got %d", c.TotMem)
+				}
+				if c.Redir != -1 {
+					t.Errorf("redir: want -1, got %d", c.Redir)
+				}
+				if c.Cmd != "psubscribe" {
+					t.Errorf("cmd: want psubscribe, got %q", c.Cmd)
+				}
+			},
+		},
+		{
+			name:  "sharded-only subscriber is included",
+			input: "id=8 addr=10.0.0.1:6000 name=shard-app flags=P sub=0 psub=0 ssub=4 cmd=ssubscribe",
+			want:  1,
+			checks: func(t *testing.T, clients []PubSubClient) {
+				t.Helper()
+				if clients[0].SSub != 4 {
+					t.Errorf("ssub: want 4, got %d", clients[0].SSub)
+				}
+			},
+		},
+		{
+			name:  "pre-redis 7 output leaves new fields zero",
+			input: "id=1 addr=10.0.0.1:1234 name=legacy sub=1 psub=0",
+			want:  1,
+			checks: func(t *testing.T, clients []PubSubClient) {
+				t.Helper()
+				c := clients[0]
+				if c.Resp != 0 || c.LAddr != "" || c.TotMem != 0 || c.Redir != 0 {
+					t.Errorf("expected zero redis 7 fields, got %+v", c)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -160,3 +210,52 @@ func BenchmarkParseClientList(b *testing.B) {
 func itoa(i int) string {
 	return strconv.Itoa(i)
 }
+
+func FuzzParseClientList(f *testing.F) {
+	f.Add("id=1 addr=10.0.0.1:12345 fd=5 name=orders-service sub=3 psub=0 cmd=subscribe")
+	f.Add("id=1 addr=10.0.0.1:1234 name=app=v2 sub=1 psub=0\nid=2 name= sub=0 psub=2")
+	f.Add("garbage data without equals signs\n\n\r\n")
+	f.Add("id=1 name=\x00\xff\xfe sub=-1 psub=99999999999999999999")
+	f.Add("sub=1 psub=1 name=" + strings.Repeat("x", 4096))
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		for _, c := range ParseClientList(raw) {
+			if c.Sub == 0 && c.PSub == 0 && c.SSub == 0 {
+				t.Errorf("client without subscriptions returned: %+v", c)
+			}
+			if c.Name == "" || c.Addr == "" {
+				t.Errorf("client with empty name or addr returned: %+v", c)
+			}
+			if strings.Contains(c.Name, "\n") || strings.Contains(c.Addr, "\n") {
+				t.Errorf("client field spans lines: %+v", c)
+			}
+		}
+	})
+}
+
+func TestCountClientFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]int
+	}{
+		{name: "empty", raw: "", want: map[string]int{}},
+		{
+			name: "mixed",
+			raw: "id=1 addr=10.0.0.1:1 flags=P sub=1\n" +
+				"id=2 addr=10.0.0.2:2 flags=S sub=0\n" +
+				"id=3 addr=10.0.0.3:3 flags=bP sub=0\n" +
+				"id=4 addr=10.0.0.4:4 flags=N name=flags=x",
+			want: map[string]int{"P": 2, "S": 1, "b": 1, "N": 1},
+		},
+		{name: "no flags field", raw: "id=1 addr=10.0.0.1:1 sub=1", want: map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CountClientFlags(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
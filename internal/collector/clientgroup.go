@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"cmp"
+	"net"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// How per-client series are broken down.
+const (
+	ClientAggregateNone = "none" // one series per connection (default)
+	ClientAggregateIP   = "ip"   // summed per source IP, for clients that never SETNAME
+)
+
+// ipClients is the sum of the pub/sub connections from one source IP.
+type ipClients struct {
+	IP    string
+	Conns int
+	Sub   int
+	PSub  int
+	OMem  int64
+}
+
+// groupByIP sums clients per source IP, dropping the port, ordered by IP.
+func groupByIP(clients []PubSubClient) []ipClients {
+	index := make(map[string]int)
+	var groups []ipClients
+	for _, cl := range clients {
+		ip := cl.Addr
+		if host, _, err := net.SplitHostPort(cl.Addr); err == nil {
+			ip = host
+		}
+		i, ok := index[ip]
+		if !ok {
+			i = len(groups)
+			index[ip] = i
+			groups = append(groups, ipClients{IP: ip})
+		}
+		g := &groups[i]
+		g.Conns++
+		g.Sub += cl.Sub
+		g.PSub += cl.PSub
+		g.OMem += cl.OMem
+	}
+	slices.SortFunc(groups, func(a, b ipClients) int { return cmp.Compare(a.IP, b.IP) })
+	return groups
+}
+
+// collectClientsByIP emits the per-IP sums of clients, capped at maxClients
+// IPs (0 = unlimited).
+func (c *RedisPubSubCollector) collectClientsByIP(ch chan<- prometheus.Metric, clients []PubSubClient, maxClients int) {
+	groups := groupByIP(clients)
+	if maxClients > 0 && len(groups) > maxClients {
+		c.logger.Warn("client IP count exceeds MAX_CLIENTS, truncating per-IP metrics",
+			"count", len(groups), "max", maxClients)
+		groups = groups[:maxClients]
+	}
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.ipConnections, prometheus.GaugeValue, float64(g.Conns), g.IP)
+		ch <- prometheus.MustNewConstMetric(c.ipChannelSubs, prometheus.GaugeValue, float64(g.Sub), g.IP)
+		ch <- prometheus.MustNewConstMetric(c.ipPatternSubs, prometheus.GaugeValue, float64(g.PSub), g.IP)
+		ch <- prometheus.MustNewConstMetric(c.ipOutputBuf, prometheus.GaugeValue, float64(g.OMem), g.IP)
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clusterSlotStates maps CLUSTER INFO slot fields to the "state" label value.
+var clusterSlotStates = []struct {
+	field string
+	state string
+}{
+	{"cluster_slots_assigned", "assigned"},
+	{"cluster_slots_ok", "ok"},
+	{"cluster_slots_pfail", "pfail"},
+	{"cluster_slots_fail", "fail"},
+}
+
+// scrapeClusterInfo emits cluster state, slot health and node counts.
+// A partially failed cluster silently drops sharded pub/sub messages, so
+// these are surfaced next to the pub/sub metrics.
+func (c *RedisPubSubCollector) scrapeClusterInfo(ctx context.Context, ch chan<- prometheus.Metric, q RedisQuerier) error {
+	raw, err := q.ClusterInfo(ctx).Result()
+	if err != nil {
+		return err
+	}
+	info := parseInfoLines(raw)
+
+	state := 0.0
+	if info["cluster_state"] == "ok" {
+		state = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(c.clusterState, prometheus.GaugeValue, state)
+
+	for _, s := range clusterSlotStates {
+		if v, ok := info[s.field]; ok {
+			ch <- prometheus.MustNewConstMetric(c.clusterSlots, prometheus.GaugeValue, parseFloat(v), s.state)
+		}
+	}
+	if v, ok := info["cluster_known_nodes"]; ok {
+		ch <- prometheus.MustNewConstMetric(c.clusterKnownNodes, prometheus.GaugeValue, parseFloat(v))
+	}
+	if v, ok := info["cluster_size"]; ok {
+		ch <- prometheus.MustNewConstMetric(c.clusterSize, prometheus.GaugeValue, parseFloat(v))
+	}
+	return nil
+}
+
+// parseInfoLines parses "key:value" lines as returned by CLUSTER INFO and INFO.
+// Comment lines ("# Section") and lines without a colon are skipped.
+func parseInfoLines(raw string) map[string]string {
+	out := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+		out[line[:idx]] = line[idx+1:]
+	}
+	return out
+}
@@ -0,0 +1,23 @@
+package collector
+
+import "testing"
+
+func TestParseInfoLines(t *testing.T) {
+	raw := "# Cluster\r\ncluster_state:ok\r\ncluster_slots_ok:16384\r\n\r\nno colon here\r\nkey_with_colon:a:b\r\n"
+
+	got := parseInfoLines(raw)
+
+	want := map[string]string{
+		"cluster_state":    "ok",
+		"cluster_slots_ok": "16384",
+		"key_with_colon":   "a:b",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d entries, got %d: %v", len(want), len(got), got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: want %q, got %q", k, v, got[k])
+		}
+	}
+}
@@ -1,20 +1,31 @@
 package collector
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/redis/go-redis/v9"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/redis-pubsub-exporter/internal/config"
 )
 
-const namespace = "redis_pubsub"
+// Namespace prefixes the names of all collector metrics.
+const Namespace = "redis_pubsub"
+
+// DefaultScrapeTimeout bounds a scrape's Redis queries unless
+// Options.Timeout is set.
+const DefaultScrapeTimeout = 10 * time.Second
 
 // hashMetricDesc pairs a config definition with its pre-built prometheus descriptor.
 type hashMetricDesc struct {
@@ -22,16 +33,195 @@ type hashMetricDesc struct {
 	desc *prometheus.Desc
 }
 
+// Options configures a RedisPubSubCollector.
+type Options struct {
+	MaxChannels    int                    // high cardinality guard for per-channel metrics
+	MaxClients     int                    // per-client series cap; 0 = unlimited
+	MaxPatterns    int                    // patterns queried per scrape, configured ones first; 0 = unlimited
+	MaxSeries      int                    // last-ditch cap on series per scrape; 0 = unlimited
+	ChannelInclude []string               // only track channels matching these globs (all if empty)
+	ChannelExclude []string               // never track channels matching these globs
+	SystemChannels string                 // keyspace notification channels: SystemChannelsInclude (default), Separate or Exclude
+	Mode           string                 // ModeFull (default), or ModeInfoOnly to read the counts from INFO without enumerating channels
+	KnownPatterns  []string               // patterns always checked for activity
+	MinSubscribers map[string]int         // expected minimum pattern_subscriber_count; such patterns are always checked
+	HashMetrics    []config.HashMetricDef // user-configured hash gauges
+	ClusterMode    bool                   // query CLUSTER INFO on each scrape
+	InfoClient     RedisQuerier           // optional replica for INFO reads; nil uses the main client
+	DBClient       func(db int) KeyReader // optional; reads all hash metrics, per database; nil uses the main client
+	DB             int                    // database of the main client, for hash metrics without db=
+	MinInterval    time.Duration          // scrapes within this window of the last one are served from cache; 0 disables
+	Timeout        time.Duration          // bound on the Redis queries of one scrape; 0 uses DefaultScrapeTimeout
+	DiffMinDelta   int                    // subscriber change logged by the debug-level scrape diff; 0 = 1
+	Protocol       *ProtocolHook          // optional; reports the negotiated RESP version, must be added to the client
+	TLSCerts       *TLSCertHook           // optional; reports server certificate expiry, must be added to the client
+
+	// CriticalChannels are queried on every scrape and always get their
+	// channel_subscriber_count, even if the filters or MaxChannels leave
+	// them out; a fall to zero subscribers since the previous scrape is
+	// reported by subscriber_drop_detected and counted in
+	// subscriber_drops_total.
+	CriticalChannels []string
+
+	// ClientIncludeName and ClientExcludeName, if set, restrict the
+	// per-client series to clients whose name matches include and not
+	// exclude, e.g. to leave out redis-cli sessions; clients_total and the
+	// per-RESP and per-command counts still cover all pub/sub clients.
+	ClientIncludeName *regexp.Regexp
+	ClientExcludeName *regexp.Regexp
+
+	// ClientAggregate is ClientAggregateNone for per-connection client
+	// series, or ClientAggregateIP to sum them per source IP instead.
+	ClientAggregate string
+
+	// HealthWeights weighs the components of health_score (HealthUp,
+	// HealthOrphans, HealthShortfall, HealthLatency); nil uses
+	// DefaultHealthWeights.
+	HealthWeights map[string]float64
+
+	// MaxCommands and MaxDuration bound the load of one scrape: once it has
+	// issued MaxCommands Redis commands or run for MaxDuration, totals are
+	// still collected but per-item detail is skipped. 0 disables.
+	MaxCommands int
+	MaxDuration time.Duration
+
+	// After a failed scrape, Redis is not queried again for FailureBackoff,
+	// doubling per consecutive failure up to FailureBackoffMax; scrapes in
+	// between replay the failed result. 0 disables.
+	FailureBackoff    time.Duration
+	FailureBackoffMax time.Duration
+
+	// After a NOAUTH or WRONGPASS reply, Redis (including health check
+	// pings) is left alone for at least AuthFailureBackoff, since retrying
+	// with the same credentials only fills its log. 0 uses FailureBackoff.
+	AuthFailureBackoff time.Duration
+
+	// IsRedisUp turns false after ReadyFailureThreshold consecutive failed
+	// scrapes (default 1) or when the last successful scrape is older than
+	// ReadyMaxStaleness (0 disables).
+	ReadyFailureThreshold int
+	ReadyMaxStaleness     time.Duration
+
+	// HeartbeatKey, if set, is written with HeartbeatTTL after every
+	// successful scrape so other tooling can see the exporter is alive.
+	HeartbeatKey string
+	HeartbeatTTL time.Duration
+
+	// OrphanMinAge is how long a channel must stay without subscribers to
+	// count towards orphan_channels_persistent (0 disables the metric).
+	OrphanMinAge time.Duration
+
+	// Activity, if set, reports message traffic per channel; channels with
+	// subscribers but no message within SilentWindow are exported as silent.
+	Activity     ActivitySource
+	SilentWindow time.Duration
+
+	// RateWindow exports channel creation, subscriber change and client
+	// churn rates averaged over this window (0 disables).
+	RateWindow time.Duration
+
+	// DiscoveryMinChannels and DiscoveryMinScrapes only query an
+	// auto-discovered prefix once it has covered at least that many channels
+	// in that many scrapes (1 = right away).
+	DiscoveryMinChannels int
+	DiscoveryMinScrapes  int
+
+	// PatternCacheTTL reuses a pattern's matching channel count for this
+	// long instead of querying it on every scrape (0 disables).
+	PatternCacheTTL time.Duration
+
+	// HistorySize keeps summaries of the last HistorySize scrapes for
+	// History (0 disables).
+	HistorySize int
+
+	// Subsystems are run after the built-in ones on every scrape, e.g. to
+	// export application-specific metrics. EnabledSubsystems, if set,
+	// restricts the scrape to the named built-in or extra subsystems;
+	// DisabledSubsystems names subsystems to skip.
+	Subsystems         []Subsystem
+	EnabledSubsystems  []string
+	DisabledSubsystems []string
+
+	// Limits, if set, is shared with other collectors and the admin API and
+	// takes precedence over MaxChannels, MaxClients and the channel filters.
+	Limits *LimitStore
+}
+
 // RedisPubSubCollector implements prometheus.Collector.
 // It queries Redis on every Prometheus scrape and returns fresh metrics.
 type RedisPubSubCollector struct {
-	client        *redis.Client
-	maxChannels   int
+	client        RedisQuerier
+	infoClient    RedisQuerier // INFO reads; may be a replica
+	dbClient      func(db int) KeyReader
+	protocol      *ProtocolHook
+	tlsCerts      *TLSCertHook
+	db            int
+	limits        *LimitStore
 	knownPatterns []string
+	minSubs       map[string]int
+	clientInclude *regexp.Regexp
+	clientExclude *regexp.Regexp
+	clientsByIP   bool
+	healthWeights map[string]float64
+	systemMode    string
+	infoOnly      bool
+	clusterMode   bool
+	minInterval   time.Duration
+	timeout       time.Duration
+	backoff       time.Duration
+	backoffMax    time.Duration
+	authBackoff   time.Duration
+	readyFailures int
+	readyStale    time.Duration
+	heartbeatKey  string
+	heartbeatTTL  time.Duration
+	orphanMinAge  time.Duration
+	activity      ActivitySource
+	silentWindow  time.Duration
 	logger        *slog.Logger
 
-	mu      sync.RWMutex // RWMutex: Collect holds write, IsRedisUp holds read
-	redisUp bool         // cached for health checks
+	// scrapeMu serializes Redis scrapes and guards the scrape state below it.
+	scrapeMu     sync.Mutex
+	unsupported  map[string]time.Time // commands the server rejected, when last sent
+	scrapeErrors float64              // persists across scrapes
+	errorCounts  map[errorKey]float64 // failures by phase and type, persist across scrapes
+	authFailures float64              // scrapes and pings rejected by AUTH, persists across scrapes
+	authRetryAt  time.Time            // no Redis queries, not even pings, before this after an auth failure
+	retryAt      time.Time            // no Redis queries before this after a failure
+	rates        *rateTracker         // nil if RateWindow is unset
+	orphanSince  map[string]time.Time // first scrape each current orphan was seen orphaned
+	net          netTracker           // pub/sub client network totals
+	patternCache *patternCache        // nil if PatternCacheTTL is unset
+	discovery    *prefixDiscovery     // nil if every discovered prefix is queried
+	diffMinDelta int64
+	maxCommands  int
+	maxDuration  time.Duration
+	lastSubs     map[string]int64 // subscribers per channel of the previous scrape, for logDiff
+	subsystems   []Subsystem      // run in order on every scrape
+	health       healthInputs     // of the last scrape, for health_score
+
+	// Critical channels, with their subscribers at the previous scrape and
+	// how often they fell to zero
+	criticalChannels []string
+	criticalLast     map[string]int64
+	criticalDrops    map[string]float64
+
+	// mu guards the results of the last scrape; it is never held while
+	// querying Redis, so health checks and stale reads don't block.
+	mu          sync.RWMutex
+	failures    int                 // consecutive failed scrapes; written with scrapeMu also held
+	lastScrape  time.Time           // start of the last Redis scrape
+	running     time.Time           // start of the Redis scrape in progress; zero if none
+	lastSuccess time.Time           // start of the last successful scrape
+	lastErr     error               // error of the last scrape, if it failed
+	cached      []prometheus.Metric // metrics of the last scrape
+	history     *history            // summaries of recent scrapes; nil if disabled
+
+	// Health check pings only tell whether Redis is reachable; they never
+	// stand in for a scrape.
+	pingFailures int       // consecutive failed pings since the last successful ping or scrape
+	lastPing     time.Time // start of the last ping
+	pingErr      error     // error of the last ping, if it failed
 
 	// ---- metric descriptors ----
 
@@ -40,295 +230,1370 @@ type RedisPubSubCollector struct {
 	channelsTotal          *prometheus.Desc
 	orphanChannelsTotal    *prometheus.Desc
 
+	orphanChannelsPersistent *prometheus.Desc
+	orphanChannelMaxAge      *prometheus.Desc
+
+	channelSilent  *prometheus.Desc
+	silentChannels *prometheus.Desc
+
+	systemChannelSubscriberCount *prometheus.Desc
+	systemChannelsTotal          *prometheus.Desc
+
+	subscriberDropDetected *prometheus.Desc
+	subscriberDrops        *prometheus.Desc
+
 	// Pattern metrics
 	patternSubscriberCount *prometheus.Desc
 	patternsTotal          *prometheus.Desc
+	patternShortfall       *prometheus.Desc
 
 	// Client metrics
 	clientsTotal      *prometheus.Desc
 	clientChannelSubs *prometheus.Desc
 	clientPatternSubs *prometheus.Desc
+	clientOutputBuf   *prometheus.Desc
+	clientsByResp     *prometheus.Desc
+	clientsByFlag     *prometheus.Desc
+	clientsByCommand  *prometheus.Desc
+	pubsubNetInput    *prometheus.Desc
+	pubsubNetOutput   *prometheus.Desc
+
+	// Client metrics per source IP
+	ipConnections *prometheus.Desc
+	ipChannelSubs *prometheus.Desc
+	ipPatternSubs *prometheus.Desc
+	ipOutputBuf   *prometheus.Desc
+
+	// Derived rates
+	channelCreationRate  *prometheus.Desc
+	subscriberChangeRate *prometheus.Desc
+	clientChurnRate      *prometheus.Desc
 
 	// Redis health
 	redisUpDesc           *prometheus.Desc
 	redisConnectedClients *prometheus.Desc
-	redisUsedMemoryBytes  *prometheus.Desc
+	redisProtocol         *prometheus.Desc
+	info                  infoDescs
+
+	// Cluster health (cluster mode only)
+	clusterState      *prometheus.Desc
+	clusterSlots      *prometheus.Desc
+	clusterKnownNodes *prometheus.Desc
+	clusterSize       *prometheus.Desc
 
 	// Exporter health
 	scrapeDurationSeconds *prometheus.Desc
 	scrapeErrorsTotal     *prometheus.Desc
+	authFailuresTotal     *prometheus.Desc
+	errorsTotal           *prometheus.Desc
+	scrapeStale           *prometheus.Desc
+	seriesLimited         *prometheus.Desc
+	collectorDuration     *prometheus.Desc
+	collectorSuccess      *prometheus.Desc
+	scrapeCommands        *prometheus.Desc
+	scrapeRoundTrips      *prometheus.Desc
+	budgetExceeded        *prometheus.Desc
+	degraded              *prometheus.Desc
+	commandUnsupported    *prometheus.Desc
+	tlsCertExpiry         *prometheus.Desc
+	limit                 *prometheus.Desc
+	healthScoreDesc       *prometheus.Desc
+	scrapeTimeout         *prometheus.Desc
 
-	// Hash metrics (generic, user-configured)
-	hashMetrics []hashMetricDesc
+	// Exporter resources, sampled after each scrape
+	scrapeAllocBytes   *prometheus.Desc
+	scrapeAllocObjects *prometheus.Desc
+	heapBytes          *prometheus.Desc
+	goroutines         *prometheus.Desc
+	trackedEntries     *prometheus.Desc
 
-	// Internal counter for scrape errors (persists across scrapes)
-	scrapeErrors float64
+	// Hash metrics (generic, user-configured)
+	hashMetrics  []hashMetricDesc
+	hashDuration *prometheus.Desc
 }
 
 // New creates a new RedisPubSubCollector.
-func New(client *redis.Client, maxChannels int, knownPatterns []string, hashDefs []config.HashMetricDef, logger *slog.Logger) *RedisPubSubCollector {
+func New(client RedisQuerier, opts Options, logger *slog.Logger) *RedisPubSubCollector {
 	// Build prometheus descriptors for each hash metric definition.
-	hashDescs := make([]hashMetricDesc, 0, len(hashDefs))
-	for _, def := range hashDefs {
+	hashDescs := make([]hashMetricDesc, 0, len(opts.HashMetrics))
+	for _, def := range opts.HashMetrics {
 		hashDescs = append(hashDescs, hashMetricDesc{
 			def: def,
 			desc: prometheus.NewDesc(
-				namespace+"_"+def.MetricName,
+				Namespace+"_"+def.MetricName,
 				def.Help,
 				[]string{def.FieldLabel}, nil,
 			),
 		})
 	}
 
-	return &RedisPubSubCollector{
+	infoClient := opts.InfoClient
+	if infoClient == nil {
+		infoClient = client
+	}
+	limits := opts.Limits
+	if limits == nil {
+		limits = NewLimitStore(Limits{
+			MaxChannels:    opts.MaxChannels,
+			MaxClients:     opts.MaxClients,
+			MaxPatterns:    opts.MaxPatterns,
+			MaxSeries:      opts.MaxSeries,
+			ChannelInclude: opts.ChannelInclude,
+			ChannelExclude: opts.ChannelExclude,
+		})
+	}
+
+	c := &RedisPubSubCollector{
 		client:        client,
-		maxChannels:   maxChannels,
-		knownPatterns: knownPatterns,
+		infoClient:    infoClient,
+		dbClient:      opts.DBClient,
+		protocol:      opts.Protocol,
+		tlsCerts:      opts.TLSCerts,
+		db:            opts.DB,
+		limits:        limits,
+		knownPatterns: knownPatterns(opts.KnownPatterns, opts.MinSubscribers),
+		minSubs:       opts.MinSubscribers,
+		clientInclude: opts.ClientIncludeName,
+		clientExclude: opts.ClientExcludeName,
+		clientsByIP:   opts.ClientAggregate == ClientAggregateIP,
+		healthWeights: opts.HealthWeights,
+		systemMode:    cmp.Or(opts.SystemChannels, SystemChannelsInclude),
+		infoOnly:      opts.Mode == ModeInfoOnly,
+		clusterMode:   opts.ClusterMode,
+		minInterval:   opts.MinInterval,
+		timeout:       cmp.Or(opts.Timeout, DefaultScrapeTimeout),
+		diffMinDelta:  int64(max(opts.DiffMinDelta, 1)),
+		maxCommands:   opts.MaxCommands,
+		maxDuration:   opts.MaxDuration,
+		backoff:       opts.FailureBackoff,
+		backoffMax:    opts.FailureBackoffMax,
+		authBackoff:   cmp.Or(opts.AuthFailureBackoff, opts.FailureBackoff),
+		readyFailures: max(opts.ReadyFailureThreshold, 1),
+		readyStale:    opts.ReadyMaxStaleness,
+		heartbeatKey:  opts.HeartbeatKey,
+		heartbeatTTL:  opts.HeartbeatTTL,
+		orphanMinAge:  opts.OrphanMinAge,
+		activity:      opts.Activity,
+		silentWindow:  opts.SilentWindow,
+		rates:         newRateTracker(opts.RateWindow),
+		patternCache:  newPatternCache(opts.PatternCacheTTL),
+		discovery:     newPrefixDiscovery(opts.DiscoveryMinChannels, opts.DiscoveryMinScrapes),
+		history:       newHistory(opts.HistorySize),
 		logger:        logger,
 
+		criticalChannels: slices.Compact(slices.Sorted(slices.Values(opts.CriticalChannels))),
+		criticalLast:     make(map[string]int64),
+		criticalDrops:    make(map[string]float64),
+
 		// Channel
 		channelSubscriberCount: prometheus.NewDesc(
-			namespace+"_channel_subscriber_count",
+			Namespace+"_channel_subscriber_count",
 			"Number of direct subscribers per channel",
 			[]string{"channel"}, nil,
 		),
 		channelsTotal: prometheus.NewDesc(
-			namespace+"_channels_total",
+			Namespace+"_channels_total",
 			"Total number of active pub/sub channels",
 			nil, nil,
 		),
 		orphanChannelsTotal: prometheus.NewDesc(
-			namespace+"_orphan_channels_total",
+			Namespace+"_orphan_channels_total",
 			"Number of channels with zero direct subscribers",
 			nil, nil,
 		),
 
+		orphanChannelsPersistent: prometheus.NewDesc(
+			Namespace+"_orphan_channels_persistent",
+			"Number of channels that have had no subscribers for longer than the orphan minimum age",
+			nil, nil,
+		),
+		orphanChannelMaxAge: prometheus.NewDesc(
+			Namespace+"_orphan_channel_max_age_seconds",
+			"Longest time any current channel has gone without subscribers",
+			nil, nil,
+		),
+
+		channelSilent: prometheus.NewDesc(
+			Namespace+"_channel_silent",
+			"Set to 1 for channels with subscribers but no message within the silent window",
+			[]string{"channel"}, nil,
+		),
+		silentChannels: prometheus.NewDesc(
+			Namespace+"_silent_channels",
+			"Number of channels with subscribers but no message within the silent window",
+			nil, nil,
+		),
+
+		systemChannelSubscriberCount: prometheus.NewDesc(
+			Namespace+"_system_channel_subscriber_count",
+			"Number of direct subscribers per keyspace notification channel",
+			[]string{"channel"}, nil,
+		),
+		systemChannelsTotal: prometheus.NewDesc(
+			Namespace+"_system_channels_total",
+			"Total number of active keyspace notification channels",
+			nil, nil,
+		),
+
+		subscriberDropDetected: prometheus.NewDesc(
+			Namespace+"_channel_subscriber_drop_detected",
+			"1 if a critical channel had subscribers at the previous scrape and has none now",
+			[]string{"channel"}, nil,
+		),
+		subscriberDrops: prometheus.NewDesc(
+			Namespace+"_channel_subscriber_drops_total",
+			"Number of times a critical channel fell to zero subscribers between scrapes",
+			[]string{"channel"}, nil,
+		),
+
 		// Pattern
 		patternSubscriberCount: prometheus.NewDesc(
-			namespace+"_pattern_subscriber_count",
+			Namespace+"_pattern_subscriber_count",
 			"Number of channels matching this pattern with active subscribers",
 			[]string{"pattern"}, nil,
 		),
 		patternsTotal: prometheus.NewDesc(
-			namespace+"_patterns_total",
+			Namespace+"_patterns_total",
 			"Total number of active pub/sub pattern subscriptions",
 			nil, nil,
 		),
+		patternShortfall: prometheus.NewDesc(
+			Namespace+"_pattern_subscriber_shortfall",
+			"1 if pattern_subscriber_count is below the configured minimum for this pattern",
+			[]string{"pattern"}, nil,
+		),
 
 		// Client
 		clientsTotal: prometheus.NewDesc(
-			namespace+"_clients_total",
+			Namespace+"_clients_total",
 			"Total number of clients with pub/sub subscriptions",
 			nil, nil,
 		),
 		clientChannelSubs: prometheus.NewDesc(
-			namespace+"_client_channel_subscriptions",
+			Namespace+"_client_channel_subscriptions",
 			"Number of channel subscriptions per client",
 			[]string{"client_name", "client_addr"}, nil,
 		),
 		clientPatternSubs: prometheus.NewDesc(
-			namespace+"_client_pattern_subscriptions",
+			Namespace+"_client_pattern_subscriptions",
 			"Number of pattern subscriptions per client",
 			[]string{"client_name", "client_addr"}, nil,
 		),
+		clientOutputBuf: prometheus.NewDesc(
+			Namespace+"_client_output_buffer_bytes",
+			"Output buffer memory of a subscribed client; grows when the subscriber can't keep up",
+			[]string{"client_name", "client_addr"}, nil,
+		),
+		clientsByCommand: prometheus.NewDesc(
+			Namespace+"_clients_by_command",
+			"Number of pub/sub clients per last command run (subscribe, psubscribe, ssubscribe, their unsubscribe counterparts, ping or other)",
+			[]string{"command"}, nil,
+		),
+		pubsubNetInput: prometheus.NewDesc(
+			Namespace+"_clients_net_input_bytes_total",
+			"Bytes Redis read from pub/sub client connections (Redis 7.2+)",
+			nil, nil,
+		),
+		pubsubNetOutput: prometheus.NewDesc(
+			Namespace+"_clients_net_output_bytes_total",
+			"Bytes Redis wrote to pub/sub client connections, i.e. delivered messages (Redis 7.2+)",
+			nil, nil,
+		),
+		clientsByFlag: prometheus.NewDesc(
+			Namespace+"_clients_by_flag",
+			"Number of Redis clients (not only pub/sub) carrying each CLIENT LIST flag, e.g. S=replica, M=master, O=monitor, b=blocked, P=pub/sub, N=none",
+			[]string{"flag"}, nil,
+		),
+		clientsByResp: prometheus.NewDesc(
+			Namespace+"_clients_by_resp",
+			"Number of pub/sub clients per negotiated RESP protocol version (unknown before Redis 7)",
+			[]string{"resp"}, nil,
+		),
+
+		// Client per IP
+		ipConnections: prometheus.NewDesc(
+			Namespace+"_client_ip_connections",
+			"Number of pub/sub connections per client source IP",
+			[]string{"client_ip"}, nil,
+		),
+		ipChannelSubs: prometheus.NewDesc(
+			Namespace+"_client_ip_channel_subscriptions",
+			"Number of channel subscriptions of the connections from a client source IP",
+			[]string{"client_ip"}, nil,
+		),
+		ipPatternSubs: prometheus.NewDesc(
+			Namespace+"_client_ip_pattern_subscriptions",
+			"Number of pattern subscriptions of the connections from a client source IP",
+			[]string{"client_ip"}, nil,
+		),
+		ipOutputBuf: prometheus.NewDesc(
+			Namespace+"_client_ip_output_buffer_bytes",
+			"Output buffer memory of the subscribed connections from a client source IP",
+			[]string{"client_ip"}, nil,
+		),
+
+		// Derived rates
+		channelCreationRate: prometheus.NewDesc(
+			Namespace+"_channel_creation_rate",
+			"New channels per second, averaged over the rate window",
+			nil, nil,
+		),
+		subscriberChangeRate: prometheus.NewDesc(
+			Namespace+"_subscriber_change_rate",
+			"Channel subscriptions added or removed per second, averaged over the rate window",
+			nil, nil,
+		),
+		clientChurnRate: prometheus.NewDesc(
+			Namespace+"_client_churn_rate",
+			"Pub/sub clients connected or disconnected per second, averaged over the rate window",
+			nil, nil,
+		),
 
 		// Redis health
 		redisUpDesc: prometheus.NewDesc(
-			namespace+"_exporter_redis_up",
+			Namespace+"_exporter_redis_up",
 			"Whether Redis is reachable (1=up, 0=down)",
 			nil, nil,
 		),
 		redisConnectedClients: prometheus.NewDesc(
-			namespace+"_exporter_redis_connected_clients",
+			Namespace+"_exporter_redis_connected_clients",
 			"Total number of connected Redis clients",
 			nil, nil,
 		),
-		redisUsedMemoryBytes: prometheus.NewDesc(
-			namespace+"_exporter_redis_used_memory_bytes",
-			"Redis used memory in bytes",
+		redisProtocol: prometheus.NewDesc(
+			Namespace+"_exporter_redis_protocol_info",
+			"RESP protocol version configured for the exporter's connections and the one negotiated with the server, which is 2 if the server or a proxy rejects HELLO",
+			[]string{"configured", "negotiated"}, nil,
+		),
+		info: newInfoDescs(),
+
+		// Cluster health
+		clusterState: prometheus.NewDesc(
+			Namespace+"_cluster_state",
+			"Whether CLUSTER INFO reports cluster_state:ok (1=ok, 0=fail)",
+			nil, nil,
+		),
+		clusterSlots: prometheus.NewDesc(
+			Namespace+"_cluster_slots",
+			"Number of hash slots per state as reported by CLUSTER INFO",
+			[]string{"state"}, nil,
+		),
+		clusterKnownNodes: prometheus.NewDesc(
+			Namespace+"_cluster_known_nodes",
+			"Number of nodes known to the cluster, including handshaking and failed nodes",
+			nil, nil,
+		),
+		clusterSize: prometheus.NewDesc(
+			Namespace+"_cluster_size",
+			"Number of master nodes serving at least one hash slot",
 			nil, nil,
 		),
 
 		// Exporter health
 		scrapeDurationSeconds: prometheus.NewDesc(
-			namespace+"_exporter_scrape_duration_seconds",
+			Namespace+"_exporter_scrape_duration_seconds",
 			"Duration of the last scrape",
 			nil, nil,
 		),
 		scrapeErrorsTotal: prometheus.NewDesc(
-			namespace+"_exporter_scrape_errors_total",
+			Namespace+"_exporter_scrape_errors_total",
 			"Total number of scrape errors",
 			nil, nil,
 		),
+		authFailuresTotal: prometheus.NewDesc(
+			Namespace+"_exporter_auth_failures_total",
+			"Scrapes and health check pings that Redis rejected with NOAUTH or WRONGPASS",
+			nil, nil,
+		),
+		errorsTotal: prometheus.NewDesc(
+			Namespace+"_exporter_errors_total",
+			"Failed Redis commands by scrape phase (ping or subsystem) and type: auth, permission, timeout, protocol, unsupported or other",
+			[]string{"phase", "type"}, nil,
+		),
+		scrapeStale: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_stale",
+			"1 if these metrics are from the previous scrape because another scrape was still querying Redis",
+			nil, nil,
+		),
+		seriesLimited: prometheus.NewDesc(
+			Namespace+"_exporter_series_limited",
+			"1 if the last scrape exceeded the series limit and metrics were dropped",
+			nil, nil,
+		),
+		collectorDuration: prometheus.NewDesc(
+			Namespace+"_exporter_collector_duration_seconds",
+			"Duration of each scrape subsystem in the last scrape",
+			[]string{"collector"}, nil,
+		),
+		scrapeCommands: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_redis_commands",
+			"Redis commands issued by the last scrape",
+			nil, nil,
+		),
+		scrapeRoundTrips: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_redis_round_trips",
+			"Redis round trips of the last scrape; pipelined commands share one",
+			nil, nil,
+		),
+		healthScoreDesc: prometheus.NewDesc(
+			Namespace+"_health_score",
+			"Weighted health of the last scrape from 0 (bad) to 1 (good): Redis up, share of channels with subscribers, share of patterns at their minimum and scrape latency",
+			nil, nil,
+		),
+		limit: prometheus.NewDesc(
+			Namespace+"_exporter_limit",
+			"Effective cardinality and scrape limit, e.g. max_channels to compare with channels_total (0 = unlimited)",
+			[]string{"limit"}, nil,
+		),
+		scrapeTimeout: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_timeout_seconds",
+			"Time a scrape may spend querying Redis before it is aborted",
+			nil, nil,
+		),
+		degraded: prometheus.NewDesc(
+			Namespace+"_exporter_degraded",
+			"1 while commands rejected by the server or a proxy in front of it are skipped, with counts taken from INFO where possible",
+			nil, nil,
+		),
+		commandUnsupported: prometheus.NewDesc(
+			Namespace+"_exporter_command_unsupported",
+			"Set to 1 for each command the server rejected as unsupported; it is retried every 10 minutes",
+			[]string{"command"}, nil,
+		),
+		tlsCertExpiry: prometheus.NewDesc(
+			Namespace+"_exporter_redis_tls_cert_expiry_timestamp_seconds",
+			"Unix time at which the first certificate of the chain presented by a Redis server expires, per dialed address",
+			[]string{"addr"}, nil,
+		),
+		budgetExceeded: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_budget_exceeded",
+			"1 if the last scrape ran out of its command or time budget and skipped per-item metrics",
+			nil, nil,
+		),
+		collectorSuccess: prometheus.NewDesc(
+			Namespace+"_exporter_collector_success",
+			"1 if the scrape subsystem succeeded in the last scrape, 0 if it failed",
+			[]string{"collector"}, nil,
+		),
+
+		// Exporter resources
+		scrapeAllocBytes: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_allocated_bytes",
+			"Bytes allocated by the exporter process while the last scrape ran",
+			nil, nil,
+		),
+		scrapeAllocObjects: prometheus.NewDesc(
+			Namespace+"_exporter_scrape_allocations",
+			"Heap objects allocated by the exporter process while the last scrape ran",
+			nil, nil,
+		),
+		heapBytes: prometheus.NewDesc(
+			Namespace+"_exporter_heap_bytes",
+			"Bytes of live and not yet swept heap objects after the last scrape",
+			nil, nil,
+		),
+		goroutines: prometheus.NewDesc(
+			Namespace+"_exporter_goroutines",
+			"Number of exporter goroutines after the last scrape",
+			nil, nil,
+		),
+		trackedEntries: prometheus.NewDesc(
+			Namespace+"_exporter_tracked_entries",
+			"Entries the exporter keeps between scrapes per internal tracker (orphan_channels, rate_channels, rate_clients, net_clients, pattern_cache, pattern_discovery, diff_channels)",
+			[]string{"tracker"}, nil,
+		),
 
 		// Hash metrics
 		hashMetrics: hashDescs,
+		hashDuration: prometheus.NewDesc(
+			Namespace+"_exporter_hash_metrics_duration_seconds",
+			"Duration of reading the hash metrics of each database in the last scrape",
+			[]string{"db"}, nil,
+		),
+	}
+	if c.healthWeights == nil {
+		c.healthWeights = DefaultHealthWeights()
 	}
+	c.subsystems = c.registerSubsystems(opts.Subsystems, opts.EnabledSubsystems, opts.DisabledSubsystems)
+	return c
 }
 
 // Describe sends all metric descriptors to the channel.
 func (c *RedisPubSubCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.channelSubscriberCount
-	ch <- c.channelsTotal
-	ch <- c.orphanChannelsTotal
-	ch <- c.patternSubscriberCount
-	ch <- c.patternsTotal
-	ch <- c.clientsTotal
-	ch <- c.clientChannelSubs
-	ch <- c.clientPatternSubs
+	for _, sub := range c.subsystems {
+		sub.Describe(ch)
+	}
 	ch <- c.redisUpDesc
-	ch <- c.redisConnectedClients
-	ch <- c.redisUsedMemoryBytes
 	ch <- c.scrapeDurationSeconds
 	ch <- c.scrapeErrorsTotal
-	for _, hm := range c.hashMetrics {
-		ch <- hm.desc
+	ch <- c.authFailuresTotal
+	ch <- c.errorsTotal
+	ch <- c.scrapeStale
+	ch <- c.seriesLimited
+	ch <- c.collectorDuration
+	ch <- c.collectorSuccess
+	ch <- c.scrapeCommands
+	ch <- c.scrapeRoundTrips
+	ch <- c.budgetExceeded
+	ch <- c.degraded
+	ch <- c.commandUnsupported
+	if c.tlsCerts != nil {
+		ch <- c.tlsCertExpiry
 	}
+	ch <- c.limit
+	ch <- c.healthScoreDesc
+	ch <- c.scrapeTimeout
+	ch <- c.scrapeAllocBytes
+	ch <- c.scrapeAllocObjects
+	ch <- c.heapBytes
+	ch <- c.goroutines
+	ch <- c.trackedEntries
 }
 
 // Collect is called by Prometheus on each scrape.
 // redis_up is emitted exactly once per scrape to avoid duplicate metric panics.
+// With MinInterval set, scrapes arriving within the window of the previous
+// one replay its metrics instead of querying Redis again. A scrape arriving
+// while another one is querying Redis is served the previous snapshot right
+// away (with scrape_stale 1) instead of queueing behind it.
 func (c *RedisPubSubCollector) Collect(ch chan<- prometheus.Metric) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.CollectContext(context.Background(), ch)
+}
+
+// CollectContext is Collect bound to ctx, typically the context of the
+// /metrics request: when it is cancelled the in-flight Redis commands are
+// aborted and the scrape is dropped without touching the cached result or
+// the failure counters.
+func (c *RedisPubSubCollector) CollectContext(parent context.Context, ch chan<- prometheus.Metric) {
+	if !c.scrapeMu.TryLock() {
+		if snapshot := c.snapshot(); snapshot != nil {
+			c.emit(ch, snapshot, true)
+			return
+		}
+		// Nothing to serve yet: wait for the running scrape.
+		c.scrapeMu.Lock()
+	}
+	defer c.scrapeMu.Unlock()
 
 	start := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	c.mu.RLock()
+	fresh := c.minInterval > 0 && c.cached != nil && start.Sub(c.lastScrape) < c.minInterval
+	c.mu.RUnlock()
+	if fresh || (c.cached != nil && start.Before(c.retryAt)) {
+		c.emit(ch, c.snapshot(), false)
+		return
+	}
+
+	c.setRunning(start)
+	defer c.setRunning(time.Time{})
+
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
 	defer cancel()
 
 	up := 0.0
-	if err := c.scrape(ctx, ch); err != nil {
+	before := readRuntimeStats()
+	var err error
+	snapshot := gather(func(ch chan<- prometheus.Metric) { err = c.scrape(ctx, ch) })
+	if err != nil && parent.Err() != nil {
+		c.logger.Debug("scrape cancelled", "error", parent.Err())
+		return
+	}
+	if err != nil {
 		c.scrapeErrors++
-		c.logger.Error("scrape failed", "error", err)
+		if errors.Is(err, ErrAuth) {
+			c.authFailures++
+		}
 	} else {
 		up = 1.0
 	}
 
-	c.redisUp = up == 1.0
-	ch <- prometheus.MustNewConstMetric(c.redisUpDesc, prometheus.GaugeValue, up)
-	ch <- prometheus.MustNewConstMetric(c.scrapeErrorsTotal, prometheus.CounterValue, c.scrapeErrors)
-	ch <- prometheus.MustNewConstMetric(c.scrapeDurationSeconds, prometheus.GaugeValue, time.Since(start).Seconds())
+	// The exporter's own metrics below are never dropped.
+	limited := 0.0
+	if limit := c.limits.Get().MaxSeries; limit > 0 && len(snapshot) > limit {
+		c.logger.Warn("series count exceeds MAX_SERIES, dropping labeled series",
+			"count", len(snapshot), "max", limit)
+		snapshot = c.limitSeries(snapshot, limit)
+		limited = 1
+	}
+
+	duration := time.Since(start)
+	snapshot = append(snapshot, gather(func(ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(c.redisUpDesc, prometheus.GaugeValue, up)
+		ch <- prometheus.MustNewConstMetric(c.scrapeErrorsTotal, prometheus.CounterValue, c.scrapeErrors)
+		ch <- prometheus.MustNewConstMetric(c.authFailuresTotal, prometheus.CounterValue, c.authFailures)
+		ch <- prometheus.MustNewConstMetric(c.scrapeDurationSeconds, prometheus.GaugeValue, duration.Seconds())
+		ch <- prometheus.MustNewConstMetric(c.seriesLimited, prometheus.GaugeValue, limited)
+		c.collectLimits(ch)
+		c.collectHealthScore(ch, err == nil, duration)
+		c.collectSelf(ch, before)
+	})...)
+
+	c.mu.Lock()
+	c.cached = snapshot
+	c.history.add(c.summarize(start, duration, err, snapshot))
+	c.lastScrape = start
+	c.lastErr = err
+	if err != nil {
+		c.failures++
+	} else {
+		c.failures = 0
+		c.lastSuccess = start
+		c.pingFailures, c.pingErr = 0, nil
+	}
+	c.mu.Unlock()
+
+	c.retryAt = time.Time{}
+	if err != nil {
+		c.retryAt = start.Add(c.nextBackoff())
+		c.logger.Error("scrape failed", "error", err, "type", errorType(err), "consecutive_failures", c.failures)
+	}
+	if errors.Is(err, ErrAuth) {
+		c.pauseAfterAuthFailure(start)
+	}
+
+	c.emit(ch, snapshot, false)
+}
+
+func (c *RedisPubSubCollector) setRunning(start time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running = start
+}
+
+// gather runs fn and returns the metrics it sent, in order.
+func gather(fn func(ch chan<- prometheus.Metric)) []prometheus.Metric {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var out []prometheus.Metric
+		for m := range metrics {
+			out = append(out, m)
+		}
+		done <- out
+	}()
+	fn(metrics)
+	close(metrics)
+	return <-done
+}
+
+// limitSeries returns at most limit of metrics, keeping their order. Series
+// without labels, such as the totals, and the exporter's own per-subsystem
+// series are always kept; the other labeled series (per channel, client,
+// pattern and so on) fill what is left and the last ones are dropped.
+func (c *RedisPubSubCollector) limitSeries(metrics []prometheus.Metric, limit int) []prometheus.Metric {
+	droppable := make([]bool, len(metrics))
+	room := limit
+	for i, m := range metrics {
+		switch m.Desc() {
+		case c.collectorDuration, c.collectorSuccess, c.errorsTotal, c.commandUnsupported,
+			c.tlsCertExpiry, c.hashDuration, c.trackedEntries:
+		default:
+			var pb dto.Metric
+			droppable[i] = m.Write(&pb) == nil && len(pb.GetLabel()) > 0
+		}
+		if !droppable[i] {
+			room--
+		}
+	}
+	out := make([]prometheus.Metric, 0, len(metrics))
+	for i, m := range metrics {
+		if droppable[i] {
+			if room <= 0 {
+				continue
+			}
+			room--
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// pauseAfterAuthFailure keeps scrapes and health checks from Redis for
+// authBackoff after an auth failure at start, unless the failure backoff is
+// already longer.
+func (c *RedisPubSubCollector) pauseAfterAuthFailure(start time.Time) {
+	c.authRetryAt = start.Add(c.authBackoff)
+	if c.authRetryAt.After(c.retryAt) {
+		c.retryAt = c.authRetryAt
+	}
+	c.logger.Error("redis rejected the credentials, pausing queries until they can be retried",
+		"retry_at", c.retryAt.Format(time.RFC3339))
+}
+
+// nextBackoff returns how long to skip Redis after the current run of
+// consecutive failures: FailureBackoff doubled per extra failure, capped at
+// FailureBackoffMax (no growth if unset).
+func (c *RedisPubSubCollector) nextBackoff() time.Duration {
+	if c.backoff <= 0 {
+		return 0
+	}
+	d := c.backoff
+	for i := 1; i < c.failures && d < c.backoffMax; i++ {
+		d *= 2
+	}
+	return min(d, max(c.backoffMax, c.backoff))
 }
 
-// IsRedisUp reports whether the last scrape reached Redis successfully.
-// Uses RLock so health checks don't block during scrapes.
+// snapshot returns the metrics of the last completed scrape, or nil.
+func (c *RedisPubSubCollector) snapshot() []prometheus.Metric {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cached
+}
+
+// emit sends a scrape's metrics followed by the staleness gauge.
+func (c *RedisPubSubCollector) emit(ch chan<- prometheus.Metric, metrics []prometheus.Metric, stale bool) {
+	for _, m := range metrics {
+		ch <- m
+	}
+	v := 0.0
+	if stale {
+		v = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeStale, prometheus.GaugeValue, v)
+}
+
+// IsRedisUp reports whether Redis is considered reachable for readiness:
+// there was a successful scrape, fewer than ReadyFailureThreshold scrapes
+// have failed since, and it is no older than ReadyMaxStaleness. Health check
+// pings only add whether Redis is reachable: ReadyFailureThreshold failed
+// pings make it unready, and before the first scrape a successful ping makes
+// it ready. It does not block on a running scrape.
 func (c *RedisPubSubCollector) IsRedisUp() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.redisUp
+	return c.ready()
+}
+
+// ready implements IsRedisUp; callers hold c.mu.
+func (c *RedisPubSubCollector) ready() bool {
+	if c.pingFailures >= c.readyFailures {
+		return false // unreachable
+	}
+	if c.lastSuccess.IsZero() && c.failures == 0 {
+		return !c.lastPing.IsZero() // not scraped yet, but Redis answers
+	}
+	if c.lastSuccess.IsZero() || c.failures >= c.readyFailures {
+		return false
+	}
+	return c.readyStale <= 0 || time.Since(c.lastSuccess) <= c.readyStale
 }
 
-// scrape queries Redis and emits metrics. Does NOT emit redis_up (caller handles that).
+// Status describes the outcome of recent scrapes for health endpoints.
+type Status struct {
+	Ready               bool      `json:"ready"`
+	LastScrape          time.Time `json:"last_scrape,omitzero"`
+	LastSuccess         time.Time `json:"last_success,omitzero"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	ScrapeRunningSince  time.Time `json:"scrape_running_since,omitzero"` // zero unless a scrape is querying Redis
+
+	// Health check pings
+	LastPing     time.Time `json:"last_ping,omitzero"`
+	PingError    string    `json:"ping_error,omitempty"`
+	PingFailures int       `json:"consecutive_ping_failures,omitempty"`
+}
+
+// Status returns the current scrape status without blocking on a running scrape.
+func (c *RedisPubSubCollector) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := Status{
+		Ready:               c.ready(),
+		LastScrape:          c.lastScrape,
+		LastSuccess:         c.lastSuccess,
+		ConsecutiveFailures: c.failures,
+		ScrapeRunningSince:  c.running,
+		LastPing:            c.lastPing,
+		PingFailures:        c.pingFailures,
+	}
+	if c.lastErr != nil {
+		s.LastError = c.lastErr.Error()
+	}
+	if c.pingErr != nil {
+		s.PingError = c.pingErr.Error()
+	}
+	return s
+}
+
+// scrape pings Redis, runs the subsystems in order and emits how long each
+// took and whether it succeeded, and how many Redis commands the scrape
+// issued. A failed subsystem fails the scrape but doesn't stop the others.
+// Does NOT emit redis_up (caller handles that).
 func (c *RedisPubSubCollector) scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
-	// Ping
-	if err := c.client.Ping(ctx).Err(); err != nil {
+	n := &commandCount{}
+	s := &ScrapeState{
+		Client:      compatQuerier{countingQuerier{c.client, n}, c},
+		Limits:      c.limits.Get(),
+		count:       n,
+		start:       time.Now(),
+		maxCommands: c.maxCommands,
+		maxDuration: c.maxDuration,
+	}
+	defer func() {
+		c.logger.Debug("scrape commands", "commands", n.commands, "round_trips", n.roundTrips)
+		exceeded := 0.0
+		if s.exceeded {
+			exceeded = 1
+			c.logger.Warn("scrape budget exceeded, skipped per-item metrics",
+				"commands", n.commands, "max_commands", c.maxCommands,
+				"duration", time.Since(s.start), "max_duration", c.maxDuration)
+		}
+		ch <- prometheus.MustNewConstMetric(c.scrapeCommands, prometheus.GaugeValue, float64(n.commands))
+		ch <- prometheus.MustNewConstMetric(c.scrapeRoundTrips, prometheus.GaugeValue, float64(n.roundTrips))
+		ch <- prometheus.MustNewConstMetric(c.budgetExceeded, prometheus.GaugeValue, exceeded)
+		c.collectDegraded(ch)
+		c.collectErrors(ch)
+		c.collectTLSCerts(ch)
+		c.observeHealth(s)
+	}()
+
+	if err := s.Client.Ping(ctx).Err(); err != nil {
+		c.countError("ping", err)
 		return err
 	}
 
-	// Redis INFO: clients
-	clientsInfo, err := c.client.InfoMap(ctx, "clients").Result()
-	if err != nil {
+	var errs []error
+	for _, sub := range c.subsystems {
+		start := time.Now()
+		err := sub.Scrape(ctx, ch, s)
+		success := 1.0
+		if err != nil {
+			c.countError(sub.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", sub.Name(), err))
+			success = 0
+		}
+		ch <- prometheus.MustNewConstMetric(c.collectorDuration, prometheus.GaugeValue, time.Since(start).Seconds(), sub.Name())
+		ch <- prometheus.MustNewConstMetric(c.collectorSuccess, prometheus.GaugeValue, success, sub.Name())
+		if errors.Is(err, ErrAuth) {
+			break // the remaining subsystems would fail the same way
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	c.writeHeartbeat(ctx, n)
+	return nil
+}
+
+func (c *RedisPubSubCollector) describeServer(ch chan<- *prometheus.Desc) {
+	ch <- c.redisConnectedClients
+	if c.protocol != nil {
+		ch <- c.redisProtocol
+	}
+	c.info.describe(ch)
+}
+
+// scrapeServer emits the INFO metrics from a single INFO (the default
+// sections) per client, parsed once.
+func (c *RedisPubSubCollector) scrapeServer(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	// Server and replication always come from the main client so that role
+	// reflects the scraped target rather than an INFO replica
+	info, err := s.Client.InfoMap(ctx).Result()
+	if err != nil && !isUnsupported(err) {
 		return err
 	}
-	if section := infoSection(clientsInfo, "clients"); section != nil {
+	s.Info = info
+	c.info.collectServer(ch, infoSection(info, "server"), infoSection(info, "replication"))
+	if c.infoClient != c.client {
+		info = c.replicaInfo(ctx, countingQuerier{c.infoClient, s.count}, info)
+	}
+
+	if c.protocol != nil {
+		if n := c.protocol.Negotiated(); n > 0 {
+			ch <- prometheus.MustNewConstMetric(c.redisProtocol, prometheus.GaugeValue, 1,
+				strconv.Itoa(c.protocol.Configured()), strconv.Itoa(n))
+		}
+	}
+
+	if section := infoSection(info, "clients"); section != nil {
 		if v, ok := section["connected_clients"]; ok {
 			ch <- prometheus.MustNewConstMetric(c.redisConnectedClients, prometheus.GaugeValue, parseFloat(v))
 		}
 	}
+	if section := infoSection(info, "memory"); section != nil {
+		c.info.collectMemory(ch, section)
+	}
+	// Stats (evictions, expirations) and cpu
+	for _, name := range []string{"stats", "cpu"} {
+		if section := infoSection(info, name); section != nil {
+			c.info.collectCounters(ch, name, section)
+		}
+	}
+	return nil
+}
 
-	// Redis INFO: memory
-	memInfo, err := c.client.InfoMap(ctx, "memory").Result()
-	if err != nil {
+func (c *RedisPubSubCollector) describeCluster(ch chan<- *prometheus.Desc) {
+	ch <- c.clusterState
+	ch <- c.clusterSlots
+	ch <- c.clusterKnownNodes
+	ch <- c.clusterSize
+}
+
+// scrapeCluster emits the cluster health metrics.
+func (c *RedisPubSubCollector) scrapeCluster(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	if err := c.scrapeClusterInfo(ctx, ch, s.Client); err != nil && !isUnsupported(err) {
 		return err
 	}
-	if section := infoSection(memInfo, "memory"); section != nil {
-		if v, ok := section["used_memory"]; ok {
-			ch <- prometheus.MustNewConstMetric(c.redisUsedMemoryBytes, prometheus.GaugeValue, parseFloat(v))
-		}
+	return nil
+}
+
+func (c *RedisPubSubCollector) describeChannels(ch chan<- *prometheus.Desc) {
+	ch <- c.channelSubscriberCount
+	ch <- c.channelsTotal
+	ch <- c.orphanChannelsTotal
+	if c.orphanMinAge > 0 {
+		ch <- c.orphanChannelsPersistent
+		ch <- c.orphanChannelMaxAge
+	}
+	if c.activity != nil && c.silentWindow > 0 {
+		ch <- c.channelSilent
+		ch <- c.silentChannels
 	}
+	if c.systemMode == SystemChannelsSeparate {
+		ch <- c.systemChannelSubscriberCount
+		ch <- c.systemChannelsTotal
+	}
+	if len(c.criticalChannels) > 0 {
+		ch <- c.subscriberDropDetected
+		ch <- c.subscriberDrops
+	}
+}
 
-	// 1. Active channels
-	channels, err := c.client.PubSubChannels(ctx, "*").Result()
+// scrapeChannels emits the active channels and their subscriber counts and
+// records them in s.
+func (c *RedisPubSubCollector) scrapeChannels(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	if c.infoOnly {
+		c.collectInfoCount(ch, c.channelsTotal, s, "stats", "pubsub_channels")
+		return nil
+	}
+	channels, err := s.Client.PubSubChannels(ctx, "*").Result()
 	if err != nil {
-		return err
+		if !isUnsupported(err) {
+			return err
+		}
+		// Unfiltered count from INFO; channels stay unknown
+		c.collectInfoCount(ch, c.channelsTotal, s, "stats", "pubsub_channels")
+		if err := c.collectCritical(ctx, ch, s); err != nil && !isUnsupported(err) {
+			return err
+		}
+		return nil
 	}
 
+	// Keyspace notification channels, unless mixed in with the rest
+	var system []string
+	if c.systemMode != SystemChannelsInclude {
+		channels = slices.DeleteFunc(channels, func(name string) bool {
+			if !isSystemChannel(name) {
+				return false
+			}
+			system = append(system, name)
+			return true
+		})
+	}
+
+	limits := s.Limits
+	if len(limits.ChannelInclude) > 0 || len(limits.ChannelExclude) > 0 {
+		channels = slices.DeleteFunc(channels, func(name string) bool { return !limits.trackChannel(name) })
+	}
+	// Sorted, so the same channels survive truncation and output is stable
+	slices.Sort(channels)
+
 	// High cardinality guard
-	if len(channels) > c.maxChannels {
+	if len(channels) > limits.MaxChannels {
 		c.logger.Warn("channel count exceeds MAX_CHANNELS, truncating",
-			"count", len(channels), "max", c.maxChannels)
-		channels = channels[:c.maxChannels]
+			"count", len(channels), "max", limits.MaxChannels)
+		channels = channels[:limits.MaxChannels]
 	}
 
 	ch <- prometheus.MustNewConstMetric(c.channelsTotal, prometheus.GaugeValue, float64(len(channels)))
 
 	// NUMSUB for each channel
-	orphanCount := 0
-	if len(channels) > 0 {
-		numsub, err := c.client.PubSubNumSub(ctx, channels...).Result()
-		if err != nil {
-			return err
-		}
-		for channel, count := range numsub {
-			ch <- prometheus.MustNewConstMetric(c.channelSubscriberCount, prometheus.GaugeValue, float64(count), channel)
-			if count == 0 {
-				orphanCount++
+	subscribers := make(map[string]int64, len(channels))
+	for _, name := range channels {
+		subscribers[name] = 0
+	}
+	s.Channels, s.Subscribers = channels, subscribers
+	switch {
+	case s.OverBudget():
+		s.Subscribers = nil // unknown
+	case len(channels) > 0:
+		numsub, err := s.Client.PubSubNumSub(ctx, channels...).Result()
+		switch {
+		case err == nil:
+			orphanCount := 0
+			for _, channel := range slices.Sorted(maps.Keys(numsub)) {
+				count := numsub[channel]
+				subscribers[channel] = count
+				ch <- prometheus.MustNewConstMetric(c.channelSubscriberCount, prometheus.GaugeValue, float64(count), channel)
+				if count == 0 {
+					orphanCount++
+				}
 			}
+			ch <- prometheus.MustNewConstMetric(c.orphanChannelsTotal, prometheus.GaugeValue, float64(orphanCount))
+			c.collectOrphanAge(ch, numsub)
+			c.collectSilent(ch, numsub)
+			c.logDiff(ctx, subscribers)
+		case !isUnsupported(err):
+			return err
 		}
+	default:
+		ch <- prometheus.MustNewConstMetric(c.orphanChannelsTotal, prometheus.GaugeValue, 0)
+		c.collectOrphanAge(ch, nil)
+		c.collectSilent(ch, nil)
+		c.logDiff(ctx, subscribers)
 	}
-	ch <- prometheus.MustNewConstMetric(c.orphanChannelsTotal, prometheus.GaugeValue, float64(orphanCount))
 
-	// 2. Pattern count
-	numpat, err := c.client.PubSubNumPat(ctx).Result()
-	if err != nil {
+	if c.systemMode == SystemChannelsSeparate {
+		if err := c.collectSystemChannels(ctx, ch, s, system); err != nil && !isUnsupported(err) {
+			return err
+		}
+	}
+	if err := c.collectCritical(ctx, ch, s); err != nil && !isUnsupported(err) {
 		return err
 	}
-	ch <- prometheus.MustNewConstMetric(c.patternsTotal, prometheus.GaugeValue, float64(numpat))
+	return nil
+}
+
+func (c *RedisPubSubCollector) describeClients(ch chan<- *prometheus.Desc) {
+	ch <- c.clientsTotal
+	if c.clientsByIP {
+		ch <- c.ipConnections
+		ch <- c.ipChannelSubs
+		ch <- c.ipPatternSubs
+		ch <- c.ipOutputBuf
+	} else {
+		ch <- c.clientChannelSubs
+		ch <- c.clientPatternSubs
+		ch <- c.clientOutputBuf
+	}
+	ch <- c.clientsByResp
+	ch <- c.clientsByFlag
+	ch <- c.clientsByCommand
+	ch <- c.pubsubNetInput
+	ch <- c.pubsubNetOutput
+}
 
-	// 3. CLIENT LIST
-	clientListRaw, err := c.client.ClientList(ctx).Result()
+// scrapeClients emits the CLIENT LIST metrics and records the pub/sub
+// clients in s.
+func (c *RedisPubSubCollector) scrapeClients(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	// pubsub_clients is reported from Redis 7.4
+	if c.infoOnly {
+		c.collectInfoCount(ch, c.clientsTotal, s, "clients", "pubsub_clients")
+		return nil
+	}
+	if s.OverBudget() {
+		return nil
+	}
+	clientListRaw, err := s.Client.ClientList(ctx).Result()
 	if err != nil {
-		return err
+		if !isUnsupported(err) {
+			return err
+		}
+		c.collectInfoCount(ch, c.clientsTotal, s, "clients", "pubsub_clients")
+		return nil
 	}
 	pubsubClients := ParseClientList(clientListRaw)
-	ch <- prometheus.MustNewConstMetric(c.clientsTotal, prometheus.GaugeValue, float64(len(pubsubClients)))
+	if pubsubClients == nil {
+		pubsubClients = []PubSubClient{}
+	}
+	slices.SortStableFunc(pubsubClients, func(a, b PubSubClient) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Addr, b.Addr))
+	})
+	s.Clients = pubsubClients
+	c.collectClients(ch, pubsubClients, s.Limits.MaxClients)
+	c.collectNetIO(ch, pubsubClients)
+	flags := CountClientFlags(clientListRaw)
+	for _, flag := range slices.Sorted(maps.Keys(flags)) {
+		ch <- prometheus.MustNewConstMetric(c.clientsByFlag, prometheus.GaugeValue, float64(flags[flag]), flag)
+	}
+	return nil
+}
 
-	for _, cl := range pubsubClients {
-		if cl.Sub > 0 {
-			ch <- prometheus.MustNewConstMetric(c.clientChannelSubs, prometheus.GaugeValue, float64(cl.Sub), cl.Name, cl.Addr)
+func (c *RedisPubSubCollector) describeRates(ch chan<- *prometheus.Desc) {
+	ch <- c.channelCreationRate
+	ch <- c.subscriberChangeRate
+	ch <- c.clientChurnRate
+}
+
+// scrapeRates emits the rates derived from the channels and clients in s.
+func (c *RedisPubSubCollector) scrapeRates(_ context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	if s.Subscribers == nil {
+		return nil // channels skipped or disabled
+	}
+	r := c.rates.observe(time.Now(), s.Subscribers, s.Clients)
+	ch <- prometheus.MustNewConstMetric(c.channelCreationRate, prometheus.GaugeValue, r.ChannelCreation)
+	ch <- prometheus.MustNewConstMetric(c.subscriberChangeRate, prometheus.GaugeValue, r.SubscriberChange)
+	ch <- prometheus.MustNewConstMetric(c.clientChurnRate, prometheus.GaugeValue, r.ClientChurn)
+	return nil
+}
+
+func (c *RedisPubSubCollector) describePatterns(ch chan<- *prometheus.Desc) {
+	ch <- c.patternSubscriberCount
+	ch <- c.patternsTotal
+	if len(c.minSubs) > 0 {
+		ch <- c.patternShortfall
+	}
+}
+
+// scrapePatterns emits the pattern count and infers pattern activity from
+// the channels in s, all uncached patterns in one pipeline.
+func (c *RedisPubSubCollector) scrapePatterns(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	if c.infoOnly {
+		c.collectInfoCount(ch, c.patternsTotal, s, "stats", "pubsub_patterns")
+		return nil
+	}
+	numpat, err := s.Client.PubSubNumPat(ctx).Result()
+	switch {
+	case err == nil:
+		ch <- prometheus.MustNewConstMetric(c.patternsTotal, prometheus.GaugeValue, float64(numpat))
+	case !isUnsupported(err):
+		return err
+	default:
+		c.collectInfoCount(ch, c.patternsTotal, s, "stats", "pubsub_patterns")
+	}
+
+	// Asserted minimums first, so the budget never silences a shortfall
+	subscribers := c.patternSubscribers(ctx, s)
+
+	now := time.Now()
+	patterns := c.patternsToQuery(s.Channels, s.Limits)
+	matching, stale := c.patternCache.split(patterns, now)
+	if matching == nil {
+		matching = make(map[string]int, len(stale))
+	}
+	// Cached patterns are still reported when the budget runs out
+	if s.OverBudget() {
+		stale = nil
+	} else if left := s.commandsLeft(); left >= 0 && len(stale) > left {
+		stale = stale[:left]
+		s.exceeded = true
+	}
+	if len(stale) > 0 {
+		for i, cmd := range s.Client.PubSubChannelsMulti(ctx, stale...) {
+			result, err := cmd.Result()
+			if err != nil {
+				c.logger.Warn("failed to query pattern channels", "pattern", stale[i], "error", err)
+				continue
+			}
+			matching[stale[i]] = len(result)
+			c.patternCache.store(stale[i], len(result), now)
 		}
-		if cl.PSub > 0 {
-			ch <- prometheus.MustNewConstMetric(c.clientPatternSubs, prometheus.GaugeValue, float64(cl.PSub), cl.Name, cl.Addr)
+	}
+	for _, pattern := range patterns {
+		if n := matching[pattern]; n > 0 {
+			ch <- prometheus.MustNewConstMetric(c.patternSubscriberCount, prometheus.GaugeValue, float64(n), pattern)
+		}
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(subscribers)) {
+		s.asserted++
+		shortfall := 0.0
+		if subscribers[pattern] < int64(c.minSubs[pattern]) {
+			shortfall = 1
+			s.shortfalls++
+		}
+		ch <- prometheus.MustNewConstMetric(c.patternShortfall, prometheus.GaugeValue, shortfall, pattern)
+	}
+	return nil
+}
+
+// patternSubscribers returns, for every pattern with a subscriber minimum,
+// the subscribers summed over its matching channels: one pipelined PUBSUB
+// CHANNELS for all patterns, then a single PUBSUB NUMSUB for the channels
+// they match. They are queried on every scrape regardless of MAX_PATTERNS
+// and the command budget. Redis doesn't report which patterns clients
+// subscribed to with PSUBSCRIBE, so those subscribers aren't counted.
+// Patterns whose query failed are left out.
+func (c *RedisPubSubCollector) patternSubscribers(ctx context.Context, s *ScrapeState) map[string]int64 {
+	if len(c.minSubs) == 0 {
+		return nil
+	}
+	patterns := slices.Sorted(maps.Keys(c.minSubs))
+	matches := make(map[string][]string, len(patterns))
+	var channels []string
+	seen := make(map[string]bool)
+	for i, cmd := range s.Client.PubSubChannelsMulti(ctx, patterns...) {
+		result, err := cmd.Result()
+		if err != nil {
+			c.logger.Warn("failed to query pattern channels", "pattern", patterns[i], "error", err)
+			continue
+		}
+		matches[patterns[i]] = result
+		for _, channel := range result {
+			if !seen[channel] {
+				seen[channel] = true
+				channels = append(channels, channel)
+			}
 		}
 	}
 
-	// 4. Hash metrics (application-managed subscriber counts)
-	c.scrapeHashMetrics(ctx, ch)
+	var numsub map[string]int64
+	if len(channels) > 0 {
+		var err error
+		if numsub, err = s.Client.PubSubNumSub(ctx, channels...).Result(); err != nil {
+			c.logger.Warn("failed to query pattern subscribers", "patterns", len(matches), "error", err)
+			return nil
+		}
+	}
+	out := make(map[string]int64, len(matches))
+	for pattern, result := range matches {
+		var n int64
+		for _, channel := range result {
+			n += numsub[channel]
+		}
+		out[pattern] = n
+	}
+	return out
+}
 
-	// 5. Pattern activity inference
-	patternSet := make(map[string]struct{})
-	for _, p := range c.knownPatterns {
-		patternSet[p] = struct{}{}
+// patternsToQuery returns the patterns to check for activity: known and
+// runtime patterns, then prefixes auto-discovered from channel names and
+// promoted by c.discovery, most channels first, minus ignored patterns,
+// capped at limits.MaxPatterns. Patterns with a subscriber minimum are never
+// dropped by the cap.
+func (c *RedisPubSubCollector) patternsToQuery(channels []string, limits Limits) []string {
+	ignored := make(map[string]bool, len(limits.IgnoredPatterns))
+	for _, p := range limits.IgnoredPatterns {
+		ignored[p] = true
 	}
+
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, p := range slices.Concat(c.knownPatterns, limits.Patterns) {
+		if !seen[p] && !ignored[p] {
+			seen[p] = true
+			patterns = append(patterns, p)
+		}
+	}
+
 	// Auto-discover prefixes from channel names
+	discovered := make(map[string]int)
 	for _, channelName := range channels {
 		if idx := strings.IndexByte(channelName, '.'); idx >= 0 {
-			patternSet[channelName[:idx]+".*"] = struct{}{}
+			if p := channelName[:idx] + ".*"; !seen[p] && !ignored[p] {
+				discovered[p]++
+			}
 		}
 	}
+	discovered = c.discovery.observe(discovered)
+	byCount := slices.Collect(maps.Keys(discovered))
+	slices.SortFunc(byCount, func(a, b string) int {
+		return cmp.Or(cmp.Compare(discovered[b], discovered[a]), strings.Compare(a, b))
+	})
+	patterns = append(patterns, byCount...)
 
-	for pattern := range patternSet {
-		matching, err := c.client.PubSubChannels(ctx, pattern).Result()
-		if err != nil {
-			c.logger.Warn("failed to query pattern channels", "pattern", pattern, "error", err)
-			continue
+	if limits.MaxPatterns > 0 && len(patterns) > limits.MaxPatterns {
+		c.logger.Warn("pattern count exceeds MAX_PATTERNS, skipping the rest",
+			"count", len(patterns), "max", limits.MaxPatterns)
+		others := limits.MaxPatterns
+		for _, p := range patterns {
+			if _, assert := c.minSubs[p]; assert {
+				others--
+			}
 		}
-		if len(matching) > 0 {
-			ch <- prometheus.MustNewConstMetric(c.patternSubscriberCount, prometheus.GaugeValue, float64(len(matching)), pattern)
+		patterns = slices.DeleteFunc(patterns, func(p string) bool {
+			if _, assert := c.minSubs[p]; assert {
+				return false
+			}
+			others--
+			return others < 0
+		})
+	}
+	return patterns
+}
+
+// knownPatterns returns known followed by the patterns with a subscriber
+// minimum that aren't already in it, in sorted order.
+func knownPatterns(known []string, minSubs map[string]int) []string {
+	out := slices.Clone(known)
+	for _, p := range slices.Sorted(maps.Keys(minSubs)) {
+		if !slices.Contains(out, p) {
+			out = append(out, p)
 		}
 	}
+	return out
+}
 
-	return nil
+// writeHeartbeat sets the heartbeat key to the current Unix time. Failures
+// (e.g. on a read-only replica) are logged but don't fail the scrape.
+func (c *RedisPubSubCollector) writeHeartbeat(ctx context.Context, n *commandCount) {
+	if c.heartbeatKey == "" {
+		return
+	}
+	w, ok := c.client.(KeyWriter)
+	if !ok {
+		return
+	}
+	n.add(1)
+	if err := w.Set(ctx, c.heartbeatKey, time.Now().Unix(), c.heartbeatTTL).Err(); err != nil {
+		c.logger.Warn("failed to write heartbeat key", "key", c.heartbeatKey, "error", err)
+	}
+}
+
+// collectClients emits the per-client subscription metrics, with per-client
+// series for at most maxClients clients (0 = all).
+func (c *RedisPubSubCollector) collectClients(ch chan<- prometheus.Metric, pubsubClients []PubSubClient, maxClients int) {
+	ch <- prometheus.MustNewConstMetric(c.clientsTotal, prometheus.GaugeValue, float64(len(pubsubClients)))
+
+	byResp := make(map[string]int)
+	byCommand := make(map[string]int)
+	var selected []PubSubClient
+	for _, cl := range pubsubClients {
+		byResp[respLabel(cl.Resp)]++
+		byCommand[commandLabel(cl.Cmd)]++
+		if c.selectClient(cl.Name) {
+			selected = append(selected, cl)
+		}
+	}
+	for _, resp := range slices.Sorted(maps.Keys(byResp)) {
+		ch <- prometheus.MustNewConstMetric(c.clientsByResp, prometheus.GaugeValue, float64(byResp[resp]), resp)
+	}
+	for _, cmd := range slices.Sorted(maps.Keys(byCommand)) {
+		ch <- prometheus.MustNewConstMetric(c.clientsByCommand, prometheus.GaugeValue, float64(byCommand[cmd]), cmd)
+	}
+
+	if c.clientsByIP {
+		c.collectClientsByIP(ch, selected, maxClients)
+		return
+	}
+	if maxClients > 0 && len(selected) > maxClients {
+		c.logger.Warn("client count exceeds MAX_CLIENTS, truncating per-client metrics",
+			"count", len(selected), "max", maxClients)
+		selected = selected[:maxClients]
+	}
+	for _, cl := range selected {
+		if cl.Sub > 0 {
+			ch <- prometheus.MustNewConstMetric(c.clientChannelSubs, prometheus.GaugeValue, float64(cl.Sub), cl.Name, cl.Addr)
+		}
+		if cl.PSub > 0 {
+			ch <- prometheus.MustNewConstMetric(c.clientPatternSubs, prometheus.GaugeValue, float64(cl.PSub), cl.Name, cl.Addr)
+		}
+		if cl.OMem > 0 {
+			ch <- prometheus.MustNewConstMetric(c.clientOutputBuf, prometheus.GaugeValue, float64(cl.OMem), cl.Name, cl.Addr)
+		}
+	}
+}
+
+// selectClient reports whether a client gets per-client series according to
+// the client name filters, which see clients without a name as "unnamed"
+// like the client_name label.
+func (c *RedisPubSubCollector) selectClient(name string) bool {
+	if c.clientInclude != nil && !c.clientInclude.MatchString(name) {
+		return false
+	}
+	return c.clientExclude == nil || !c.clientExclude.MatchString(name)
+}
+
+// replicaInfo reads INFO from the separate INFO replica, falling back to
+// master, the main client's INFO, if the replica is unreachable.
+func (c *RedisPubSubCollector) replicaInfo(ctx context.Context, replica RedisQuerier, master map[string]map[string]string) map[string]map[string]string {
+	info, err := replica.InfoMap(ctx).Result()
+	if err != nil {
+		c.logger.Warn("replica INFO failed, falling back to master", "error", err)
+		return master
+	}
+	return info
 }
 
 // infoSection does a case-insensitive lookup for a section key in Redis InfoMap output.
@@ -348,36 +1613,122 @@ func infoSection(m map[string]map[string]string, key string) map[string]string {
 	return nil
 }
 
+// respLabel maps a CLIENT LIST resp= value to a label; servers older than
+// Redis 7 do not report it.
+func respLabel(resp int) string {
+	if resp <= 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(resp)
+}
+
+// subscriberCommands are the commands a pub/sub client can run; anything
+// else is labelled "other" to keep clients_by_command low-cardinality.
+var subscriberCommands = map[string]bool{
+	"subscribe": true, "psubscribe": true, "ssubscribe": true,
+	"unsubscribe": true, "punsubscribe": true, "sunsubscribe": true,
+	"ping": true,
+}
+
+// commandLabel maps a CLIENT LIST cmd= value to a clients_by_command label.
+func commandLabel(cmd string) string {
+	cmd = strings.ToLower(cmd)
+	if subscriberCommands[cmd] {
+		return cmd
+	}
+	return "other"
+}
+
 func parseFloat(s string) float64 {
 	s = strings.TrimSpace(s)
 	f, _ := strconv.ParseFloat(s, 64)
 	return f
 }
 
-// scrapeHashMetrics reads each configured Redis hash and emits field values as gauges.
-// Individual hash failures are logged and skipped — they do not fail the overall scrape.
-func (c *RedisPubSubCollector) scrapeHashMetrics(ctx context.Context, ch chan<- prometheus.Metric) {
+// scrapeHashMetrics reads the configured hashes grouped by database and
+// reports how long each database took. With a DBClient every database,
+// including the main one, is read through its own clients, so key reads
+// never queue behind or hold up the pub/sub commands. Individual hash
+// failures are logged and skipped — they do not fail the overall scrape.
+func (c *RedisPubSubCollector) scrapeHashMetrics(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	byDB := make(map[int][]hashMetricDesc)
 	for _, hm := range c.hashMetrics {
-		result, err := c.client.HGetAll(ctx, hm.def.RedisKey).Result()
+		db := c.db
+		if hm.def.DB != nil {
+			db = *hm.def.DB
+		}
+		byDB[db] = append(byDB[db], hm)
+	}
+	for _, db := range slices.Sorted(maps.Keys(byDB)) {
+		var q KeyReader = s.Client
+		if c.dbClient != nil {
+			q = countingReader{c.dbClient(db), s.count}
+		}
+		if s.OverBudget() {
+			break
+		}
+		start := time.Now()
+		for _, hm := range byDB[db] {
+			if s.OverBudget() {
+				break
+			}
+			c.scrapeHash(ctx, ch, q, hm)
+		}
+		ch <- prometheus.MustNewConstMetric(c.hashDuration, prometheus.GaugeValue, time.Since(start).Seconds(), strconv.Itoa(db))
+	}
+	return nil
+}
+
+func (c *RedisPubSubCollector) describeHashMetrics(ch chan<- *prometheus.Desc) {
+	for _, hm := range c.hashMetrics {
+		ch <- hm.desc
+	}
+	ch <- c.hashDuration
+}
+
+// scrapeHash emits one gauge per field of the hash of hm, read through q.
+func (c *RedisPubSubCollector) scrapeHash(ctx context.Context, ch chan<- prometheus.Metric, q KeyReader, hm hashMetricDesc) {
+	result, err := q.HGetAll(ctx, hm.def.RedisKey).Result()
+	if err != nil {
+		c.logger.Warn("failed to read hash metric",
+			"redis_key", hm.def.RedisKey,
+			"error", err,
+		)
+		return
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(result)) {
+		valStr := result[field]
+		val, err := parseHashValue(hm.def, valStr)
 		if err != nil {
-			c.logger.Warn("failed to read hash metric",
+			c.logger.Warn("hash metric field has unparseable value, skipping",
 				"redis_key", hm.def.RedisKey,
-				"error", err,
+				"field", field,
+				"value", valStr,
 			)
 			continue
 		}
-
-		for field, valStr := range result {
-			val, err := strconv.ParseFloat(strings.TrimSpace(valStr), 64)
-			if err != nil {
-				c.logger.Warn("hash metric field has non-numeric value, skipping",
-					"redis_key", hm.def.RedisKey,
-					"field", field,
-					"value", valStr,
-				)
-				continue
-			}
-			ch <- prometheus.MustNewConstMetric(hm.desc, prometheus.GaugeValue, val, field)
-		}
+		ch <- prometheus.MustNewConstMetric(hm.desc, prometheus.GaugeValue, val, field)
 	}
 }
+
+// ContextCollector is a prometheus.Collector that can also collect bound to
+// a caller-supplied context.
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// WithContext returns a collector whose Collect runs c.CollectContext with
+// ctx, so it can be registered into a per-request registry.
+func WithContext(ctx context.Context, c ContextCollector) prometheus.Collector {
+	return contextCollector{ctx: ctx, c: c}
+}
+
+type contextCollector struct {
+	ctx context.Context
+	c   ContextCollector
+}
+
+func (cc contextCollector) Describe(ch chan<- *prometheus.Desc) { cc.c.Describe(ch) }
+func (cc contextCollector) Collect(ch chan<- prometheus.Metric) { cc.c.CollectContext(cc.ctx, ch) }
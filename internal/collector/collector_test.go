@@ -0,0 +1,1485 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// fakeQuerier is an in-memory RedisQuerier used by collector tests.
+type fakeQuerier struct {
+	pingHook    func() // optional; called on every Ping
+	pingErr     error
+	infoErr     error
+	info        map[string]map[string]string
+	channels    map[string]int64 // channel -> subscriber count
+	channelsErr error
+	numPat      int64
+	numPatErr   error
+	clientList  string
+	clientErr   error
+	hashes      map[string]map[string]string
+	clusterInfo string
+	sets        map[string]time.Duration // key -> expiration of SET calls
+	pipelines   int                      // PubSubChannelsMulti calls
+	infoCalls   int                      // InfoMap calls
+	clientCalls int                      // ClientList calls
+	numsubCalls int                      // PubSubNumSub calls
+}
+
+func (f *fakeQuerier) Ping(context.Context) *redis.StatusCmd {
+	if f.pingHook != nil {
+		f.pingHook()
+	}
+	return redis.NewStatusResult("PONG", f.pingErr)
+}
+
+func (f *fakeQuerier) InfoMap(ctx context.Context, sections ...string) *redis.InfoCmd {
+	f.infoCalls++
+	cmd := redis.NewInfoCmd(ctx)
+	if f.infoErr != nil {
+		cmd.SetErr(f.infoErr)
+		return cmd
+	}
+	if len(sections) == 0 {
+		cmd.SetVal(f.info) // INFO without arguments returns the default sections
+		return cmd
+	}
+	out := make(map[string]map[string]string)
+	for _, s := range sections {
+		if v, ok := f.info[s]; ok {
+			out[s] = v
+		}
+	}
+	cmd.SetVal(out)
+	return cmd
+}
+
+func (f *fakeQuerier) PubSubChannels(_ context.Context, pattern string) *redis.StringSliceCmd {
+	var out []string
+	for ch := range f.channels {
+		if matchPattern(pattern, ch) {
+			out = append(out, ch)
+		}
+	}
+	sort.Strings(out)
+	return redis.NewStringSliceResult(out, f.channelsErr)
+}
+
+func (f *fakeQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	f.pipelines++
+	cmds := make([]*redis.StringSliceCmd, len(patterns))
+	for i, p := range patterns {
+		cmds[i] = f.PubSubChannels(ctx, p)
+	}
+	return cmds
+}
+
+func (f *fakeQuerier) PubSubNumSub(_ context.Context, channels ...string) *redis.MapStringIntCmd {
+	f.numsubCalls++
+	out := make(map[string]int64, len(channels))
+	for _, ch := range channels {
+		out[ch] = f.channels[ch]
+	}
+	return redis.NewMapStringIntCmdResult(out, nil)
+}
+
+func (f *fakeQuerier) PubSubNumPat(context.Context) *redis.IntCmd {
+	return redis.NewIntResult(f.numPat, f.numPatErr)
+}
+
+func (f *fakeQuerier) ClientList(context.Context) *redis.StringCmd {
+	f.clientCalls++
+	return redis.NewStringResult(f.clientList, f.clientErr)
+}
+
+func (f *fakeQuerier) HGetAll(_ context.Context, key string) *redis.MapStringStringCmd {
+	h, ok := f.hashes[key]
+	if !ok {
+		return redis.NewMapStringStringResult(map[string]string{}, nil)
+	}
+	return redis.NewMapStringStringResult(h, nil)
+}
+
+func (f *fakeQuerier) Set(_ context.Context, key string, _ interface{}, expiration time.Duration) *redis.StatusCmd {
+	if f.sets == nil {
+		f.sets = make(map[string]time.Duration)
+	}
+	f.sets[key] = expiration
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeQuerier) ClusterInfo(context.Context) *redis.StringCmd {
+	return redis.NewStringResult(f.clusterInfo, nil)
+}
+
+// matchPattern supports the trailing-"*" globs used by the collector.
+func matchPattern(pattern, s string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(s, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == s
+}
+
+// collect runs one scrape and returns emitted samples keyed by "name{k=v,...}".
+func collect(t *testing.T, c prometheus.Collector) map[string]float64 {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	out := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var labels []string
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, lp.GetName()+"="+lp.GetValue())
+			}
+			key := mf.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case m.GetGauge() != nil:
+				out[key] = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				out[key] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	return out
+}
+
+func newTestCollector(q RedisQuerier, hashDefs []config.HashMetricDef) *RedisPubSubCollector {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(q, Options{
+		MaxChannels:   100,
+		KnownPatterns: []string{"orders.*"},
+		HashMetrics:   hashDefs,
+	}, logger)
+}
+
+func TestCollect(t *testing.T) {
+	q := &fakeQuerier{
+		info: map[string]map[string]string{
+			"clients": {"connected_clients": "7"},
+			"memory": {
+				"used_memory":             "1024",
+				"maxmemory":               "4096",
+				"maxmemory_policy":        "noeviction",
+				"mem_fragmentation_ratio": "1.5",
+			},
+			"stats": {"evicted_keys": "12", "expired_keys": "40", "total_net_output_bytes": "9000"},
+			"cpu":   {"used_cpu_sys": "3.25", "used_cpu_user": "7.5"},
+			"server": {
+				"redis_version":     "7.2.4",
+				"redis_mode":        "standalone",
+				"uptime_in_seconds": "3600",
+			},
+			"replication": {"role": "master"},
+		},
+		channels: map[string]int64{
+			"orders.created": 2,
+			"orders.deleted": 0,
+			"users.login":    1,
+		},
+		numPat: 3,
+		clientList: "id=1 addr=10.0.0.1:1 name=orders flags=P sub=2 psub=0 omem=2048 resp=3 cmd=subscribe\n" +
+			"id=2 addr=10.0.0.2:2 name=users flags=P sub=0 psub=1 cmd=psubscribe\n" +
+			"id=3 addr=10.0.0.3:3 name=web flags=N sub=0 psub=0\n" +
+			"id=4 addr=10.0.0.4:4 name= flags=S sub=0 psub=0",
+		hashes: map[string]map[string]string{
+			"app:sessions": {"eu": "5", "us": "bogus"},
+		},
+	}
+	hashDefs := []config.HashMetricDef{{
+		RedisKey: "app:sessions", MetricName: "session_count", Help: "Sessions", FieldLabel: "region",
+	}}
+
+	got := collect(t, newTestCollector(q, hashDefs))
+	if q.infoCalls != 1 {
+		t.Errorf("want all INFO sections from one call, got %d calls", q.infoCalls)
+	}
+
+	want := map[string]float64{
+		"redis_pubsub_exporter_redis_up{}":                                                     1,
+		"redis_pubsub_exporter_redis_connected_clients{}":                                      7,
+		"redis_pubsub_exporter_redis_used_memory_bytes{}":                                      1024,
+		"redis_pubsub_exporter_redis_maxmemory_bytes{}":                                        4096,
+		"redis_pubsub_exporter_redis_mem_fragmentation_ratio{}":                                1.5,
+		"redis_pubsub_exporter_redis_maxmemory_policy_info{policy=noeviction}":                 1,
+		"redis_pubsub_exporter_redis_evicted_keys_total{}":                                     12,
+		"redis_pubsub_exporter_redis_expired_keys_total{}":                                     40,
+		"redis_pubsub_exporter_redis_net_output_bytes_total{}":                                 9000,
+		"redis_pubsub_exporter_redis_used_cpu_sys_seconds_total{}":                             3.25,
+		"redis_pubsub_exporter_redis_used_cpu_user_seconds_total{}":                            7.5,
+		"redis_pubsub_exporter_redis_info{mode=standalone,role=master,version=7.2.4}":          1,
+		"redis_pubsub_exporter_redis_uptime_seconds{}":                                         3600,
+		"redis_pubsub_channels_total{}":                                                        3,
+		"redis_pubsub_orphan_channels_total{}":                                                 1,
+		"redis_pubsub_channel_subscriber_count{channel=orders.created}":                        2,
+		"redis_pubsub_channel_subscriber_count{channel=orders.deleted}":                        0,
+		"redis_pubsub_patterns_total{}":                                                        3,
+		"redis_pubsub_clients_total{}":                                                         2,
+		"redis_pubsub_client_channel_subscriptions{client_addr=10.0.0.1:1,client_name=orders}": 2,
+		"redis_pubsub_client_pattern_subscriptions{client_addr=10.0.0.2:2,client_name=users}":  1,
+		"redis_pubsub_client_output_buffer_bytes{client_addr=10.0.0.1:1,client_name=orders}":   2048,
+		"redis_pubsub_pattern_subscriber_count{pattern=orders.*}":                              2,
+		"redis_pubsub_pattern_subscriber_count{pattern=users.*}":                               1,
+		"redis_pubsub_session_count{region=eu}":                                                5,
+		"redis_pubsub_clients_by_resp{resp=3}":                                                 1,
+		"redis_pubsub_clients_by_resp{resp=unknown}":                                           1,
+		"redis_pubsub_clients_by_flag{flag=P}":                                                 2,
+		"redis_pubsub_clients_by_command{command=subscribe}":                                   1,
+		"redis_pubsub_clients_by_command{command=psubscribe}":                                  1,
+		"redis_pubsub_clients_by_flag{flag=N}":                                                 1,
+		"redis_pubsub_clients_by_flag{flag=S}":                                                 1,
+	}
+	for key, v := range want {
+		gv, ok := got[key]
+		if !ok {
+			t.Errorf("missing sample %s", key)
+			continue
+		}
+		if gv != v {
+			t.Errorf("%s: want %v, got %v", key, v, gv)
+		}
+	}
+	if _, ok := got["redis_pubsub_session_count{region=us}"]; ok {
+		t.Error("non-numeric hash field should be skipped")
+	}
+}
+
+func TestCollectRedisDown(t *testing.T) {
+	q := &fakeQuerier{pingErr: errors.New("connection refused")}
+	c := newTestCollector(q, nil)
+
+	got := collect(t, c)
+
+	if got["redis_pubsub_exporter_redis_up{}"] != 0 {
+		t.Error("expected redis_up 0")
+	}
+	if got["redis_pubsub_exporter_scrape_errors_total{}"] != 1 {
+		t.Errorf("expected 1 scrape error, got %v", got["redis_pubsub_exporter_scrape_errors_total{}"])
+	}
+	if c.IsRedisUp() {
+		t.Error("IsRedisUp should report false after failed scrape")
+	}
+}
+
+func TestCollectContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1}}
+	q.pingHook = func() {
+		// The scrape request goes away while Redis is being queried.
+		cancel()
+		q.pingErr = ctx.Err()
+	}
+	c := newTestCollector(q, nil)
+
+	ch := make(chan prometheus.Metric, 100)
+	c.CollectContext(ctx, ch)
+	close(ch)
+	if n := len(ch); n != 0 {
+		t.Errorf("cancelled scrape should emit nothing, got %d metrics", n)
+	}
+	if c.Counters().ScrapeErrors != 0 || c.failures != 0 || c.snapshot() != nil {
+		t.Error("cancelled scrape should not be recorded")
+	}
+
+	q.pingHook, q.pingErr = nil, nil
+	if got := collect(t, c); got["redis_pubsub_exporter_redis_up{}"] != 1 {
+		t.Error("expected the next scrape to query Redis")
+	}
+}
+
+func TestRestoreCounters(t *testing.T) {
+	q := &fakeQuerier{pingErr: errors.New("connection refused")}
+	c := newTestCollector(q, nil)
+	c.RestoreCounters(Counters{ScrapeErrors: 41})
+
+	got := collect(t, c)
+
+	if got["redis_pubsub_exporter_scrape_errors_total{}"] != 42 {
+		t.Errorf("want restored count plus one error, got %v", got["redis_pubsub_exporter_scrape_errors_total{}"])
+	}
+	if c.Counters().ScrapeErrors != 42 {
+		t.Errorf("want Counters to report 42, got %v", c.Counters().ScrapeErrors)
+	}
+}
+
+func TestCollectOrphanAge(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 0, "orders.deleted": 0, "users.login": 2}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, OrphanMinAge: time.Minute}, logger)
+
+	got := collect(t, c)
+	if got["redis_pubsub_orphan_channels_persistent{}"] != 0 {
+		t.Errorf("new orphans should not be persistent, got %v", got["redis_pubsub_orphan_channels_persistent{}"])
+	}
+
+	// orders.created has been orphaned for two minutes; orders.deleted gets
+	// a subscriber, so its age resets.
+	c.orphanSince["orders.created"] = time.Now().Add(-2 * time.Minute)
+	q.channels["orders.deleted"] = 1
+	got = collect(t, c)
+	if got["redis_pubsub_orphan_channels_persistent{}"] != 1 {
+		t.Errorf("want 1 persistent orphan, got %v", got["redis_pubsub_orphan_channels_persistent{}"])
+	}
+	if age := got["redis_pubsub_orphan_channel_max_age_seconds{}"]; age < 120 {
+		t.Errorf("want max age of at least 120s, got %v", age)
+	}
+	if _, ok := c.orphanSince["orders.deleted"]; ok {
+		t.Error("channel with subscribers should no longer be tracked")
+	}
+}
+
+// allocSink keeps TestCollectSelfMetrics' allocation from being optimized away.
+var allocSink []byte
+
+func TestCollectSelfMetrics(t *testing.T) {
+	q := &fakeQuerier{
+		channels:   map[string]int64{"orders.created": 0, "users.login": 2},
+		clientList: "id=1 addr=10.0.0.1:1 name=users sub=1 psub=0",
+		// Small allocations reach the runtime statistics only when a P
+		// flushes its cache; a large one is accounted right away.
+		pingHook: func() { allocSink = make([]byte, 1<<20) },
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, OrphanMinAge: time.Minute, RateWindow: time.Minute}, logger)
+
+	got := collect(t, c)
+	want := map[string]float64{
+		"redis_pubsub_exporter_tracked_entries{tracker=orphan_channels}": 1,
+		"redis_pubsub_exporter_tracked_entries{tracker=rate_channels}":   2,
+		"redis_pubsub_exporter_tracked_entries{tracker=rate_clients}":    1,
+	}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s: want %v, got %v", key, v, got[key])
+		}
+	}
+	for _, key := range []string{
+		"redis_pubsub_exporter_scrape_allocated_bytes{}",
+		"redis_pubsub_exporter_scrape_allocations{}",
+		"redis_pubsub_exporter_heap_bytes{}",
+		"redis_pubsub_exporter_goroutines{}",
+	} {
+		if got[key] <= 0 {
+			t.Errorf("%s: want > 0, got %v", key, got[key])
+		}
+	}
+}
+
+func TestCollectClientNetIO(t *testing.T) {
+	q := &fakeQuerier{
+		channels:   map[string]int64{"orders.created": 1},
+		clientList: "id=1 addr=10.0.0.1:1 name=legacy sub=1 psub=0",
+	}
+	c := newTestCollector(q, nil)
+
+	got := collect(t, c)
+	if _, ok := got["redis_pubsub_clients_net_output_bytes_total{}"]; ok {
+		t.Error("net counters should be absent before Redis 7.2")
+	}
+
+	steps := []struct {
+		clientList      string
+		wantIn, wantOut float64
+	}{
+		{
+			clientList: "id=1 addr=10.0.0.1:1 sub=1 tot-net-in=100 tot-net-out=1000\n" +
+				"id=2 addr=10.0.0.2:2 sub=1 tot-net-in=50 tot-net-out=500",
+			wantIn: 150, wantOut: 1500,
+		},
+		{
+			// 10.0.0.2 disconnected; 10.0.0.3 is new.
+			clientList: "id=1 addr=10.0.0.1:1 sub=1 tot-net-in=120 tot-net-out=1800\n" +
+				"id=3 addr=10.0.0.3:3 sub=1 tot-net-in=10 tot-net-out=20",
+			wantIn: 180, wantOut: 2320,
+		},
+		{
+			// 10.0.0.1 reconnected from the same address.
+			clientList: "id=4 addr=10.0.0.1:1 sub=1 tot-net-in=5 tot-net-out=7\n" +
+				"id=3 addr=10.0.0.3:3 sub=1 tot-net-in=10 tot-net-out=20",
+			wantIn: 185, wantOut: 2327,
+		},
+		{
+			// 10.0.0.3 is between subscriptions and not listed.
+			clientList: "id=4 addr=10.0.0.1:1 sub=1 tot-net-in=5 tot-net-out=7",
+			wantIn:     185, wantOut: 2327,
+		},
+		{
+			// 10.0.0.3 is back on the same connection: only its growth counts.
+			clientList: "id=4 addr=10.0.0.1:1 sub=1 tot-net-in=5 tot-net-out=7\n" +
+				"id=3 addr=10.0.0.3:3 sub=1 tot-net-in=15 tot-net-out=25",
+			wantIn: 190, wantOut: 2332,
+		},
+	}
+	for i, s := range steps {
+		q.clientList = s.clientList
+		got := collect(t, c)
+		if in := got["redis_pubsub_clients_net_input_bytes_total{}"]; in != s.wantIn {
+			t.Errorf("step %d: input: want %v, got %v", i, s.wantIn, in)
+		}
+		if out := got["redis_pubsub_clients_net_output_bytes_total{}"]; out != s.wantOut {
+			t.Errorf("step %d: output: want %v, got %v", i, s.wantOut, out)
+		}
+	}
+}
+
+func TestCollectMaxChannels(t *testing.T) {
+	q := &fakeQuerier{channels: make(map[string]int64)}
+	for i := 0; i < 10; i++ {
+		q.channels["ch"+strconv.Itoa(i)] = 1
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 4}, logger)
+
+	got := collect(t, c)
+
+	if got["redis_pubsub_channels_total{}"] != 4 {
+		t.Errorf("expected channels_total truncated to 4, got %v", got["redis_pubsub_channels_total{}"])
+	}
+}
+
+func TestCollectMaxSeries(t *testing.T) {
+	q := &fakeQuerier{channels: make(map[string]int64)}
+	for i := 0; i < 10; i++ {
+		q.channels["ch"+strconv.Itoa(i)] = 1
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100}, logger)
+
+	tests := []struct {
+		name        string
+		maxSeries   int
+		wantLimited float64
+	}{
+		{name: "unlimited", maxSeries: 0, wantLimited: 0},
+		{name: "below limit", maxSeries: 1000, wantLimited: 0},
+		{name: "exceeded", maxSeries: 5, wantLimited: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.limits.Update(func(l *Limits) { l.MaxSeries = tt.maxSeries })
+			got := collect(t, c)
+
+			if v := got["redis_pubsub_exporter_series_limited{}"]; v != tt.wantLimited {
+				t.Errorf("series_limited: want %v, got %v", tt.wantLimited, v)
+			}
+			if got["redis_pubsub_exporter_redis_up{}"] != 1 {
+				t.Error("exporter metrics must survive the series limit")
+			}
+			for _, key := range []string{
+				"redis_pubsub_channels_total{}",
+				"redis_pubsub_clients_total{}",
+				"redis_pubsub_exporter_collector_success{collector=clients}",
+			} {
+				if _, ok := got[key]; !ok {
+					t.Errorf("%s must survive the series limit", key)
+				}
+			}
+			if v := got["redis_pubsub_channels_total{}"]; v != 10 {
+				t.Errorf("channels_total: want 10, got %v", v)
+			}
+			if tt.wantLimited == 1 {
+				var channels int
+				for key := range got {
+					if strings.HasPrefix(key, "redis_pubsub_channel_subscriber_count{") {
+						channels++
+					}
+				}
+				if channels >= 10 {
+					t.Errorf("expected per-channel series to be dropped, got %d", channels)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectSystemChannels(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{
+		"orders.created":                1,
+		"__keyspace@0__:session:1":      2,
+		"__keyevent@0__:expired":        3,
+		"__keyspace_lookalike:not_mine": 1,
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		mode         string
+		wantChannels float64
+		wantSystem   map[string]float64
+	}{
+		{mode: SystemChannelsInclude, wantChannels: 4},
+		{mode: SystemChannelsExclude, wantChannels: 2},
+		{mode: SystemChannelsSeparate, wantChannels: 2, wantSystem: map[string]float64{
+			"redis_pubsub_system_channels_total{}":                                           2,
+			"redis_pubsub_system_channel_subscriber_count{channel=__keyspace@0__:session:1}": 2,
+			"redis_pubsub_system_channel_subscriber_count{channel=__keyevent@0__:expired}":   3,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			c := New(q, Options{MaxChannels: 100, SystemChannels: tt.mode}, logger)
+			got := collect(t, c)
+
+			if v := got["redis_pubsub_channels_total{}"]; v != tt.wantChannels {
+				t.Errorf("channels_total: want %v, got %v", tt.wantChannels, v)
+			}
+			if _, ok := got["redis_pubsub_channel_subscriber_count{channel=__keyevent@0__:expired}"]; ok != (tt.mode == SystemChannelsInclude) {
+				t.Errorf("keyevent channel among application channels: %v", ok)
+			}
+			for key, want := range tt.wantSystem {
+				if got[key] != want {
+					t.Errorf("%s: want %v, got %v", key, want, got[key])
+				}
+			}
+			if _, ok := got["redis_pubsub_system_channels_total{}"]; ok != (tt.wantSystem != nil) {
+				t.Errorf("system_channels_total exported: %v", ok)
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		c := New(q, Options{MaxChannels: 1, SystemChannels: SystemChannelsSeparate}, logger)
+		got := collect(t, c)
+
+		if v := got["redis_pubsub_system_channels_total{}"]; v != 2 {
+			t.Errorf("system_channels_total must count channels beyond MAX_CHANNELS: want 2, got %v", v)
+		}
+		var series int
+		for key := range got {
+			if strings.HasPrefix(key, "redis_pubsub_system_channel_subscriber_count{") {
+				series++
+			}
+		}
+		if series != 1 {
+			t.Errorf("want 1 system channel series, got %d", series)
+		}
+	})
+}
+
+func TestCollectRuntimeLimits(t *testing.T) {
+	q := &fakeQuerier{
+		channels: map[string]int64{"orders.created": 1, "orders.debug": 1, "users.login": 1},
+		clientList: "id=1 addr=10.0.0.1:1 name=a sub=1 psub=0\n" +
+			"id=2 addr=10.0.0.2:2 name=b sub=1 psub=0",
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := NewLimitStore(Limits{MaxChannels: 100})
+	c := New(q, Options{Limits: store}, logger)
+
+	got := collect(t, c)
+	if got["redis_pubsub_channels_total{}"] != 3 {
+		t.Fatalf("want 3 channels, got %v", got["redis_pubsub_channels_total{}"])
+	}
+	if got["redis_pubsub_exporter_limit{limit=max_channels}"] != 100 || got["redis_pubsub_exporter_limit{limit=max_clients}"] != 0 {
+		t.Errorf("want the configured limits exported, got %v", got)
+	}
+	if got["redis_pubsub_exporter_scrape_timeout_seconds{}"] != DefaultScrapeTimeout.Seconds() {
+		t.Errorf("want the default scrape timeout, got %v", got["redis_pubsub_exporter_scrape_timeout_seconds{}"])
+	}
+
+	store.Update(func(l *Limits) {
+		l.MaxClients = 1
+		l.ChannelInclude = []string{"orders.*"}
+		l.ChannelExclude = []string{"*.debug"}
+	})
+	got = collect(t, c)
+
+	if got["redis_pubsub_channels_total{}"] != 1 {
+		t.Errorf("want 1 channel after filtering, got %v", got["redis_pubsub_channels_total{}"])
+	}
+	if _, ok := got["redis_pubsub_channel_subscriber_count{channel=orders.created}"]; !ok {
+		t.Error("included channel should be tracked")
+	}
+	if got["redis_pubsub_clients_total{}"] != 2 {
+		t.Errorf("clients_total should count all clients, got %v", got["redis_pubsub_clients_total{}"])
+	}
+	if _, ok := got["redis_pubsub_client_channel_subscriptions{client_addr=10.0.0.2:2,client_name=b}"]; ok {
+		t.Error("per-client series should be capped at MaxClients")
+	}
+	if got["redis_pubsub_exporter_limit{limit=max_clients}"] != 1 {
+		t.Errorf("runtime limit should be exported, got max_clients %v", got["redis_pubsub_exporter_limit{limit=max_clients}"])
+	}
+}
+
+func TestCollectClientNameFilters(t *testing.T) {
+	clientList := "id=1 addr=10.0.0.1:1 name=svc-orders sub=2 psub=0\n" +
+		"id=2 addr=10.0.0.2:2 name=svc-debug sub=1 psub=0\n" +
+		"id=3 addr=10.0.0.3:3 name= sub=1 psub=0\n" +
+		"id=4 addr=10.0.0.4:4 name=istio-proxy sub=0 psub=1"
+	tests := []struct {
+		name       string
+		include    string
+		exclude    string
+		maxClients int
+		want       []string
+	}{
+		{name: "no filters", want: []string{"svc-debug", "unnamed", "svc-orders", "istio-proxy"}},
+		{name: "include", include: "^svc-", want: []string{"svc-debug", "svc-orders"}},
+		{name: "include and exclude", include: "^svc-", exclude: "debug", want: []string{"svc-orders"}},
+		{name: "exclude unnamed sessions", exclude: "^unnamed$", want: []string{"svc-debug", "svc-orders", "istio-proxy"}},
+		{name: "MaxClients applies after filtering", include: "^svc-", maxClients: 1, want: []string{"svc-debug"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{MaxChannels: 100, MaxClients: tt.maxClients}
+			if tt.include != "" {
+				opts.ClientIncludeName = regexp.MustCompile(tt.include)
+			}
+			if tt.exclude != "" {
+				opts.ClientExcludeName = regexp.MustCompile(tt.exclude)
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			got := collect(t, New(&fakeQuerier{clientList: clientList}, opts, logger))
+
+			if got["redis_pubsub_clients_total{}"] != 4 {
+				t.Errorf("clients_total should count all clients, got %v", got["redis_pubsub_clients_total{}"])
+			}
+			var names []string
+			for key := range got {
+				if name, ok := strings.CutPrefix(key, "redis_pubsub_client_"); ok && strings.Contains(name, "subscriptions{") {
+					names = append(names, key[strings.Index(key, "client_name=")+len("client_name="):len(key)-1])
+				}
+			}
+			slices.Sort(names)
+			want := slices.Sorted(slices.Values(tt.want))
+			if !slices.Equal(names, want) {
+				t.Errorf("want per-client series for %q, got %q", want, names)
+			}
+		})
+	}
+}
+
+func TestCollectClientsByIP(t *testing.T) {
+	q := &fakeQuerier{
+		clientList: "id=1 addr=10.0.0.1:5001 name= sub=2 psub=0 omem=100\n" +
+			"id=2 addr=10.0.0.1:5002 name= sub=1 psub=1 omem=0\n" +
+			"id=3 addr=[::1]:6000 name=svc-a sub=3 psub=0\n" +
+			"id=4 addr=10.0.0.2:7000 name=debug sub=1 psub=0",
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{
+		MaxChannels:       100,
+		ClientAggregate:   ClientAggregateIP,
+		ClientExcludeName: regexp.MustCompile("^debug$"),
+	}, logger)
+	got := collect(t, c)
+
+	for key, want := range map[string]float64{
+		"redis_pubsub_client_ip_connections{client_ip=10.0.0.1}":           2,
+		"redis_pubsub_client_ip_channel_subscriptions{client_ip=10.0.0.1}": 3,
+		"redis_pubsub_client_ip_pattern_subscriptions{client_ip=10.0.0.1}": 1,
+		"redis_pubsub_client_ip_output_buffer_bytes{client_ip=10.0.0.1}":   100,
+		"redis_pubsub_client_ip_connections{client_ip=::1}":                1,
+		"redis_pubsub_client_ip_channel_subscriptions{client_ip=::1}":      3,
+		"redis_pubsub_clients_total{}":                                     4,
+	} {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("want %s %v, got %v", key, want, v)
+		}
+	}
+	for key := range got {
+		if strings.Contains(key, "client_ip=10.0.0.2") {
+			t.Errorf("excluded client should not be grouped: %s", key)
+		}
+		if strings.HasPrefix(key, "redis_pubsub_client_channel_subscriptions{") {
+			t.Errorf("per-connection series should be replaced: %s", key)
+		}
+	}
+}
+
+func TestCollectRuntimePatterns(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1, "users.login": 1, "jobs:1": 1}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := NewLimitStore(Limits{MaxChannels: 100})
+	c := New(q, Options{KnownPatterns: []string{"orders.*"}, Limits: store}, logger)
+
+	store.Update(func(l *Limits) {
+		l.AddPattern("jobs:*")
+		l.RemovePattern("users.*")
+	})
+	got := collect(t, c)
+
+	if got["redis_pubsub_pattern_subscriber_count{pattern=jobs:*}"] != 1 {
+		t.Error("runtime pattern should be checked")
+	}
+	if _, ok := got["redis_pubsub_pattern_subscriber_count{pattern=users.*}"]; ok {
+		t.Error("removed auto-discovered pattern should not be checked")
+	}
+	if _, ok := got["redis_pubsub_pattern_subscriber_count{pattern=orders.*}"]; !ok {
+		t.Error("known pattern should still be checked")
+	}
+}
+
+func TestCollectPatternShortfall(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{
+		"orders.created": 0, "orders.deleted": 3, // one busy channel is enough
+		"users.login": 0, "users.logout": 1, // two channels, but one subscriber
+		"payments.a": 0, "payments.b": 0,
+	}}
+	minSubs := map[string]int{"orders.*": 2, "users.*": 2, "payments.*": 1, "jobs.*": 0}
+	want := map[string]float64{
+		"redis_pubsub_pattern_subscriber_shortfall{pattern=orders.*}":   0,
+		"redis_pubsub_pattern_subscriber_shortfall{pattern=users.*}":    1,
+		"redis_pubsub_pattern_subscriber_shortfall{pattern=payments.*}": 1,
+		"redis_pubsub_pattern_subscriber_shortfall{pattern=jobs.*}":     0,
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "unlimited", opts: Options{MaxChannels: 100, KnownPatterns: []string{"orders.*"}, MinSubscribers: minSubs}},
+		{name: "pattern cap", opts: Options{MaxChannels: 100, MaxPatterns: 1, MinSubscribers: minSubs}},
+		{name: "command budget", opts: Options{MaxChannels: 100, MaxCommands: 3, MinSubscribers: minSubs}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, New(q, tt.opts, logger))
+			for key, w := range want {
+				if v, ok := got[key]; !ok || v != w {
+					t.Errorf("%s: want %v, got %v (present %v)", key, w, v, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternSubscribers(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{
+		"orders.created": 1, "orders.deleted": 3, "users.login": 2, "plain": 5,
+	}}
+	minSubs := map[string]int{"orders.*": 1, "orders.d*": 1, "users.*": 1, "jobs.*": 1}
+	c := New(q, Options{MinSubscribers: minSubs}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	got := c.patternSubscribers(context.Background(), &ScrapeState{Client: q})
+	want := map[string]int64{"orders.*": 4, "orders.d*": 3, "users.*": 2, "jobs.*": 0}
+	if !maps.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if q.pipelines != 1 || q.numsubCalls != 1 {
+		t.Errorf("want 1 PUBSUB CHANNELS pipeline and 1 PUBSUB NUMSUB, got %d and %d", q.pipelines, q.numsubCalls)
+	}
+}
+
+func TestPatternsToQuery(t *testing.T) {
+	channels := []string{"orders.created", "orders.deleted", "users.login", "jobs.a", "jobs.b", "jobs.c", "plain"}
+	tests := []struct {
+		name   string
+		known  []string
+		limits Limits
+		want   []string
+	}{
+		{
+			name:  "known first, then discovered by channel count",
+			known: []string{"users.*", "billing.*"},
+			want:  []string{"users.*", "billing.*", "jobs.*", "orders.*"},
+		},
+		{
+			name:   "runtime and ignored patterns",
+			known:  []string{"users.*"},
+			limits: Limits{Patterns: []string{"billing.*", "users.*"}, IgnoredPatterns: []string{"jobs.*"}},
+			want:   []string{"users.*", "billing.*", "orders.*"},
+		},
+		{
+			name:   "capped",
+			known:  []string{"billing.*"},
+			limits: Limits{MaxPatterns: 2},
+			want:   []string{"billing.*", "jobs.*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&fakeQuerier{}, Options{KnownPatterns: tt.known}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			got := c.patternsToQuery(channels, tt.limits)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPatternsToQueryDiscovery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(&fakeQuerier{}, Options{DiscoveryMinChannels: 2, DiscoveryMinScrapes: 2}, logger)
+
+	steady := []string{"orders.created", "orders.deleted"}
+	steps := []struct {
+		channels []string
+		want     []string
+	}{
+		{channels: steady, want: nil},
+		{channels: append(steady, "tmp.1", "tmp.2"), want: []string{"orders.*"}},
+		{channels: append(steady, "tmp.1", "tmp.2"), want: []string{"orders.*", "tmp.*"}},
+		// tmp.* decays over two scrapes; a single channel doesn't count.
+		{channels: append(steady, "tmp.1"), want: []string{"orders.*", "tmp.*"}},
+		{channels: steady, want: []string{"orders.*"}},
+		{channels: append(steady, "tmp.1", "tmp.2"), want: []string{"orders.*"}},
+	}
+	for i, s := range steps {
+		got := c.patternsToQuery(s.channels, c.limits.Get())
+		slices.Sort(got)
+		if !slices.Equal(got, s.want) {
+			t.Errorf("scrape %d: want %v, got %v", i, s.want, got)
+		}
+	}
+}
+
+func TestCollectPatternsPipelined(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1, "users.login": 1, "jobs.a": 1}}
+	got := collect(t, newTestCollector(q, nil))
+	if q.pipelines != 1 {
+		t.Errorf("want patterns queried in 1 pipeline, got %d", q.pipelines)
+	}
+	for _, p := range []string{"orders.*", "users.*", "jobs.*"} {
+		if got["redis_pubsub_pattern_subscriber_count{pattern="+p+"}"] != 1 {
+			t.Errorf("pattern %s should be checked", p)
+		}
+	}
+}
+
+func TestCollectPatternCache(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1, "orders.deleted": 1}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, PatternCacheTTL: time.Minute}, logger)
+	const key = "redis_pubsub_pattern_subscriber_count{pattern=orders.*}"
+
+	if got := collect(t, c); got[key] != 2 {
+		t.Fatalf("want 2 matching channels, got %v", got[key])
+	}
+	q.channels["orders.updated"] = 1
+	if got := collect(t, c); got[key] != 2 || q.pipelines != 1 {
+		t.Errorf("want cached count 2 without a query, got %v after %d pipelines", got[key], q.pipelines)
+	}
+
+	c.patternCache.entries["orders.*"] = patternResult{matching: 2, at: time.Now().Add(-2 * time.Minute)}
+	if got := collect(t, c); got[key] != 3 || q.pipelines != 2 {
+		t.Errorf("want expired entry re-queried, got %v after %d pipelines", got[key], q.pipelines)
+	}
+}
+
+func TestCollectStableOrder(t *testing.T) {
+	q := &fakeQuerier{
+		channels: map[string]int64{"orders.b": 1, "orders.a": 0, "users.z": 2, "users.y": 1, "jobs.x": 3},
+		clientList: "id=1 addr=10.0.0.9:1 name=zeta flags=P sub=1 resp=3 cmd=subscribe\n" +
+			"id=2 addr=10.0.0.1:1 name=alpha flags=P sub=1 resp=2 cmd=ping\n" +
+			"id=3 addr=10.0.0.5:1 name=alpha flags=S psub=1 cmd=psubscribe",
+	}
+	c := newTestCollector(q, nil)
+
+	// emitted returns the metrics of one scrape in emission order,
+	// without values, which differ between scrapes.
+	emitted := func() []string {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			c.Collect(ch)
+			close(ch)
+		}()
+		var out []string
+		for m := range ch {
+			var pb dto.Metric
+			if err := m.Write(&pb); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			s := m.Desc().String()
+			for _, lp := range pb.GetLabel() {
+				s += " " + lp.GetName() + "=" + lp.GetValue()
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+
+	want := emitted()
+	for i := 0; i < 5; i++ {
+		if got := emitted(); !slices.Equal(got, want) {
+			t.Fatalf("scrape %d emitted a different order:\nwant %v\ngot  %v", i, want, got)
+		}
+	}
+}
+
+func TestCollectHeartbeat(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		pingErr error
+		want    bool
+	}{
+		{name: "disabled"},
+		{name: "written after scrape", key: "exporter:heartbeat:pod-1", want: true},
+		{name: "not written on failure", key: "exporter:heartbeat:pod-1", pingErr: errors.New("connection refused")},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{pingErr: tt.pingErr}
+			c := New(q, Options{MaxChannels: 100, HeartbeatKey: tt.key, HeartbeatTTL: time.Minute}, logger)
+			collect(t, c)
+
+			ttl, ok := q.sets[tt.key]
+			if ok != tt.want {
+				t.Fatalf("want heartbeat written %v, got %v", tt.want, ok)
+			}
+			if ok && ttl != time.Minute {
+				t.Errorf("want TTL 1m, got %s", ttl)
+			}
+		})
+	}
+}
+
+func TestCollectClusterInfo(t *testing.T) {
+	q := &fakeQuerier{
+		clusterInfo: "cluster_state:fail\r\n" +
+			"cluster_slots_assigned:16384\r\n" +
+			"cluster_slots_ok:16000\r\n" +
+			"cluster_slots_pfail:284\r\n" +
+			"cluster_slots_fail:100\r\n" +
+			"cluster_known_nodes:6\r\n" +
+			"cluster_size:3\r\n",
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, ClusterMode: true}, logger)
+
+	got := collect(t, c)
+
+	want := map[string]float64{
+		"redis_pubsub_cluster_state{}":               0,
+		"redis_pubsub_cluster_slots{state=assigned}": 16384,
+		"redis_pubsub_cluster_slots{state=ok}":       16000,
+		"redis_pubsub_cluster_slots{state=pfail}":    284,
+		"redis_pubsub_cluster_slots{state=fail}":     100,
+		"redis_pubsub_cluster_known_nodes{}":         6,
+		"redis_pubsub_cluster_size{}":                3,
+	}
+	for key, v := range want {
+		if gv, ok := got[key]; !ok || gv != v {
+			t.Errorf("%s: want %v, got %v (present=%v)", key, v, gv, ok)
+		}
+	}
+}
+
+func TestCollectWithoutClusterMode(t *testing.T) {
+	q := &fakeQuerier{clusterInfo: "cluster_state:ok\r\n"}
+	got := collect(t, newTestCollector(q, nil))
+
+	if _, ok := got["redis_pubsub_cluster_state{}"]; ok {
+		t.Error("cluster metrics should only be emitted in cluster mode")
+	}
+}
+
+func TestCollectInfoFromReplica(t *testing.T) {
+	master := &fakeQuerier{info: map[string]map[string]string{
+		"clients": {"connected_clients": "100"},
+		"memory":  {"used_memory": "100"},
+	}}
+	replica := &fakeQuerier{info: map[string]map[string]string{
+		"clients": {"connected_clients": "7"},
+		"memory":  {"used_memory": "2048"},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(master, Options{MaxChannels: 100, InfoClient: replica}, logger)
+
+	got := collect(t, c)
+
+	if master.infoCalls != 1 || replica.infoCalls != 1 {
+		t.Errorf("want one INFO per client, got %d on master and %d on replica", master.infoCalls, replica.infoCalls)
+	}
+	if got["redis_pubsub_exporter_redis_connected_clients{}"] != 7 {
+		t.Errorf("connected_clients should come from replica, got %v", got["redis_pubsub_exporter_redis_connected_clients{}"])
+	}
+
+	// Replica failure falls back to master without failing the scrape.
+	replica.infoErr = errors.New("replica down")
+	got = collect(t, c)
+
+	if got["redis_pubsub_exporter_redis_up{}"] != 1 {
+		t.Error("replica failure should not fail the scrape")
+	}
+	if got["redis_pubsub_exporter_redis_used_memory_bytes{}"] != 100 {
+		t.Errorf("used_memory should fall back to master, got %v", got["redis_pubsub_exporter_redis_used_memory_bytes{}"])
+	}
+}
+
+func TestCollectUnsupportedCommands(t *testing.T) {
+	tests := []struct {
+		name            string
+		channelsErr     error
+		numPatErr       error
+		clientErr       error
+		wantUp          float64
+		wantDegraded    float64
+		wantClientCalls int
+		wantAbsent      []string
+		wantValues      map[string]float64
+	}{
+		{
+			name:            "dragonfly without NUMPAT and CLIENT LIST",
+			numPatErr:       errors.New("ERR unknown subcommand 'NUMPAT'"),
+			clientErr:       errors.New("ERR unknown command 'CLIENT', with args beginning with: 'LIST'"),
+			wantUp:          1,
+			wantDegraded:    1,
+			wantClientCalls: 1,
+			wantValues: map[string]float64{
+				"redis_pubsub_channel_subscriber_count{channel=orders.created}":    1,
+				"redis_pubsub_patterns_total{}":                                    4,
+				"redis_pubsub_clients_total{}":                                     2,
+				"redis_pubsub_exporter_command_unsupported{command=PUBSUB NUMPAT}": 1,
+				"redis_pubsub_exporter_command_unsupported{command=CLIENT LIST}":   1,
+			},
+		},
+		{
+			name:            "proxy rejecting PUBSUB CHANNELS falls back to INFO",
+			channelsErr:     errors.New("ERR unsupported command"),
+			wantUp:          1,
+			wantDegraded:    1,
+			wantClientCalls: 2,
+			wantAbsent:      []string{"redis_pubsub_channel_subscriber_count{channel=orders.created}", "redis_pubsub_orphan_channels_total{}"},
+			wantValues: map[string]float64{
+				"redis_pubsub_channels_total{}":                                      7,
+				"redis_pubsub_exporter_command_unsupported{command=PUBSUB CHANNELS}": 1,
+			},
+		},
+		{
+			name:            "other errors still fail the scrape",
+			clientErr:       errors.New("i/o timeout"),
+			wantUp:          0,
+			wantClientCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{
+				info: map[string]map[string]string{
+					"stats":   {"pubsub_channels": "7", "pubsub_patterns": "4"},
+					"clients": {"pubsub_clients": "2"},
+				},
+				channels:    map[string]int64{"orders.created": 1},
+				channelsErr: tt.channelsErr,
+				numPatErr:   tt.numPatErr,
+				clientErr:   tt.clientErr,
+			}
+			c := newTestCollector(q, nil)
+
+			for i := 0; i < 2; i++ {
+				got := collect(t, c)
+				if got["redis_pubsub_exporter_redis_up{}"] != tt.wantUp {
+					t.Fatalf("scrape %d: want redis_up %v, got %v", i, tt.wantUp, got["redis_pubsub_exporter_redis_up{}"])
+				}
+				if got["redis_pubsub_exporter_degraded{}"] != tt.wantDegraded {
+					t.Errorf("scrape %d: want degraded %v, got %v", i, tt.wantDegraded, got["redis_pubsub_exporter_degraded{}"])
+				}
+				for key, want := range tt.wantValues {
+					if v, ok := got[key]; !ok || v != want {
+						t.Errorf("scrape %d: want %s %v, got %v", i, key, want, v)
+					}
+				}
+				for _, key := range tt.wantAbsent {
+					if _, ok := got[key]; ok {
+						t.Errorf("scrape %d: %s should be skipped", i, key)
+					}
+				}
+			}
+			// Rejected commands are not sent again until the recheck
+			if q.clientCalls != tt.wantClientCalls {
+				t.Errorf("want %d CLIENT LIST calls, got %d", tt.wantClientCalls, q.clientCalls)
+			}
+		})
+	}
+}
+
+func TestCollectCriticalChannels(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{
+		MaxChannels:      100,
+		ChannelExclude:   []string{"payments.*"},
+		CriticalChannels: []string{"payments.settled", "orders.created", "payments.settled"},
+	}, logger)
+
+	tests := []struct {
+		name        string
+		subscribers int64
+		wantDropped float64
+		wantDrops   float64
+	}{
+		{name: "first scrape has no previous count", subscribers: 2, wantDropped: 0, wantDrops: 0},
+		{name: "fall to zero is detected", subscribers: 0, wantDropped: 1, wantDrops: 1},
+		{name: "staying at zero is not a new drop", subscribers: 0, wantDropped: 0, wantDrops: 1},
+		{name: "recovery", subscribers: 1, wantDropped: 0, wantDrops: 1},
+		{name: "second drop is counted", subscribers: 0, wantDropped: 1, wantDrops: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Excluded from tracking, still pinned as critical
+			q.channels["payments.settled"] = tt.subscribers
+			got := collect(t, c)
+
+			if v := got["redis_pubsub_channel_subscriber_drop_detected{channel=payments.settled}"]; v != tt.wantDropped {
+				t.Errorf("want drop_detected %v, got %v", tt.wantDropped, v)
+			}
+			if v := got["redis_pubsub_channel_subscriber_drops_total{channel=payments.settled}"]; v != tt.wantDrops {
+				t.Errorf("want drops_total %v, got %v", tt.wantDrops, v)
+			}
+			if v, ok := got["redis_pubsub_channel_subscriber_drop_detected{channel=orders.created}"]; !ok || v != 0 {
+				t.Errorf("a critical channel that never had subscribers should report 0, got %v", v)
+			}
+			if v, ok := got["redis_pubsub_channel_subscriber_count{channel=payments.settled}"]; !ok || v != float64(tt.subscribers) {
+				t.Errorf("critical channels should bypass the channel filters, want %d subscribers, got %v", tt.subscribers, v)
+			}
+		})
+	}
+}
+
+func TestCollectCriticalChannelsPinned(t *testing.T) {
+	// A noisy neighbour floods the channel space ahead of the critical channel
+	q := &fakeQuerier{channels: map[string]int64{"zz.orders": 3}}
+	for i := range 10 {
+		q.channels["aa.noise."+strconv.Itoa(i)] = 1
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 5, CriticalChannels: []string{"zz.orders", "aa.noise.0"}}, logger)
+
+	got := collect(t, c)
+	if got["redis_pubsub_channel_subscriber_count{channel=zz.orders}"] != 3 {
+		t.Errorf("critical channel beyond MAX_CHANNELS should be emitted, got %v", got["redis_pubsub_channel_subscriber_count{channel=zz.orders}"])
+	}
+	if got["redis_pubsub_channels_total{}"] != 5 {
+		t.Errorf("pinned channels should not count as tracked, want channels_total 5, got %v", got["redis_pubsub_channels_total{}"])
+	}
+	n := 0
+	for key := range got {
+		if strings.HasPrefix(key, "redis_pubsub_channel_subscriber_count{") {
+			n++
+		}
+	}
+	if n != 6 {
+		t.Errorf("want 5 tracked channels plus the pinned one, got %d series", n)
+	}
+}
+
+func TestCollectInfoOnly(t *testing.T) {
+	q := &fakeQuerier{
+		info: map[string]map[string]string{
+			"stats":   {"pubsub_channels": "120000", "pubsub_patterns": "12"},
+			"clients": {"connected_clients": "900", "pubsub_clients": "850"},
+		},
+		channels: map[string]int64{"orders.created": 1},
+		numPat:   3,
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, Mode: ModeInfoOnly, KnownPatterns: []string{"orders.*"}}, logger)
+
+	got := collect(t, c)
+	if got["redis_pubsub_exporter_redis_up{}"] != 1 {
+		t.Fatalf("want redis_up 1, got %v", got["redis_pubsub_exporter_redis_up{}"])
+	}
+	for key, want := range map[string]float64{
+		"redis_pubsub_channels_total{}": 120000,
+		"redis_pubsub_patterns_total{}": 12,
+		"redis_pubsub_clients_total{}":  850,
+	} {
+		if got[key] != want {
+			t.Errorf("want %s %v, got %v", key, want, got[key])
+		}
+	}
+	for _, key := range []string{
+		"redis_pubsub_channel_subscriber_count{channel=orders.created}",
+		"redis_pubsub_orphan_channels_total{}",
+		"redis_pubsub_pattern_subscriber_count{pattern=orders.*}",
+	} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s should not be emitted in info-only mode", key)
+		}
+	}
+	if q.clientCalls != 0 || q.pipelines != 0 {
+		t.Errorf("info-only mode should not enumerate, got %d CLIENT LIST and %d pipelines", q.clientCalls, q.pipelines)
+	}
+	if got["redis_pubsub_exporter_scrape_redis_commands{}"] != 2 {
+		t.Errorf("want PING and INFO only, got %v commands", got["redis_pubsub_exporter_scrape_redis_commands{}"])
+	}
+}
+
+func TestCollectUnsupportedRecheck(t *testing.T) {
+	q := &fakeQuerier{
+		channels:  map[string]int64{"orders.created": 1},
+		clientErr: errors.New("ERR unknown command 'CLIENT'"),
+	}
+	c := newTestCollector(q, nil)
+	collect(t, c)
+
+	// The proxy was replaced by one that allows CLIENT LIST
+	q.clientErr = nil
+	collect(t, c)
+	if q.clientCalls != 1 {
+		t.Fatalf("CLIENT LIST should be skipped before the recheck, got %d calls", q.clientCalls)
+	}
+	c.unsupported["CLIENT LIST"] = time.Now().Add(-unsupportedRecheck)
+	got := collect(t, c)
+	if q.clientCalls != 2 {
+		t.Errorf("CLIENT LIST should be retried after the recheck, got %d calls", q.clientCalls)
+	}
+	if got["redis_pubsub_exporter_degraded{}"] != 0 {
+		t.Errorf("want degraded 0 once the command is accepted, got %v", got["redis_pubsub_exporter_degraded{}"])
+	}
+	if _, ok := got["redis_pubsub_exporter_command_unsupported{command=CLIENT LIST}"]; ok {
+		t.Error("command_unsupported should be cleared once the command is accepted")
+	}
+}
+
+func TestCollectHashMetricsFromDB(t *testing.T) {
+	main := &fakeQuerier{hashes: map[string]map[string]string{"app:sessions": {"eu": "0"}}}
+	dbs := map[int]*fakeQuerier{
+		1: {hashes: map[string]map[string]string{"app:sessions": {"eu": "1"}}},
+		2: {hashes: map[string]map[string]string{"app:sessions": {"eu": "42"}}},
+	}
+	var requested []int
+
+	two := 2
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(main, Options{
+		MaxChannels: 100,
+		HashMetrics: []config.HashMetricDef{
+			{RedisKey: "app:sessions", MetricName: "sessions_default", Help: "h", FieldLabel: "region"},
+			{RedisKey: "app:sessions", MetricName: "sessions_db2", Help: "h", FieldLabel: "region", DB: &two},
+		},
+		DB: 1,
+		DBClient: func(db int) KeyReader {
+			requested = append(requested, db)
+			return dbs[db]
+		},
+	}, logger)
+
+	got := collect(t, c)
+
+	if got["redis_pubsub_sessions_default{region=eu}"] != 1 {
+		t.Errorf("definition without db should read the configured db through its own client, got %v", got["redis_pubsub_sessions_default{region=eu}"])
+	}
+	if got["redis_pubsub_sessions_db2{region=eu}"] != 42 {
+		t.Errorf("definition with db=2 should read db 2, got %v", got["redis_pubsub_sessions_db2{region=eu}"])
+	}
+	if !slices.Equal(requested, []int{1, 2}) {
+		t.Errorf("want one request per db, got %v", requested)
+	}
+	for _, db := range []string{"1", "2"} {
+		if _, ok := got["redis_pubsub_exporter_hash_metrics_duration_seconds{db="+db+"}"]; !ok {
+			t.Errorf("missing hash metrics duration for db %s", db)
+		}
+	}
+}
+
+func TestCollectHashValueMap(t *testing.T) {
+	q := &fakeQuerier{hashes: map[string]map[string]string{
+		"app:health": {"api": "up", "worker": "degraded", "cron": "down", "db": "1", "cache": "unknown"},
+	}}
+	hashDefs := []config.HashMetricDef{{
+		RedisKey: "app:health", MetricName: "service_status", Help: "Status", FieldLabel: "service",
+		ValueMap: map[string]float64{"up": 1, "down": 0, "degraded": 0.5},
+	}}
+
+	got := collect(t, newTestCollector(q, hashDefs))
+
+	want := map[string]float64{
+		"redis_pubsub_service_status{service=api}":    1,
+		"redis_pubsub_service_status{service=worker}": 0.5,
+		"redis_pubsub_service_status{service=cron}":   0,
+		"redis_pubsub_service_status{service=db}":     1,
+	}
+	for key, v := range want {
+		if gv, ok := got[key]; !ok || gv != v {
+			t.Errorf("%s: want %v, got %v (present=%v)", key, v, gv, ok)
+		}
+	}
+	if _, ok := got["redis_pubsub_service_status{service=cache}"]; ok {
+		t.Error("unmapped non-numeric value should be skipped")
+	}
+}
+
+func TestCollectMinInterval(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name        string
+		minInterval time.Duration
+		want        float64 // subscriber count seen by the second scrape
+	}{
+		{name: "disabled queries every scrape", minInterval: 0, want: 5},
+		{name: "second scrape within window is cached", minInterval: time.Hour, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q.channels["orders.created"] = 1
+			c := New(q, Options{MaxChannels: 100, MinInterval: tt.minInterval}, logger)
+
+			collect(t, c)
+			q.channels["orders.created"] = 5
+			got := collect(t, c)
+
+			key := "redis_pubsub_channel_subscriber_count{channel=orders.created}"
+			if got[key] != tt.want {
+				t.Errorf("%s: want %v, got %v", key, tt.want, got[key])
+			}
+			if got["redis_pubsub_exporter_redis_up{}"] != 1 {
+				t.Error("cached scrape should keep redis_up")
+			}
+		})
+	}
+}
+
+func TestCollectServesStaleWhileScraping(t *testing.T) {
+	q := &fakeQuerier{channels: map[string]int64{"orders.created": 1}}
+	c := newTestCollector(q, nil)
+	collect(t, c) // initial snapshot
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	q.pingHook = func() {
+		close(entered)
+		<-release
+	}
+	q.channels["orders.created"] = 5
+
+	slow := make(chan map[string]float64)
+	go func() { slow <- collect(t, c) }()
+	<-entered
+
+	// A concurrent scrape must not wait for the slow one.
+	got := collect(t, c)
+	if got["redis_pubsub_exporter_scrape_stale{}"] != 1 {
+		t.Error("concurrent scrape should be marked stale")
+	}
+	if got["redis_pubsub_channel_subscriber_count{channel=orders.created}"] != 1 {
+		t.Errorf("concurrent scrape should serve the previous snapshot, got %v",
+			got["redis_pubsub_channel_subscriber_count{channel=orders.created}"])
+	}
+	if !c.IsRedisUp() {
+		t.Error("IsRedisUp should not block on or be reset by a running scrape")
+	}
+
+	close(release)
+	got = <-slow
+	if got["redis_pubsub_exporter_scrape_stale{}"] != 0 {
+		t.Error("fresh scrape should not be marked stale")
+	}
+	if got["redis_pubsub_channel_subscriber_count{channel=orders.created}"] != 5 {
+		t.Errorf("fresh scrape should see new data, got %v",
+			got["redis_pubsub_channel_subscriber_count{channel=orders.created}"])
+	}
+}
+
+func TestCollectFailureBackoff(t *testing.T) {
+	pings := 0
+	q := &fakeQuerier{pingErr: errors.New("connection refused")}
+	q.pingHook = func() { pings++ }
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, FailureBackoff: time.Hour}, logger)
+
+	for i := 0; i < 3; i++ {
+		got := collect(t, c)
+		if got["redis_pubsub_exporter_redis_up{}"] != 0 {
+			t.Fatalf("scrape %d: expected redis_up 0", i)
+		}
+		if got["redis_pubsub_exporter_scrape_errors_total{}"] != 1 {
+			t.Errorf("scrape %d: errors should not grow while backing off, got %v", i, got["redis_pubsub_exporter_scrape_errors_total{}"])
+		}
+	}
+	if pings != 1 {
+		t.Errorf("redis should be queried once during backoff, got %d pings", pings)
+	}
+
+	// Once the window has passed Redis is queried again.
+	c.retryAt = time.Now().Add(-time.Second)
+	q.pingErr = nil
+	if got := collect(t, c); got["redis_pubsub_exporter_redis_up{}"] != 1 {
+		t.Error("expected recovery after the backoff window")
+	}
+	if pings != 2 {
+		t.Errorf("want 2 pings, got %d", pings)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{name: "disabled", base: 0, max: time.Minute, failures: 3, want: 0},
+		{name: "first failure", base: 5 * time.Second, max: time.Minute, failures: 1, want: 5 * time.Second},
+		{name: "doubles", base: 5 * time.Second, max: time.Minute, failures: 3, want: 20 * time.Second},
+		{name: "capped", base: 5 * time.Second, max: time.Minute, failures: 10, want: time.Minute},
+		{name: "no max means no growth", base: 5 * time.Second, failures: 4, want: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &RedisPubSubCollector{backoff: tt.base, backoffMax: tt.max, failures: tt.failures}
+			if got := c.nextBackoff(); got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsRedisUp(t *testing.T) {
+	tests := []struct {
+		name         string
+		threshold    int
+		maxStaleness time.Duration
+		failures     int
+		lastSuccess  time.Duration // age of the last successful scrape; 0 = never
+		want         bool
+	}{
+		{name: "never scraped", want: false},
+		{name: "healthy", lastSuccess: time.Second, want: true},
+		{name: "default threshold", failures: 1, lastSuccess: time.Second, want: false},
+		{name: "below threshold", threshold: 3, failures: 2, lastSuccess: time.Second, want: true},
+		{name: "threshold reached", threshold: 3, failures: 3, lastSuccess: time.Second, want: false},
+		{name: "fresh", threshold: 3, maxStaleness: time.Minute, failures: 1, lastSuccess: 30 * time.Second, want: true},
+		{name: "stale", threshold: 3, maxStaleness: time.Minute, failures: 1, lastSuccess: 2 * time.Minute, want: false},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&fakeQuerier{}, Options{
+				ReadyFailureThreshold: tt.threshold,
+				ReadyMaxStaleness:     tt.maxStaleness,
+			}, logger)
+			c.failures = tt.failures
+			if tt.lastSuccess > 0 {
+				c.lastSuccess = time.Now().Add(-tt.lastSuccess)
+			}
+			if got := c.IsRedisUp(); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
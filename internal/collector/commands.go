@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// commandCount tallies the Redis commands of one scrape and the round trips
+// they took; a pipeline is several commands in one round trip.
+type commandCount struct {
+	commands, roundTrips int
+}
+
+func (n *commandCount) add(commands int) {
+	n.commands += commands
+	n.roundTrips++
+}
+
+// countingQuerier counts the commands issued through a RedisQuerier and
+// wraps their failures in CommandErrors. It is what subsystems see as
+// ScrapeState.Client.
+type countingQuerier struct {
+	RedisQuerier
+	n *commandCount
+}
+
+func (q countingQuerier) Ping(ctx context.Context) *redis.StatusCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.Ping(ctx)
+	wrapCmdErr("PING", cmd)
+	return cmd
+}
+
+func (q countingQuerier) InfoMap(ctx context.Context, sections ...string) *redis.InfoCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.InfoMap(ctx, sections...)
+	wrapCmdErr("INFO", cmd)
+	return cmd
+}
+
+func (q countingQuerier) PubSubChannels(ctx context.Context, pattern string) *redis.StringSliceCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.PubSubChannels(ctx, pattern)
+	wrapCmdErr("PUBSUB CHANNELS", cmd)
+	return cmd
+}
+
+func (q countingQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	q.n.add(len(patterns))
+	cmds := q.RedisQuerier.PubSubChannelsMulti(ctx, patterns...)
+	for _, cmd := range cmds {
+		wrapCmdErr("PUBSUB CHANNELS", cmd)
+	}
+	return cmds
+}
+
+func (q countingQuerier) PubSubNumSub(ctx context.Context, channels ...string) *redis.MapStringIntCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.PubSubNumSub(ctx, channels...)
+	wrapCmdErr("PUBSUB NUMSUB", cmd)
+	return cmd
+}
+
+func (q countingQuerier) PubSubNumPat(ctx context.Context) *redis.IntCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.PubSubNumPat(ctx)
+	wrapCmdErr("PUBSUB NUMPAT", cmd)
+	return cmd
+}
+
+func (q countingQuerier) ClientList(ctx context.Context) *redis.StringCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.ClientList(ctx)
+	wrapCmdErr("CLIENT LIST", cmd)
+	return cmd
+}
+
+func (q countingQuerier) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.HGetAll(ctx, key)
+	wrapCmdErr("HGETALL", cmd)
+	return cmd
+}
+
+func (q countingQuerier) ClusterInfo(ctx context.Context) *redis.StringCmd {
+	q.n.add(1)
+	cmd := q.RedisQuerier.ClusterInfo(ctx)
+	wrapCmdErr("CLUSTER INFO", cmd)
+	return cmd
+}
+
+// countingReader counts the commands issued through a KeyReader.
+type countingReader struct {
+	KeyReader
+	n *commandCount
+}
+
+func (r countingReader) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	r.n.add(1)
+	cmd := r.KeyReader.HGetAll(ctx, key)
+	wrapCmdErr("HGETALL", cmd)
+	return cmd
+}
@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+func TestCollectRedisCommands(t *testing.T) {
+	tests := []struct {
+		name           string
+		hashDefs       []config.HashMetricDef
+		wantCommands   float64
+		wantRoundTrips float64
+	}{
+		{
+			// PING, INFO, PUBSUB CHANNELS, NUMSUB, CLIENT LIST, NUMPAT and
+			// the orders.* and users.* patterns in one pipeline.
+			name:           "defaults",
+			wantCommands:   8,
+			wantRoundTrips: 7,
+		},
+		{
+			name:           "hash metrics",
+			hashDefs:       []config.HashMetricDef{{RedisKey: "app:sessions", MetricName: "sessions", Help: "Sessions", FieldLabel: "region"}},
+			wantCommands:   9,
+			wantRoundTrips: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{channels: map[string]int64{"orders.created": 1, "users.login": 2}}
+			got := collect(t, newTestCollector(q, tt.hashDefs))
+
+			if v := got["redis_pubsub_exporter_scrape_redis_commands{}"]; v != tt.wantCommands {
+				t.Errorf("want %v commands, got %v", tt.wantCommands, v)
+			}
+			if v := got["redis_pubsub_exporter_scrape_redis_round_trips{}"]; v != tt.wantRoundTrips {
+				t.Errorf("want %v round trips, got %v", tt.wantRoundTrips, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// unsupportedReplies are fragments of error replies that Redis-compatible
+// servers (Dragonfly, older KeyDB, managed proxies) return for commands or
+// subcommands they do not implement.
+var unsupportedReplies = []string{
+	"unknown command",
+	"unknown subcommand",
+	"not supported",
+	"not implemented",
+	"unsupported",
+}
+
+// unsupportedRecheck is how long a rejected command is not sent again. It is
+// then retried once, in case the proxy in front of Redis was replaced.
+const unsupportedRecheck = 10 * time.Minute
+
+// errSkipped is returned for commands skipped because the server rejected
+// them before; isUnsupported matches it.
+var errSkipped = errors.New("command not supported by the server, skipped")
+
+// isUnsupported reports whether err is a server reply rejecting the command
+// itself rather than a connection or timeout failure.
+func isUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range unsupportedReplies {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compatQuerier stops sending commands the server rejected as unsupported,
+// e.g. admin commands blocked by Envoy or Twemproxy, until unsupportedRecheck
+// has passed. Skipped commands fail with errSkipped without a round trip.
+// It is only used under c.scrapeMu.
+type compatQuerier struct {
+	RedisQuerier
+	c *RedisPubSubCollector
+}
+
+func (q compatQuerier) InfoMap(ctx context.Context, sections ...string) *redis.InfoCmd {
+	if q.c.skipping("INFO") {
+		cmd := redis.NewInfoCmd(ctx)
+		cmd.SetErr(errSkipped)
+		return cmd
+	}
+	cmd := q.RedisQuerier.InfoMap(ctx, sections...)
+	q.c.observe("INFO", cmd.Err())
+	return cmd
+}
+
+func (q compatQuerier) PubSubChannels(ctx context.Context, pattern string) *redis.StringSliceCmd {
+	if q.c.skipping("PUBSUB CHANNELS") {
+		return redis.NewStringSliceResult(nil, errSkipped)
+	}
+	cmd := q.RedisQuerier.PubSubChannels(ctx, pattern)
+	q.c.observe("PUBSUB CHANNELS", cmd.Err())
+	return cmd
+}
+
+func (q compatQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	if q.c.skipping("PUBSUB CHANNELS") {
+		cmds := make([]*redis.StringSliceCmd, len(patterns))
+		for i := range cmds {
+			cmds[i] = redis.NewStringSliceResult(nil, errSkipped)
+		}
+		return cmds
+	}
+	cmds := q.RedisQuerier.PubSubChannelsMulti(ctx, patterns...)
+	if len(cmds) > 0 {
+		q.c.observe("PUBSUB CHANNELS", cmds[0].Err())
+	}
+	return cmds
+}
+
+func (q compatQuerier) PubSubNumSub(ctx context.Context, channels ...string) *redis.MapStringIntCmd {
+	if q.c.skipping("PUBSUB NUMSUB") {
+		return redis.NewMapStringIntCmdResult(nil, errSkipped)
+	}
+	cmd := q.RedisQuerier.PubSubNumSub(ctx, channels...)
+	q.c.observe("PUBSUB NUMSUB", cmd.Err())
+	return cmd
+}
+
+func (q compatQuerier) PubSubNumPat(ctx context.Context) *redis.IntCmd {
+	if q.c.skipping("PUBSUB NUMPAT") {
+		return redis.NewIntResult(0, errSkipped)
+	}
+	cmd := q.RedisQuerier.PubSubNumPat(ctx)
+	q.c.observe("PUBSUB NUMPAT", cmd.Err())
+	return cmd
+}
+
+func (q compatQuerier) ClientList(ctx context.Context) *redis.StringCmd {
+	if q.c.skipping("CLIENT LIST") {
+		return redis.NewStringResult("", errSkipped)
+	}
+	cmd := q.RedisQuerier.ClientList(ctx)
+	q.c.observe("CLIENT LIST", cmd.Err())
+	return cmd
+}
+
+func (q compatQuerier) ClusterInfo(ctx context.Context) *redis.StringCmd {
+	if q.c.skipping("CLUSTER INFO") {
+		return redis.NewStringResult("", errSkipped)
+	}
+	cmd := q.RedisQuerier.ClusterInfo(ctx)
+	q.c.observe("CLUSTER INFO", cmd.Err())
+	return cmd
+}
+
+// skipping reports whether command was rejected within unsupportedRecheck.
+func (c *RedisPubSubCollector) skipping(command string) bool {
+	at, ok := c.unsupported[command]
+	return ok && time.Since(at) < unsupportedRecheck
+}
+
+// observe records whether the server accepted command. The first rejection
+// is logged; its metrics are absent, or derived from INFO, from then on.
+func (c *RedisPubSubCollector) observe(command string, err error) {
+	_, known := c.unsupported[command]
+	switch {
+	case isUnsupported(err):
+		if !known {
+			c.logger.Warn("server does not support command, skipping its metrics",
+				"command", command,
+				"error", err,
+			)
+		}
+		if c.unsupported == nil {
+			c.unsupported = make(map[string]time.Time)
+		}
+		c.unsupported[command] = time.Now()
+	case err == nil && known:
+		c.logger.Info("server supports command again", "command", command)
+		delete(c.unsupported, command)
+	}
+}
+
+// collectDegraded emits whether the exporter runs with commands skipped and
+// which ones.
+func (c *RedisPubSubCollector) collectDegraded(ch chan<- prometheus.Metric) {
+	degraded := 0.0
+	if len(c.unsupported) > 0 {
+		degraded = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.degraded, prometheus.GaugeValue, degraded)
+	for _, command := range slices.Sorted(maps.Keys(c.unsupported)) {
+		ch <- prometheus.MustNewConstMetric(c.commandUnsupported, prometheus.GaugeValue, 1, command)
+	}
+}
@@ -0,0 +1,25 @@
+package collector
+
+// Counters holds the collector's cumulative counters. They can be saved and
+// restored so counter metrics don't reset when the exporter restarts.
+type Counters struct {
+	ScrapeErrors float64 `json:"scrape_errors_total"`
+	AuthFailures float64 `json:"auth_failures_total,omitempty"`
+}
+
+// Counters returns the current counter values. It waits for a running scrape.
+func (c *RedisPubSubCollector) Counters() Counters {
+	c.scrapeMu.Lock()
+	defer c.scrapeMu.Unlock()
+	return Counters{ScrapeErrors: c.scrapeErrors, AuthFailures: c.authFailures}
+}
+
+// RestoreCounters adds saved counter values to the current ones. Call it
+// before the first scrape; adding rather than replacing keeps counters
+// monotonic if a scrape already happened.
+func (c *RedisPubSubCollector) RestoreCounters(saved Counters) {
+	c.scrapeMu.Lock()
+	defer c.scrapeMu.Unlock()
+	c.scrapeErrors += saved.ScrapeErrors
+	c.authFailures += saved.AuthFailures
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectCritical queries the subscribers of the critical channels, tracked
+// or not, and emits whether each fell to zero since the previous scrape.
+// A channel without subscribers drops out of PUBSUB CHANNELS, so they are
+// queried by name. Critical channels left out by the filters or MaxChannels
+// still get their channel_subscriber_count. The drop counters are emitted
+// even if the query fails.
+func (c *RedisPubSubCollector) collectCritical(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	if len(c.criticalChannels) == 0 {
+		return nil
+	}
+	var err error
+	if !s.OverBudget() {
+		var numsub map[string]int64
+		numsub, err = s.Client.PubSubNumSub(ctx, c.criticalChannels...).Result()
+		if err == nil {
+			c.observeCritical(ch, numsub)
+			for _, channel := range c.criticalChannels {
+				if _, tracked := s.Subscribers[channel]; !tracked {
+					ch <- prometheus.MustNewConstMetric(c.channelSubscriberCount, prometheus.GaugeValue, float64(numsub[channel]), channel)
+				}
+			}
+		}
+	}
+	for _, channel := range c.criticalChannels {
+		ch <- prometheus.MustNewConstMetric(c.subscriberDrops, prometheus.CounterValue, c.criticalDrops[channel], channel)
+	}
+	return err
+}
+
+// observeCritical records the subscribers of the critical channels and
+// emits subscriber_drop_detected for each.
+func (c *RedisPubSubCollector) observeCritical(ch chan<- prometheus.Metric, numsub map[string]int64) {
+	for _, channel := range c.criticalChannels {
+		n := numsub[channel]
+		dropped := 0.0
+		if prev := c.criticalLast[channel]; prev > 0 && n == 0 {
+			dropped = 1
+			c.criticalDrops[channel]++
+			c.logger.Warn("critical channel lost all subscribers", "channel", channel, "previous", prev)
+		}
+		c.criticalLast[channel] = n
+		ch <- prometheus.MustNewConstMetric(c.subscriberDropDetected, prometheus.GaugeValue, dropped, channel)
+	}
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// maxDiffChannels caps the channel names listed per kind of change, so a
+// mass reconnect doesn't produce a huge log line; the counts stay exact.
+const maxDiffChannels = 10
+
+// logDiff logs, at debug level, how the tracked channels changed since the
+// previous scrape: channels added and removed, and subscriber counts that
+// moved by at least DiffMinDelta. Nothing is kept between scrapes unless
+// debug logging is enabled. Called under scrapeMu.
+func (c *RedisPubSubCollector) logDiff(ctx context.Context, subscribers map[string]int64) {
+	if !c.logger.Enabled(ctx, slog.LevelDebug) {
+		c.lastSubs = nil
+		return
+	}
+	last := c.lastSubs
+	c.lastSubs = maps.Clone(subscribers)
+	if last == nil {
+		return
+	}
+
+	var added, removed, changed []string
+	for _, channel := range slices.Sorted(maps.Keys(subscribers)) {
+		n := subscribers[channel]
+		prev, ok := last[channel]
+		switch {
+		case !ok:
+			added = append(added, channel)
+		case n-prev >= c.diffMinDelta || prev-n >= c.diffMinDelta:
+			changed = append(changed, fmt.Sprintf("%s:%+d", channel, n-prev))
+		}
+	}
+	for _, channel := range slices.Sorted(maps.Keys(last)) {
+		if _, ok := subscribers[channel]; !ok {
+			removed = append(removed, channel)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return
+	}
+	c.logger.Debug("scrape diff",
+		"channels_added", len(added), "added", firstN(added),
+		"channels_removed", len(removed), "removed", firstN(removed),
+		"subscribers_changed", len(changed), "changed", firstN(changed),
+	)
+}
+
+// firstN returns at most maxDiffChannels entries of s.
+func firstN(s []string) []string {
+	return s[:min(len(s), maxDiffChannels)]
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    slog.Level
+		before   map[string]int64
+		after    map[string]int64
+		minDelta int
+		want     []string // substrings of the diff line; nil = no line
+	}{
+		{
+			name:   "added, removed and changed channels",
+			level:  slog.LevelDebug,
+			before: map[string]int64{"orders.created": 1, "orders.paid": 5},
+			after:  map[string]int64{"orders.paid": 2, "orders.shipped": 1},
+			want: []string{
+				"channels_added=1 added=[orders.shipped]",
+				"channels_removed=1 removed=[orders.created]",
+				"subscribers_changed=1 changed=[orders.paid:-3]",
+			},
+		},
+		{
+			name:     "changes below the threshold are ignored",
+			level:    slog.LevelDebug,
+			before:   map[string]int64{"orders.created": 1},
+			after:    map[string]int64{"orders.created": 3},
+			minDelta: 5,
+		},
+		{
+			name:   "nothing at info level",
+			level:  slog.LevelInfo,
+			before: map[string]int64{"orders.created": 1},
+			after:  map[string]int64{"orders.created": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{channels: tt.before}
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level}))
+			c := New(q, Options{MaxChannels: 100, DiffMinDelta: tt.minDelta}, logger)
+
+			collect(t, c)
+			q.channels = tt.after
+			buf.Reset()
+			collect(t, c)
+
+			var line string
+			for _, l := range strings.Split(buf.String(), "\n") {
+				if strings.Contains(l, "scrape diff") {
+					line = l
+				}
+			}
+			if tt.want == nil {
+				if line != "" {
+					t.Fatalf("want no diff, got %q", line)
+				}
+				return
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(line, w) {
+					t.Errorf("diff %q should contain %q", line, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLogDiffFirstScrape(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := New(&fakeQuerier{channels: map[string]int64{"orders.created": 1}}, Options{MaxChannels: 100}, logger)
+
+	collect(t, c)
+	if strings.Contains(buf.String(), "scrape diff") {
+		t.Error("the first scrape has nothing to diff against")
+	}
+}
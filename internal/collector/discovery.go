@@ -0,0 +1,63 @@
+package collector
+
+// prefixDiscovery filters auto-discovered pattern prefixes so short-lived
+// noise doesn't cost a PUBSUB query per scrape: a prefix is promoted once it
+// has covered at least minChannels channels in minScrapes scrapes, and its
+// score decays by one per scrape without, so it is dropped again after up to
+// minScrapes scrapes. It is only used under scrapeMu.
+type prefixDiscovery struct {
+	minChannels int
+	minScrapes  int
+	scores      map[string]int // capped at minScrapes
+	promoted    map[string]bool
+}
+
+// newPrefixDiscovery returns nil, promoting every prefix right away, if
+// neither threshold is above 1.
+func newPrefixDiscovery(minChannels, minScrapes int) *prefixDiscovery {
+	if minChannels <= 1 && minScrapes <= 1 {
+		return nil
+	}
+	return &prefixDiscovery{
+		minChannels: max(minChannels, 1),
+		minScrapes:  max(minScrapes, 1),
+		scores:      make(map[string]int),
+		promoted:    make(map[string]bool),
+	}
+}
+
+// observe records the channel count per prefix of one scrape and returns the
+// counts of the promoted prefixes.
+func (d *prefixDiscovery) observe(counts map[string]int) map[string]int {
+	if d == nil {
+		return counts
+	}
+	for prefix, n := range counts {
+		if n >= d.minChannels {
+			d.scores[prefix] = min(d.scores[prefix]+1, d.minScrapes)
+			if d.scores[prefix] == d.minScrapes {
+				d.promoted[prefix] = true
+			}
+		}
+	}
+	for prefix := range d.scores {
+		if counts[prefix] >= d.minChannels {
+			continue
+		}
+		if d.scores[prefix]--; d.scores[prefix] <= 0 {
+			delete(d.scores, prefix)
+			delete(d.promoted, prefix)
+		}
+	}
+
+	out := make(map[string]int, len(d.promoted))
+	for prefix := range d.promoted {
+		out[prefix] = counts[prefix]
+	}
+	return out
+}
+
+// len returns the number of prefixes being tracked.
+func (d *prefixDiscovery) len() int {
+	return len(d.scores)
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// Kinds of failed Redis commands. A CommandError matches its kind with
+// errors.Is, e.g. errors.Is(err, ErrAuth).
+var (
+	ErrAuth       = errors.New("authentication failed")
+	ErrTimeout    = errors.New("timeout")
+	ErrPermission = errors.New("permission denied")
+	ErrProtocol   = errors.New("protocol error")
+)
+
+// protocolErrors are fragments of go-redis errors for replies it could not
+// parse, and of the server's reply to a malformed request.
+var protocolErrors = []string{
+	"redis: can't parse",
+	"redis: invalid reply",
+	"redis: got ",
+	"redis: unexpected",
+	"ERR Protocol error",
+}
+
+// CommandError is a failed Redis command. It wraps both the go-redis error
+// and its kind, if known, so errors.Is matches either.
+type CommandError struct {
+	Command string
+	Kind    error // ErrAuth, ErrTimeout, ErrPermission, ErrProtocol or nil
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Kind == nil {
+		return e.Command + ": " + e.Err.Error()
+	}
+	return e.Command + ": " + e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// errorKind classifies err as one of the Err* kinds, or returns nil.
+func errorKind(err error) error {
+	var netErr net.Error
+	switch {
+	case redis.IsAuthError(err):
+		return ErrAuth
+	case redis.IsPermissionError(err):
+		return ErrPermission
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	}
+	msg := err.Error()
+	for _, s := range protocolErrors {
+		if strings.Contains(msg, s) {
+			return ErrProtocol
+		}
+	}
+	return nil
+}
+
+// errorCmd is the part of every go-redis command result wrapCmdErr needs.
+type errorCmd interface {
+	Err() error
+	SetErr(error)
+}
+
+// wrapCmdErr replaces a failure of cmd with a CommandError naming command.
+func wrapCmdErr(command string, cmd errorCmd) {
+	err := cmd.Err()
+	if err == nil {
+		return
+	}
+	var wrapped *CommandError
+	if errors.As(err, &wrapped) {
+		return
+	}
+	cmd.SetErr(&CommandError{Command: command, Kind: errorKind(err), Err: err})
+}
+
+// errorType is the type label of the errors_total metric for err.
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrPermission):
+		return "permission"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrProtocol):
+		return "protocol"
+	case isUnsupported(err):
+		return "unsupported"
+	}
+	return "other"
+}
+
+// errorKey identifies a series of the errors_total metric.
+type errorKey struct {
+	phase, typ string
+}
+
+// countError counts a failure of phase (ping or a subsystem) by type.
+func (c *RedisPubSubCollector) countError(phase string, err error) {
+	if c.errorCounts == nil {
+		c.errorCounts = make(map[errorKey]float64)
+	}
+	c.errorCounts[errorKey{phase, errorType(err)}]++
+}
+
+// collectErrors emits the failures counted so far by phase and type.
+func (c *RedisPubSubCollector) collectErrors(ch chan<- prometheus.Metric) {
+	for key, n := range c.errorCounts {
+		ch <- prometheus.MustNewConstMetric(c.errorsTotal, prometheus.CounterValue, n, key.phase, key.typ)
+	}
+}
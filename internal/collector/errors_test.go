@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCommandError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+		wantType string
+		wantMsg  string
+	}{
+		{name: "wrong password", err: errors.New("WRONGPASS invalid username-password pair"), wantKind: ErrAuth, wantType: "auth",
+			wantMsg: "INFO: authentication failed: WRONGPASS invalid username-password pair"},
+		{name: "no auth", err: errors.New("NOAUTH Authentication required."), wantKind: ErrAuth, wantType: "auth"},
+		{name: "acl", err: errors.New("NOPERM User exporter has no permissions to run the 'info' command"), wantKind: ErrPermission, wantType: "permission"},
+		{name: "deadline", err: fmt.Errorf("read: %w", context.DeadlineExceeded), wantKind: ErrTimeout, wantType: "timeout"},
+		{name: "unparsable reply", err: errors.New("redis: can't parse \"?\""), wantKind: ErrProtocol, wantType: "protocol"},
+		{name: "unsupported", err: errors.New("ERR unknown command 'INFO'"), wantType: "unsupported"},
+		{name: "other", err: errors.New("connection refused"), wantType: "other",
+			wantMsg: "INFO: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := redis.NewInfoCmd(context.Background())
+			cmd.SetErr(tt.err)
+			wrapCmdErr("INFO", cmd)
+			err := cmd.Err()
+
+			var ce *CommandError
+			if !errors.As(err, &ce) || ce.Command != "INFO" {
+				t.Fatalf("want a CommandError for INFO, got %#v", err)
+			}
+			if ce.Kind != tt.wantKind {
+				t.Errorf("want kind %v, got %v", tt.wantKind, ce.Kind)
+			}
+			if tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+				t.Errorf("errors.Is(%v, %v) should hold", err, tt.wantKind)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("the go-redis error should stay wrapped")
+			}
+			if got := errorType(err); got != tt.wantType {
+				t.Errorf("want type %q, got %q", tt.wantType, got)
+			}
+			if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+				t.Errorf("want message %q, got %q", tt.wantMsg, err.Error())
+			}
+
+			// Wrapping again keeps the first command
+			wrapCmdErr("PING", cmd)
+			if cmd.Err() != err {
+				t.Error("wrapped error should not be wrapped again")
+			}
+		})
+	}
+}
+
+func TestCollectErrorsByPhase(t *testing.T) {
+	q := &fakeQuerier{
+		channels:  map[string]int64{"orders.created": 1},
+		clientErr: errors.New("NOPERM User exporter has no permissions to run the 'client|list' command"),
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100}, logger)
+
+	collect(t, c)
+	got := collect(t, c)
+	if v := got["redis_pubsub_exporter_errors_total{phase=clients,type=permission}"]; v != 2 {
+		t.Errorf("want 2 permission errors in the clients phase, got %v", v)
+	}
+
+	q.clientErr = nil
+	q.pingErr = errors.New("WRONGPASS invalid username-password pair")
+	got = collect(t, c)
+	if v := got["redis_pubsub_exporter_errors_total{phase=ping,type=auth}"]; v != 1 {
+		t.Errorf("want 1 auth error on ping, got %v", v)
+	}
+	if v := got["redis_pubsub_exporter_errors_total{phase=clients,type=permission}"]; v != 2 {
+		t.Errorf("earlier errors should still be counted, got %v", v)
+	}
+}
+
+func TestCollectAuthFailureBackoff(t *testing.T) {
+	tests := []struct {
+		name        string
+		pingErr     error
+		authBackoff time.Duration
+		backoff     time.Duration
+		wantPings   int
+		wantAuth    float64
+	}{
+		{name: "wrong password pauses queries", pingErr: errors.New("WRONGPASS invalid username-password pair"), authBackoff: time.Hour, wantPings: 1, wantAuth: 1},
+		{name: "other failures keep pinging", pingErr: errors.New("connection refused"), authBackoff: time.Hour, wantPings: 3, wantAuth: 0},
+		{name: "zero auth backoff uses failure backoff", pingErr: errors.New("WRONGPASS invalid username-password pair"), backoff: time.Hour, wantPings: 1, wantAuth: 1},
+		{name: "no backoff", pingErr: errors.New("WRONGPASS invalid username-password pair"), wantPings: 3, wantAuth: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pings := 0
+			q := &fakeQuerier{pingErr: tt.pingErr, pingHook: func() { pings++ }}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			c := New(q, Options{MaxChannels: 100, AuthFailureBackoff: tt.authBackoff, FailureBackoff: tt.backoff}, logger)
+
+			collect(t, c)
+			got := collect(t, c)
+			c.healthCheck(context.Background())
+
+			if pings != tt.wantPings {
+				t.Errorf("want %d pings, got %d", tt.wantPings, pings)
+			}
+			if v := got["redis_pubsub_exporter_auth_failures_total{}"]; v != tt.wantAuth {
+				t.Errorf("want auth_failures_total %v, got %v", tt.wantAuth, v)
+			}
+			if got["redis_pubsub_exporter_redis_up{}"] != 0 {
+				t.Error("redis_up should stay 0 while paused")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// RunHealthCheck pings Redis right away and then every interval until ctx is
+// done, so IsRedisUp notices an unreachable Redis between Prometheus scrapes
+// and turns ready before the first one. Pings only track reachability: they
+// don't reset failed scrapes, staleness or the failure backoff, and don't
+// produce metrics.
+func (c *RedisPubSubCollector) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.healthCheck(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthCheck pings Redis once. It is skipped while a scrape is running,
+// since the scrape updates the same state, and after an auth failure.
+func (c *RedisPubSubCollector) healthCheck(ctx context.Context) {
+	if !c.scrapeMu.TryLock() {
+		return
+	}
+	defer c.scrapeMu.Unlock()
+	if time.Now().Before(c.authRetryAt) {
+		return
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := c.client.Ping(pingCtx).Err()
+	if ctx.Err() != nil {
+		return // shutting down
+	}
+
+	c.mu.Lock()
+	c.lastPing, c.pingErr = start, err
+	if err != nil {
+		c.pingFailures++
+	} else {
+		c.pingFailures = 0
+	}
+	failures := c.pingFailures
+	c.mu.Unlock()
+
+	if err != nil {
+		c.logger.Warn("health check ping failed", "error", err, "consecutive_failures", failures)
+		if errorKind(err) == ErrAuth {
+			c.authFailures++
+			c.pauseAfterAuthFailure(start)
+		}
+	}
+}
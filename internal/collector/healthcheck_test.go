@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	q := &fakeQuerier{}
+	c := newTestCollector(q, nil)
+
+	if c.IsRedisUp() {
+		t.Fatal("should not be ready before any check")
+	}
+	c.healthCheck(context.Background())
+	if !c.IsRedisUp() {
+		t.Error("a successful ping should make the collector ready without a scrape")
+	}
+
+	q.pingErr = errors.New("connection refused")
+	c.healthCheck(context.Background())
+	if c.IsRedisUp() {
+		t.Error("a failed ping should make the collector unready")
+	}
+	if s := c.Status(); s.PingError != "connection refused" || s.PingFailures != 1 || s.ConsecutiveFailures != 0 {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestHealthCheckDoesNotOverrideScrapes(t *testing.T) {
+	q := &fakeQuerier{pingErr: errors.New("connection refused")}
+	c := newTestCollector(q, nil)
+	collect(t, c) // failed scrape, starts the failure backoff
+	retryAt := c.retryAt
+	if retryAt.IsZero() {
+		t.Fatal("a failed scrape should start the failure backoff")
+	}
+
+	q.pingErr = nil
+	c.healthCheck(context.Background())
+	if c.IsRedisUp() {
+		t.Error("a successful ping must not make up for a failed scrape")
+	}
+	if !c.retryAt.Equal(retryAt) {
+		t.Error("a successful ping must not lift the failure backoff")
+	}
+	if s := c.Status(); s.ConsecutiveFailures != 1 || s.LastError == "" {
+		t.Errorf("a ping must not reset the scrape status, got %+v", s)
+	}
+
+	// A stale successful scrape stays stale however often Redis answers.
+	c.failures, c.lastSuccess, c.readyStale = 0, time.Now().Add(-time.Hour), time.Minute
+	c.healthCheck(context.Background())
+	if c.IsRedisUp() {
+		t.Error("a successful ping must not refresh a stale scrape")
+	}
+}
+
+func TestHealthCheckSkippedDuringScrape(t *testing.T) {
+	pings := 0
+	q := &fakeQuerier{pingHook: func() { pings++ }}
+	c := newTestCollector(q, nil)
+
+	c.scrapeMu.Lock()
+	c.healthCheck(context.Background())
+	c.scrapeMu.Unlock()
+
+	if pings != 0 {
+		t.Errorf("health check should not ping while a scrape is running, got %d pings", pings)
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Snapshot summarizes one scrape with its totals, for a quick look at recent
+// history without Prometheus.
+type Snapshot struct {
+	Time             time.Time `json:"time"`
+	DurationSeconds  float64   `json:"duration_seconds"`
+	Up               bool      `json:"up"`
+	Error            string    `json:"error,omitempty"`
+	Channels         int64     `json:"channels"`
+	OrphanChannels   int64     `json:"orphan_channels"`
+	Patterns         int64     `json:"patterns"`
+	PubSubClients    int64     `json:"pubsub_clients"`
+	ConnectedClients int64     `json:"connected_clients"`
+	UsedMemoryBytes  int64     `json:"used_memory_bytes"`
+	Rates            *Rates    `json:"rates,omitempty"` // nil unless RateWindow is set
+}
+
+// history is a fixed-size ring of the most recent snapshots.
+type history struct {
+	buf  []Snapshot
+	next int
+	full bool
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		return nil
+	}
+	return &history{buf: make([]Snapshot, size)}
+}
+
+func (h *history) add(s Snapshot) {
+	if h == nil {
+		return
+	}
+	h.buf[h.next] = s
+	h.next = (h.next + 1) % len(h.buf)
+	h.full = h.full || h.next == 0
+}
+
+// list returns the snapshots oldest first.
+func (h *history) list() []Snapshot {
+	if h == nil {
+		return nil
+	}
+	if !h.full {
+		return append([]Snapshot(nil), h.buf[:h.next]...)
+	}
+	return append(append([]Snapshot(nil), h.buf[h.next:]...), h.buf[:h.next]...)
+}
+
+// summarize builds a Snapshot from the totals among a scrape's metrics.
+func (c *RedisPubSubCollector) summarize(start time.Time, duration time.Duration, err error, metrics []prometheus.Metric) Snapshot {
+	s := Snapshot{Time: start, DurationSeconds: duration.Seconds(), Up: err == nil}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	totals := map[*prometheus.Desc]*int64{
+		c.channelsTotal:              &s.Channels,
+		c.orphanChannelsTotal:        &s.OrphanChannels,
+		c.patternsTotal:              &s.Patterns,
+		c.clientsTotal:               &s.PubSubClients,
+		c.redisConnectedClients:      &s.ConnectedClients,
+		c.info.gauges["used_memory"]: &s.UsedMemoryBytes,
+	}
+	var r Rates
+	rates := map[*prometheus.Desc]*float64{
+		c.channelCreationRate:  &r.ChannelCreation,
+		c.subscriberChangeRate: &r.SubscriberChange,
+		c.clientChurnRate:      &r.ClientChurn,
+	}
+	for _, m := range metrics {
+		var pb dto.Metric
+		if dst, ok := totals[m.Desc()]; ok && m.Write(&pb) == nil {
+			*dst = int64(pb.GetGauge().GetValue())
+		}
+		if dst, ok := rates[m.Desc()]; ok && m.Write(&pb) == nil {
+			*dst = pb.GetGauge().GetValue()
+			s.Rates = &r
+		}
+	}
+	return s
+}
+
+// History returns summaries of the most recent scrapes, oldest first. It is
+// empty unless Options.HistorySize is set.
+func (c *RedisPubSubCollector) History() []Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.history.list()
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestHistoryRing(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		add  int
+		want []int64 // Channels of the returned snapshots
+	}{
+		{name: "disabled", size: 0, add: 3},
+		{name: "partial", size: 3, add: 2, want: []int64{1, 2}},
+		{name: "full", size: 3, add: 3, want: []int64{1, 2, 3}},
+		{name: "wrapped", size: 3, add: 5, want: []int64{3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHistory(tt.size)
+			for i := 1; i <= tt.add; i++ {
+				h.add(Snapshot{Channels: int64(i)})
+			}
+			got := h.list()
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %d snapshots, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if got[i].Channels != tt.want[i] {
+					t.Errorf("snapshot %d: want %d, got %d", i, tt.want[i], got[i].Channels)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectHistory(t *testing.T) {
+	q := &fakeQuerier{
+		info:     map[string]map[string]string{"clients": {"connected_clients": "7"}},
+		channels: map[string]int64{"orders.created": 2, "orders.deleted": 0},
+		numPat:   3,
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(q, Options{MaxChannels: 100, HistorySize: 5}, logger)
+	collect(t, c)
+	collect(t, c)
+
+	got := c.History()
+	if len(got) != 2 {
+		t.Fatalf("want 2 snapshots, got %d", len(got))
+	}
+	s := got[1]
+	if !s.Up || s.Channels != 2 || s.OrphanChannels != 1 || s.Patterns != 3 || s.ConnectedClients != 7 {
+		t.Errorf("unexpected snapshot %+v", s)
+	}
+	if got[0].Time.After(s.Time) {
+		t.Error("want snapshots oldest first")
+	}
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// memoryGauges maps INFO memory fields to gauge metric names.
+var memoryGauges = []struct {
+	field string
+	name  string
+	help  string
+}{
+	{"used_memory", "used_memory_bytes", "Redis used memory in bytes"},
+	{"maxmemory", "maxmemory_bytes", "Redis maxmemory setting in bytes (0 = no limit)"},
+	{"mem_fragmentation_ratio", "mem_fragmentation_ratio", "Ratio of memory allocated by the OS to memory used by Redis"},
+}
+
+// infoCounters maps INFO stats and cpu fields to counter metric names.
+var infoCounters = []struct {
+	section string
+	field   string
+	name    string
+	help    string
+}{
+	{"stats", "evicted_keys", "evicted_keys_total", "Keys evicted by Redis due to the maxmemory limit"},
+	{"stats", "expired_keys", "expired_keys_total", "Keys removed by Redis because their TTL expired"},
+	{"stats", "total_net_input_bytes", "net_input_bytes_total", "Bytes Redis read from the network"},
+	{"stats", "total_net_output_bytes", "net_output_bytes_total", "Bytes Redis wrote to the network"},
+	{"cpu", "used_cpu_sys", "used_cpu_sys_seconds_total", "System CPU time consumed by Redis in seconds"},
+	{"cpu", "used_cpu_user", "used_cpu_user_seconds_total", "User CPU time consumed by Redis in seconds"},
+}
+
+// infoDescs holds the descriptors for metrics read from INFO: server
+// identity, memory, eviction and CPU pressure. Once Redis reaches maxmemory
+// under a noeviction policy it rejects writes, PUBLISH included, with OOM
+// errors, and publish storms show up as CPU saturation first; these metrics
+// make both diagnosable.
+type infoDescs struct {
+	server   *prometheus.Desc
+	uptime   *prometheus.Desc
+	gauges   map[string]*prometheus.Desc // by INFO field
+	counters map[string]*prometheus.Desc // by INFO field
+	policy   *prometheus.Desc
+}
+
+func newInfoDescs() infoDescs {
+	d := infoDescs{
+		server: prometheus.NewDesc(
+			Namespace+"_exporter_redis_info",
+			"Redis server version, mode and replication role; always 1",
+			[]string{"version", "mode", "role"}, nil,
+		),
+		uptime: prometheus.NewDesc(
+			Namespace+"_exporter_redis_uptime_seconds",
+			"Seconds since the Redis server started",
+			nil, nil,
+		),
+		gauges:   make(map[string]*prometheus.Desc, len(memoryGauges)),
+		counters: make(map[string]*prometheus.Desc, len(infoCounters)),
+		policy: prometheus.NewDesc(
+			Namespace+"_exporter_redis_maxmemory_policy_info",
+			"Redis maxmemory eviction policy; always 1",
+			[]string{"policy"}, nil,
+		),
+	}
+	for _, g := range memoryGauges {
+		d.gauges[g.field] = prometheus.NewDesc(Namespace+"_exporter_redis_"+g.name, g.help, nil, nil)
+	}
+	for _, s := range infoCounters {
+		d.counters[s.field] = prometheus.NewDesc(Namespace+"_exporter_redis_"+s.name, s.help, nil, nil)
+	}
+	return d
+}
+
+func (d infoDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.server
+	ch <- d.uptime
+	for _, g := range memoryGauges {
+		ch <- d.gauges[g.field]
+	}
+	ch <- d.policy
+	for _, s := range infoCounters {
+		ch <- d.counters[s.field]
+	}
+}
+
+// collectServer emits the server info metric and uptime from the INFO
+// server and replication sections; either may be nil.
+func (d infoDescs) collectServer(ch chan<- prometheus.Metric, server, replication map[string]string) {
+	if server == nil && replication == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(d.server, prometheus.GaugeValue, 1,
+		server["redis_version"], server["redis_mode"], replication["role"])
+	if v, ok := server["uptime_in_seconds"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.uptime, prometheus.GaugeValue, parseFloat(v))
+	}
+}
+
+// collectMemory emits the memory gauges and eviction policy from an INFO
+// memory section.
+func (d infoDescs) collectMemory(ch chan<- prometheus.Metric, section map[string]string) {
+	for _, g := range memoryGauges {
+		if v, ok := section[g.field]; ok {
+			ch <- prometheus.MustNewConstMetric(d.gauges[g.field], prometheus.GaugeValue, parseFloat(v))
+		}
+	}
+	if v, ok := section["maxmemory_policy"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.policy, prometheus.GaugeValue, 1, v)
+	}
+}
+
+// collectCounters emits the counters read from the named INFO section.
+func (d infoDescs) collectCounters(ch chan<- prometheus.Metric, name string, section map[string]string) {
+	for _, s := range infoCounters {
+		if s.section != name {
+			continue
+		}
+		if v, ok := section[s.field]; ok {
+			ch <- prometheus.MustNewConstMetric(d.counters[s.field], prometheus.CounterValue, parseFloat(v))
+		}
+	}
+}
@@ -0,0 +1,202 @@
+//go:build integration
+
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// Integration tests run against a real Redis server:
+//
+//	REDIS_ADDR=localhost:6379 go test -tags integration ./internal/collector/
+func integrationClient(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis not reachable at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb
+}
+
+// subscribe opens a named subscriber connection and waits until Redis has
+// registered all subscriptions.
+func subscribe(t *testing.T, rdb *redis.Client, name string, channels, patterns []string) {
+	t.Helper()
+	ctx := context.Background()
+	sub := redis.NewClient(&redis.Options{Addr: rdb.Options().Addr, ClientName: name})
+	t.Cleanup(func() { _ = sub.Close() })
+
+	ps := sub.Subscribe(ctx)
+	t.Cleanup(func() { _ = ps.Close() })
+	if len(channels) > 0 {
+		if err := ps.Subscribe(ctx, channels...); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+	}
+	if len(patterns) > 0 {
+		if err := ps.PSubscribe(ctx, patterns...); err != nil {
+			t.Fatalf("psubscribe: %v", err)
+		}
+	}
+	for i := 0; i < len(channels)+len(patterns); i++ {
+		if _, err := ps.ReceiveTimeout(ctx, 2*time.Second); err != nil {
+			t.Fatalf("waiting for subscription confirmation: %v", err)
+		}
+	}
+}
+
+// scrape serves the collector through promhttp and parses the text exposition.
+func scrape(t *testing.T, c prometheus.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	srv := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", resp.StatusCode)
+	}
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatalf("parse exposition: %v", err)
+	}
+	return families
+}
+
+func sampleValue(mf *dto.MetricFamily, labels map[string]string) (float64, bool) {
+	if mf == nil {
+		return 0, false
+	}
+	for _, m := range mf.GetMetric() {
+		matched := 0
+		for _, lp := range m.GetLabel() {
+			if v, ok := labels[lp.GetName()]; ok && v == lp.GetValue() {
+				matched++
+			}
+		}
+		if matched != len(labels) {
+			continue
+		}
+		if g := m.GetGauge(); g != nil {
+			return g.GetValue(), true
+		}
+		if c := m.GetCounter(); c != nil {
+			return c.GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestIntegrationScrape(t *testing.T) {
+	rdb := integrationClient(t)
+	ctx := context.Background()
+
+	subscribe(t, rdb, "itest-orders", []string{"itest.orders.created", "itest.orders.deleted"}, nil)
+	subscribe(t, rdb, "itest-audit", nil, []string{"itest.audit.*"})
+
+	if err := rdb.HSet(ctx, "itest:sessions", "eu", 4, "us", 2).Err(); err != nil {
+		t.Fatalf("hset: %v", err)
+	}
+	t.Cleanup(func() { rdb.Del(ctx, "itest:sessions") })
+
+	hashDefs := []config.HashMetricDef{{
+		RedisKey: "itest:sessions", MetricName: "itest_sessions", Help: "Sessions", FieldLabel: "region",
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	coll := New(NewQuerier(rdb), Options{
+		MaxChannels:   1000,
+		KnownPatterns: []string{"itest.*"},
+		HashMetrics:   hashDefs,
+	}, logger)
+
+	families := scrape(t, coll)
+
+	checks := []struct {
+		family string
+		labels map[string]string
+		want   float64
+	}{
+		{"redis_pubsub_exporter_redis_up", nil, 1},
+		{"redis_pubsub_channel_subscriber_count", map[string]string{"channel": "itest.orders.created"}, 1},
+		{"redis_pubsub_channel_subscriber_count", map[string]string{"channel": "itest.orders.deleted"}, 1},
+		{"redis_pubsub_client_channel_subscriptions", map[string]string{"client_name": "itest-orders"}, 2},
+		{"redis_pubsub_client_pattern_subscriptions", map[string]string{"client_name": "itest-audit"}, 1},
+		{"redis_pubsub_pattern_subscriber_count", map[string]string{"pattern": "itest.*"}, 2},
+		{"redis_pubsub_itest_sessions", map[string]string{"region": "eu"}, 4},
+		{"redis_pubsub_itest_sessions", map[string]string{"region": "us"}, 2},
+	}
+	for _, c := range checks {
+		got, ok := sampleValue(families[c.family], c.labels)
+		if !ok {
+			t.Errorf("%s%v: sample missing", c.family, c.labels)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s%v: want %v, got %v", c.family, c.labels, c.want, got)
+		}
+	}
+
+	if got, _ := sampleValue(families["redis_pubsub_patterns_total"], nil); got < 1 {
+		t.Errorf("redis_pubsub_patterns_total: want >= 1, got %v", got)
+	}
+	for _, name := range []string{
+		"redis_pubsub_exporter_redis_connected_clients",
+		"redis_pubsub_exporter_redis_used_memory_bytes",
+		"redis_pubsub_channels_total",
+		"redis_pubsub_clients_total",
+	} {
+		if _, ok := families[name]; !ok {
+			t.Errorf("family %s missing", name)
+		}
+	}
+}
+
+func TestIntegrationRedisDown(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 200 * time.Millisecond})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	coll := New(NewQuerier(rdb), Options{MaxChannels: 1000}, logger)
+
+	families := scrape(t, coll)
+
+	if got, ok := sampleValue(families["redis_pubsub_exporter_redis_up"], nil); !ok || got != 0 {
+		t.Errorf("redis_up: want 0, got %v (present=%v)", got, ok)
+	}
+	if got, _ := sampleValue(families["redis_pubsub_exporter_scrape_errors_total"], nil); got != 1 {
+		t.Errorf("scrape_errors_total: want 1, got %v", got)
+	}
+	if _, ok := families["redis_pubsub_channels_total"]; ok {
+		t.Error("channels_total should not be emitted when Redis is down")
+	}
+}
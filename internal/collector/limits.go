@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Limits are the cardinality limits, channel filters and pattern changes
+// applied on every scrape. They can be changed at runtime through a
+// LimitStore.
+type Limits struct {
+	MaxChannels    int      `json:"max_channels"`    // high cardinality guard for per-channel metrics
+	MaxClients     int      `json:"max_clients"`     // per-client series cap; 0 = unlimited
+	MaxPatterns    int      `json:"max_patterns"`    // patterns queried per scrape; 0 = unlimited
+	MaxSeries      int      `json:"max_series"`      // Redis-derived series per scrape; 0 = unlimited
+	ChannelInclude []string `json:"channel_include"` // glob patterns; empty tracks all channels
+	ChannelExclude []string `json:"channel_exclude"` // glob patterns dropped after ChannelInclude
+
+	// Patterns are checked in addition to each collector's KnownPatterns;
+	// IgnoredPatterns are never checked, even if known or auto-discovered.
+	Patterns        []string `json:"patterns"`
+	IgnoredPatterns []string `json:"ignored_patterns"`
+}
+
+// AddPattern starts monitoring pattern.
+func (l *Limits) AddPattern(pattern string) {
+	l.IgnoredPatterns = slices.DeleteFunc(l.IgnoredPatterns, func(p string) bool { return p == pattern })
+	if !slices.Contains(l.Patterns, pattern) {
+		l.Patterns = append(l.Patterns, pattern)
+	}
+}
+
+// RemovePattern stops monitoring pattern: a runtime pattern is dropped,
+// a known or auto-discovered one is ignored from then on.
+func (l *Limits) RemovePattern(pattern string) {
+	if slices.Contains(l.Patterns, pattern) {
+		l.Patterns = slices.DeleteFunc(l.Patterns, func(p string) bool { return p == pattern })
+		return
+	}
+	if !slices.Contains(l.IgnoredPatterns, pattern) {
+		l.IgnoredPatterns = append(l.IgnoredPatterns, pattern)
+	}
+}
+
+// trackChannel reports whether a channel passes the include/exclude filters.
+func (l Limits) trackChannel(channel string) bool {
+	if len(l.ChannelInclude) > 0 && !slices.ContainsFunc(l.ChannelInclude, func(p string) bool { return MatchGlob(p, channel) }) {
+		return false
+	}
+	return !slices.ContainsFunc(l.ChannelExclude, func(p string) bool { return MatchGlob(p, channel) })
+}
+
+// LimitStore holds the Limits shared by one or more collectors: the
+// configured defaults plus any runtime override.
+type LimitStore struct {
+	mu         sync.RWMutex
+	defaults   Limits
+	current    Limits
+	overridden bool
+}
+
+// NewLimitStore returns a store starting at defaults.
+func NewLimitStore(defaults Limits) *LimitStore {
+	return &LimitStore{defaults: defaults, current: defaults}
+}
+
+// Get returns the limits in effect.
+func (s *LimitStore) Get() Limits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Overridden reports whether the limits differ from the configured defaults
+// because of a runtime update.
+func (s *LimitStore) Overridden() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overridden
+}
+
+// Update applies fn to a copy of the current limits and stores the result,
+// which stays in effect until Reset or SetDefaults.
+func (s *LimitStore) Update(fn func(*Limits)) Limits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.current
+	l.ChannelInclude = slices.Clone(l.ChannelInclude)
+	l.ChannelExclude = slices.Clone(l.ChannelExclude)
+	l.Patterns = slices.Clone(l.Patterns)
+	l.IgnoredPatterns = slices.Clone(l.IgnoredPatterns)
+	fn(&l)
+	s.current = l
+	s.overridden = true
+	return l
+}
+
+// Reset drops any runtime override.
+func (s *LimitStore) Reset() Limits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = s.defaults
+	s.overridden = false
+	return s.current
+}
+
+// SetDefaults replaces the configured defaults, e.g. on a configuration
+// reload, and drops any runtime override.
+func (s *LimitStore) SetDefaults(defaults Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults = defaults
+	s.current = defaults
+	s.overridden = false
+}
+
+// SetFilters replaces the channel filters in both the defaults and the
+// limits in effect, e.g. when a watched filter file changes. Other runtime
+// overrides are kept.
+func (s *LimitStore) SetFilters(include, exclude []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults.ChannelInclude, s.defaults.ChannelExclude = include, exclude
+	s.current.ChannelInclude, s.current.ChannelExclude = include, exclude
+}
+
+// MatchGlob reports whether s matches a Redis-style glob pattern supporting
+// *, ? and [...] (with ^ negation and a-z ranges). Unlike path.Match, '*'
+// also matches '/'.
+func MatchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if MatchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		case '[':
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				end++
+			}
+			if s == "" || end == len(pattern) {
+				return false
+			}
+			if !matchClass(pattern[1:end], s[0]) {
+				return false
+			}
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return s == ""
+}
+
+// matchClass matches b against the body of a [...] character class.
+func matchClass(class string, b byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= b && b <= class[i+2] {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == b {
+			matched = true
+		}
+	}
+	return matched != negate
+}
+
+// collectLimits emits the limits in effect, so dashboards can plot usage
+// against them and alerts can fire before truncation starts.
+func (c *RedisPubSubCollector) collectLimits(ch chan<- prometheus.Metric) {
+	l := c.limits.Get()
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"max_channels", l.MaxChannels},
+		{"max_clients", l.MaxClients},
+		{"max_patterns", l.MaxPatterns},
+		{"max_series", l.MaxSeries},
+		{"scrape_max_commands", c.maxCommands},
+	} {
+		ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, float64(limit.value), limit.name)
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeTimeout, prometheus.GaugeValue, c.timeout.Seconds())
+}
@@ -0,0 +1,49 @@
+package collector
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "orders.*", s: "orders.created", want: true},
+		{pattern: "orders.*", s: "users.login", want: false},
+		{pattern: "*", s: "a/b/c", want: true},
+		{pattern: "*.debug", s: "orders.debug", want: true},
+		{pattern: "user?", s: "users", want: true},
+		{pattern: "user?", s: "user", want: false},
+		{pattern: "shard[0-2]", s: "shard1", want: true},
+		{pattern: "shard[0-2]", s: "shard5", want: false},
+		{pattern: "shard[^0-2]", s: "shard5", want: true},
+		{pattern: `price\*`, s: "price*", want: true},
+		{pattern: `price\*`, s: "prices", want: false},
+		{pattern: "exact", s: "exact", want: true},
+		{pattern: "exact", s: "exactly", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.s, func(t *testing.T) {
+			if got := MatchGlob(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("MatchGlob(%q, %q): want %v, got %v", tt.pattern, tt.s, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLimitStore(t *testing.T) {
+	s := NewLimitStore(Limits{MaxChannels: 10, ChannelExclude: []string{"a"}})
+
+	s.Update(func(l *Limits) {
+		l.MaxChannels = 20
+		l.ChannelExclude = append(l.ChannelExclude, "b")
+	})
+	if got := s.Get(); got.MaxChannels != 20 || len(got.ChannelExclude) != 2 || !s.Overridden() {
+		t.Errorf("unexpected limits after update: %+v", got)
+	}
+
+	if got := s.Reset(); got.MaxChannels != 10 || len(got.ChannelExclude) != 1 || s.Overridden() {
+		t.Errorf("reset should restore defaults untouched by the update, got %+v", got)
+	}
+}
@@ -0,0 +1,18 @@
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collection modes.
+const (
+	ModeFull     = "full"      // enumerate channels, subscribers and clients (default)
+	ModeInfoOnly = "info-only" // channel, pattern and client counts from INFO only
+)
+
+// collectInfoCount emits desc from an integer field of the INFO read by
+// "server", in place of commands skipped in ModeInfoOnly or rejected by the
+// server. Nothing is emitted if the field is missing.
+func (c *RedisPubSubCollector) collectInfoCount(ch chan<- prometheus.Metric, desc *prometheus.Desc, s *ScrapeState, section, field string) {
+	if v, ok := infoSection(s.Info, section)[field]; ok {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, parseFloat(v))
+	}
+}
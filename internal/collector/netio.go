@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// netClientGrace is how long the totals of a client are kept after the last
+// scrape that listed it, so that one missing from a scrape, e.g. between two
+// subscriptions, only adds its growth when it comes back.
+const netClientGrace = 10 * time.Minute
+
+// netTracker turns the per-connection tot-net-in/tot-net-out totals of
+// pub/sub clients into monotonic counters. Connection totals vanish when a
+// client disconnects, so instead of summing them it adds each client's growth
+// since it was last seen; traffic of clients that come and go between two
+// scrapes is missed. It is only used under scrapeMu.
+type netTracker struct {
+	in, out  float64
+	last     map[string]netSeen // by client id (address if not reported)
+	reported bool               // the server reports tot-net-* (Redis 7.2+)
+}
+
+// netSeen is a client's totals as of the last scrape that listed it.
+type netSeen struct {
+	in, out int64
+	at      time.Time
+}
+
+// observe adds the traffic since each client was last seen. A client not
+// seen within netClientGrace contributes its whole total.
+func (n *netTracker) observe(now time.Time, clients []PubSubClient) {
+	if n.last == nil {
+		n.last = make(map[string]netSeen)
+	}
+	maps.DeleteFunc(n.last, func(_ string, s netSeen) bool { return now.Sub(s.at) > netClientGrace })
+	for _, cl := range clients {
+		if cl.NetIn > 0 || cl.NetOut > 0 {
+			n.reported = true
+		}
+		key := cl.Addr
+		if cl.ID != 0 {
+			key = strconv.FormatInt(cl.ID, 10)
+		}
+		prev := n.last[key]
+		if cl.NetIn >= prev.in && cl.NetOut >= prev.out {
+			n.in += float64(cl.NetIn - prev.in)
+			n.out += float64(cl.NetOut - prev.out)
+		} else {
+			// A new connection with a reused id (after a restart) or address.
+			n.in += float64(cl.NetIn)
+			n.out += float64(cl.NetOut)
+		}
+		n.last[key] = netSeen{in: cl.NetIn, out: cl.NetOut, at: now}
+	}
+}
+
+// collectNetIO emits the pub/sub network counters once the server has
+// reported per-client network totals.
+func (c *RedisPubSubCollector) collectNetIO(ch chan<- prometheus.Metric, clients []PubSubClient) {
+	c.net.observe(time.Now(), clients)
+	if !c.net.reported {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.pubsubNetInput, prometheus.CounterValue, c.net.in)
+	ch <- prometheus.MustNewConstMetric(c.pubsubNetOutput, prometheus.CounterValue, c.net.out)
+}
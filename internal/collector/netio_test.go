@@ -0,0 +1,32 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetTrackerGrace(t *testing.T) {
+	start := time.Unix(1000, 0)
+	client := PubSubClient{ID: 7, Addr: "10.0.0.1:1", Sub: 1, NetIn: 100, NetOut: 1000}
+	tests := []struct {
+		name            string
+		back            time.Duration
+		wantIn, wantOut float64
+	}{
+		{name: "back within grace", back: time.Minute, wantIn: 110, wantOut: 1010},
+		{name: "back after grace", back: netClientGrace + time.Minute, wantIn: 210, wantOut: 2010},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n netTracker
+			n.observe(start, []PubSubClient{client})
+			back := client
+			back.NetIn, back.NetOut = 110, 1010
+			n.observe(start.Add(tt.back), []PubSubClient{back})
+
+			if n.in != tt.wantIn || n.out != tt.wantOut {
+				t.Errorf("want %v/%v, got %v/%v", tt.wantIn, tt.wantOut, n.in, n.out)
+			}
+		})
+	}
+}
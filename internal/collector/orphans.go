@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectOrphanAge tracks since when each channel has had no subscribers and
+// emits how many have stayed orphaned for at least OrphanMinAge, so a
+// subscriber reconnecting between two scrapes doesn't look like a real
+// publisher/subscriber mismatch. numsub holds the subscriber count of every
+// tracked channel; called under scrapeMu.
+func (c *RedisPubSubCollector) collectOrphanAge(ch chan<- prometheus.Metric, numsub map[string]int64) {
+	if c.orphanMinAge <= 0 {
+		return
+	}
+	now := time.Now()
+	since := make(map[string]time.Time)
+	persistent := 0
+	var maxAge time.Duration
+	for channel, count := range numsub {
+		if count > 0 {
+			continue
+		}
+		t, ok := c.orphanSince[channel]
+		if !ok {
+			t = now
+		}
+		since[channel] = t
+		age := now.Sub(t)
+		if age >= c.orphanMinAge {
+			persistent++
+		}
+		maxAge = max(maxAge, age)
+	}
+	c.orphanSince = since
+
+	ch <- prometheus.MustNewConstMetric(c.orphanChannelsPersistent, prometheus.GaugeValue, float64(persistent))
+	ch <- prometheus.MustNewConstMetric(c.orphanChannelMaxAge, prometheus.GaugeValue, maxAge.Seconds())
+}
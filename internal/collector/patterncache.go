@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"time"
+)
+
+// patternCache remembers how many channels matched each pattern, since
+// pattern activity changes slowly and checking it dominates the scrape cost
+// on instances with many patterns. It is only used under scrapeMu.
+type patternCache struct {
+	ttl     time.Duration
+	entries map[string]patternResult
+}
+
+type patternResult struct {
+	matching int
+	at       time.Time
+}
+
+func newPatternCache(ttl time.Duration) *patternCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &patternCache{ttl: ttl, entries: make(map[string]patternResult)}
+}
+
+// split returns the cached match counts still fresh at now and the patterns
+// that need querying. Entries for patterns no longer checked are dropped.
+func (p *patternCache) split(patterns []string, now time.Time) (cached map[string]int, stale []string) {
+	if p == nil {
+		return nil, patterns
+	}
+	cached = make(map[string]int)
+	entries := make(map[string]patternResult, len(patterns))
+	for _, pattern := range patterns {
+		e, ok := p.entries[pattern]
+		if ok && now.Sub(e.at) < p.ttl {
+			cached[pattern] = e.matching
+			entries[pattern] = e
+		} else {
+			stale = append(stale, pattern)
+		}
+	}
+	p.entries = entries
+	return cached, stale
+}
+
+// store records a fresh query result.
+func (p *patternCache) store(pattern string, matching int, now time.Time) {
+	if p != nil {
+		p.entries[pattern] = patternResult{matching: matching, at: now}
+	}
+}
+
+func (p *patternCache) len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.entries)
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ProtocolHook is a go-redis hook that records the RESP protocol version
+// negotiated on new connections. go-redis sends HELLO with the configured
+// version on every new connection and falls back to RESP2 if the server
+// rejects it, as proxies such as Twemproxy do, so the negotiated version may
+// be lower than the configured one. Add it to the client with AddHook.
+type ProtocolHook struct {
+	configured int
+	negotiated atomic.Int64
+}
+
+// NewProtocolHook returns a ProtocolHook for a client configured with the
+// given protocol version.
+func NewProtocolHook(configured int) *ProtocolHook {
+	return &ProtocolHook{configured: configured}
+}
+
+// Configured returns the protocol version the client asks for.
+func (h *ProtocolHook) Configured() int { return h.configured }
+
+// Negotiated returns the protocol version of the last connection set up, or
+// 0 before the first one.
+func (h *ProtocolHook) Negotiated() int { return int(h.negotiated.Load()) }
+
+// DialHook implements redis.Hook.
+func (h *ProtocolHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook implements redis.Hook.
+func (h *ProtocolHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if strings.EqualFold(cmd.Name(), "hello") {
+			h.observe(cmd, err)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (h *ProtocolHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return next(ctx, cmds)
+	}
+}
+
+func (h *ProtocolHook) observe(cmd redis.Cmder, err error) {
+	var redisErr redis.Error
+	switch {
+	case err == nil:
+		if hello, ok := cmd.(*redis.MapStringInterfaceCmd); ok {
+			if proto, ok := hello.Val()["proto"].(int64); ok {
+				h.negotiated.Store(proto)
+			}
+		}
+	case errors.As(err, &redisErr):
+		// The server doesn't know HELLO; go-redis continues in RESP2.
+		h.negotiated.Store(2)
+	}
+}
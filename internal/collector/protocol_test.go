@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// serverError is an error reply from Redis, e.g. to an unknown command.
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+func (e serverError) RedisError()   {}
+
+func TestProtocolHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		reply      map[string]interface{}
+		err        error
+		want       int
+		configured int
+	}{
+		{name: "resp3", cmd: "hello", reply: map[string]interface{}{"proto": int64(3)}, configured: 3, want: 3},
+		{name: "resp2 requested", cmd: "hello", reply: map[string]interface{}{"proto": int64(2)}, configured: 2, want: 2},
+		{name: "hello rejected by a proxy", cmd: "hello", err: serverError("ERR unknown command 'HELLO'"), configured: 3, want: 2},
+		{name: "connection error", cmd: "hello", err: io.EOF, configured: 3, want: 0},
+		{name: "other command", cmd: "info", reply: map[string]interface{}{"proto": int64(3)}, configured: 3, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewProtocolHook(tt.configured)
+			process := h.ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+				cmd.(*redis.MapStringInterfaceCmd).SetVal(tt.reply)
+				return tt.err
+			})
+			_ = process(context.Background(), redis.NewMapStringInterfaceCmd(context.Background(), tt.cmd))
+
+			if got := h.Negotiated(); got != tt.want {
+				t.Errorf("want negotiated protocol %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCollectProtocol(t *testing.T) {
+	h := NewProtocolHook(3)
+	c := New(&fakeQuerier{}, Options{MaxChannels: 100, Protocol: h}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, ok := collect(t, c)["redis_pubsub_exporter_redis_protocol_info{configured=3,negotiated=2}"]; ok {
+		t.Error("protocol should not be reported before a connection was set up")
+	}
+
+	h.negotiated.Store(2)
+	if got := collect(t, c)["redis_pubsub_exporter_redis_protocol_info{configured=3,negotiated=2}"]; got != 1 {
+		t.Errorf("want protocol info 1, got %v", got)
+	}
+}
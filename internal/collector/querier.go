@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisQuerier is the subset of the go-redis client API used by the collector.
+// Use NewQuerier to adapt a redis.UniversalClient (standalone, failover or
+// cluster); tests provide their own fake.
+type RedisQuerier interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+	InfoMap(ctx context.Context, sections ...string) *redis.InfoCmd
+	PubSubChannels(ctx context.Context, pattern string) *redis.StringSliceCmd
+	PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd
+	PubSubNumSub(ctx context.Context, channels ...string) *redis.MapStringIntCmd
+	PubSubNumPat(ctx context.Context) *redis.IntCmd
+	ClientList(ctx context.Context) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	ClusterInfo(ctx context.Context) *redis.StringCmd
+}
+
+// KeyReader reads application keys for key-based metrics. It is satisfied by
+// RedisQuerier and by any go-redis client.
+type KeyReader interface {
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+}
+
+// KeyWriter writes keys, e.g. the heartbeat key. It is satisfied by any
+// go-redis client and by the querier returned from NewQuerier.
+type KeyWriter interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+}
+
+// universalQuerier adapts a redis.UniversalClient to RedisQuerier.
+// InfoMap is implemented by every concrete client but is not part of the
+// UniversalClient interface, so it is issued through Process.
+type universalQuerier struct {
+	redis.UniversalClient
+}
+
+// NewQuerier wraps a redis.UniversalClient for use by the collector.
+func NewQuerier(client redis.UniversalClient) RedisQuerier {
+	return universalQuerier{client}
+}
+
+func (q universalQuerier) InfoMap(ctx context.Context, sections ...string) *redis.InfoCmd {
+	args := make([]interface{}, 0, 1+len(sections))
+	args = append(args, "info")
+	for _, s := range sections {
+		args = append(args, s)
+	}
+	cmd := redis.NewInfoCmd(ctx, args...)
+	_ = q.Process(ctx, cmd)
+	return cmd
+}
+
+// PubSubChannelsMulti runs PUBSUB CHANNELS for every pattern in a single
+// pipeline, so many patterns cost one round trip. Errors are reported per
+// command.
+func (q universalQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	cmds := make([]*redis.StringSliceCmd, len(patterns))
+	_, _ = q.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, pattern := range patterns {
+			cmds[i] = pipe.PubSubChannels(ctx, pattern)
+		}
+		return nil
+	})
+	return cmds
+}
@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"time"
+)
+
+// Rates are per-second averages of changes between scrapes over a sliding
+// window, for consumers without PromQL.
+type Rates struct {
+	ChannelCreation  float64 `json:"channel_creation_per_second"`
+	SubscriberChange float64 `json:"subscriber_change_per_second"`
+	ClientChurn      float64 `json:"client_churn_per_second"`
+}
+
+// rateTracker diffs consecutive scrapes and averages the changes over a
+// window. It is only used under scrapeMu.
+type rateTracker struct {
+	window   time.Duration
+	since    time.Time           // first observation
+	channels map[string]int64    // subscribers per channel at the last scrape
+	clients  map[string]struct{} // pub/sub client addresses at the last scrape; nil if unknown
+	changes  []rateChange
+}
+
+// rateChange is what changed since the previous scrape.
+type rateChange struct {
+	at                            time.Time
+	created, subscribers, churned float64
+}
+
+func newRateTracker(window time.Duration) *rateTracker {
+	if window <= 0 {
+		return nil
+	}
+	return &rateTracker{window: window}
+}
+
+// observe records a scrape's channels (with subscriber counts) and pub/sub
+// clients (nil if CLIENT LIST was unavailable) and returns the current rates.
+func (t *rateTracker) observe(now time.Time, channels map[string]int64, clients []PubSubClient) Rates {
+	var addrs map[string]struct{}
+	if clients != nil {
+		addrs = make(map[string]struct{}, len(clients))
+		for _, cl := range clients {
+			addrs[cl.Addr] = struct{}{}
+		}
+	}
+
+	if t.since.IsZero() {
+		t.since = now
+	} else {
+		var change rateChange
+		change.at = now
+		for name, n := range channels {
+			prev, ok := t.channels[name]
+			if !ok {
+				change.created++
+			}
+			change.subscribers += float64(abs(n - prev))
+		}
+		for name, prev := range t.channels {
+			if _, ok := channels[name]; !ok {
+				change.subscribers += float64(prev)
+			}
+		}
+		if addrs != nil && t.clients != nil {
+			change.churned = float64(setDiff(addrs, t.clients) + setDiff(t.clients, addrs))
+		}
+		t.changes = append(t.changes, change)
+	}
+	t.channels, t.clients = channels, addrs
+
+	cutoff := now.Add(-t.window)
+	i := 0
+	for i < len(t.changes) && !t.changes[i].at.After(cutoff) {
+		i++
+	}
+	t.changes = t.changes[i:]
+
+	span := now.Sub(t.since)
+	if span > t.window {
+		span = t.window
+	}
+	var r Rates
+	if span <= 0 {
+		return r
+	}
+	for _, ch := range t.changes {
+		r.ChannelCreation += ch.created
+		r.SubscriberChange += ch.subscribers
+		r.ClientChurn += ch.churned
+	}
+	r.ChannelCreation /= span.Seconds()
+	r.SubscriberChange /= span.Seconds()
+	r.ClientChurn /= span.Seconds()
+	return r
+}
+
+// setDiff counts the keys of a that are not in b.
+func setDiff(a, b map[string]struct{}) int {
+	n := 0
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
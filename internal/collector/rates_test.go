@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateTracker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	type scrape struct {
+		after    time.Duration
+		channels map[string]int64
+		clients  []PubSubClient
+	}
+	tests := []struct {
+		name    string
+		scrapes []scrape
+		want    Rates
+	}{
+		{
+			name:    "first scrape only seeds",
+			scrapes: []scrape{{channels: map[string]int64{"a": 1}, clients: []PubSubClient{{Addr: "c1"}}}},
+		},
+		{
+			name: "changes averaged over elapsed time",
+			scrapes: []scrape{
+				{channels: map[string]int64{"a": 1}, clients: []PubSubClient{{Addr: "c1"}}},
+				{after: 10 * time.Second, channels: map[string]int64{"a": 3, "b": 1}, clients: []PubSubClient{{Addr: "c2"}}},
+			},
+			// created b; subscribers +2 on a, +1 on b; c1 left, c2 joined
+			want: Rates{ChannelCreation: 0.1, SubscriberChange: 0.3, ClientChurn: 0.2},
+		},
+		{
+			name: "removed channels count their subscribers",
+			scrapes: []scrape{
+				{channels: map[string]int64{"a": 4}},
+				{after: 10 * time.Second, channels: map[string]int64{}},
+			},
+			want: Rates{SubscriberChange: 0.4},
+		},
+		{
+			name: "old changes leave the window",
+			scrapes: []scrape{
+				{channels: map[string]int64{}},
+				{after: 10 * time.Second, channels: map[string]int64{"a": 0}},
+				{after: 70 * time.Second, channels: map[string]int64{"a": 0}},
+			},
+			want: Rates{},
+		},
+		{
+			name: "unknown clients are not churn",
+			scrapes: []scrape{
+				{channels: map[string]int64{}, clients: []PubSubClient{{Addr: "c1"}}},
+				{after: 10 * time.Second, channels: map[string]int64{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newRateTracker(time.Minute)
+			var got Rates
+			for _, s := range tt.scrapes {
+				got = tr.observe(start.Add(s.after), s.channels, s.clients)
+			}
+			for _, v := range [][2]float64{
+				{tt.want.ChannelCreation, got.ChannelCreation},
+				{tt.want.SubscriberChange, got.SubscriberChange},
+				{tt.want.ClientChurn, got.ClientChurn},
+			} {
+				if math.Abs(v[0]-v[1]) > 1e-9 {
+					t.Fatalf("want %+v, got %+v", tt.want, got)
+				}
+			}
+		})
+	}
+}
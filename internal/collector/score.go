@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Components of the health score, the keys of Options.HealthWeights.
+const (
+	HealthUp        = "up"        // Redis reachable and the scrape succeeded
+	HealthOrphans   = "orphans"   // share of tracked channels with subscribers
+	HealthShortfall = "shortfall" // share of patterns at or above their minimum
+	HealthLatency   = "latency"   // scrape duration against the scrape timeout
+)
+
+// DefaultHealthWeights returns the weights used if Options.HealthWeights is
+// unset.
+func DefaultHealthWeights() map[string]float64 {
+	return map[string]float64{
+		HealthUp:        0.4,
+		HealthOrphans:   0.2,
+		HealthShortfall: 0.2,
+		HealthLatency:   0.2,
+	}
+}
+
+// healthInputs is what the last scrape found for the health score.
+type healthInputs struct {
+	channels, orphans    int // tracked channels and those without subscribers
+	asserted, shortfalls int // patterns with a minimum and those below it
+}
+
+// observeHealth records the health inputs from s at the end of a scrape.
+func (c *RedisPubSubCollector) observeHealth(s *ScrapeState) {
+	in := healthInputs{asserted: s.asserted, shortfalls: s.shortfalls}
+	if s.Subscribers != nil {
+		in.channels = len(s.Subscribers)
+		for _, n := range s.Subscribers {
+			if n == 0 {
+				in.orphans++
+			}
+		}
+	}
+	c.health = in
+}
+
+// healthScore is the weighted mean of the health components, each between
+// 0 (bad) and 1 (good). Components without data, e.g. orphans when no
+// channel is tracked, are left out. A failed scrape scores 0.
+func (c *RedisPubSubCollector) healthScore(up bool, duration time.Duration) float64 {
+	if !up {
+		return 0
+	}
+	in := c.health
+	components := map[string]float64{
+		HealthUp:      1,
+		HealthLatency: max(0, 1-duration.Seconds()/c.timeout.Seconds()),
+	}
+	if in.channels > 0 {
+		components[HealthOrphans] = 1 - float64(in.orphans)/float64(in.channels)
+	}
+	if in.asserted > 0 {
+		components[HealthShortfall] = 1 - float64(in.shortfalls)/float64(in.asserted)
+	}
+	var sum, weights float64
+	for name, v := range components {
+		sum += c.healthWeights[name] * v
+		weights += c.healthWeights[name]
+	}
+	if weights == 0 {
+		return 1
+	}
+	return sum / weights
+}
+
+// collectHealthScore emits the health score of the last scrape.
+func (c *RedisPubSubCollector) collectHealthScore(ch chan<- prometheus.Metric, up bool, duration time.Duration) {
+	ch <- prometheus.MustNewConstMetric(c.healthScoreDesc, prometheus.GaugeValue, c.healthScore(up, duration))
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCollectHealthScore(t *testing.T) {
+	noLatency := map[string]float64{HealthUp: 0.4, HealthOrphans: 0.3, HealthShortfall: 0.3}
+	tests := []struct {
+		name    string
+		q       *fakeQuerier
+		minSubs map[string]int
+		weights map[string]float64
+		wantMin float64
+		wantMax float64
+	}{
+		{
+			name:    "healthy",
+			q:       &fakeQuerier{channels: map[string]int64{"orders.created": 1, "orders.paid": 2}},
+			minSubs: map[string]int{"orders.*": 2},
+			weights: noLatency,
+			wantMin: 1, wantMax: 1,
+		},
+		{
+			name:    "half of the channels orphaned",
+			q:       &fakeQuerier{channels: map[string]int64{"orders.created": 1, "orders.paid": 0}},
+			weights: noLatency,
+			// shortfall has no data and is left out: (0.4 + 0.3*0.5) / 0.7
+			wantMin: 0.55 / 0.7, wantMax: 0.55 / 0.7,
+		},
+		{
+			name:    "pattern shortfall",
+			q:       &fakeQuerier{channels: map[string]int64{"orders.created": 1}},
+			minSubs: map[string]int{"orders.*": 2},
+			weights: noLatency,
+			wantMin: 0.7, wantMax: 0.7,
+		},
+		{
+			name:    "redis down",
+			q:       &fakeQuerier{pingErr: errors.New("connection refused")},
+			wantMin: 0, wantMax: 0,
+		},
+		{
+			name:    "default weights include latency",
+			q:       &fakeQuerier{channels: map[string]int64{"orders.created": 1}},
+			wantMin: 0.9, wantMax: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			c := New(tt.q, Options{MaxChannels: 100, MinSubscribers: tt.minSubs, HealthWeights: tt.weights}, logger)
+			got := collect(t, c)["redis_pubsub_health_score{}"]
+			if got < tt.wantMin-1e-9 || got > tt.wantMax+1e-9 {
+				t.Errorf("want health_score in [%v, %v], got %v", tt.wantMin, tt.wantMax, got)
+			}
+		})
+	}
+}
+
+func TestHealthScoreLatency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(&fakeQuerier{}, Options{Timeout: 10 * time.Second, HealthWeights: map[string]float64{HealthLatency: 1}}, logger)
+	for _, tt := range []struct {
+		duration time.Duration
+		want     float64
+	}{
+		{0, 1},
+		{2500 * time.Millisecond, 0.75},
+		{10 * time.Second, 0},
+		{time.Minute, 0},
+	} {
+		if got := c.healthScore(true, tt.duration); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("duration %s: want %v, got %v", tt.duration, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ActivitySource reports message traffic per channel, e.g. from the sampler.
+type ActivitySource interface {
+	// ObservingSince returns since when messages have been observed, or
+	// zero while the source isn't observing (e.g. on a standby replica).
+	ObservingSince() time.Time
+	// LastMessage returns when a message was last seen on channel, or zero.
+	LastMessage(channel string) time.Time
+}
+
+// collectSilent emits the channels that have subscribers but received no
+// message within SilentWindow: the publisher is gone while consumers keep
+// waiting. Nothing is emitted until the activity source has observed for a
+// full window. numsub holds the subscriber count of every tracked channel.
+func (c *RedisPubSubCollector) collectSilent(ch chan<- prometheus.Metric, numsub map[string]int64) {
+	if c.activity == nil || c.silentWindow <= 0 {
+		return
+	}
+	now := time.Now()
+	if since := c.activity.ObservingSince(); since.IsZero() || now.Sub(since) < c.silentWindow {
+		return
+	}
+	silent := 0
+	for _, channel := range slices.Sorted(maps.Keys(numsub)) {
+		if numsub[channel] == 0 {
+			continue
+		}
+		if last := c.activity.LastMessage(channel); last.IsZero() || now.Sub(last) >= c.silentWindow {
+			silent++
+			ch <- prometheus.MustNewConstMetric(c.channelSilent, prometheus.GaugeValue, 1, channel)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.silentChannels, prometheus.GaugeValue, float64(silent))
+}
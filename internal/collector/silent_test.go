@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeActivity struct {
+	since time.Time
+	last  map[string]time.Time
+}
+
+func (f fakeActivity) ObservingSince() time.Time            { return f.since }
+func (f fakeActivity) LastMessage(channel string) time.Time { return f.last[channel] }
+
+func TestCollectSilentChannels(t *testing.T) {
+	now := time.Now()
+	channels := map[string]int64{"orders.created": 2, "orders.deleted": 1, "users.login": 0, "jobs": 3}
+	last := map[string]time.Time{
+		"orders.created": now.Add(-10 * time.Second),
+		"orders.deleted": now.Add(-10 * time.Minute),
+	}
+
+	tests := []struct {
+		name       string
+		activity   ActivitySource
+		want       float64
+		wantSilent []string
+	}{
+		{name: "no activity source"},
+		{name: "not observing", activity: fakeActivity{last: last}},
+		{name: "observing for less than a window", activity: fakeActivity{since: now.Add(-time.Minute), last: last}},
+		{
+			name:       "silent channels with subscribers",
+			activity:   fakeActivity{since: now.Add(-time.Hour), last: last},
+			want:       2,
+			wantSilent: []string{"orders.deleted", "jobs"},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{channels: channels}
+			c := New(q, Options{MaxChannels: 100, Activity: tt.activity, SilentWindow: 5 * time.Minute}, logger)
+			got := collect(t, c)
+
+			v, ok := got["redis_pubsub_silent_channels{}"]
+			if ok != (tt.wantSilent != nil) || v != tt.want {
+				t.Fatalf("want silent_channels %v (emitted %v), got %v (emitted %v)", tt.want, tt.wantSilent != nil, v, ok)
+			}
+			for _, channel := range tt.wantSilent {
+				if got["redis_pubsub_channel_silent{channel="+channel+"}"] != 1 {
+					t.Errorf("want %s silent", channel)
+				}
+			}
+			if _, ok := got["redis_pubsub_channel_silent{channel=users.login}"]; ok {
+				t.Error("channels without subscribers are orphans, not silent")
+			}
+		})
+	}
+}
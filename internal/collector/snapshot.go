@@ -0,0 +1,243 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SnapshotVersion is the format version written to ServerSnapshot.Version.
+const SnapshotVersion = 1
+
+// snapshotBatch is the number of channels per PUBSUB NUMSUB when taking a
+// snapshot, so servers with many channels are not blocked by one command.
+const snapshotBatch = 1000
+
+// ServerSnapshot is the complete state the collector reads from a Redis
+// server, without limits or filters applied. Replaying it through
+// NewSnapshotQuerier reproduces the exporter's output for any configuration
+// offline.
+type ServerSnapshot struct {
+	Version int       `json:"version"`
+	Taken   time.Time `json:"taken"`
+
+	Info        map[string]map[string]string `json:"info"`
+	Channels    map[string]int64             `json:"channels"` // channel -> subscribers
+	NumPat      int64                        `json:"numpat"`
+	Clients     []string                     `json:"clients"` // CLIENT LIST lines
+	ClusterInfo string                       `json:"cluster_info,omitempty"`
+
+	// Commands the server rejected as unsupported; replay rejects them too.
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// TakeSnapshot reads everything the collector scrapes from q. Commands the
+// server does not support are recorded in Unsupported rather than failing
+// the snapshot; CLUSTER INFO is only read in cluster mode. Hash metrics are
+// not part of a snapshot.
+func TakeSnapshot(ctx context.Context, q RedisQuerier, clusterMode bool) (*ServerSnapshot, error) {
+	s := &ServerSnapshot{Version: SnapshotVersion, Taken: time.Now().UTC(), Channels: map[string]int64{}}
+	var err error
+	// unsupported records a rejected command and swallows its error.
+	unsupported := func(command string) bool {
+		if !isUnsupported(err) {
+			return false
+		}
+		s.Unsupported = append(s.Unsupported, command)
+		err = nil
+		return true
+	}
+
+	if s.Info, err = q.InfoMap(ctx).Result(); err != nil && !unsupported("INFO") {
+		return nil, fmt.Errorf("INFO: %w", err)
+	}
+
+	var channels []string
+	if channels, err = q.PubSubChannels(ctx, "*").Result(); err != nil && !unsupported("PUBSUB CHANNELS") {
+		return nil, fmt.Errorf("PUBSUB CHANNELS: %w", err)
+	}
+	slices.Sort(channels)
+	for batch := range slices.Chunk(channels, snapshotBatch) {
+		numsub, err := q.PubSubNumSub(ctx, batch...).Result()
+		if err != nil {
+			if isUnsupported(err) {
+				s.Unsupported = append(s.Unsupported, "PUBSUB NUMSUB")
+				break
+			}
+			return nil, fmt.Errorf("PUBSUB NUMSUB: %w", err)
+		}
+		for _, channel := range batch {
+			s.Channels[channel] = numsub[channel]
+		}
+	}
+
+	if s.NumPat, err = q.PubSubNumPat(ctx).Result(); err != nil && !unsupported("PUBSUB NUMPAT") {
+		return nil, fmt.Errorf("PUBSUB NUMPAT: %w", err)
+	}
+
+	var clients string
+	if clients, err = q.ClientList(ctx).Result(); err != nil && !unsupported("CLIENT LIST") {
+		return nil, fmt.Errorf("CLIENT LIST: %w", err)
+	}
+	for line := range strings.Lines(clients) {
+		if line = strings.TrimSpace(line); line != "" {
+			s.Clients = append(s.Clients, line)
+		}
+	}
+
+	if clusterMode {
+		if s.ClusterInfo, err = q.ClusterInfo(ctx).Result(); err != nil && !unsupported("CLUSTER INFO") {
+			return nil, fmt.Errorf("CLUSTER INFO: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// ReadSnapshot loads a snapshot written as JSON, e.g. by --dump-snapshot.
+func ReadSnapshot(path string) (*ServerSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s ServerSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse snapshot %s: %w", path, err)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s: unsupported version %d", path, s.Version)
+	}
+	return &s, nil
+}
+
+// snapshotQuerier answers the collector's commands from a snapshot.
+type snapshotQuerier struct {
+	s *ServerSnapshot
+}
+
+// NewSnapshotQuerier returns a RedisQuerier serving s, so the collector can
+// be run against a saved snapshot. Hashes are always empty.
+func NewSnapshotQuerier(s *ServerSnapshot) RedisQuerier {
+	return snapshotQuerier{s}
+}
+
+// reject returns the error a server gives for a command it does not support,
+// if the snapshot recorded it as such.
+func (q snapshotQuerier) reject(command string) error {
+	if slices.Contains(q.s.Unsupported, command) {
+		return fmt.Errorf("ERR unknown command '%s' (replayed from snapshot)", command)
+	}
+	return nil
+}
+
+func (q snapshotQuerier) Ping(context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func (q snapshotQuerier) InfoMap(ctx context.Context, _ ...string) *redis.InfoCmd {
+	cmd := redis.NewInfoCmd(ctx)
+	if err := q.reject("INFO"); err != nil {
+		cmd.SetErr(err)
+		return cmd
+	}
+	cmd.SetVal(q.s.Info)
+	return cmd
+}
+
+func (q snapshotQuerier) PubSubChannels(_ context.Context, pattern string) *redis.StringSliceCmd {
+	if err := q.reject("PUBSUB CHANNELS"); err != nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+	var out []string
+	for channel := range q.s.Channels {
+		if MatchGlob(pattern, channel) {
+			out = append(out, channel)
+		}
+	}
+	slices.Sort(out)
+	return redis.NewStringSliceResult(out, nil)
+}
+
+func (q snapshotQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	cmds := make([]*redis.StringSliceCmd, len(patterns))
+	for i, pattern := range patterns {
+		cmds[i] = q.PubSubChannels(ctx, pattern)
+	}
+	return cmds
+}
+
+func (q snapshotQuerier) PubSubNumSub(_ context.Context, channels ...string) *redis.MapStringIntCmd {
+	if err := q.reject("PUBSUB NUMSUB"); err != nil {
+		return redis.NewMapStringIntCmdResult(nil, err)
+	}
+	out := make(map[string]int64, len(channels))
+	for _, channel := range channels {
+		out[channel] = q.s.Channels[channel]
+	}
+	return redis.NewMapStringIntCmdResult(out, nil)
+}
+
+func (q snapshotQuerier) PubSubNumPat(context.Context) *redis.IntCmd {
+	return redis.NewIntResult(q.s.NumPat, q.reject("PUBSUB NUMPAT"))
+}
+
+func (q snapshotQuerier) ClientList(context.Context) *redis.StringCmd {
+	if err := q.reject("CLIENT LIST"); err != nil {
+		return redis.NewStringResult("", err)
+	}
+	var b strings.Builder
+	for _, line := range q.s.Clients {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return redis.NewStringResult(b.String(), nil)
+}
+
+func (q snapshotQuerier) HGetAll(context.Context, string) *redis.MapStringStringCmd {
+	return redis.NewMapStringStringResult(map[string]string{}, nil)
+}
+
+func (q snapshotQuerier) ClusterInfo(context.Context) *redis.StringCmd {
+	return redis.NewStringResult(q.s.ClusterInfo, q.reject("CLUSTER INFO"))
+}
+
+// fakeGroups prefix the channels of FakeSnapshot, so they fall into a few
+// patterns like real channel names.
+var fakeGroups = []string{"orders", "users", "payments", "notifications", "jobs"}
+
+// FakeSnapshot returns a synthetic snapshot with the given numbers of
+// channels and subscribed clients, for benchmarking the collector without a
+// Redis that large. Every fourth channel is orphaned; clients spread over
+// 256 addresses and 50 names.
+func FakeSnapshot(channels, clients int) *ServerSnapshot {
+	s := &ServerSnapshot{
+		Version:  SnapshotVersion,
+		Taken:    time.Now().UTC(),
+		Channels: make(map[string]int64, channels),
+		NumPat:   int64(len(fakeGroups)),
+		Clients:  make([]string, 0, clients),
+	}
+	for i := range channels {
+		s.Channels[fmt.Sprintf("%s.%d", fakeGroups[i%len(fakeGroups)], i)] = int64(i % 4)
+	}
+	for i := range clients {
+		s.Clients = append(s.Clients, fmt.Sprintf(
+			"id=%d addr=10.0.%d.%d:%d name=fake-%d sub=%d psub=%d omem=%d cmd=subscribe",
+			i+1, i/256%256, i%256, 20000+i%40000, i%50, 1+i%10, i%2, i%7*1024))
+	}
+	s.Info = map[string]map[string]string{
+		"server":  {"redis_version": "7.2.0"},
+		"clients": {"connected_clients": strconv.Itoa(clients), "pubsub_clients": strconv.Itoa(clients)},
+		"stats": {
+			"pubsub_channels": strconv.Itoa(channels),
+			"pubsub_patterns": strconv.Itoa(len(fakeGroups)),
+		},
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotReplay(t *testing.T) {
+	live := &fakeQuerier{
+		info: map[string]map[string]string{
+			"server": {"redis_version": "7.2.4"},
+			"stats":  {"pubsub_channels": "3"},
+		},
+		channels: map[string]int64{"orders.1": 2, "orders.2": 0, "users.login": 1},
+		numPat:   1,
+		clientList: "id=1 addr=10.0.0.1:5001 name=svc sub=2 psub=0\n" +
+			"id=2 addr=10.0.0.2:5002 name= sub=0 psub=1\n",
+	}
+	snap, err := TakeSnapshot(context.Background(), live, false)
+	if err != nil {
+		t.Fatalf("TakeSnapshot: %v", err)
+	}
+	if len(snap.Clients) != 2 || snap.Channels["orders.1"] != 2 || snap.NumPat != 1 {
+		t.Fatalf("incomplete snapshot: %+v", snap)
+	}
+
+	// Round trip through a file, as with --dump-snapshot and replay
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("ReadSnapshot: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := Options{MaxChannels: 100, KnownPatterns: []string{"orders.*"}}
+	want := collect(t, New(live, opts, logger))
+	got := collect(t, New(NewSnapshotQuerier(loaded), opts, logger))
+
+	for key, v := range want {
+		if strings.Contains(key, "duration") || strings.Contains(key, "exporter_") || strings.Contains(key, "health_score") {
+			continue // timing dependent
+		}
+		if got[key] != v {
+			t.Errorf("replayed %s = %v, want %v", key, got[key], v)
+		}
+	}
+}
+
+func TestSnapshotUnsupported(t *testing.T) {
+	live := &fakeQuerier{
+		channels:  map[string]int64{"orders.1": 2},
+		clientErr: errors.New("ERR unknown command 'CLIENT', with args beginning with: 'LIST'"),
+	}
+	snap, err := TakeSnapshot(context.Background(), live, false)
+	if err != nil {
+		t.Fatalf("TakeSnapshot: %v", err)
+	}
+	if !slices.Equal(snap.Unsupported, []string{"CLIENT LIST"}) {
+		t.Errorf("want CLIENT LIST unsupported, got %v", snap.Unsupported)
+	}
+	if err := NewSnapshotQuerier(snap).ClientList(context.Background()).Err(); !isUnsupported(err) {
+		t.Errorf("replay should reject CLIENT LIST as unsupported, got %v", err)
+	}
+
+	live.clientErr = errors.New("i/o timeout")
+	if _, err := TakeSnapshot(context.Background(), live, false); err == nil {
+		t.Error("want error for a failed command")
+	}
+}
+
+func TestFakeSnapshot(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := New(NewSnapshotQuerier(FakeSnapshot(1000, 20)), Options{MaxChannels: 100}, logger)
+	got := collect(t, c)
+
+	for key, want := range map[string]float64{
+		"redis_pubsub_channels_total{}": 100,
+		"redis_pubsub_clients_total{}":  20,
+		"redis_pubsub_patterns_total{}": 5,
+	} {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("want %s %v, got %v", key, want, v)
+		}
+	}
+	channels := 0
+	for key := range got {
+		if strings.HasPrefix(key, "redis_pubsub_channel_subscriber_count{") {
+			channels++
+		}
+	}
+	if channels != 100 {
+		t.Errorf("want 100 channel series, got %d", channels)
+	}
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Subsystem is one part of a scrape, e.g. the channel or the client
+// metrics. The collector pings Redis, then runs its subsystems in order.
+type Subsystem interface {
+	// Name identifies the subsystem in Options.DisabledSubsystems, in scrape
+	// errors and in the collector label of collector_success and
+	// collector_duration_seconds.
+	Name() string
+	Describe(ch chan<- *prometheus.Desc)
+	// Scrape emits the subsystem's metrics. An error marks the subsystem
+	// and the scrape as failed, so optional data should be skipped rather
+	// than reported; the following subsystems still run.
+	Scrape(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error
+}
+
+// ScrapeState is shared by the subsystems of one scrape, so that later ones
+// can build on what earlier ones read instead of querying it again.
+type ScrapeState struct {
+	Client RedisQuerier // the main client; commands issued through it are counted
+	Limits Limits
+
+	Info map[string]map[string]string // INFO of the main client; set by "server"
+
+	Channels    []string         // tracked channels; set by "channels"
+	Subscribers map[string]int64 // subscribers per tracked channel; set by "channels"
+	Clients     []PubSubClient   // pub/sub clients; set by "clients", nil if unavailable
+
+	count       *commandCount
+	start       time.Time
+	maxCommands int
+	maxDuration time.Duration
+	exceeded    bool // set by OverBudget
+	asserted    int  // patterns with a minimum subscriber count; set by "patterns"
+	shortfalls  int  // of which below their minimum
+}
+
+// Names of the built-in subsystems, in scrape order.
+const (
+	SubsystemServer      = "server"       // INFO sections
+	SubsystemCluster     = "cluster"      // CLUSTER INFO, with ClusterMode
+	SubsystemChannels    = "channels"     // channels, subscribers, orphans, silent and system channels
+	SubsystemClients     = "clients"      // CLIENT LIST
+	SubsystemRates       = "rates"        // with RateWindow; needs channels and clients
+	SubsystemHashMetrics = "hash_metrics" // with HashMetrics
+	SubsystemPatterns    = "patterns"     // pattern count and activity
+)
+
+// BuiltinSubsystems returns the names of all built-in subsystems in scrape
+// order, including those that only run with the corresponding option.
+func BuiltinSubsystems() []string {
+	return []string{
+		SubsystemServer, SubsystemCluster, SubsystemChannels, SubsystemClients,
+		SubsystemRates, SubsystemHashMetrics, SubsystemPatterns,
+	}
+}
+
+// funcSubsystem is a Subsystem made of functions, used for the built-ins.
+type funcSubsystem struct {
+	name     string
+	describe func(ch chan<- *prometheus.Desc)
+	scrape   func(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error
+}
+
+func (f funcSubsystem) Name() string                        { return f.name }
+func (f funcSubsystem) Describe(ch chan<- *prometheus.Desc) { f.describe(ch) }
+func (f funcSubsystem) Scrape(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState) error {
+	return f.scrape(ctx, ch, s)
+}
+
+// registerSubsystems returns the built-in subsystems that apply to c,
+// followed by extra, restricted to the enabled ones if any are named and
+// minus the disabled ones.
+func (c *RedisPubSubCollector) registerSubsystems(extra []Subsystem, enabled, disabled []string) []Subsystem {
+	var subs []Subsystem
+	subs = append(subs, funcSubsystem{SubsystemServer, c.describeServer, c.scrapeServer})
+	if c.clusterMode {
+		subs = append(subs, funcSubsystem{SubsystemCluster, c.describeCluster, c.scrapeCluster})
+	}
+	subs = append(subs,
+		funcSubsystem{SubsystemChannels, c.describeChannels, c.scrapeChannels},
+		funcSubsystem{SubsystemClients, c.describeClients, c.scrapeClients},
+	)
+	if c.rates != nil {
+		subs = append(subs, funcSubsystem{SubsystemRates, c.describeRates, c.scrapeRates})
+	}
+	if len(c.hashMetrics) > 0 {
+		subs = append(subs, funcSubsystem{SubsystemHashMetrics, c.describeHashMetrics, c.scrapeHashMetrics})
+	}
+	subs = append(subs, funcSubsystem{SubsystemPatterns, c.describePatterns, c.scrapePatterns})
+	subs = append(subs, extra...)
+
+	for _, name := range enabled {
+		if !slices.ContainsFunc(subs, func(s Subsystem) bool { return s.Name() == name }) {
+			c.logger.Warn("cannot enable unknown or inactive subsystem", "subsystem", name)
+		}
+	}
+	for _, name := range disabled {
+		if !slices.ContainsFunc(subs, func(s Subsystem) bool { return s.Name() == name }) {
+			c.logger.Warn("cannot disable unknown or inactive subsystem", "subsystem", name)
+		}
+	}
+	return slices.DeleteFunc(subs, func(s Subsystem) bool {
+		return len(enabled) > 0 && !slices.Contains(enabled, s.Name()) || slices.Contains(disabled, s.Name())
+	})
+}
+
+// Subsystems returns the names of the subsystems run on each scrape, in order.
+func (c *RedisPubSubCollector) Subsystems() []string {
+	names := make([]string, len(c.subsystems))
+	for i, s := range c.subsystems {
+		names[i] = s.Name()
+	}
+	return names
+}
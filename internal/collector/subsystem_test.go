@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelCountSubsystem exports the number of channels seen by "channels".
+type channelCountSubsystem struct {
+	desc *prometheus.Desc
+	err  error
+}
+
+func (s channelCountSubsystem) Name() string                        { return "app" }
+func (s channelCountSubsystem) Describe(ch chan<- *prometheus.Desc) { ch <- s.desc }
+func (s channelCountSubsystem) Scrape(_ context.Context, ch chan<- prometheus.Metric, state *ScrapeState) error {
+	if s.err != nil {
+		return s.err
+	}
+	ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, float64(len(state.Channels)))
+	return nil
+}
+
+func TestCollectSubsystems(t *testing.T) {
+	desc := prometheus.NewDesc("app_channels", "Channels seen by the app subsystem", nil, nil)
+	tests := []struct {
+		name         string
+		extra        Subsystem
+		enabled      []string
+		disabled     []string
+		wantUp       float64
+		wantNames    []string
+		wantPresent  []string
+		wantAbsent   []string
+		wantAppValue float64
+		wantSuccess  map[string]float64
+	}{
+		{
+			name:         "extra subsystem runs after the built-ins",
+			extra:        channelCountSubsystem{desc: desc},
+			wantUp:       1,
+			wantNames:    []string{"server", "channels", "clients", "patterns", "app"},
+			wantPresent:  []string{"redis_pubsub_clients_total{}", "redis_pubsub_exporter_collector_duration_seconds{collector=app}"},
+			wantAppValue: 2,
+			wantSuccess:  map[string]float64{"server": 1, "channels": 1, "clients": 1, "patterns": 1, "app": 1},
+		},
+		{
+			name:        "disabled subsystems are skipped",
+			disabled:    []string{"clients", "server"},
+			wantUp:      1,
+			wantNames:   []string{"channels", "patterns"},
+			wantPresent: []string{"redis_pubsub_channels_total{}"},
+			wantAbsent: []string{
+				"redis_pubsub_clients_total{}",
+				"redis_pubsub_exporter_redis_connected_clients{}",
+				"redis_pubsub_exporter_collector_success{collector=clients}",
+			},
+		},
+		{
+			name:        "only enabled subsystems run",
+			enabled:     []string{"server", "channels"},
+			wantUp:      1,
+			wantNames:   []string{"server", "channels"},
+			wantPresent: []string{"redis_pubsub_channels_total{}", "redis_pubsub_exporter_redis_connected_clients{}"},
+			wantAbsent:  []string{"redis_pubsub_clients_total{}"},
+		},
+		{
+			name:        "subsystem errors fail the scrape but not the other subsystems",
+			extra:       channelCountSubsystem{desc: desc, err: errors.New("boom")},
+			wantUp:      0,
+			wantNames:   []string{"server", "channels", "clients", "patterns", "app"},
+			wantPresent: []string{"redis_pubsub_channels_total{}", "redis_pubsub_exporter_collector_duration_seconds{collector=app}"},
+			wantAbsent:  []string{"app_channels{}"},
+			wantSuccess: map[string]float64{"server": 1, "channels": 1, "clients": 1, "patterns": 1, "app": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{
+				info:     map[string]map[string]string{"clients": {"connected_clients": "3"}},
+				channels: map[string]int64{"orders.created": 1, "orders.paid": 0},
+			}
+			var extra []Subsystem
+			if tt.extra != nil {
+				extra = append(extra, tt.extra)
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			c := New(q, Options{MaxChannels: 100, Subsystems: extra, EnabledSubsystems: tt.enabled, DisabledSubsystems: tt.disabled}, logger)
+
+			if names := c.Subsystems(); !slices.Equal(names, tt.wantNames) {
+				t.Errorf("want subsystems %v, got %v", tt.wantNames, names)
+			}
+			got := collect(t, c)
+			if got["redis_pubsub_exporter_redis_up{}"] != tt.wantUp {
+				t.Fatalf("want redis_up %v, got %v", tt.wantUp, got["redis_pubsub_exporter_redis_up{}"])
+			}
+			for _, key := range tt.wantPresent {
+				if _, ok := got[key]; !ok {
+					t.Errorf("%s should be emitted", key)
+				}
+			}
+			for _, key := range tt.wantAbsent {
+				if _, ok := got[key]; ok {
+					t.Errorf("%s should not be emitted", key)
+				}
+			}
+			for name, want := range tt.wantSuccess {
+				key := "redis_pubsub_exporter_collector_success{collector=" + name + "}"
+				if v, ok := got[key]; !ok || v != want {
+					t.Errorf("want %s %v, got %v", key, want, v)
+				}
+			}
+			if tt.wantAppValue > 0 && got["app_channels{}"] != tt.wantAppValue {
+				t.Errorf("want app_channels %v, got %v", tt.wantAppValue, got["app_channels{}"])
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// How keyspace notification channels (__keyspace@<db>__:<key> and
+// __keyevent@<db>__:<event>) are exported.
+const (
+	SystemChannelsInclude  = "include"  // mixed in with application channels (default)
+	SystemChannelsSeparate = "separate" // exported as system_channel_* metrics
+	SystemChannelsExclude  = "exclude"  // not exported at all
+)
+
+// isSystemChannel reports whether channel carries Redis keyspace
+// notifications rather than application messages.
+func isSystemChannel(channel string) bool {
+	return strings.HasPrefix(channel, "__keyspace@") || strings.HasPrefix(channel, "__keyevent@")
+}
+
+// collectSystemChannels emits the number of keyspace notification channels
+// and their subscriber counts, capped at MaxChannels like application
+// channels.
+func (c *RedisPubSubCollector) collectSystemChannels(ctx context.Context, ch chan<- prometheus.Metric, s *ScrapeState, channels []string) error {
+	maxChannels := s.Limits.MaxChannels
+	ch <- prometheus.MustNewConstMetric(c.systemChannelsTotal, prometheus.GaugeValue, float64(len(channels)))
+	slices.Sort(channels)
+	if len(channels) > maxChannels {
+		c.logger.Warn("system channel count exceeds MAX_CHANNELS, truncating",
+			"count", len(channels), "max", maxChannels)
+		channels = channels[:maxChannels]
+	}
+	if len(channels) == 0 || s.OverBudget() {
+		return nil
+	}
+
+	numsub, err := s.Client.PubSubNumSub(ctx, channels...).Result()
+	if err != nil {
+		return err
+	}
+	for _, channel := range slices.Sorted(maps.Keys(numsub)) {
+		ch <- prometheus.MustNewConstMetric(c.systemChannelSubscriberCount, prometheus.GaugeValue, float64(numsub[channel]), channel)
+	}
+	return nil
+}
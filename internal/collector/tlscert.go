@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// TLSCertHook is a go-redis hook that records when the certificate chain
+// presented by each Redis server expires, from the TLS connections the
+// dialer returns. Add it to the client with AddHook, or to every node in
+// cluster mode.
+type TLSCertHook struct {
+	mu     sync.Mutex
+	expiry map[string]time.Time // dialed address -> earliest NotAfter of the peer chain
+}
+
+// NewTLSCertHook returns an empty TLSCertHook.
+func NewTLSCertHook() *TLSCertHook {
+	return &TLSCertHook{expiry: make(map[string]time.Time)}
+}
+
+// DialHook implements redis.Hook.
+func (h *TLSCertHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if tlsConn, ok := conn.(tlsConnection); ok && err == nil {
+			h.observe(addr, tlsConn.ConnectionState())
+		}
+		return conn, err
+	}
+}
+
+// tlsConnection is implemented by *tls.Conn and by connections wrapping one.
+type tlsConnection interface {
+	ConnectionState() tls.ConnectionState
+}
+
+// ProcessHook implements redis.Hook.
+func (h *TLSCertHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (h *TLSCertHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// observe records the earliest expiry in the chain the server sent; the
+// connection breaks as soon as any certificate in it expires.
+func (h *TLSCertHook) observe(addr string, state tls.ConnectionState) {
+	var earliest time.Time
+	for _, cert := range state.PeerCertificates {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	if earliest.IsZero() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expiry[addr] = earliest
+}
+
+// Expiry returns the certificate expiry per server address seen so far.
+func (h *TLSCertHook) Expiry() map[string]time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return maps.Clone(h.expiry)
+}
+
+// collectTLSCerts emits the certificate expiry of every server connected to
+// over TLS.
+func (c *RedisPubSubCollector) collectTLSCerts(ch chan<- prometheus.Metric) {
+	if c.tlsCerts == nil {
+		return
+	}
+	expiry := c.tlsCerts.Expiry()
+	for _, addr := range slices.Sorted(maps.Keys(expiry)) {
+		ch <- prometheus.MustNewConstMetric(c.tlsCertExpiry, prometheus.GaugeValue, float64(expiry[addr].Unix()), addr)
+	}
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// tlsPipe returns the client side of a completed TLS handshake with a
+// server presenting a self-signed certificate valid until notAfter.
+func tlsPipe(t *testing.T, notAfter time.Time) net.Conn {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientSide, serverSide := net.Pipe()
+	t.Cleanup(func() { _ = clientSide.Close(); _ = serverSide.Close() })
+	server := tls.Server(serverSide, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	go func() { _ = server.Handshake() }()
+	client := tls.Client(clientSide, &tls.Config{InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	return client
+}
+
+func TestTLSCertHook(t *testing.T) {
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	h := NewTLSCertHook()
+
+	tests := []struct {
+		name string
+		addr string
+		conn func(t *testing.T) net.Conn
+	}{
+		{name: "tls", addr: "redis-a:6379", conn: func(t *testing.T) net.Conn { return tlsPipe(t, notAfter) }},
+		{name: "plain", addr: "redis-b:6379", conn: func(*testing.T) net.Conn { c, _ := net.Pipe(); return c }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dial := h.DialHook(func(context.Context, string, string) (net.Conn, error) { return tt.conn(t), nil })
+			if _, err := dial(context.Background(), "tcp", tt.addr); err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+		})
+	}
+
+	c := New(&fakeQuerier{}, Options{MaxChannels: 100, TLSCerts: h}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	got := collect(t, c)
+	if v := got["redis_pubsub_exporter_redis_tls_cert_expiry_timestamp_seconds{addr=redis-a:6379}"]; v != float64(notAfter.Unix()) {
+		t.Errorf("want expiry %d, got %v", notAfter.Unix(), v)
+	}
+	if _, ok := got["redis_pubsub_exporter_redis_tls_cert_expiry_timestamp_seconds{addr=redis-b:6379}"]; ok {
+		t.Error("plain connections should not report an expiry")
+	}
+}
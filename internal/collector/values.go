@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// parseHashValue converts a hash field value using the definition's value
+// map, falling back to its parse mode.
+func parseHashValue(def config.HashMetricDef, raw string) (float64, error) {
+	raw = strings.TrimSpace(raw)
+	if v, ok := def.ValueMap[raw]; ok {
+		return v, nil
+	}
+
+	switch def.ParseMode {
+	case config.ParseBool:
+		return parseBoolValue(raw)
+	case config.ParseDuration:
+		return parseDurationValue(raw)
+	default:
+		return strconv.ParseFloat(raw, 64)
+	}
+}
+
+// parseBoolValue accepts true/false, yes/no, on/off and 1/0 (case-insensitive).
+func parseBoolValue(raw string) (float64, error) {
+	switch strings.ToLower(raw) {
+	case "true", "yes", "on", "1":
+		return 1, nil
+	case "false", "no", "off", "0":
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid boolean %q", raw)
+}
+
+// parseDurationValue accepts Go durations ("5m30s") and plain numbers, which
+// are taken as seconds. The result is in seconds.
+func parseDurationValue(raw string) (float64, error) {
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
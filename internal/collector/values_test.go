@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+func TestParseHashValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		valMap  map[string]float64
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{name: "number", input: " 42.5 ", want: 42.5},
+		{name: "number rejects text", input: "yes", wantErr: true},
+		{name: "bool true", mode: config.ParseBool, input: "true", want: 1},
+		{name: "bool yes uppercase", mode: config.ParseBool, input: "YES", want: 1},
+		{name: "bool off", mode: config.ParseBool, input: "off", want: 0},
+		{name: "bool numeric", mode: config.ParseBool, input: "0", want: 0},
+		{name: "bool invalid", mode: config.ParseBool, input: "maybe", wantErr: true},
+		{name: "duration", mode: config.ParseDuration, input: "5m30s", want: 330},
+		{name: "duration sub-second", mode: config.ParseDuration, input: "250ms", want: 0.25},
+		{name: "duration plain seconds", mode: config.ParseDuration, input: "90", want: 90},
+		{name: "duration invalid", mode: config.ParseDuration, input: "soon", wantErr: true},
+		{name: "map wins over mode", mode: config.ParseBool, valMap: map[string]float64{"maybe": 0.5}, input: "maybe", want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := config.HashMetricDef{ParseMode: tt.mode, ValueMap: tt.valMap}
+			got, err := parseHashValue(def, tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
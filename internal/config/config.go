@@ -1,18 +1,125 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/netip"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// HTTPWriteTimeout bounds writing an HTTP response; scrapes must finish
+// well within it.
+const HTTPWriteTimeout = 30 * time.Second
+
 const (
 	DefaultRedisHost     = "localhost"
 	DefaultRedisPort     = 6379
 	DefaultRedisDB       = 0
 	DefaultListenAddress = ":9123"
 	DefaultMaxChannels   = 500
+	DefaultMaxPatterns   = 100
+
+	DefaultHashMetricsPoolSize = 2
+
+	DefaultRedisClientName = "redis-pubsub-exporter"
+	DefaultRedisProtocol   = 3
+
+	// Below the idle timeout of common NAT gateways (e.g. 350s on AWS), which
+	// drop idle connections without telling either end.
+	DefaultRedisConnMaxIdleTime = 4 * time.Minute
+	DefaultRedisTCPKeepAlive    = time.Minute
+
+	DefaultMetricsErrorHandling = "continue"
+	DefaultSystemChannels       = "include"
+	DefaultMode                 = "full"
+	DefaultClientAggregate      = "none"
+
+	DefaultConsulAddr             = "http://127.0.0.1:8500"
+	DefaultTargetsRefreshInterval = 30 * time.Second
+
+	DefaultVaultKubernetesMount = "kubernetes"
+	DefaultVaultPasswordKey     = "password"
+	DefaultVaultRefreshInterval = 5 * time.Minute
+
+	DefaultScrapeTimeout           = 10 * time.Second
+	DefaultScrapeFailureBackoff    = 5 * time.Second
+	DefaultScrapeFailureBackoffMax = time.Minute
+	DefaultScrapeAuthBackoff       = 5 * time.Minute
+
+	DefaultReadyFailureThreshold = 1
+	DefaultHealthCheckInterval   = 15 * time.Second
+
+	DefaultLogLevel         = "info"
+	DefaultLogDedupInterval = time.Minute
+	DefaultLogDiffMinDelta  = 1
+
+	DefaultHeartbeatTTL = time.Minute
+
+	DefaultHALockTTL = 15 * time.Second
+
+	DefaultStateSaveInterval = time.Minute
+
+	DefaultHistorySize = 60
+
+	DefaultOrphanMinAge = 5 * time.Minute
+
+	DefaultSamplerPatterns = "*"
+	DefaultSilentWindow    = 5 * time.Minute
+	DefaultSamplerMaxChan  = 1000
+	DefaultSamplerMaxRate  = 10000
+
+	DefaultSamplerMode            = "psubscribe"
+	DefaultSamplerMonitorDuration = 5 * time.Second
+	DefaultSamplerMonitorInterval = time.Minute
+)
+
+// Collection profiles, selected with PROFILE or --profile.
+const (
+	ProfileMinimal  = "minimal"  // server and channel metrics only, tight limits; for small or edge deployments
+	ProfileStandard = "standard" // the defaults
+	ProfileDeep     = "deep"     // higher limits and change rates; for central instances
+
+	DefaultProfile = ProfileStandard
+)
+
+// profiles maps each profile to the defaults it changes, by variable name
+// without prefix. Variables and flags that are set explicitly still win.
+var profiles = map[string]map[string]string{
+	ProfileMinimal: {
+		"SCRAPE_DISABLE":              "clients,patterns",
+		"SCRAPE_MIN_INTERVAL":         "30s",
+		"SCRAPE_MAX_COMMANDS":         "20",
+		"MAX_CHANNELS":                "100",
+		"HISTORY_SIZE":                "0",
+		"REDIS_HEALTH_CHECK_INTERVAL": "0",
+	},
+	ProfileStandard: {},
+	ProfileDeep: {
+		"MAX_CHANNELS": "5000",
+		"MAX_PATTERNS": "500",
+		"HISTORY_SIZE": "360",
+		"RATE_WINDOW":  "5m",
+	},
+}
+
+// Profiles returns the names of the collection profiles.
+func Profiles() []string {
+	return []string{ProfileMinimal, ProfileStandard, ProfileDeep}
+}
+
+// Value parse modes for hash metric definitions (parse=).
+const (
+	ParseNumber   = "number"   // plain float (default)
+	ParseBool     = "bool"     // true/false, yes/no, on/off -> 1/0
+	ParseDuration = "duration" // Go duration such as 5m30s -> seconds
 )
 
 // HashMetricDef defines a single Redis hash to expose as a Prometheus gauge.
@@ -22,73 +129,578 @@ type HashMetricDef struct {
 	MetricName string // Prometheus metric name (namespace prefix added by collector)
 	Help       string // Metric HELP string
 	FieldLabel string // Label name for hash fields
+	DB         *int   // Optional database (db=) the key lives in; nil uses REDIS_DB
+
+	// ValueMap (map=up:1|down:0) translates non-numeric field values to numbers.
+	ValueMap map[string]float64
+	// ParseMode (parse=) selects how unmapped values are parsed; empty means ParseNumber.
+	ParseMode string
 }
 
 // Config holds all configuration for the exporter.
 type Config struct {
+	// Profile is the collection profile whose defaults were applied.
+	Profile string
+
 	RedisHost     string
 	RedisPort     int
 	RedisPassword string
 	RedisDB       int
 	RedisTLS      bool
+	PreferReplica bool // route INFO reads to a replica
+
+	// PEM files with the TLS client certificate and key and the CA bundle,
+	// reloaded when they change.
+	RedisTLSCertFile string
+	RedisTLSKeyFile  string
+	RedisTLSCAFile   string
+
+	// RedisClientName is set with CLIENT SETNAME on every connection so the
+	// exporter can be told apart in CLIENT LIST and the slowlog; empty
+	// leaves connections unnamed.
+	RedisClientName string
+
+	// RedisProtocol is the RESP version requested with HELLO: 3 (default)
+	// or 2 for proxies that only speak RESP2.
+	RedisProtocol int
+
+	// DNS: RedisSRV replaces RedisHost/RedisPort with an SRV lookup on every
+	// dial; DNSRefreshInterval recycles pooled connections so they re-resolve.
+	RedisSRV           string
+	DNSRefreshInterval time.Duration
+
+	// Connection lifetime: pooled connections idle for RedisConnMaxIdleTime
+	// or older than RedisConnMaxLifetime are closed (0 = never), and TCP
+	// keepalive probes are sent every RedisTCPKeepAlive (0 = off).
+	RedisConnMaxIdleTime time.Duration
+	RedisConnMaxLifetime time.Duration
+	RedisTCPKeepAlive    time.Duration
+
+	// ProxyURL dials Redis through a SOCKS5 or HTTP CONNECT proxy.
+	ProxyURL string
+
+	// SSH tunnel: when SSHAddr is set, Redis is dialed through this bastion.
+	SSHAddr                  string
+	SSHUser                  string
+	SSHKeyFile               string
+	SSHKeyPassphrase         string
+	SSHAgent                 bool
+	SSHKnownHosts            string
+	SSHInsecureIgnoreHostKey bool
+
+	// Sentinel mode: when SentinelMaster is set, RedisHost/RedisPort are
+	// ignored and the master is discovered through SentinelAddrs.
+	SentinelAddrs    []string
+	SentinelMaster   string
+	SentinelPassword string
+
+	// Cluster mode: when ClusterAddrs is set, RedisHost/RedisPort are ignored
+	// and the cluster topology is discovered from these seed nodes.
+	ClusterAddrs []string
+
+	// Multi-target mode: targets are discovered from a file or Consul
+	// instead of RedisHost/RedisPort.
+	TargetsFile            string
+	ConsulAddr             string
+	ConsulService          string
+	ConsulTag              string
+	ConsulToken            string
+	TargetsRefreshInterval time.Duration
+
+	// Probe endpoint: ProbeEnabled serves /probe?target=host:port, which
+	// scrapes the named instance on request. ProbeModulesFile defines the
+	// modules a probe can select with ?module=. ProbeAllowedTargets are
+	// host:port globs of targets probed with the global credentials and
+	// client certificate; empty allows only the configured targets.
+	ProbeEnabled        bool
+	ProbeModulesFile    string
+	ProbeAllowedTargets []string
+
+	// Vault: when VaultAddr and VaultPath are set, the Redis password and
+	// TLS material are read from Vault instead of RedisPassword.
+	VaultAddr            string
+	VaultPath            string
+	VaultToken           string
+	VaultKubernetesRole  string
+	VaultKubernetesMount string
+	VaultPasswordKey     string
+	VaultRefreshInterval time.Duration
+
 	ListenAddress string
 	MaxChannels   int
 	KnownPatterns []string
 	HashMetrics   []HashMetricDef
+
+	// HashMetricsPoolSize is the connection pool size of the client each
+	// database's hash metrics are read through.
+	HashMetricsPoolSize int
+
+	// PatternMinSubscribers is the expected minimum of
+	// pattern_subscriber_count per pattern; patterns below it are flagged
+	// by pattern_subscriber_shortfall. They are always queried.
+	PatternMinSubscribers map[string]int
+
+	// HealthScoreWeights weighs the components of health_score (up,
+	// orphans, shortfall, latency); nil uses the collector's defaults.
+	HealthScoreWeights map[string]float64
+
+	// GRPCHealthAddress serves the grpc.health.v1 service on a separate
+	// listener; empty disables it.
+	GRPCHealthAddress string
+
+	// MaxClients caps per-client series, MaxPatterns the patterns queried
+	// per scrape and MaxSeries all Redis-derived series per scrape
+	// (0 = unlimited); ChannelInclude and ChannelExclude are glob lists
+	// selecting the tracked channels.
+	MaxClients     int
+	MaxPatterns    int
+	MaxSeries      int
+	ChannelInclude []string
+	ChannelExclude []string
+
+	// ClientIncludeName and ClientExcludeName are regular expressions on the
+	// client name selecting the clients with per-client series.
+	ClientIncludeName string
+	ClientExcludeName string
+
+	// ClientAggregate is "none" for per-connection client series or "ip"
+	// to sum them per source IP.
+	ClientAggregate string
+
+	// CriticalChannels are watched for their subscribers dropping to zero
+	// between scrapes, whether tracked or not.
+	CriticalChannels []string
+
+	// ChannelFilterFile holds include/exclude rules that replace
+	// ChannelInclude and ChannelExclude and are reloaded when it changes.
+	ChannelFilterFile string
+
+	// SystemChannels decides what happens to keyspace notification channels
+	// (__keyspace@*, __keyevent@*): "include" mixes them in with application
+	// channels, "separate" exports them as system_channel_* metrics and
+	// "exclude" drops them.
+	SystemChannels string
+
+	// AdminToken enables the runtime admin API, authenticated with this
+	// bearer token.
+	AdminToken string
+
+	// ScrapeMinInterval serves scrapes from cache if Redis was queried less
+	// than this long ago; 0 always queries Redis.
+	ScrapeMinInterval time.Duration
+
+	// ScrapeTimeout bounds the Redis queries of one scrape; it must be
+	// shorter than the HTTP write timeout.
+	ScrapeTimeout time.Duration
+
+	// ScrapeMaxCommands and ScrapeMaxDuration are the per-scrape budget:
+	// once used up, per-item metrics are skipped; 0 disables.
+	ScrapeMaxCommands int
+	ScrapeMaxDuration time.Duration
+
+	// Mode is "full", or "info-only" to take the channel, pattern and
+	// client counts from INFO without PUBSUB CHANNELS, NUMSUB or CLIENT LIST.
+	Mode string
+
+	// ScrapeDisable names scrape subsystems (channels, clients, patterns,
+	// ...) that are skipped on every scrape.
+	ScrapeDisable []string
+
+	// After a failed scrape Redis is left alone for ScrapeFailureBackoff,
+	// doubling per consecutive failure up to ScrapeFailureBackoffMax.
+	ScrapeFailureBackoff    time.Duration
+	ScrapeFailureBackoffMax time.Duration
+
+	// After NOAUTH or WRONGPASS Redis is left alone for ScrapeAuthBackoff.
+	ScrapeAuthBackoff time.Duration
+
+	// /readyz fails after ReadyFailureThreshold consecutive failed scrapes
+	// or once the last successful scrape is older than ReadyMaxStaleness
+	// (0 disables the staleness check).
+	ReadyFailureThreshold int
+	ReadyMaxStaleness     time.Duration
+
+	// HealthCheckInterval pings Redis in the background to keep readiness
+	// current without scrapes; 0 disables.
+	HealthCheckInterval time.Duration
+
+	// LogLevel is the minimum level logged: debug, info, warn or error.
+	LogLevel string
+
+	// LogDedupInterval logs a repeated warning or error at most once per
+	// interval, with a repeat count; 0 logs every occurrence.
+	LogDedupInterval time.Duration
+
+	// LogDiffMinDelta is the subscriber change of a channel between two
+	// scrapes that the debug-level scrape diff reports.
+	LogDiffMinDelta int
+
+	// AccessLog logs every HTTP request with method, path, status,
+	// duration and remote address.
+	AccessLog bool
+
+	// MetricsErrorHandling is how /metrics reacts to a metric that fails to
+	// gather: "continue" serves the rest, "http-error" fails the request
+	// with 500, "panic" crashes the exporter.
+	MetricsErrorHandling string
+
+	// MetricsDisableCompression turns off gzip on /metrics, which is
+	// otherwise used whenever the client accepts it.
+	MetricsDisableCompression bool
+
+	// HeartbeatKey is written to Redis with HeartbeatTTL after every
+	// successful scrape; "{instance}" is replaced by the hostname. Empty
+	// disables the heartbeat.
+	HeartbeatKey string
+	HeartbeatTTL time.Duration
+
+	// HALockKey is a Redis key that exporter replicas compete for; only the
+	// holder runs subsystems that subscribe or publish. Empty disables
+	// leader election and the replica always acts as leader.
+	HALockKey string
+	HALockTTL time.Duration
+
+	// StateFile or StateRedisKey persists cumulative counters every
+	// StateSaveInterval and on shutdown, so they survive restarts. At most
+	// one may be set; both empty disables persistence.
+	StateFile         string
+	StateRedisKey     string
+	StateSaveInterval time.Duration
+
+	// HistorySize is the number of recent scrape summaries kept per target
+	// and served at /api/v1/history; 0 disables the history.
+	HistorySize int
+
+	// RateWindow exports channel creation, subscriber change and client
+	// churn rates averaged over this window; 0 disables them.
+	RateWindow time.Duration
+
+	// OrphanMinAge is how long a channel must go without subscribers to be
+	// counted as a persistent orphan rather than a transient blip; 0
+	// disables orphan age tracking.
+	OrphanMinAge time.Duration
+
+	// PatternCacheTTL reuses pattern activity results for this long instead
+	// of querying every pattern on every scrape; 0 disables caching.
+	PatternCacheTTL time.Duration
+
+	// PatternDiscoveryMinChannels and PatternDiscoveryMinScrapes only query
+	// an auto-discovered prefix once it covered that many channels in that
+	// many scrapes; it is dropped after as many scrapes without.
+	PatternDiscoveryMinChannels int
+	PatternDiscoveryMinScrapes  int
+
+	// SamplerEnabled subscribes to SamplerPatterns and counts messages per
+	// channel. With SilentWindow set, channels with subscribers but no
+	// message for that long are reported as silent. Messages are counted per
+	// known pattern, and per channel unless SamplerChannelCounters is off.
+	SamplerEnabled         bool
+	SamplerPatterns        []string
+	SamplerChannelCounters bool
+	SilentWindow           time.Duration
+
+	// SamplerMaxChannels caps the channels the sampler tracks individually
+	// and SamplerMaxRate the messages it processes per second (0 =
+	// unlimited), so a firehose can't exhaust the exporter's memory or CPU.
+	SamplerMaxChannels int
+	SamplerMaxRate     int
+
+	// SamplerMode is "psubscribe" or "monitor". Monitor mode observes
+	// PUBLISH commands through MONITOR for SamplerMonitorDuration every
+	// SamplerMonitorInterval; it is expensive for Redis and meant for
+	// servers where subscribing is not an option.
+	SamplerMode            string
+	SamplerMonitorDuration time.Duration
+	SamplerMonitorInterval time.Duration
+
+	// SamplerClassifyPayloads counts sampled messages per known pattern and
+	// payload class (json, text, binary). SamplerPayloadTypeField, if set,
+	// also counts JSON messages by that field's value and implies
+	// classification.
+	SamplerClassifyPayloads bool
+	SamplerPayloadTypeField string
+
+	// StrictConfig fails startup on unknown environment variables in the
+	// exporter's namespace instead of only warning about them.
+	StrictConfig bool
+
+	errs      []error         // settings Load could not parse; see Validate
+	envPrefix string          // prefix passed to LoadPrefix
+	envKnown  map[string]bool // prefixed names of all variables Load reads
+
+	origins map[string]origin // prefixed variable name -> where Load took its value
+
+	deprecations []Deprecation // deprecated options that are set
+}
+
+// Deprecation is a deprecated option that is still set and honoured.
+type Deprecation struct {
+	Option      string // unprefixed variable name, e.g. EXPORTER_PORT
+	Replacement string // the setting to use instead
+}
+
+// Sources of a setting's value, from lowest to highest precedence.
+const (
+	SourceDefault = "default"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// origin is where Load took the value of a setting.
+type origin struct {
+	source string // SourceProfile or SourceEnv
+	from   string // the profile, or the variable that was set
 }
 
 // Load reads configuration from environment variables.
 // Flags set via kingpin will override after this call.
 func Load() *Config {
+	return LoadPrefix("")
+}
+
+// LoadPrefix is Load with every variable name prefixed, e.g. "RPE_" reads
+// RPE_REDIS_HOST instead of REDIS_HOST.
+func LoadPrefix(prefix string) *Config {
+	return LoadProfile(prefix, "")
+}
+
+// LoadProfile is LoadPrefix with the defaults of the named collection
+// profile; an empty name reads the profile from PROFILE.
+func LoadProfile(prefix, profile string) *Config {
+	env := &environ{prefix: prefix}
+	if fromEnv := env.string("PROFILE", DefaultProfile); profile == "" {
+		profile = fromEnv
+	}
+	defaults, ok := profiles[profile]
+	if !ok {
+		env.errs = append(env.errs, fmt.Errorf("%sPROFILE/--profile: unknown profile %q (want one of %s)",
+			prefix, profile, strings.Join(Profiles(), ", ")))
+		profile = DefaultProfile
+	}
+	env.defaults, env.profile = defaults, profile
+
 	c := &Config{
-		RedisHost:     envString("REDIS_HOST", DefaultRedisHost),
-		RedisPort:     envInt("REDIS_PORT", DefaultRedisPort),
-		RedisDB:       envInt("REDIS_DB", DefaultRedisDB),
-		RedisTLS:      envBool("REDIS_TLS", false),
-		ListenAddress: envString("EXPORTER_LISTEN_ADDRESS", DefaultListenAddress),
-		MaxChannels:   envInt("MAX_CHANNELS", DefaultMaxChannels),
+		Profile: profile,
+
+		RedisHost:       env.string("REDIS_HOST", DefaultRedisHost),
+		RedisPort:       env.int("REDIS_PORT", DefaultRedisPort),
+		RedisDB:         env.int("REDIS_DB", DefaultRedisDB),
+		RedisTLS:        env.bool("REDIS_TLS", false),
+		PreferReplica:   env.bool("REDIS_PREFER_REPLICA", false),
+		RedisClientName: env.stringOrEmpty("REDIS_CLIENT_NAME", DefaultRedisClientName),
+		RedisProtocol:   env.int("REDIS_PROTOCOL", DefaultRedisProtocol),
+		RedisSRV:        env.get("REDIS_SRV"),
+
+		DNSRefreshInterval: env.duration("REDIS_DNS_REFRESH_INTERVAL", 0),
+
+		ListenAddress: env.string("EXPORTER_LISTEN_ADDRESS", DefaultListenAddress),
+		MaxChannels:   env.int("MAX_CHANNELS", DefaultMaxChannels),
+		MaxClients:    env.int("MAX_CLIENTS", 0),
+		MaxPatterns:   env.int("MAX_PATTERNS", DefaultMaxPatterns),
+		MaxSeries:     env.int("MAX_SERIES", 0),
+
+		GRPCHealthAddress: env.string("GRPC_HEALTH_ADDRESS", ""),
+
+		ScrapeMinInterval:       env.duration("SCRAPE_MIN_INTERVAL", 0),
+		ScrapeTimeout:           env.duration("SCRAPE_TIMEOUT", DefaultScrapeTimeout),
+		ScrapeMaxCommands:       env.int("SCRAPE_MAX_COMMANDS", 0),
+		ScrapeMaxDuration:       env.duration("SCRAPE_MAX_DURATION", 0),
+		ScrapeFailureBackoff:    env.duration("SCRAPE_FAILURE_BACKOFF", DefaultScrapeFailureBackoff),
+		ScrapeFailureBackoffMax: env.duration("SCRAPE_FAILURE_BACKOFF_MAX", DefaultScrapeFailureBackoffMax),
+
+		ScrapeAuthBackoff: env.duration("SCRAPE_AUTH_FAILURE_BACKOFF", DefaultScrapeAuthBackoff),
+
+		ReadyFailureThreshold: env.int("READY_FAILURE_THRESHOLD", DefaultReadyFailureThreshold),
+		ReadyMaxStaleness:     env.duration("READY_MAX_STALENESS", 0),
+		HealthCheckInterval:   env.duration("REDIS_HEALTH_CHECK_INTERVAL", DefaultHealthCheckInterval),
+
+		LogLevel:         env.string("LOG_LEVEL", DefaultLogLevel),
+		LogDedupInterval: env.duration("LOG_DEDUP_INTERVAL", DefaultLogDedupInterval),
+		LogDiffMinDelta:  env.int("LOG_DIFF_MIN_DELTA", DefaultLogDiffMinDelta),
+		AccessLog:        env.bool("ACCESS_LOG", false),
+
+		MetricsErrorHandling:      env.string("METRICS_ERROR_HANDLING", DefaultMetricsErrorHandling),
+		MetricsDisableCompression: env.bool("METRICS_DISABLE_COMPRESSION", false),
+
+		HeartbeatKey: env.get("HEARTBEAT_KEY"),
+		HeartbeatTTL: env.duration("HEARTBEAT_TTL", DefaultHeartbeatTTL),
+
+		HALockKey: env.get("HA_LOCK_KEY"),
+		HALockTTL: env.duration("HA_LOCK_TTL", DefaultHALockTTL),
+
+		StateFile:         env.get("STATE_FILE"),
+		StateRedisKey:     env.get("STATE_REDIS_KEY"),
+		StateSaveInterval: env.duration("STATE_SAVE_INTERVAL", DefaultStateSaveInterval),
+
+		HistorySize: env.int("HISTORY_SIZE", DefaultHistorySize),
+		RateWindow:  env.duration("RATE_WINDOW", 0),
+
+		OrphanMinAge: env.duration("ORPHAN_MIN_AGE", DefaultOrphanMinAge),
+
+		PatternCacheTTL: env.duration("PATTERN_CACHE_TTL", 0),
+
+		PatternDiscoveryMinChannels: env.int("PATTERN_DISCOVERY_MIN_CHANNELS", 1),
+		PatternDiscoveryMinScrapes:  env.int("PATTERN_DISCOVERY_MIN_SCRAPES", 1),
+
+		SamplerEnabled:  env.bool("SAMPLER_ENABLED", false),
+		SamplerPatterns: SplitList(env.string("SAMPLER_PATTERNS", DefaultSamplerPatterns)),
+
+		SamplerChannelCounters: env.bool("SAMPLER_CHANNEL_COUNTERS", true),
+		SilentWindow:           env.duration("SILENT_CHANNEL_WINDOW", DefaultSilentWindow),
+
+		SamplerMaxChannels: env.int("SAMPLER_MAX_CHANNELS", DefaultSamplerMaxChan),
+		SamplerMaxRate:     env.int("SAMPLER_MAX_MESSAGES_PER_SECOND", DefaultSamplerMaxRate),
+
+		SamplerMode:            env.string("SAMPLER_MODE", DefaultSamplerMode),
+		SamplerMonitorDuration: env.duration("SAMPLER_MONITOR_DURATION", DefaultSamplerMonitorDuration),
+		SamplerMonitorInterval: env.duration("SAMPLER_MONITOR_INTERVAL", DefaultSamplerMonitorInterval),
+
+		SamplerClassifyPayloads: env.bool("SAMPLER_CLASSIFY_PAYLOADS", false),
+		SamplerPayloadTypeField: env.string("SAMPLER_PAYLOAD_TYPE_FIELD", ""),
 	}
 
 	// Backward compat: EXPORTER_PORT overrides listen address if set
-	if port := os.Getenv("EXPORTER_PORT"); port != "" {
+	if port := env.get("EXPORTER_PORT"); port != "" {
 		c.ListenAddress = ":" + port
+		env.setOrigin("EXPORTER_LISTEN_ADDRESS", SourceEnv, prefix+"EXPORTER_PORT")
+		c.deprecations = append(c.deprecations, Deprecation{
+			Option:      "EXPORTER_PORT",
+			Replacement: prefix + "EXPORTER_LISTEN_ADDRESS (--web.listen-address)",
+		})
 	}
 
-	// Empty password string is treated as no password
-	if pw := os.Getenv("REDIS_PASSWORD"); pw != "" {
-		c.RedisPassword = pw
-	}
+	// Secrets: each can also be read from the file named by <VAR>_FILE,
+	// e.g. a mounted Docker or Kubernetes secret.
+	c.RedisPassword = env.secret("REDIS_PASSWORD")
+	c.SentinelPassword = env.secret("REDIS_SENTINEL_PASSWORD")
+	c.SSHKeyPassphrase = env.secret("REDIS_SSH_KEY_PASSPHRASE")
+	c.AdminToken = env.secret("ADMIN_TOKEN")
+	c.ConsulToken = env.secret("CONSUL_HTTP_TOKEN")
+	c.VaultToken = env.secret("VAULT_TOKEN")
 
 	// Comma-separated patterns
-	if raw := os.Getenv("KNOWN_PATTERNS"); raw != "" {
-		for _, p := range strings.Split(raw, ",") {
-			p = strings.TrimSpace(p)
-			if p != "" {
-				c.KnownPatterns = append(c.KnownPatterns, p)
-			}
+	c.KnownPatterns = SplitList(env.get("KNOWN_PATTERNS"))
+	if raw := env.get("PATTERN_MIN_SUBSCRIBERS"); raw != "" {
+		mins, err := ParsePatternMinimums(raw)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%sPATTERN_MIN_SUBSCRIBERS: %w", env.prefix, err))
+		}
+		c.PatternMinSubscribers = mins
+	}
+	if raw := env.get("HEALTH_SCORE_WEIGHTS"); raw != "" {
+		weights, err := ParseHealthWeights(raw)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%sHEALTH_SCORE_WEIGHTS: %w", env.prefix, err))
 		}
+		c.HealthScoreWeights = weights
 	}
+	c.ChannelInclude = SplitList(env.get("CHANNEL_INCLUDE"))
+	c.ChannelExclude = SplitList(env.get("CHANNEL_EXCLUDE"))
+	c.CriticalChannels = SplitList(env.get("CRITICAL_CHANNELS"))
+	c.ClientIncludeName = env.get("CLIENT_INCLUDE_NAME")
+	c.ClientExcludeName = env.get("CLIENT_EXCLUDE_NAME")
+	c.ClientAggregate = env.string("CLIENT_AGGREGATE_BY", DefaultClientAggregate)
+	c.SystemChannels = env.string("SYSTEM_CHANNELS", DefaultSystemChannels)
+	c.Mode = env.string("MODE", DefaultMode)
+	c.ScrapeDisable = SplitList(env.get("SCRAPE_DISABLE"))
+	c.ChannelFilterFile = env.get("CHANNEL_FILTER_FILE")
+
+	c.ProxyURL = env.get("REDIS_PROXY_URL")
+
+	// Connection lifetime
+	c.RedisConnMaxIdleTime = env.duration("REDIS_CONN_MAX_IDLE_TIME", DefaultRedisConnMaxIdleTime)
+	c.RedisConnMaxLifetime = env.duration("REDIS_CONN_MAX_LIFETIME", 0)
+	c.RedisTCPKeepAlive = env.duration("REDIS_TCP_KEEPALIVE", DefaultRedisTCPKeepAlive)
+
+	// TLS material
+	c.RedisTLSCertFile = env.get("REDIS_TLS_CERT_FILE")
+	c.RedisTLSKeyFile = env.get("REDIS_TLS_KEY_FILE")
+	c.RedisTLSCAFile = env.get("REDIS_TLS_CA_FILE")
+
+	// SSH tunnel
+	c.SSHAddr = env.get("REDIS_SSH_ADDR")
+	c.SSHUser = env.get("REDIS_SSH_USER")
+	c.SSHKeyFile = env.get("REDIS_SSH_KEY_FILE")
+	c.SSHAgent = env.bool("REDIS_SSH_AGENT", false)
+	c.SSHKnownHosts = env.get("REDIS_SSH_KNOWN_HOSTS")
+	c.SSHInsecureIgnoreHostKey = env.bool("REDIS_SSH_INSECURE_IGNORE_HOST_KEY", false)
+
+	// Sentinel
+	c.SentinelAddrs = SplitList(env.get("REDIS_SENTINEL_ADDRS"))
+	c.SentinelMaster = env.get("REDIS_SENTINEL_MASTER")
+
+	// Cluster
+	c.ClusterAddrs = SplitList(env.get("REDIS_CLUSTER_ADDRS"))
+
+	// Multi-target
+	c.TargetsFile = env.get("TARGETS_FILE")
+	c.ConsulAddr = env.string("CONSUL_HTTP_ADDR", DefaultConsulAddr)
+	c.ConsulService = env.get("TARGETS_CONSUL_SERVICE")
+	c.ConsulTag = env.get("TARGETS_CONSUL_TAG")
+	c.TargetsRefreshInterval = env.duration("TARGETS_REFRESH_INTERVAL", DefaultTargetsRefreshInterval)
+
+	// Probe
+	c.ProbeEnabled = env.bool("PROBE_ENABLED", false)
+	c.ProbeModulesFile = env.get("PROBE_MODULES_FILE")
+	c.ProbeAllowedTargets = SplitList(env.get("PROBE_ALLOWED_TARGETS"))
+
+	// Vault
+	c.VaultAddr = env.get("VAULT_ADDR")
+	c.VaultPath = env.get("VAULT_PATH")
+	c.VaultKubernetesRole = env.get("VAULT_KUBERNETES_ROLE")
+	c.VaultKubernetesMount = env.string("VAULT_KUBERNETES_MOUNT", DefaultVaultKubernetesMount)
+	c.VaultPasswordKey = env.string("VAULT_PASSWORD_KEY", DefaultVaultPasswordKey)
+	c.VaultRefreshInterval = env.duration("VAULT_REFRESH_INTERVAL", DefaultVaultRefreshInterval)
 
 	// Hash metrics: semicolon-separated definitions
-	if raw := os.Getenv("HASH_METRICS"); raw != "" {
+	c.HashMetricsPoolSize = env.int("HASH_METRICS_POOL_SIZE", DefaultHashMetricsPoolSize)
+	if raw := env.get("HASH_METRICS"); raw != "" {
 		defs, err := ParseHashMetrics(raw)
-		if err == nil {
-			c.HashMetrics = defs
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%sHASH_METRICS: %w", env.prefix, err))
 		}
-		// Invalid definitions are silently skipped; main.go logs the result.
+		c.HashMetrics = defs
 	}
 
+	c.StrictConfig = env.bool("STRICT_CONFIG", false)
+
+	c.errs = env.errs
+	c.envPrefix, c.envKnown = prefix, env.known
+	c.origins = env.origins
 	return c
 }
 
+// Deprecations returns the deprecated options that are set. They still take
+// effect, but will be removed in a future release.
+func (c *Config) Deprecations() []Deprecation {
+	return c.deprecations
+}
+
+// Origin reports where Load took the value of the setting read from the
+// environment variable name (including the prefix): SourceEnv and the
+// variable actually set, which differs from name for EXPORTER_PORT and
+// _FILE secrets; SourceProfile and the profile; or SourceDefault.
+func (c *Config) Origin(name string) (source, from string) {
+	if o, ok := c.origins[name]; ok {
+		return o.source, o.from
+	}
+	return SourceDefault, ""
+}
+
 // ParseHashMetrics parses a HASH_METRICS string into HashMetricDef slice.
 //
 // Format: definitions separated by ";", fields separated by ",".
-// Each definition requires: redis_key, metric, help, label.
+// Each definition requires: redis_key, metric, help, label. The optional
+// db field reads the key from another database than REDIS_DB, map
+// translates string values ("|"-separated value:number pairs) and parse
+// selects the value format (number, bool or duration).
 //
 // Example:
 //
-//	redis_key=myapp:stats,metric=active_count,help=Active items,label=item
+//	redis_key=myapp:stats,metric=active_count,help=Active items,label=item,db=2
+//	redis_key=myapp:health,metric=service_status,label=service,map=up:1|down:0|degraded:0.5
+//	redis_key=myapp:timeouts,metric=timeout_seconds,label=job,parse=duration
 func ParseHashMetrics(raw string) ([]HashMetricDef, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -123,6 +735,26 @@ func ParseHashMetrics(raw string) ([]HashMetricDef, error) {
 		if def.RedisKey == "" || def.MetricName == "" || def.FieldLabel == "" {
 			return nil, fmt.Errorf("hash metric definition missing required field (redis_key, metric, label): %q", segment)
 		}
+		if v, ok := fields["db"]; ok {
+			db, err := strconv.Atoi(v)
+			if err != nil || db < 0 {
+				return nil, fmt.Errorf("hash metric definition has invalid db %q: %q", v, segment)
+			}
+			def.DB = &db
+		}
+		if v, ok := fields["map"]; ok {
+			m, err := parseValueMap(v)
+			if err != nil {
+				return nil, fmt.Errorf("hash metric definition has invalid map: %w: %q", err, segment)
+			}
+			def.ValueMap = m
+		}
+		switch mode := fields["parse"]; mode {
+		case "", ParseNumber, ParseBool, ParseDuration:
+			def.ParseMode = mode
+		default:
+			return nil, fmt.Errorf("hash metric definition has invalid parse mode %q (want number, bool or duration): %q", mode, segment)
+		}
 		if def.Help == "" {
 			def.Help = "Value from Redis hash " + def.RedisKey
 		}
@@ -133,38 +765,452 @@ func ParseHashMetrics(raw string) ([]HashMetricDef, error) {
 	return defs, nil
 }
 
-// RedisAddr returns "host:port" for the Redis connection.
+// parseValueMap parses "up:1|down:0|degraded:0.5" into a value map.
+func parseValueMap(raw string) (map[string]float64, error) {
+	m := make(map[string]float64)
+	for _, pair := range strings.Split(raw, "|") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idx := strings.LastIndexByte(pair, ':')
+		if idx <= 0 {
+			return nil, fmt.Errorf("entry %q is not value:number", pair)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(pair[idx+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q is not value:number", pair)
+		}
+		m[strings.TrimSpace(pair[:idx])] = f
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("empty map")
+	}
+	return m, nil
+}
+
+// ParsePatternMinimums parses a comma-separated list of pattern>=N
+// assertions, e.g. "orders.*>=2,users.*>=1", into a pattern -> minimum map.
+// Returns nil for an empty input.
+func ParsePatternMinimums(raw string) (map[string]int, error) {
+	var out map[string]int
+	for _, entry := range SplitList(raw) {
+		pattern, value, ok := strings.Cut(entry, ">=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%q: want pattern>=N", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q: minimum must be a non-negative integer", entry)
+		}
+		if out == nil {
+			out = make(map[string]int)
+		}
+		out[pattern] = n
+	}
+	return out, nil
+}
+
+// healthComponents are the components of the health score.
+var healthComponents = []string{"up", "orphans", "shortfall", "latency"}
+
+// ParseHealthWeights parses comma-separated component=weight pairs, e.g.
+// "up=0.5,latency=0.5". Components not listed get weight 0; at least one
+// weight must be positive.
+func ParseHealthWeights(raw string) (map[string]float64, error) {
+	out := make(map[string]float64, len(healthComponents))
+	for _, name := range healthComponents {
+		out[name] = 0
+	}
+	var total float64
+	for _, entry := range SplitList(raw) {
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !slices.Contains(healthComponents, name) {
+			return nil, fmt.Errorf("%q: want component=weight with component one of %s", entry, strings.Join(healthComponents, ", "))
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("%q: weight must be a non-negative number", entry)
+		}
+		out[name] = w
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New("at least one weight must be positive")
+	}
+	return out, nil
+}
+
+// SplitList splits a comma-separated list, trimming whitespace and dropping
+// empty entries. Returns nil for an empty input.
+func SplitList(raw string) []string {
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// SentinelEnabled reports whether the exporter connects through Redis Sentinel.
+func (c *Config) SentinelEnabled() bool {
+	return c.SentinelMaster != ""
+}
+
+// ClusterEnabled reports whether the exporter connects to a Redis Cluster.
+func (c *Config) ClusterEnabled() bool {
+	return len(c.ClusterAddrs) > 0
+}
+
+// ClientNameFilters returns the compiled ClientIncludeName and
+// ClientExcludeName, nil if unset. Both must have passed Validate.
+func (c *Config) ClientNameFilters() (include, exclude *regexp.Regexp) {
+	if c.ClientIncludeName != "" {
+		include = regexp.MustCompile(c.ClientIncludeName)
+	}
+	if c.ClientExcludeName != "" {
+		exclude = regexp.MustCompile(c.ClientExcludeName)
+	}
+	return include, exclude
+}
+
+// MultiTargetEnabled reports whether targets are discovered dynamically.
+func (c *Config) MultiTargetEnabled() bool {
+	return c.TargetsFile != "" || c.ConsulService != ""
+}
+
+// VaultEnabled reports whether Redis credentials are read from Vault.
+func (c *Config) VaultEnabled() bool {
+	return c.VaultAddr != "" && c.VaultPath != ""
+}
+
+// Validate reports every setting Load could not parse, such as a
+// non-numeric REDIS_PORT or an unreadable <VAR>_FILE secret, and every
+// out-of-range value, naming the flag to fix. Call it after flags are
+// parsed.
+func (c *Config) Validate() error {
+	errs := slices.Clone(c.errs)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validAddress(c.ListenAddress), "--web.listen-address: %q is not a valid host:port", c.ListenAddress)
+	check(c.GRPCHealthAddress == "" || validAddress(c.GRPCHealthAddress),
+		"--web.grpc-health-address: %q is not a valid host:port", c.GRPCHealthAddress)
+	check(validHost(c.RedisHost),
+		"--redis.host: %q is not a host name or IP address; set the port with --redis.port", c.RedisHost)
+	for _, list := range []struct {
+		flag  string
+		addrs []string
+	}{
+		{"--redis.sentinel-addrs", c.SentinelAddrs},
+		{"--redis.cluster-addrs", c.ClusterAddrs},
+	} {
+		for _, addr := range list.addrs {
+			check(validHostPort(addr), "%s: %q is not a valid host:port (IPv6 literals need brackets, e.g. [::1]:6379)", list.flag, addr)
+		}
+	}
+	check(c.RedisPort > 0 && c.RedisPort <= 65535, "--redis.port: must be between 1 and 65535, got %d", c.RedisPort)
+	check(!strings.ContainsFunc(c.RedisClientName, func(r rune) bool { return r <= ' ' || r > '~' }),
+		"--redis.client-name: %q must not contain spaces or special characters", c.RedisClientName)
+	check(c.RedisProtocol == 2 || c.RedisProtocol == 3, "--redis.protocol: must be 2 or 3, got %d", c.RedisProtocol)
+	check(c.RedisDB >= 0, "--redis.db: must not be negative, got %d", c.RedisDB)
+	for _, d := range []struct {
+		flag  string
+		value time.Duration
+	}{
+		{"--redis.conn-max-idle-time", c.RedisConnMaxIdleTime},
+		{"--redis.conn-max-lifetime", c.RedisConnMaxLifetime},
+		{"--redis.tcp-keepalive", c.RedisTCPKeepAlive},
+	} {
+		check(d.value >= 0, "%s: must not be negative (0 = off), got %s", d.flag, d.value)
+	}
+	check(c.MaxChannels > 0, "--max-channels: must be positive, got %d", c.MaxChannels)
+	check(c.MaxClients >= 0, "--max-clients: must not be negative (0 = unlimited), got %d", c.MaxClients)
+	for _, re := range []struct{ flag, expr string }{
+		{"--clients.include-name", c.ClientIncludeName},
+		{"--clients.exclude-name", c.ClientExcludeName},
+	} {
+		_, err := regexp.Compile(re.expr)
+		check(err == nil, "%s: %v", re.flag, err)
+	}
+	check(c.MaxPatterns >= 0, "--patterns.max: must not be negative (0 = unlimited), got %d", c.MaxPatterns)
+	check(c.HashMetricsPoolSize > 0, "HASH_METRICS_POOL_SIZE: must be positive, got %d", c.HashMetricsPoolSize)
+	check(c.LogDiffMinDelta > 0, "--log.diff-min-delta: must be positive, got %d", c.LogDiffMinDelta)
+	check(c.ScrapeTimeout > 0 && c.ScrapeTimeout < HTTPWriteTimeout,
+		"--scrape.timeout: must be positive and shorter than the HTTP write timeout (%s), got %s", HTTPWriteTimeout, c.ScrapeTimeout)
+	check(c.ScrapeMaxCommands >= 0, "--scrape.max-commands: must not be negative (0 = unlimited), got %d", c.ScrapeMaxCommands)
+	check(c.ScrapeMaxDuration >= 0, "--scrape.max-duration: must not be negative (0 = unlimited), got %s", c.ScrapeMaxDuration)
+	check(c.Mode != "info-only" || !slices.Contains(c.ScrapeDisable, "server"),
+		"--mode: info-only reads the counts from INFO and needs the server subsystem, which --scrape.disable turns off")
+	check(!c.SamplerEnabled || c.SamplerMode != "monitor" ||
+		c.SamplerMonitorDuration > 0 && c.SamplerMonitorDuration < c.SamplerMonitorInterval,
+		"--sampler.monitor-duration: must be positive and shorter than --sampler.monitor-interval (%s), got %s",
+		c.SamplerMonitorInterval, c.SamplerMonitorDuration)
+	check(c.ProbeModulesFile == "" || c.ProbeEnabled, "--probe.modules-file: requires --probe.enabled")
+	check(len(c.ProbeAllowedTargets) == 0 || c.ProbeEnabled, "--probe.allowed-targets: requires --probe.enabled")
+	check((c.RedisTLSCertFile == "") == (c.RedisTLSKeyFile == ""),
+		"--redis.tls-cert-file and --redis.tls-key-file: must be set together")
+	check(c.RedisTLS || c.RedisTLSCertFile == "" && c.RedisTLSCAFile == "",
+		"--redis.tls-cert-file, --redis.tls-ca-file: require --redis.tls")
+	check(c.MaxSeries >= 0, "--max-series: must not be negative (0 = unlimited), got %d", c.MaxSeries)
+	check(c.HALockTTL >= time.Second, "--ha.lock-ttl: must be at least 1s, got %s", c.HALockTTL)
+	check(c.StateSaveInterval > 0, "--state.save-interval: must be positive, got %s", c.StateSaveInterval)
+	check(c.TargetsRefreshInterval > 0, "--targets.refresh-interval: must be positive, got %s", c.TargetsRefreshInterval)
+	for _, list := range []struct {
+		name  string
+		globs []string
+	}{
+		{"KNOWN_PATTERNS", c.KnownPatterns},
+		{"PATTERN_MIN_SUBSCRIBERS", slices.Sorted(maps.Keys(c.PatternMinSubscribers))},
+		{"--channels.include", c.ChannelInclude},
+		{"--channels.exclude", c.ChannelExclude},
+		{"--probe.allowed-targets", c.ProbeAllowedTargets},
+	} {
+		for _, g := range list.globs {
+			check(validGlob(g), "%s: %q has an unterminated [ character class", list.name, g)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// serviceLinkEnv matches the variables Kubernetes sets for every service in
+// the namespace, e.g. REDIS_SERVICE_HOST and REDIS_PORT_6379_TCP_ADDR for a
+// service named redis.
+var serviceLinkEnv = regexp.MustCompile(`_SERVICE_(HOST|PORT)(_[A-Z0-9_]+)?$|_PORT_[0-9]+_(TCP|UDP|SCTP)(_(PROTO|PORT|ADDR))?$`)
+
+// CheckEnv reports environment variables in the exporter's namespace that no
+// setting reads, such as a misspelled REDIS_PASWORD. The namespace is the
+// --env-prefix or, without one, REDIS_. Kubernetes service links are
+// ignored.
+func (c *Config) CheckEnv() error {
+	namespace := cmp.Or(c.envPrefix, "REDIS_")
+	var errs []error
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, namespace) || c.envKnown[name] || serviceLinkEnv.MatchString(name) {
+			continue
+		}
+		if hint := c.closestEnv(name); hint != "" {
+			errs = append(errs, fmt.Errorf("unknown environment variable %s (did you mean %s?)", name, hint))
+		} else {
+			errs = append(errs, fmt.Errorf("unknown environment variable %s", name))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// closestEnv returns the known variable within two edits of name, if any.
+func (c *Config) closestEnv(name string) string {
+	best, bestDist := "", 3
+	for known := range c.envKnown {
+		if d := editDistance(name, known); d < bestDist || (d == bestDist && known < best) {
+			best, bestDist = known, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// validAddress reports whether addr is a host:port listen address with a
+// numeric or well-known port; the host may be empty.
+func validAddress(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil || port == "" {
+		return false
+	}
+	_, err = net.LookupPort("tcp", port)
+	return err == nil
+}
+
+// validHostPort reports whether addr is a host:port to dial, i.e. a valid
+// address with a host; IPv6 literals must be bracketed.
+func validHostPort(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && validHost(host) && validAddress(addr)
+}
+
+// validGlob reports whether every [ in a Redis-style glob is closed.
+func validGlob(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		}
+	}
+	return true
+}
+
+// RedisAddr returns "host:port" for the Redis connection, with IPv6
+// literals in brackets, e.g. "[::1]:6379".
 func (c *Config) RedisAddr() string {
-	return c.RedisHost + ":" + strconv.Itoa(c.RedisPort)
+	return net.JoinHostPort(unbracket(c.RedisHost), strconv.Itoa(c.RedisPort))
+}
+
+// unbracket strips the brackets of an IPv6 literal such as "[::1]".
+func unbracket(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// validHost reports whether host is a host name or IP address without a
+// port. IPv6 literals may be bracketed and carry a zone.
+func validHost(host string) bool {
+	host = unbracket(host)
+	if host == "" || strings.ContainsAny(host, "[]/ ") {
+		return false
+	}
+	if strings.Contains(host, ":") {
+		_, err := netip.ParseAddr(host)
+		return err == nil
+	}
+	return true
+}
+
+// environ reads environment variables whose names start with prefix and
+// collects the values it cannot parse. Unset variables fall back to the
+// profile defaults.
+type environ struct {
+	prefix   string
+	defaults map[string]string // profile defaults by unprefixed name
+	profile  string            // name of the profile defaults came from
+	errs     []error
+	known    map[string]bool
+	origins  map[string]origin
+}
+
+func (e *environ) get(key string) string {
+	if e.known == nil {
+		e.known = make(map[string]bool)
+	}
+	e.known[e.prefix+key] = true
+	if v := os.Getenv(e.prefix + key); v != "" {
+		e.setOrigin(key, SourceEnv, e.prefix+key)
+		return v
+	}
+	if v, ok := e.defaults[key]; ok {
+		e.setOrigin(key, SourceProfile, e.profile)
+		return v
+	}
+	return ""
+}
+
+// setOrigin records where the value of key came from.
+func (e *environ) setOrigin(key, source, from string) {
+	if e.origins == nil {
+		e.origins = make(map[string]origin)
+	}
+	e.origins[e.prefix+key] = origin{source, from}
+}
+
+// secret returns the value of key or, if unset, the contents of the file
+// named by key_FILE without trailing newlines. Setting both is an error.
+func (e *environ) secret(key string) string {
+	v, file := e.get(key), e.get(key+"_FILE")
+	if file == "" {
+		return v
+	}
+	if v != "" {
+		e.errs = append(e.errs, fmt.Errorf("%s%s and %s%s_FILE are mutually exclusive", e.prefix, key, e.prefix, key))
+		return ""
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s%s_FILE: %w", e.prefix, key, err))
+		return ""
+	}
+	e.setOrigin(key, SourceEnv, e.prefix+key+"_FILE")
+	return strings.TrimRight(string(b), "\r\n")
 }
 
-func envString(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
+func (e *environ) string(key, fallback string) string {
+	if v := e.get(key); v != "" {
 		return v
 	}
 	return fallback
 }
 
-func envInt(key string, fallback int) int {
-	v := os.Getenv(key)
+// stringOrEmpty is string, except that a variable set to the empty string
+// yields "" instead of fallback.
+func (e *environ) stringOrEmpty(key, fallback string) string {
+	if v, ok := os.LookupEnv(e.prefix + key); ok && v == "" {
+		e.get(key)
+		e.setOrigin(key, SourceEnv, e.prefix+key)
+		return ""
+	}
+	return e.string(key, fallback)
+}
+
+func (e *environ) int(key string, fallback int) int {
+	v := e.get(key)
 	if v == "" {
 		return fallback
 	}
 	i, err := strconv.Atoi(v)
 	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s%s=%q: not an integer", e.prefix, key, v))
 		return fallback
 	}
 	return i
 }
 
-func envBool(key string, fallback bool) bool {
-	v := os.Getenv(key)
+func (e *environ) bool(key string, fallback bool) bool {
+	v := e.get(key)
 	if v == "" {
 		return fallback
 	}
 	b, err := strconv.ParseBool(v)
 	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s%s=%q: not a boolean (use true or false)", e.prefix, key, v))
 		return fallback
 	}
 	return b
 }
+
+func (e *environ) duration(key string, fallback time.Duration) time.Duration {
+	v := e.get(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s%s=%q: not a duration (e.g. 30s, 5m)", e.prefix, key, v))
+		return fallback
+	}
+	return d
+}
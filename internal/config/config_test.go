@@ -1,6 +1,11 @@
 package config
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -48,6 +53,53 @@ func TestParseHashMetrics(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "db selects another database",
+			input: "redis_key=app:counters,metric=request_count,label=endpoint,db=3",
+			want:  1,
+			checks: func(t *testing.T, defs []HashMetricDef) {
+				t.Helper()
+				if defs[0].DB == nil || *defs[0].DB != 3 {
+					t.Errorf("DB: want 3, got %v", defs[0].DB)
+				}
+			},
+		},
+		{
+			name:  "map translates string values",
+			input: "redis_key=app:health,metric=status,label=service,map=up:1|down:0|degraded:0.5",
+			want:  1,
+			checks: func(t *testing.T, defs []HashMetricDef) {
+				t.Helper()
+				m := defs[0].ValueMap
+				if len(m) != 3 || m["up"] != 1 || m["down"] != 0 || m["degraded"] != 0.5 {
+					t.Errorf("unexpected value map %v", m)
+				}
+			},
+		},
+		{
+			name:  "parse mode",
+			input: "redis_key=app:timeouts,metric=timeout_seconds,label=job,parse=duration",
+			want:  1,
+			checks: func(t *testing.T, defs []HashMetricDef) {
+				t.Helper()
+				assertEqual(t, "ParseMode", defs[0].ParseMode, ParseDuration)
+			},
+		},
+		{
+			name:    "unknown parse mode returns error",
+			input:   "redis_key=app:timeouts,metric=timeout_seconds,label=job,parse=date",
+			wantErr: true,
+		},
+		{
+			name:    "malformed map returns error",
+			input:   "redis_key=app:health,metric=status,label=service,map=up|down:0",
+			wantErr: true,
+		},
+		{
+			name:    "invalid db returns error",
+			input:   "redis_key=app:counters,metric=request_count,label=endpoint,db=two",
+			wantErr: true,
+		},
 		{
 			name:    "missing redis_key returns error",
 			input:   "metric=request_count,help=test,label=endpoint",
@@ -113,9 +165,413 @@ func TestParseHashMetrics(t *testing.T) {
 	}
 }
 
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a,b", []string{"a", "b"}},
+		{" a , ,b ,", []string{"a", "b"}},
+		{",,,", nil},
+	}
+	for _, tt := range tests {
+		got := SplitList(tt.input)
+		if len(got) != len(tt.want) {
+			t.Errorf("SplitList(%q): want %v, got %v", tt.input, tt.want, got)
+			continue
+		}
+		for i := range got {
+			assertEqual(t, "SplitList("+tt.input+")", got[i], tt.want[i])
+		}
+	}
+}
+
+func TestRedisAddr(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"redis", "redis:6380"},
+		{"10.0.0.5", "10.0.0.5:6380"},
+		{"::1", "[::1]:6380"},
+		{"[fd00::1]", "[fd00::1]:6380"},
+	}
+	for _, tt := range tests {
+		c := Config{RedisHost: tt.host, RedisPort: 6380}
+		assertEqual(t, "RedisAddr("+tt.host+")", c.RedisAddr(), tt.want)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := os.WriteFile(file, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "env", env: map[string]string{"REDIS_PASSWORD": "plain"}, want: "plain"},
+		{name: "file", env: map[string]string{"REDIS_PASSWORD_FILE": file}, want: "s3cret"},
+		{name: "missing file", env: map[string]string{"REDIS_PASSWORD_FILE": filepath.Join(dir, "nope")}, wantErr: true},
+		{name: "both set", env: map[string]string{"REDIS_PASSWORD": "plain", "REDIS_PASSWORD_FILE": file}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REDIS_PASSWORD", "")
+			t.Setenv("REDIS_PASSWORD_FILE", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c := Load()
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			assertEqual(t, "RedisPassword", c.RedisPassword, tt.want)
+		})
+	}
+}
+
+func TestLoadPrefix(t *testing.T) {
+	t.Setenv("REDIS_HOST", "shared.example.com")
+	t.Setenv("RPE_REDIS_HOST", "pubsub.example.com")
+	t.Setenv("RPE_REDIS_PORT", "6380")
+
+	c := LoadPrefix("RPE_")
+	assertEqual(t, "RedisHost", c.RedisHost, "pubsub.example.com")
+	if c.RedisPort != 6380 {
+		t.Errorf("RedisPort: want 6380, got %d", c.RedisPort)
+	}
+
+	c = Load()
+	assertEqual(t, "RedisHost", c.RedisHost, "shared.example.com")
+}
+
+func TestLoadClientName(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "unset", want: DefaultRedisClientName},
+		{name: "set", env: map[string]string{"REDIS_CLIENT_NAME": "pubsub-a"}, want: "pubsub-a"},
+		{name: "empty leaves connections unnamed", env: map[string]string{"REDIS_CLIENT_NAME": ""}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			assertEqual(t, "RedisClientName", Load().RedisClientName, tt.want)
+		})
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(pwFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RPE_REDIS_HOST", "pubsub.example.com")
+	t.Setenv("RPE_EXPORTER_PORT", "9999")
+	t.Setenv("RPE_REDIS_PASSWORD_FILE", pwFile)
+
+	c := LoadProfile("RPE_", ProfileDeep)
+	tests := []struct {
+		name       string
+		wantSource string
+		wantFrom   string
+	}{
+		{name: "RPE_REDIS_HOST", wantSource: SourceEnv, wantFrom: "RPE_REDIS_HOST"},
+		{name: "RPE_EXPORTER_LISTEN_ADDRESS", wantSource: SourceEnv, wantFrom: "RPE_EXPORTER_PORT"},
+		{name: "RPE_REDIS_PASSWORD", wantSource: SourceEnv, wantFrom: "RPE_REDIS_PASSWORD_FILE"},
+		{name: "RPE_MAX_CHANNELS", wantSource: SourceProfile, wantFrom: ProfileDeep},
+		{name: "RPE_REDIS_PORT", wantSource: SourceDefault},
+	}
+	for _, tt := range tests {
+		source, from := c.Origin(tt.name)
+		assertEqual(t, tt.name+" source", source, tt.wantSource)
+		assertEqual(t, tt.name+" from", from, tt.wantFrom)
+	}
+}
+
+func TestDeprecations(t *testing.T) {
+	if d := Load().Deprecations(); len(d) != 0 {
+		t.Errorf("want no deprecations, got %+v", d)
+	}
+
+	t.Setenv("EXPORTER_PORT", "9999")
+	c := Load()
+	assertEqual(t, "ListenAddress", c.ListenAddress, ":9999")
+	d := c.Deprecations()
+	if len(d) != 1 {
+		t.Fatalf("want 1 deprecation, got %+v", d)
+	}
+	assertEqual(t, "Option", d[0].Option, "EXPORTER_PORT")
+	assertEqual(t, "Replacement", d[0].Replacement, "EXPORTER_LISTEN_ADDRESS (--web.listen-address)")
+}
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		profile         string
+		wantProfile     string
+		wantMaxChannels int
+		wantDisable     []string
+		wantErr         string
+	}{
+		{name: "default", wantProfile: "standard", wantMaxChannels: DefaultMaxChannels},
+		{
+			name:            "from env",
+			env:             map[string]string{"PROFILE": "minimal"},
+			wantProfile:     "minimal",
+			wantMaxChannels: 100,
+			wantDisable:     []string{"clients", "patterns"},
+		},
+		{
+			name:            "flag wins over env",
+			env:             map[string]string{"PROFILE": "minimal"},
+			profile:         "deep",
+			wantProfile:     "deep",
+			wantMaxChannels: 5000,
+		},
+		{
+			name:            "explicit setting wins over profile",
+			env:             map[string]string{"PROFILE": "minimal", "MAX_CHANNELS": "250"},
+			wantProfile:     "minimal",
+			wantMaxChannels: 250,
+			wantDisable:     []string{"clients", "patterns"},
+		},
+		{
+			name:            "unknown profile",
+			profile:         "tiny",
+			wantProfile:     "standard",
+			wantMaxChannels: DefaultMaxChannels,
+			wantErr:         `unknown profile "tiny"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c := LoadProfile("", tt.profile)
+			assertEqual(t, "Profile", c.Profile, tt.wantProfile)
+			if c.MaxChannels != tt.wantMaxChannels {
+				t.Errorf("MaxChannels: want %d, got %d", tt.wantMaxChannels, c.MaxChannels)
+			}
+			if !slices.Equal(c.ScrapeDisable, tt.wantDisable) {
+				t.Errorf("ScrapeDisable: want %v, got %v", tt.wantDisable, c.ScrapeDisable)
+			}
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "bad env int", env: map[string]string{"MAX_CLIENTS": "ten"}, wantErr: `MAX_CLIENTS="ten": not an integer`},
+		{name: "bad env bool", env: map[string]string{"REDIS_TLS": "yes please"}, wantErr: "REDIS_TLS"},
+		{name: "bad env duration", env: map[string]string{"SCRAPE_TIMEOUT": "10"}, wantErr: "SCRAPE_TIMEOUT"},
+		{name: "bad hash metrics", env: map[string]string{"HASH_METRICS": "metric=x"}, wantErr: "HASH_METRICS"},
+		{name: "listen address without port", modify: func(c *Config) { c.ListenAddress = "0.0.0.0" }, wantErr: "--web.listen-address"},
+		{name: "grpc address", modify: func(c *Config) { c.GRPCHealthAddress = ":99999" }, wantErr: "--web.grpc-health-address"},
+		{name: "redis port", modify: func(c *Config) { c.RedisPort = 70000 }, wantErr: "--redis.port"},
+		{name: "redis host ipv6", env: map[string]string{"REDIS_HOST": "::1"}},
+		{name: "redis host bracketed ipv6", env: map[string]string{"REDIS_HOST": "[fd00::1]"}},
+		{name: "redis host with port", env: map[string]string{"REDIS_HOST": "redis:6380"}, wantErr: "--redis.host"},
+		{name: "cluster ipv6 without brackets", env: map[string]string{"REDIS_CLUSTER_ADDRS": "[fd00::1]:7000,fd00::2:7000"}, wantErr: "--redis.cluster-addrs"},
+		{name: "client name with space", env: map[string]string{"REDIS_CLIENT_NAME": "pubsub exporter"}, wantErr: "--redis.client-name"},
+		{name: "redis protocol", env: map[string]string{"REDIS_PROTOCOL": "4"}, wantErr: "--redis.protocol"},
+		{name: "tls cert without key", env: map[string]string{"REDIS_TLS": "true", "REDIS_TLS_CERT_FILE": "tls.crt"}, wantErr: "--redis.tls-cert-file"},
+		{name: "tls ca without tls", env: map[string]string{"REDIS_TLS_CA_FILE": "ca.crt"}, wantErr: "--redis.tls"},
+		{name: "negative db", modify: func(c *Config) { c.RedisDB = -1 }, wantErr: "--redis.db"},
+		{name: "negative keepalive", env: map[string]string{"REDIS_TCP_KEEPALIVE": "-1s"}, wantErr: "--redis.tcp-keepalive"},
+		{name: "keepalive off", env: map[string]string{"REDIS_TCP_KEEPALIVE": "0"}},
+		{name: "zero max channels", modify: func(c *Config) { c.MaxChannels = 0 }, wantErr: "--max-channels"},
+		{name: "bad client name regex", env: map[string]string{"CLIENT_EXCLUDE_NAME": "redis-cli("}, wantErr: "--clients.exclude-name"},
+		{name: "info-only without server", env: map[string]string{"MODE": "info-only", "SCRAPE_DISABLE": "server"}, wantErr: "--mode"},
+		{name: "probe modules without probe", env: map[string]string{"PROBE_MODULES_FILE": "modules.yml"}, wantErr: "--probe.modules-file"},
+		{name: "unterminated glob", modify: func(c *Config) { c.ChannelExclude = []string{"debug.[ab"} }, wantErr: "--channels.exclude"},
+		{name: "escaped bracket", modify: func(c *Config) { c.ChannelInclude = []string{`orders.\[`} }},
+		{name: "zero lock ttl", env: map[string]string{"HA_LOCK_TTL": "0s"}, wantErr: "--ha.lock-ttl"},
+		{name: "zero state save interval", env: map[string]string{"STATE_SAVE_INTERVAL": "0s"}, wantErr: "--state.save-interval"},
+		{name: "zero scrape timeout", env: map[string]string{"SCRAPE_TIMEOUT": "0s"}, wantErr: "--scrape.timeout"},
+		{name: "scrape timeout past write timeout", env: map[string]string{"SCRAPE_TIMEOUT": "30s"}, wantErr: "--scrape.timeout"},
+		{name: "monitor duration past interval", env: map[string]string{"SAMPLER_ENABLED": "true", "SAMPLER_MODE": "monitor", "SAMPLER_MONITOR_DURATION": "2m"}, wantErr: "--sampler.monitor-duration"},
+		{name: "zero targets refresh interval", env: map[string]string{"TARGETS_FILE": "targets.yml", "TARGETS_REFRESH_INTERVAL": "0s"}, wantErr: "--targets.refresh-interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c := Load()
+			if tt.modify != nil {
+				tt.modify(c)
+			}
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "known", env: map[string]string{"REDIS_PASSWORD": "x", "REDIS_PASSWORD_FILE": ""}},
+		{name: "typo", env: map[string]string{"REDIS_PASWORD": "x"}, wantErr: "REDIS_PASWORD (did you mean REDIS_PASSWORD?)"},
+		{name: "unrelated", env: map[string]string{"REDIS_SOMETHING_ELSE_ENTIRELY": "x"}, wantErr: "unknown environment variable REDIS_SOMETHING_ELSE_ENTIRELY"},
+		{name: "outside namespace", env: map[string]string{"MAX_CHANELS": "x"}},
+		{name: "kubernetes service links", env: map[string]string{
+			"REDIS_SERVICE_HOST":            "10.0.0.1",
+			"REDIS_SERVICE_PORT":            "6379",
+			"REDIS_SERVICE_PORT_REDIS":      "6379",
+			"REDIS_PORT":                    "tcp://10.0.0.1:6379",
+			"REDIS_PORT_6379_TCP":           "tcp://10.0.0.1:6379",
+			"REDIS_PORT_6379_TCP_PROTO":     "tcp",
+			"REDIS_PORT_6379_TCP_PORT":      "6379",
+			"REDIS_PORT_6379_TCP_ADDR":      "10.0.0.1",
+			"REDIS_SENTINEL_SERVICE_HOST":   "10.0.0.2",
+			"REDIS_SENTINEL_PORT_26379_TCP": "tcp://10.0.0.2:26379",
+		}},
+		{name: "prefixed typo", prefix: "RPE_", env: map[string]string{"RPE_MAX_CHANELS": "x"}, wantErr: "did you mean RPE_MAX_CHANNELS?"},
+		{name: "unprefixed with prefix", prefix: "RPE_", env: map[string]string{"REDIS_PASWORD": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			err := LoadPrefix(tt.prefix).CheckEnv()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParsePatternMinimums(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "orders.*>=2", want: map[string]int{"orders.*": 2}},
+		{input: " orders.* >= 2 , users.*>=0 ", want: map[string]int{"orders.*": 2, "users.*": 0}},
+		{input: "orders.*", wantErr: true},
+		{input: ">=2", wantErr: true},
+		{input: "orders.*>=two", wantErr: true},
+		{input: "orders.*>=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePatternMinimums(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseHealthWeights(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{input: "up=1", want: map[string]float64{"up": 1, "orphans": 0, "shortfall": 0, "latency": 0}},
+		{input: " up = 0.5 , latency=0.5 ", want: map[string]float64{"up": 0.5, "orphans": 0, "shortfall": 0, "latency": 0.5}},
+		{input: "", wantErr: true},
+		{input: "up=0,latency=0", wantErr: true},
+		{input: "memory=1", wantErr: true},
+		{input: "up", wantErr: true},
+		{input: "up=-1", wantErr: true},
+		{input: "up=high", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHealthWeights(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func assertEqual(t *testing.T, field, got, want string) {
 	t.Helper()
 	if got != want {
 		t.Errorf("%s: want %q, got %q", field, want, got)
 	}
 }
+
+func FuzzParseHashMetrics(f *testing.F) {
+	f.Add("redis_key=myapp:stats,metric=active_count,help=Active items,label=item")
+	f.Add("redis_key=a,metric=b,label=c;redis_key=d,metric=e,label=f;;;")
+	f.Add("  redis_key = a , metric = b , label = c  ")
+	f.Add("redis_key=a\n,metric=b\x00,label=\xff")
+	f.Add("=,=;=;,,,;redis_key==,metric==,label==")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		defs, err := ParseHashMetrics(raw)
+		if err != nil {
+			return
+		}
+		for _, d := range defs {
+			if d.RedisKey == "" || d.MetricName == "" || d.FieldLabel == "" {
+				t.Errorf("definition missing required field: %+v", d)
+			}
+			if d.Help == "" {
+				t.Errorf("definition without help: %+v", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,248 @@
+// Package dial builds the network dialer used for Redis connections.
+package dial
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Func dials a network connection. It matches the signature of
+// redis.Options.Dialer.
+type Func func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Resolver is the subset of *net.Resolver used for lookups.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Direct returns a Func that dials TCP directly with the given timeout,
+// sending TCP keepalive probes every keepAlive (0 disables them).
+func Direct(timeout, keepAlive time.Duration) Func {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: keepAlivePeriod(keepAlive)}
+	return d.DialContext
+}
+
+// keepAlivePeriod maps a keepalive setting where 0 disables probes to the
+// net.Dialer convention, where 0 means the default and negative disables.
+func keepAlivePeriod(d time.Duration) time.Duration {
+	if d <= 0 {
+		return -1
+	}
+	return d
+}
+
+// Dialer resolves the Redis target on every dial instead of relying on the
+// address the pool was created with, so DNS-based failover is followed as
+// soon as connections are re-established. When SRV is set, the dialed
+// address is ignored and picked from the SRV records instead.
+//
+// Because go-redis skips its own TLS handling when a custom dialer is set,
+// the TLS handshake happens here, using the hostname (not the resolved IP)
+// for certificate verification.
+type Dialer struct {
+	Base      Func        // underlying dialer; defaults to Direct(5s, 5m)
+	Resolver  Resolver    // defaults to net.DefaultResolver
+	SRV       string      // optional SRV name, e.g. _redis._tcp.redis.example.com
+	TLSConfig *tls.Config // optional; enables TLS on dialed connections
+	Logger    *slog.Logger
+
+	// TLSConfigFunc, if set, is called on every dial and takes precedence over
+	// TLSConfig, so rotated certificates apply to new connections.
+	TLSConfigFunc func() *tls.Config
+
+	// Tracker, if set, records every connection dialed, so existing ones can
+	// be closed when the TLS material changes.
+	Tracker *Tracker
+
+	mu       sync.Mutex
+	resolved map[string]string // host -> last resolved addresses, for change logging
+}
+
+// DialContext implements Func.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	targets, err := d.targets(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, t := range targets {
+		ips, err := d.resolveHost(ctx, t.host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, ip := range ips {
+			conn, err := d.base()(ctx, network, net.JoinHostPort(ip, t.port))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if tlsConfig := d.tlsConfig(); tlsConfig != nil {
+				if conn, err = handshake(ctx, conn, tlsConfig, t.host); err != nil {
+					return nil, err
+				}
+			}
+			if d.Tracker != nil {
+				conn = d.Tracker.track(conn)
+			}
+			return conn, nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("dial %s: no addresses", addr)
+	}
+	return nil, errors.Join(errs...)
+}
+
+type target struct {
+	host string
+	port string
+}
+
+// targets returns the host:port candidates to try, in order.
+func (d *Dialer) targets(ctx context.Context, addr string) ([]target, error) {
+	if d.SRV == "" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		return []target{{host: host, port: port}}, nil
+	}
+
+	_, records, err := d.resolver().LookupSRV(ctx, "", "", d.SRV)
+	if err != nil {
+		return nil, fmt.Errorf("lookup SRV %s: %w", d.SRV, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("lookup SRV %s: no records", d.SRV)
+	}
+
+	out := make([]target, 0, len(records))
+	for _, r := range OrderSRV(records) {
+		out = append(out, target{
+			host: strings.TrimSuffix(r.Target, "."),
+			port: strconv.Itoa(int(r.Port)),
+		})
+	}
+	d.logChange("srv:"+d.SRV, out[0].host+":"+out[0].port)
+	return out, nil
+}
+
+// resolveHost looks up host, logging when its addresses change. IP literals
+// are returned as-is.
+func (d *Dialer) resolveHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	ips, err := d.resolver().LookupHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", host, err)
+	}
+	sorted := slices.Clone(ips)
+	sort.Strings(sorted)
+	d.logChange(host, strings.Join(sorted, ","))
+	return ips, nil
+}
+
+func (d *Dialer) logChange(key, value string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.resolved == nil {
+		d.resolved = make(map[string]string)
+	}
+	prev, seen := d.resolved[key]
+	d.resolved[key] = value
+	if seen && prev != value && d.Logger != nil {
+		d.Logger.Info("redis target re-resolved", "name", key, "previous", prev, "current", value)
+	}
+}
+
+func (d *Dialer) tlsConfig() *tls.Config {
+	if d.TLSConfigFunc != nil {
+		return d.TLSConfigFunc()
+	}
+	return d.TLSConfig
+}
+
+func handshake(ctx context.Context, conn net.Conn, tlsConfig *tls.Config, host string) (net.Conn, error) {
+	cfg := tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (d *Dialer) base() Func {
+	if d.Base != nil {
+		return d.Base
+	}
+	return Direct(5*time.Second, 5*time.Minute)
+}
+
+func (d *Dialer) resolver() Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// OrderSRV orders SRV records by ascending priority and, within a priority,
+// by weighted random selection as described in RFC 2782.
+func OrderSRV(records []*net.SRV) []*net.SRV {
+	byPriority := make(map[uint16][]*net.SRV)
+	var priorities []uint16
+	for _, r := range records {
+		if _, ok := byPriority[r.Priority]; !ok {
+			priorities = append(priorities, r.Priority)
+		}
+		byPriority[r.Priority] = append(byPriority[r.Priority], r)
+	}
+	slices.Sort(priorities)
+
+	out := make([]*net.SRV, 0, len(records))
+	for _, p := range priorities {
+		group := slices.Clone(byPriority[p])
+		for len(group) > 0 {
+			i := pickWeighted(group)
+			out = append(out, group[i])
+			group = slices.Delete(group, i, i+1)
+		}
+	}
+	return out
+}
+
+func pickWeighted(group []*net.SRV) int {
+	total := 0
+	for _, r := range group {
+		total += int(r.Weight)
+	}
+	if total == 0 {
+		return 0
+	}
+	n := rand.IntN(total)
+	for i, r := range group {
+		n -= int(r.Weight)
+		if n < 0 {
+			return i
+		}
+	}
+	return len(group) - 1
+}
@@ -0,0 +1,181 @@
+package dial
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+type fakeResolver struct {
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if ips, ok := r.hosts[host]; ok {
+		return ips, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	if recs, ok := r.srv[name]; ok {
+		return name, recs, nil
+	}
+	return "", nil, errors.New("no such host")
+}
+
+// recordingBase fails for addresses in refuse and records every attempt.
+func recordingBase(attempts *[]string, refuse map[string]bool) Func {
+	return func(_ context.Context, _, addr string) (net.Conn, error) {
+		*attempts = append(*attempts, addr)
+		if refuse[addr] {
+			return nil, errors.New("connection refused")
+		}
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+}
+
+func TestDialerResolvesOnEveryDial(t *testing.T) {
+	res := &fakeResolver{hosts: map[string][]string{"redis.internal": {"10.0.0.1"}}}
+	var attempts []string
+	d := &Dialer{Base: recordingBase(&attempts, nil), Resolver: res}
+
+	conn, err := d.DialContext(context.Background(), "tcp", "redis.internal:6379")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = conn.Close()
+
+	// DNS failover: the name now points elsewhere.
+	res.hosts["redis.internal"] = []string{"10.0.0.2"}
+	conn, err = d.DialContext(context.Background(), "tcp", "redis.internal:6379")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = conn.Close()
+
+	want := []string{"10.0.0.1:6379", "10.0.0.2:6379"}
+	if len(attempts) != len(want) || attempts[0] != want[0] || attempts[1] != want[1] {
+		t.Errorf("want attempts %v, got %v", want, attempts)
+	}
+}
+
+func TestDialerTriesAllAddresses(t *testing.T) {
+	res := &fakeResolver{hosts: map[string][]string{"redis.internal": {"10.0.0.1", "10.0.0.2"}}}
+	var attempts []string
+	d := &Dialer{Base: recordingBase(&attempts, map[string]bool{"10.0.0.1:6379": true}), Resolver: res}
+
+	conn, err := d.DialContext(context.Background(), "tcp", "redis.internal:6379")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = conn.Close()
+
+	if len(attempts) != 2 || attempts[1] != "10.0.0.2:6379" {
+		t.Errorf("expected fallback to second address, got %v", attempts)
+	}
+}
+
+func TestDialerSRV(t *testing.T) {
+	res := &fakeResolver{
+		hosts: map[string][]string{
+			"redis-a.internal": {"10.0.0.1"},
+			"redis-b.internal": {"10.0.0.2"},
+		},
+		srv: map[string][]*net.SRV{
+			"_redis._tcp.internal": {
+				{Target: "redis-b.internal.", Port: 6380, Priority: 20, Weight: 1},
+				{Target: "redis-a.internal.", Port: 6379, Priority: 10, Weight: 1},
+			},
+		},
+	}
+	var attempts []string
+	d := &Dialer{
+		Base:     recordingBase(&attempts, map[string]bool{"10.0.0.1:6379": true}),
+		Resolver: res,
+		SRV:      "_redis._tcp.internal",
+	}
+
+	conn, err := d.DialContext(context.Background(), "tcp", "ignored:6379")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = conn.Close()
+
+	want := []string{"10.0.0.1:6379", "10.0.0.2:6380"}
+	if len(attempts) != 2 || attempts[0] != want[0] || attempts[1] != want[1] {
+		t.Errorf("want attempts %v, got %v", want, attempts)
+	}
+}
+
+func TestDialerErrors(t *testing.T) {
+	var attempts []string
+	d := &Dialer{Base: recordingBase(&attempts, nil), Resolver: &fakeResolver{}}
+
+	if _, err := d.DialContext(context.Background(), "tcp", "missing.internal:6379"); err == nil {
+		t.Error("expected error for unresolvable host")
+	}
+	d.SRV = "_redis._tcp.missing"
+	if _, err := d.DialContext(context.Background(), "tcp", "x:1"); err == nil {
+		t.Error("expected error for missing SRV record")
+	}
+	if len(attempts) != 0 {
+		t.Errorf("no dial should be attempted, got %v", attempts)
+	}
+}
+
+func TestOrderSRV(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "c", Priority: 30, Weight: 0},
+		{Target: "a1", Priority: 10, Weight: 50},
+		{Target: "b", Priority: 20, Weight: 0},
+		{Target: "a2", Priority: 10, Weight: 50},
+	}
+
+	for i := 0; i < 20; i++ {
+		got := OrderSRV(records)
+		if len(got) != 4 {
+			t.Fatalf("want 4 records, got %d", len(got))
+		}
+		if got[0].Priority != 10 || got[1].Priority != 10 {
+			t.Fatalf("priority 10 records must come first, got %s,%s", got[0].Target, got[1].Target)
+		}
+		if got[2].Target != "b" || got[3].Target != "c" {
+			t.Fatalf("want b,c last, got %s,%s", got[2].Target, got[3].Target)
+		}
+	}
+}
+
+func TestTrackerCloseAll(t *testing.T) {
+	var attempts []string
+	tracker := NewTracker()
+	d := &Dialer{Base: recordingBase(&attempts, nil), Tracker: tracker}
+
+	var conns []net.Conn
+	for range 3 {
+		conn, err := d.DialContext(context.Background(), "tcp", "10.0.0.1:6379")
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	// A connection closed by its owner is no longer tracked.
+	_ = conns[0].Close()
+
+	if got := tracker.CloseAll(); got != 2 {
+		t.Errorf("want 2 connections closed, got %d", got)
+	}
+	for i, conn := range conns {
+		if _, err := conn.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("conn %d: want closed, got %v", i, err)
+		}
+	}
+	if got := tracker.CloseAll(); got != 0 {
+		t.Errorf("want nothing left to close, got %d", got)
+	}
+}
@@ -0,0 +1,112 @@
+package dial
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Proxy returns a Func that dials through the proxy at rawURL. Supported
+// schemes are socks5 (and socks5h) and http (HTTP CONNECT); credentials may
+// be given as URL user info. Host names are sent to the proxy unresolved, so
+// pair it with PassthroughResolver. keepAlive is the TCP keepalive period of
+// connections to the proxy; 0 disables keepalives.
+func Proxy(rawURL string, timeout, keepAlive time.Duration) (Func, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q: missing host", rawURL)
+	}
+	forward := &net.Dialer{Timeout: timeout, KeepAlive: keepAlivePeriod(keepAlive)}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+		}
+		d, err := proxy.SOCKS5("tcp", u.Host, auth, forward)
+		if err != nil {
+			return nil, err
+		}
+		return d.(proxy.ContextDialer).DialContext, nil
+
+	case "http":
+		return httpConnect(u, Func(forward.DialContext)), nil
+
+	default:
+		return nil, fmt.Errorf("proxy url %q: unsupported scheme %q (want socks5 or http)", rawURL, u.Scheme)
+	}
+}
+
+// httpConnect returns a Func that opens a tunnel with an HTTP CONNECT request.
+func httpConnect(u *url.URL, forward Func) Func {
+	var authHeader string
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		authHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+pass))
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := forward(ctx, network, u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("dial proxy %s: %w", u.Host, err)
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if authHeader != "" {
+			req.Header.Set("Proxy-Authorization", authHeader)
+		}
+		if err := req.Write(conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+		}
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+		}
+
+		_ = conn.SetDeadline(time.Time{})
+		if br.Buffered() > 0 {
+			// Don't lose tunnelled bytes read along with the response.
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+		return conn, nil
+	}
+}
+
+// bufferedConn reads through r first, which may hold data already read from Conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
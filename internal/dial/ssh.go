@@ -0,0 +1,215 @@
+package dial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig configures an SSH tunnel through a bastion host.
+type SSHConfig struct {
+	Addr                  string // bastion host:port
+	User                  string
+	KeyFile               string // optional private key (PEM/OpenSSH)
+	KeyPassphrase         string // optional passphrase for KeyFile
+	UseAgent              bool   // authenticate via the agent at $SSH_AUTH_SOCK
+	KnownHostsFile        string // known_hosts used to verify the bastion
+	InsecureIgnoreHostKey bool   // skip host key verification
+	Timeout               time.Duration
+	KeepAlive             time.Duration // TCP keepalive period to the bastion; 0 disables
+	Dial                  Func          // dials the bastion; defaults to Direct(Timeout, KeepAlive)
+}
+
+// SSHTunnel dials through an SSH connection to a bastion host. The SSH
+// connection is established lazily and re-established after it drops.
+// Hostnames are resolved by the bastion, so pair it with PassthroughResolver.
+type SSHTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+	base   Func
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewSSHTunnel validates cfg and returns a tunnel. No connection is made
+// until the first dial.
+func NewSSHTunnel(cfg SSHConfig) (*SSHTunnel, error) {
+	if cfg.Addr == "" || cfg.User == "" {
+		return nil, errors.New("ssh tunnel requires an address and a user")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		cfg.Addr = net.JoinHostPort(cfg.Addr, "22")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		signer, err := loadSigner(cfg.KeyFile, cfg.KeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.UseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, errors.New("ssh agent requested but SSH_AUTH_SOCK is not set")
+		}
+		auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, fmt.Errorf("connect to ssh agent: %w", err)
+			}
+			defer func() { _ = conn.Close() }()
+			return agent.NewClient(conn).Signers()
+		}))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("ssh tunnel requires a key file or agent authentication")
+	}
+
+	var hostKey ssh.HostKeyCallback
+	switch {
+	case cfg.InsecureIgnoreHostKey:
+		hostKey = ssh.InsecureIgnoreHostKey()
+	case cfg.KnownHostsFile != "":
+		cb, err := knownhosts.New(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("load known_hosts: %w", err)
+		}
+		hostKey = cb
+	default:
+		return nil, errors.New("ssh tunnel requires a known_hosts file (or explicitly ignoring host keys)")
+	}
+
+	base := cfg.Dial
+	if base == nil {
+		base = Direct(cfg.Timeout, cfg.KeepAlive)
+	}
+	return &SSHTunnel{
+		addr: cfg.Addr,
+		config: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            auth,
+			HostKeyCallback: hostKey,
+			Timeout:         cfg.Timeout,
+		},
+		base: base,
+	}, nil
+}
+
+// DialContext implements Func by opening a channel through the tunnel. A dial
+// failure on a dead SSH connection reconnects once; a channel the bastion
+// rejects, e.g. because Redis is down, leaves the connection and every other
+// channel on it alone.
+func (t *SSHTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil {
+		return conn, nil
+	}
+	var rejected *ssh.OpenChannelError
+	if errors.As(err, &rejected) || ctx.Err() != nil {
+		return nil, err
+	}
+
+	// The SSH connection may have dropped; retry once on a fresh one.
+	t.reset(client)
+	if client, err = t.connect(ctx); err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+// Close closes the SSH connection.
+func (t *SSHTunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == nil {
+		return nil
+	}
+	err := t.client.Close()
+	t.client = nil
+	return err
+}
+
+func (t *SSHTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	conn, err := t.base(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh dial %s: %w", t.addr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("ssh handshake %s: %w", t.addr, err)
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	go func() {
+		_ = client.Wait()
+		t.reset(client)
+	}()
+	t.client = client
+	return client, nil
+}
+
+// reset drops client if it is still the current connection.
+func (t *SSHTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == client {
+		_ = client.Close()
+		t.client = nil
+	}
+}
+
+func loadSigner(path, passphrase string) (ssh.Signer, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ssh key: %w", err)
+	}
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse ssh key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// PassthroughResolver leaves host name resolution to the Base dialer, for
+// transports such as SSH tunnels that resolve names on the remote side. SRV
+// lookups still use the local resolver.
+type PassthroughResolver struct{}
+
+// LookupHost returns host unchanged.
+func (PassthroughResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	return []string{host}, nil
+}
+
+// LookupSRV delegates to net.DefaultResolver.
+func (PassthroughResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+}
@@ -0,0 +1,269 @@
+package dial
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewSSHTunnelValidation(t *testing.T) {
+	keyFile := writeKey(t, newKey(t))
+
+	tests := []struct {
+		name    string
+		cfg     SSHConfig
+		wantErr bool
+	}{
+		{
+			name: "key auth",
+			cfg:  SSHConfig{Addr: "bastion", User: "ops", KeyFile: keyFile, InsecureIgnoreHostKey: true},
+		},
+		{
+			name:    "missing user",
+			cfg:     SSHConfig{Addr: "bastion", KeyFile: keyFile, InsecureIgnoreHostKey: true},
+			wantErr: true,
+		},
+		{
+			name:    "no auth method",
+			cfg:     SSHConfig{Addr: "bastion", User: "ops", InsecureIgnoreHostKey: true},
+			wantErr: true,
+		},
+		{
+			name:    "no host key verification",
+			cfg:     SSHConfig{Addr: "bastion", User: "ops", KeyFile: keyFile},
+			wantErr: true,
+		},
+		{
+			name:    "missing key file",
+			cfg:     SSHConfig{Addr: "bastion", User: "ops", KeyFile: "/nonexistent", InsecureIgnoreHostKey: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSSHTunnel(tt.cfg)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSSHTunnelDial(t *testing.T) {
+	// Backend the tunnel forwards to.
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = backend.Close() }()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.WriteString(conn, "+PONG\r\n")
+			_ = conn.Close()
+		}
+	}()
+
+	clientKey := newKey(t)
+	bastion := startSSHServer(t, newSigner(t, clientKey).PublicKey())
+
+	tunnel, err := NewSSHTunnel(SSHConfig{
+		Addr:                  bastion,
+		User:                  "ops",
+		KeyFile:               writeKey(t, clientKey),
+		InsecureIgnoreHostKey: true,
+	})
+	if err != nil {
+		t.Fatalf("new tunnel: %v", err)
+	}
+	defer func() { _ = tunnel.Close() }()
+
+	d := &Dialer{Base: tunnel.DialContext, Resolver: PassthroughResolver{}}
+	for i := 0; i < 2; i++ {
+		conn, err := d.DialContext(context.Background(), "tcp", backend.Addr().String())
+		if err != nil {
+			t.Fatalf("dial %d: %v", i, err)
+		}
+		got, _ := io.ReadAll(conn)
+		_ = conn.Close()
+		if string(got) != "+PONG\r\n" {
+			t.Errorf("dial %d: want PONG through tunnel, got %q", i, got)
+		}
+	}
+}
+
+func TestSSHTunnelRejectedChannelKeepsConnection(t *testing.T) {
+	// Echo backend holding its connections open.
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = backend.Close() }()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(conn, conn) }()
+		}
+	}()
+
+	// A port nothing listens on, so the bastion rejects the channel.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := closed.Addr().String()
+	_ = closed.Close()
+
+	clientKey := newKey(t)
+	tunnel, err := NewSSHTunnel(SSHConfig{
+		Addr:                  startSSHServer(t, newSigner(t, clientKey).PublicKey()),
+		User:                  "ops",
+		KeyFile:               writeKey(t, clientKey),
+		InsecureIgnoreHostKey: true,
+	})
+	if err != nil {
+		t.Fatalf("new tunnel: %v", err)
+	}
+	defer func() { _ = tunnel.Close() }()
+
+	conn, err := tunnel.DialContext(context.Background(), "tcp", backend.Addr().String())
+	if err != nil {
+		t.Fatalf("dial backend: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = tunnel.DialContext(context.Background(), "tcp", refused)
+	var rejected *ssh.OpenChannelError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("want channel rejection, got %v", err)
+	}
+
+	// The established channel still works.
+	if _, err := io.WriteString(conn, "PING\r\n"); err != nil {
+		t.Fatalf("write after rejection: %v", err)
+	}
+	got := make([]byte, 6)
+	if _, err := io.ReadFull(conn, got); err != nil || string(got) != "PING\r\n" {
+		t.Fatalf("want echo after rejection, got %q, %v", got, err)
+	}
+}
+
+// startSSHServer runs a minimal SSH server that accepts the authorized key and
+// forwards direct-tcpip channels.
+func startSSHServer(t *testing.T, authorized ssh.PublicKey) string {
+	t.Helper()
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(newSigner(t, newKey(t)))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, cfg)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveSSH(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		var req struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if nc.ChannelType() != "direct-tcpip" || ssh.Unmarshal(nc.ExtraData(), &req) != nil {
+			_ = nc.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(req.Host, strconv.FormatUint(uint64(req.Port), 10)))
+		if err != nil {
+			_ = nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(creqs)
+		go func() { _, _ = io.Copy(target, ch) }()
+		go func() {
+			_, _ = io.Copy(ch, target)
+			_ = ch.Close()
+			_ = target.Close()
+		}()
+	}
+}
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func newSigner(t *testing.T, key ed25519.PrivateKey) ssh.Signer {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func writeKey(t *testing.T, key ed25519.PrivateKey) string {
+	t.Helper()
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,63 @@
+package dial
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// Tracker keeps the open connections of a Dialer so they can be closed at
+// once, e.g. to make a pool reconnect with rotated TLS material. go-redis
+// drops closed idle connections when it next takes them from the pool and
+// retries a command that was using one.
+type Tracker struct {
+	mu    sync.Mutex
+	conns map[*trackedConn]struct{}
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{conns: make(map[*trackedConn]struct{})}
+}
+
+// CloseAll closes every open connection and returns how many there were.
+func (t *Tracker) CloseAll() int {
+	t.mu.Lock()
+	conns := t.conns
+	t.conns = make(map[*trackedConn]struct{})
+	t.mu.Unlock()
+	for c := range conns {
+		_ = c.Conn.Close()
+	}
+	return len(conns)
+}
+
+func (t *Tracker) track(conn net.Conn) net.Conn {
+	c := &trackedConn{Conn: conn, t: t}
+	t.mu.Lock()
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+	return c
+}
+
+// trackedConn removes itself from its Tracker when closed.
+type trackedConn struct {
+	net.Conn
+	t *Tracker
+}
+
+// ConnectionState returns the TLS state of the wrapped connection, which is
+// empty unless it is a *tls.Conn.
+func (c *trackedConn) ConnectionState() tls.ConnectionState {
+	if tlsConn, ok := c.Conn.(*tls.Conn); ok {
+		return tlsConn.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+func (c *trackedConn) Close() error {
+	c.t.mu.Lock()
+	delete(c.t.conns, c)
+	c.t.mu.Unlock()
+	return c.Conn.Close()
+}
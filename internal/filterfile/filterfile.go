@@ -0,0 +1,111 @@
+// Package filterfile loads channel include/exclude rules from a file and
+// re-applies them whenever the file changes, so a ConfigMap update changes
+// filtering without a restart.
+package filterfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+// Rules is the content of a filter file. Both lists hold Redis-style globs:
+//
+//	# channel-filters.yml
+//	include: ["orders.*", "users.*"]
+//	exclude: ["*.debug"]
+type Rules struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Parse parses a YAML (or JSON) filter file.
+func Parse(raw []byte) (Rules, error) {
+	var r Rules
+	if err := yaml.UnmarshalStrict(raw, &r); err != nil {
+		return Rules{}, fmt.Errorf("parse filter file: %w", err)
+	}
+	return r, nil
+}
+
+// Watcher applies the rules of a file to a LimitStore.
+type Watcher struct {
+	path   string
+	store  *collector.LimitStore
+	logger *slog.Logger
+	raw    []byte // content last applied
+}
+
+// New returns a Watcher for path.
+func New(path string, store *collector.LimitStore, logger *slog.Logger) *Watcher {
+	return &Watcher{path: path, store: store, logger: logger}
+}
+
+// Load reads the file and applies its rules if they changed.
+func (w *Watcher) Load() error {
+	raw, err := os.ReadFile(w.path)
+	if err != nil {
+		return fmt.Errorf("read filter file: %w", err)
+	}
+	if w.raw != nil && bytes.Equal(raw, w.raw) {
+		return nil
+	}
+	rules, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+	w.store.SetFilters(rules.Include, rules.Exclude)
+	w.raw = raw
+	w.logger.Info("channel filters loaded", "file", w.path, "include", rules.Include, "exclude", rules.Exclude)
+	return nil
+}
+
+// Run watches the file's directory, which also catches the symlink swap
+// Kubernetes uses to update ConfigMap volumes, and reloads on every change
+// until ctx is done. An invalid file keeps the previous rules.
+func (w *Watcher) Run(ctx context.Context) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		w.logger.Error("filter file watch failed", "error", err)
+		return
+	}
+	defer func() { _ = fw.Close() }()
+	if err := fw.Add(filepath.Dir(w.path)); err != nil {
+		w.logger.Error("filter file watch failed", "file", w.path, "error", err)
+		return
+	}
+
+	// Editors and ConfigMap updates produce bursts of events; reload once
+	// they settle.
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("filter file watch error", "error", err)
+		case _, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			settle = time.After(100 * time.Millisecond)
+		case <-settle:
+			settle = nil
+			if err := w.Load(); err != nil {
+				w.logger.Error("filter file reload failed, keeping previous rules", "file", w.path, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package filterfile
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Rules
+		wantErr bool
+	}{
+		{
+			name:  "yaml",
+			input: "include: [\"orders.*\"]\nexclude:\n  - \"*.debug\"\n",
+			want:  Rules{Include: []string{"orders.*"}, Exclude: []string{"*.debug"}},
+		},
+		{
+			name:  "json",
+			input: `{"exclude": ["tmp.*"]}`,
+			want:  Rules{Exclude: []string{"tmp.*"}},
+		},
+		{name: "empty", input: ""},
+		{name: "unknown key", input: "includes: [\"a\"]\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got.Include, tt.want.Include) || !slices.Equal(got.Exclude, tt.want.Exclude) {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.yml")
+	if err := os.WriteFile(path, []byte("exclude: [\"a.*\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := collector.NewLimitStore(collector.Limits{MaxChannels: 10})
+	w := New(path, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := w.Load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := store.Get().ChannelExclude; !slices.Equal(got, []string{"a.*"}) {
+		t.Fatalf("want initial rules applied, got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	time.Sleep(50 * time.Millisecond) // let the watch start
+
+	// Replace the file the way a ConfigMap update does: write elsewhere, rename.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("exclude: [\"b.*\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !slices.Equal(store.Get().ChannelExclude, []string{"b.*"}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("rules not reloaded, got %v", store.Get().ChannelExclude)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if store.Get().MaxChannels != 10 {
+		t.Error("reload should not touch other limits")
+	}
+}
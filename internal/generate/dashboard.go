@@ -0,0 +1,245 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+// DashboardOptions parameterizes the generated Grafana dashboard.
+type DashboardOptions struct {
+	Namespace   string                 // metric name prefix, e.g. "redis_pubsub"
+	Patterns    []string               // known patterns, charted individually
+	HashMetrics []config.HashMetricDef // one panel per hash metric
+	Title       string                 // optional; defaults to "Redis Pub/Sub"
+	UID         string                 // optional; defaults to "redis-pubsub"
+}
+
+type dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid"`
+	Editable      bool       `json:"editable"`
+	GraphTooltip  int        `json:"graphTooltip"`
+	Refresh       string     `json:"refresh"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Tags          []string   `json:"tags"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []panel    `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type panel struct {
+	ID          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	GridPos     gridPos      `json:"gridPos"`
+	Datasource  *datasource  `json:"datasource,omitempty"`
+	Targets     []target     `json:"targets,omitempty"`
+	FieldConfig *fieldConfig `json:"fieldConfig,omitempty"`
+	Collapsed   *bool        `json:"collapsed,omitempty"`
+	Panels      []panel      `json:"panels,omitempty"` // rows only; always empty for expanded rows
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type target struct {
+	Datasource   datasource `json:"datasource"`
+	Expr         string     `json:"expr"`
+	LegendFormat string     `json:"legendFormat,omitempty"`
+	RefID        string     `json:"refId"`
+}
+
+type fieldConfig struct {
+	Defaults fieldDefaults `json:"defaults"`
+}
+
+type fieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+var promDatasource = datasource{Type: "prometheus", UID: "${datasource}"}
+
+// layout places panels left to right in the 24-column grid, wrapping to a
+// new line when a panel doesn't fit, and starts a full-width row per section.
+type layout struct {
+	panels []panel
+	x, y   int
+	lineH  int
+}
+
+func (l *layout) row(title string) {
+	l.newline()
+	collapsed := false
+	l.add(panel{Type: "row", Title: title, Collapsed: &collapsed}, 24, 1)
+	l.newline()
+}
+
+func (l *layout) newline() {
+	l.x, l.y, l.lineH = 0, l.y+l.lineH, 0
+}
+
+func (l *layout) add(p panel, w, h int) {
+	if l.x+w > 24 {
+		l.newline()
+	}
+	p.ID = len(l.panels) + 1
+	p.GridPos = gridPos{H: h, W: w, X: l.x, Y: l.y}
+	if p.Type != "row" {
+		p.Datasource = &promDatasource
+	}
+	l.panels = append(l.panels, p)
+	l.x += w
+	l.lineH = max(l.lineH, h)
+}
+
+// query builds panel targets from expr/legend pairs.
+func query(exprLegend ...string) []target {
+	var out []target
+	for i := 0; i+1 < len(exprLegend); i += 2 {
+		out = append(out, target{
+			Datasource:   promDatasource,
+			Expr:         exprLegend[i],
+			LegendFormat: exprLegend[i+1],
+			RefID:        string(rune('A' + len(out))),
+		})
+	}
+	return out
+}
+
+func unit(u string) *fieldConfig {
+	return &fieldConfig{Defaults: fieldDefaults{Unit: u}}
+}
+
+// Dashboard returns Grafana dashboard JSON for the exporter's metrics.
+func Dashboard(opts DashboardOptions) ([]byte, error) {
+	ns := opts.Namespace
+	if opts.Title == "" {
+		opts.Title = "Redis Pub/Sub"
+	}
+	if opts.UID == "" {
+		opts.UID = "redis-pubsub"
+	}
+
+	var l layout
+	l.row("Overview")
+	for _, s := range []struct{ title, expr, unit string }{
+		{"Redis", ns + "_exporter_redis_up", ""},
+		{"Channels", ns + "_channels_total", ""},
+		{"Orphan Channels", ns + "_orphan_channels_total", ""},
+		{"Patterns", ns + "_patterns_total", ""},
+		{"Pub/Sub Clients", ns + "_clients_total", ""},
+		{"Scrape Time", ns + "_exporter_scrape_duration_seconds", "s"},
+	} {
+		p := panel{Type: "stat", Title: s.title, Targets: query(s.expr, "")}
+		if s.unit != "" {
+			p.FieldConfig = unit(s.unit)
+		}
+		l.add(p, 4, 4)
+	}
+
+	l.row("Channels")
+	l.add(panel{Type: "timeseries", Title: "Channels Over Time", Targets: query(
+		ns+"_channels_total", "channels",
+		ns+"_orphan_channels_total", "orphans",
+	)}, 12, 8)
+	l.add(panel{Type: "timeseries", Title: "Top Channels by Subscribers", Targets: query(
+		"topk(20, "+ns+"_channel_subscriber_count)", "{{channel}}",
+	)}, 12, 8)
+
+	l.row("Patterns")
+	patternExpr := ns + "_pattern_subscriber_count"
+	if len(opts.Patterns) > 0 {
+		quoted := make([]string, len(opts.Patterns))
+		for i, p := range opts.Patterns {
+			quoted[i] = regexpQuote(p)
+		}
+		patternExpr = fmt.Sprintf("%s{pattern=~%q}", patternExpr, strings.Join(quoted, "|"))
+	}
+	l.add(panel{Type: "timeseries", Title: "Active Channels per Pattern", Targets: query(patternExpr, "{{pattern}}")}, 24, 8)
+
+	l.row("Clients")
+	l.add(panel{Type: "timeseries", Title: "Channel Subscriptions by Client", Targets: query(
+		"sum by (client_name) ("+ns+"_client_channel_subscriptions)", "{{client_name}}",
+	)}, 12, 8)
+	l.add(panel{Type: "timeseries", Title: "Client Output Buffers", FieldConfig: unit("bytes"), Targets: query(
+		"topk(10, "+ns+"_client_output_buffer_bytes)", "{{client_name}} {{client_addr}}",
+	)}, 12, 8)
+
+	if len(opts.HashMetrics) > 0 {
+		l.row("Hash Metrics")
+		for _, def := range opts.HashMetrics {
+			title := def.Help
+			if title == "" {
+				title = def.MetricName
+			}
+			p := panel{Type: "timeseries", Title: title, Targets: query(
+				ns+"_"+def.MetricName, "{{"+def.FieldLabel+"}}",
+			)}
+			if def.ParseMode == config.ParseDuration {
+				p.FieldConfig = unit("s")
+			}
+			l.add(p, 12, 8)
+		}
+	}
+
+	l.row("Exporter")
+	l.add(panel{Type: "timeseries", Title: "Scrape Duration", FieldConfig: unit("s"), Targets: query(
+		ns+"_exporter_scrape_duration_seconds", "{{instance}}",
+	)}, 12, 8)
+	l.add(panel{Type: "timeseries", Title: "Scrape Errors", Targets: query(
+		"rate("+ns+"_exporter_scrape_errors_total[5m])", "{{instance}}",
+	)}, 12, 8)
+
+	return json.MarshalIndent(dashboard{
+		Title:         opts.Title,
+		UID:           opts.UID,
+		Editable:      true,
+		GraphTooltip:  1,
+		Refresh:       "30s",
+		SchemaVersion: 38,
+		Tags:          []string{"redis", "pubsub"},
+		Time:          timeRange{From: "now-1h", To: "now"},
+		Templating:    templating{List: []variable{{Name: "datasource", Type: "datasource", Query: "prometheus"}}},
+		Panels:        l.panels,
+	}, "", "  ")
+}
+
+// regexpQuote escapes a glob pattern for a PromQL regex matcher, where the
+// pattern label value must match literally.
+func regexpQuote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
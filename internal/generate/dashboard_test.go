@@ -0,0 +1,52 @@
+package generate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/redis-pubsub-exporter/internal/config"
+)
+
+func TestDashboard(t *testing.T) {
+	out, err := Dashboard(DashboardOptions{
+		Namespace: "redis_pubsub",
+		Patterns:  []string{"orders.*", "users.*"},
+		HashMetrics: []config.HashMetricDef{{
+			RedisKey: "app:sessions", MetricName: "session_count", Help: "Sessions", FieldLabel: "region",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("dashboard: %v", err)
+	}
+
+	var got dashboard
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("generated dashboard is not valid JSON: %v", err)
+	}
+
+	exprs := make(map[string]string)
+	ids := make(map[int]bool)
+	for _, p := range got.Panels {
+		if ids[p.ID] {
+			t.Errorf("duplicate panel id %d", p.ID)
+		}
+		ids[p.ID] = true
+		if p.GridPos.X+p.GridPos.W > 24 {
+			t.Errorf("panel %q overflows the grid: %+v", p.Title, p.GridPos)
+		}
+		if len(p.Targets) > 0 {
+			exprs[p.Title] = p.Targets[0].Expr
+		}
+	}
+
+	want := map[string]string{
+		"Redis":                       "redis_pubsub_exporter_redis_up",
+		"Active Channels per Pattern": `redis_pubsub_pattern_subscriber_count{pattern=~"orders\\.\\*|users\\.\\*"}`,
+		"Sessions":                    "redis_pubsub_session_count",
+	}
+	for title, expr := range want {
+		if exprs[title] != expr {
+			t.Errorf("%s: want %q, got %q", title, expr, exprs[title])
+		}
+	}
+}
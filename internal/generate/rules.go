@@ -0,0 +1,119 @@
+// Package generate produces configuration for other tools (Prometheus rules,
+// Grafana dashboards) that matches the exporter's metric names and config.
+package generate
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// RulesOptions parameterizes the generated rules file.
+type RulesOptions struct {
+	Namespace string   // metric name prefix, e.g. "redis_pubsub"
+	Patterns  []string // known patterns that should always have subscribers
+
+	// SlowSubscriberBytes is the client output buffer size above which a
+	// subscriber is considered too slow.
+	SlowSubscriberBytes int64
+}
+
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Rules returns a Prometheus rules file with recording rules and alerts for
+// the exporter's metrics.
+func Rules(opts RulesOptions) ([]byte, error) {
+	ns := opts.Namespace
+
+	recording := []rule{
+		{Record: ns + ":channel_subscribers:sum", Expr: fmt.Sprintf("sum without (channel) (%s_channel_subscriber_count)", ns)},
+		{Record: ns + ":client_output_buffer_bytes:max", Expr: fmt.Sprintf("max without (client_name, client_addr) (%s_client_output_buffer_bytes)", ns)},
+	}
+
+	alerts := []rule{
+		{
+			Alert:  "RedisPubSubRedisDown",
+			Expr:   ns + "_exporter_redis_up == 0",
+			For:    "2m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "Redis is unreachable from redis-pubsub-exporter",
+				"description": "{{ $labels.instance }} has failed to scrape Redis for 2 minutes.",
+			},
+		},
+		{
+			Alert:  "RedisPubSubOrphanChannels",
+			Expr:   ns + "_orphan_channels_total > 0",
+			For:    "15m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Pub/Sub channels without subscribers",
+				"description": "{{ $value }} channels on {{ $labels.instance }} have had no subscribers for 15 minutes; messages published to them are lost.",
+			},
+		},
+		{
+			Alert:  "RedisPubSubChannelSubscribersGone",
+			Expr:   fmt.Sprintf("%[1]s_channel_subscriber_count == 0 and %[1]s_channel_subscriber_count offset 15m > 0", ns),
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Channel lost all its subscribers",
+				"description": "Channel {{ $labels.channel }} had subscribers 15 minutes ago and now has none.",
+			},
+		},
+		{
+			Alert:  "RedisPubSubSlowSubscriber",
+			Expr:   fmt.Sprintf("%s_client_output_buffer_bytes > %d", ns, opts.SlowSubscriberBytes),
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Subscriber is not keeping up",
+				"description": "Client {{ $labels.client_name }} ({{ $labels.client_addr }}) has {{ $value | humanize1024 }}B queued in its output buffer; Redis disconnects it once client-output-buffer-limit pubsub is reached.",
+			},
+		},
+		{
+			Alert:  "RedisPubSubTLSCertExpiring",
+			Expr:   ns + "_exporter_redis_tls_cert_expiry_timestamp_seconds - time() < 14 * 86400",
+			For:    "1h",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Redis TLS certificate expires soon",
+				"description": "The certificate chain of {{ $labels.addr }} expires in {{ $value | humanizeDuration }}; connections fail once it does.",
+			},
+		},
+	}
+
+	for _, p := range opts.Patterns {
+		alerts = append(alerts, rule{
+			Alert:  "RedisPubSubPatternNoSubscribers",
+			Expr:   fmt.Sprintf("absent(%s_pattern_subscriber_count{pattern=%q})", ns, p),
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning", "pattern": p},
+			Annotations: map[string]string{
+				"summary":     "No subscribed channels match " + p,
+				"description": "No channel matching " + p + " has had a subscriber for 10 minutes.",
+			},
+		})
+	}
+
+	return yaml.Marshal(ruleFile{Groups: []ruleGroup{
+		{Name: "redis-pubsub-exporter.rules", Rules: recording},
+		{Name: "redis-pubsub-exporter.alerts", Rules: alerts},
+	}})
+}
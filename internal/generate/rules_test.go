@@ -0,0 +1,47 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v2"
+)
+
+func TestRules(t *testing.T) {
+	out, err := Rules(RulesOptions{
+		Namespace:           "redis_pubsub",
+		Patterns:            []string{"orders.*"},
+		SlowSubscriberBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	var got ruleFile
+	if err := yaml.UnmarshalStrict(out, &got); err != nil {
+		t.Fatalf("generated rules are not valid YAML: %v\n%s", err, out)
+	}
+	if len(got.Groups) != 2 {
+		t.Fatalf("want 2 groups, got %d", len(got.Groups))
+	}
+
+	exprs := make(map[string]string)
+	for _, r := range got.Groups[1].Rules {
+		exprs[r.Alert+r.Labels["pattern"]] = r.Expr
+	}
+	want := map[string]string{
+		"RedisPubSubRedisDown":                    "redis_pubsub_exporter_redis_up == 0",
+		"RedisPubSubOrphanChannels":               "redis_pubsub_orphan_channels_total > 0",
+		"RedisPubSubSlowSubscriber":               "redis_pubsub_client_output_buffer_bytes > 1048576",
+		"RedisPubSubTLSCertExpiring":              "redis_pubsub_exporter_redis_tls_cert_expiry_timestamp_seconds - time() < 14 * 86400",
+		"RedisPubSubPatternNoSubscribersorders.*": `absent(redis_pubsub_pattern_subscriber_count{pattern="orders.*"})`,
+	}
+	for alert, expr := range want {
+		if exprs[alert] != expr {
+			t.Errorf("%s: want %q, got %q", alert, expr, exprs[alert])
+		}
+	}
+	if !strings.Contains(exprs["RedisPubSubChannelSubscribersGone"], "offset 15m") {
+		t.Errorf("unexpected subscribers-gone expr %q", exprs["RedisPubSubChannelSubscribersGone"])
+	}
+}
@@ -0,0 +1,121 @@
+// Package health tracks the liveness of the exporter itself: its background
+// loops are still running and its shared state can still be locked. It
+// deliberately knows nothing about Redis, whose reachability is readiness.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultProbeTimeout bounds a single liveness probe.
+const DefaultProbeTimeout = time.Second
+
+// Liveness records the state of background loops and runs probes that detect
+// deadlocked components.
+type Liveness struct {
+	logger       *slog.Logger
+	probeTimeout time.Duration
+
+	mu     sync.Mutex
+	exited map[string]string // loop name -> why it stopped
+	probes map[string]func() error
+}
+
+// New returns an empty Liveness.
+func New(logger *slog.Logger) *Liveness {
+	return &Liveness{
+		logger:       logger,
+		probeTimeout: DefaultProbeTimeout,
+		exited:       make(map[string]string),
+		probes:       make(map[string]func() error),
+	}
+}
+
+// Go runs fn in a goroutine. If fn returns or panics before ctx is done, the
+// exporter is reported as not live.
+func (l *Liveness) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.logger.Error("background loop panicked", "loop", name, "panic", r)
+				l.markExited(name, fmt.Sprintf("panicked: %v", r))
+				return
+			}
+			if ctx.Err() == nil {
+				l.logger.Error("background loop exited unexpectedly", "loop", name)
+				l.markExited(name, "exited")
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+func (l *Liveness) markExited(name, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exited[name] = reason
+}
+
+// AddProbe registers fn, which must return promptly unless the component it
+// exercises is deadlocked (e.g. it takes the component's lock). A component
+// that is stuck without holding a lock is reported by returning an error.
+func (l *Liveness) AddProbe(name string, fn func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.probes[name] = fn
+}
+
+// Problems returns a description of every failed loop and probe, sorted by
+// name. An empty result means the exporter is live.
+func (l *Liveness) Problems() []string {
+	l.mu.Lock()
+	var problems []string
+	for name, reason := range l.exited {
+		problems = append(problems, name+": "+reason)
+	}
+	probes := make(map[string]func() error, len(l.probes))
+	for name, fn := range l.probes {
+		probes[name] = fn
+	}
+	l.mu.Unlock()
+
+	// Run probes concurrently so one stuck probe costs one timeout.
+	var (
+		wg    sync.WaitGroup
+		resMu sync.Mutex
+	)
+	for name, fn := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := finishes(fn, l.probeTimeout); err != nil {
+				resMu.Lock()
+				problems = append(problems, name+": "+err.Error())
+				resMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(problems)
+	return problems
+}
+
+// finishes returns the error of fn, or an error if fn doesn't return within
+// timeout. A stuck fn leaks its goroutine, which is acceptable since the
+// process is about to be restarted.
+func finishes(fn func() error, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("no response within %s", timeout)
+	}
+}
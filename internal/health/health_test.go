@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLiveness() *Liveness {
+	l := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	l.probeTimeout = 50 * time.Millisecond
+	return l
+}
+
+// waitProblems polls until Problems reports want entries.
+func waitProblems(t *testing.T, l *Liveness, want int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got := l.Problems()
+		if len(got) == want || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLivenessLoops(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context)
+		want []string
+	}{
+		{name: "running", fn: func(ctx context.Context) { <-ctx.Done() }},
+		{name: "exited", fn: func(context.Context) {}, want: []string{"loop: exited"}},
+		{name: "panicked", fn: func(context.Context) { panic("boom") }, want: []string{"loop: panicked: boom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			l := newTestLiveness()
+			l.Go(ctx, "loop", tt.fn)
+
+			got := waitProblems(t, l, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("want %q, got %q", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLivenessStopOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := newTestLiveness()
+	stopped := make(chan struct{})
+	l.Go(ctx, "loop", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	cancel()
+	<-stopped
+
+	if got := waitProblems(t, l, 0); len(got) != 0 {
+		t.Errorf("a loop stopped by shutdown is not a problem, got %v", got)
+	}
+}
+
+func TestLivenessProbes(t *testing.T) {
+	l := newTestLiveness()
+	var (
+		mu        sync.Mutex
+		scrapeErr error
+	)
+	l.AddProbe("state", func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return nil
+	})
+	l.AddProbe("scrape", func() error { return scrapeErr })
+
+	if got := l.Problems(); len(got) != 0 {
+		t.Fatalf("want no problems, got %v", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := l.Problems(); len(got) != 1 || got[0] != "state: no response within 50ms" {
+		t.Errorf("want a stuck probe, got %v", got)
+	}
+
+	scrapeErr = errors.New("scrape running for 1m0s")
+	if got := l.Problems(); len(got) != 2 || got[0] != "scrape: scrape running for 1m0s" {
+		t.Errorf("want a failed probe, got %v", got)
+	}
+}
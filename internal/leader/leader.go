@@ -0,0 +1,202 @@
+// Package leader elects one of several exporter replicas through a lock key in
+// Redis, so that active subsystems (those that subscribe or publish) run only
+// once while every replica keeps serving scrape metrics.
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker is the subset of the go-redis client API used for the lock.
+type Locker interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// renewScript extends the lock only if this replica still holds it.
+const renewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`
+
+// releaseScript deletes the lock only if this replica still holds it.
+const releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// Elector competes for the lock key and tracks whether this replica leads.
+type Elector struct {
+	client Locker
+	key    string
+	id     string
+	ttl    time.Duration
+	logger *slog.Logger
+	desc   *prometheus.Desc
+
+	renewedAt time.Time // last successful acquire or renew; used by Run only
+
+	mu      sync.Mutex
+	leading bool
+	changed chan struct{} // closed and replaced on every leadership change
+}
+
+// New returns an Elector for key. id must be unique per replica; the lock
+// expires ttl after the leader stops renewing it.
+func New(client Locker, key, id string, ttl time.Duration, logger *slog.Logger) *Elector {
+	return &Elector{
+		client:  client,
+		key:     key,
+		id:      id,
+		ttl:     ttl,
+		logger:  logger,
+		changed: make(chan struct{}),
+		desc: prometheus.NewDesc(
+			"redis_pubsub_exporter_leader",
+			"Whether this exporter replica holds the leader lock (1) or not (0)",
+			nil, nil,
+		),
+	}
+}
+
+// Run acquires and renews the lock every ttl/3 until ctx is done, then
+// releases it so another replica can take over right away.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.tick(ctx)
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) tick(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	defer cancel()
+
+	if e.Leading() {
+		start := time.Now()
+		n, err := e.client.Eval(ctx, renewScript, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+		switch {
+		case err != nil && start.Sub(e.renewedAt) < e.ttl:
+			// The lock can't have expired yet; retry on the next tick.
+			e.logger.Warn("leader lock renewal failed", "key", e.key, "error", err)
+		case err != nil:
+			e.logger.Warn("leader lock expired while unreachable, stepping down", "key", e.key, "error", err)
+			e.setLeading(false)
+		case n == 0:
+			e.logger.Warn("leader lock lost", "key", e.key)
+			e.setLeading(false)
+		default:
+			e.renewedAt = start
+		}
+		return
+	}
+
+	start := time.Now()
+	ok, err := e.client.SetNX(ctx, e.key, e.id, e.ttl).Result()
+	if err != nil {
+		e.logger.Warn("leader lock acquisition failed", "key", e.key, "error", err)
+		return
+	}
+	if ok {
+		e.logger.Info("acquired leader lock", "key", e.key, "id", e.id)
+		e.renewedAt = start
+		e.setLeading(true)
+	}
+}
+
+func (e *Elector) release() {
+	if !e.Leading() {
+		return
+	}
+	e.setLeading(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := e.client.Eval(ctx, releaseScript, []string{e.key}, e.id).Err(); err != nil {
+		e.logger.Warn("leader lock release failed", "key", e.key, "error", err)
+	}
+}
+
+func (e *Elector) setLeading(v bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leading == v {
+		return
+	}
+	e.leading = v
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// Leading reports whether this replica currently holds the lock.
+func (e *Elector) Leading() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+func (e *Elector) state() (bool, <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading, e.changed
+}
+
+// RunWhileLeading runs fn whenever this replica becomes leader and cancels
+// its context when leadership is lost. It returns once ctx is done and fn has
+// returned.
+func (e *Elector) RunWhileLeading(ctx context.Context, fn func(ctx context.Context)) {
+	for {
+		leading, changed := e.state()
+		if !leading {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				continue
+			}
+		}
+
+		fnCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fn(fnCtx)
+		}()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		case <-done:
+			// fn gave up on its own; wait for the next change.
+			select {
+			case <-changed:
+			case <-ctx.Done():
+			}
+		}
+		cancel()
+		<-done
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (e *Elector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.desc
+}
+
+// Collect implements prometheus.Collector.
+func (e *Elector) Collect(ch chan<- prometheus.Metric) {
+	v := 0.0
+	if e.Leading() {
+		v = 1
+	}
+	ch <- prometheus.MustNewConstMetric(e.desc, prometheus.GaugeValue, v)
+}
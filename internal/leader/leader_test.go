@@ -0,0 +1,127 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeLocker is an in-memory Locker shared by several electors.
+type fakeLocker struct {
+	mu    sync.Mutex
+	owner string
+	err   error
+}
+
+func (f *fakeLocker) SetNX(_ context.Context, _ string, value interface{}, _ time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewBoolResult(false, f.err)
+	}
+	if f.owner != "" {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.owner = value.(string)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *fakeLocker) Eval(_ context.Context, script string, _ []string, args ...interface{}) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewCmdResult(nil, f.err)
+	}
+	if f.owner != args[0].(string) {
+		return redis.NewCmdResult(int64(0), nil)
+	}
+	if script == releaseScript {
+		f.owner = ""
+	}
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func newTestElector(l Locker, id string) *Elector {
+	return New(l, "lock", id, 30*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestElector(t *testing.T) {
+	lock := &fakeLocker{}
+	a, b := newTestElector(lock, "a"), newTestElector(lock, "b")
+
+	ctx := context.Background()
+	a.tick(ctx)
+	b.tick(ctx)
+	if !a.Leading() || b.Leading() {
+		t.Fatalf("want a leading, got a=%v b=%v", a.Leading(), b.Leading())
+	}
+
+	// The lock is taken over (e.g. after expiry): a steps down on renewal.
+	lock.owner = "b"
+	a.tick(ctx)
+	if a.Leading() {
+		t.Error("a should step down once it no longer owns the lock")
+	}
+
+	// b acquires, releases on shutdown, and a can take over.
+	lock.owner = ""
+	b.tick(ctx)
+	b.release()
+	a.tick(ctx)
+	if b.Leading() || !a.Leading() {
+		t.Errorf("want a leading after b released, got a=%v b=%v", a.Leading(), b.Leading())
+	}
+}
+
+func TestElectorStepsDownWhenUnreachable(t *testing.T) {
+	lock := &fakeLocker{}
+	e := newTestElector(lock, "a")
+	e.tick(context.Background())
+
+	lock.err = errors.New("connection refused")
+	e.tick(context.Background())
+	if !e.Leading() {
+		t.Fatal("a single failed renewal within the TTL should keep leadership")
+	}
+
+	e.renewedAt = time.Now().Add(-time.Minute)
+	e.tick(context.Background())
+	if e.Leading() {
+		t.Error("should step down once the lock may have expired")
+	}
+}
+
+func TestRunWhileLeading(t *testing.T) {
+	e := newTestElector(&fakeLocker{}, "a")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{}, 2)
+	stopped := make(chan struct{}, 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.RunWhileLeading(ctx, func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+		})
+	}()
+
+	e.setLeading(true)
+	<-started
+	e.setLeading(false)
+	<-stopped
+	e.setLeading(true)
+	<-started
+
+	cancel()
+	<-stopped
+	<-done
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name:    "implicit ok",
+			handler: func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) },
+			want:    "status=200",
+		},
+		{
+			name:    "explicit status",
+			handler: func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusUnauthorized) },
+			want:    "status=401",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := AccessLog(tt.handler, slog.New(slog.NewTextHandler(&buf, nil)))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/history?x=1", nil)
+			req.RemoteAddr = "10.0.0.1:4321"
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			got := buf.String()
+			for _, want := range []string{tt.want, "method=GET", "path=/api/v1/history ", "remote_addr=10.0.0.1:4321", "duration="} {
+				if !strings.Contains(got, want) {
+					t.Errorf("want %q in %q", want, got)
+				}
+			}
+		})
+	}
+}
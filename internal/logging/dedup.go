@@ -0,0 +1,117 @@
+// Package logging holds slog helpers shared by the exporter.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxDedupEntries bounds the number of distinct messages tracked; beyond it,
+// entries idle for a full interval are dropped.
+const maxDedupEntries = 1024
+
+// DedupHandler wraps a slog.Handler and rate-limits repeated warnings and
+// errors: the first occurrence of a message is logged, identical ones within
+// the interval are dropped, and the next one logged after it carries the
+// number dropped as "repeated". Records are identical when they share level,
+// message, "error" attribute and the attributes added with WithAttrs (such as
+// the target). Lower levels pass through unchanged.
+type DedupHandler struct {
+	next     slog.Handler
+	interval time.Duration
+	prefix   string // identifies the WithAttrs/WithGroup chain
+	state    *dedupState
+}
+
+type dedupState struct {
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	last       time.Time
+	suppressed int
+}
+
+// NewDedupHandler returns next wrapped to deduplicate within interval.
+func NewDedupHandler(next slog.Handler, interval time.Duration) *DedupHandler {
+	return &DedupHandler{
+		next:     next,
+		interval: interval,
+		state:    &dedupState{entries: make(map[string]*dedupEntry)},
+	}
+}
+
+// Enabled implements slog.Handler.
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return h.next.Handle(ctx, r)
+	}
+
+	key := h.prefix + "|" + r.Level.String() + "|" + r.Message
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "error" {
+			key += "|" + a.Value.String()
+			return false
+		}
+		return true
+	})
+
+	repeated, ok := h.state.record(key, r.Time, h.interval)
+	if !ok {
+		return nil
+	}
+	if repeated > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("repeated", repeated))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// record reports whether a record with key logged at now should be emitted,
+// and how many were suppressed since the last one that was.
+func (s *dedupState) record(key string, now time.Time, interval time.Duration) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		if len(s.entries) >= maxDedupEntries {
+			for k, old := range s.entries {
+				if now.Sub(old.last) >= interval {
+					delete(s.entries, k)
+				}
+			}
+		}
+		s.entries[key] = &dedupEntry{last: now}
+		return 0, true
+	}
+	if now.Sub(e.last) < interval {
+		e.suppressed++
+		return 0, false
+	}
+	repeated := e.suppressed
+	e.last, e.suppressed = now, 0
+	return repeated, true
+}
+
+// WithAttrs implements slog.Handler.
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := h.prefix
+	for _, a := range attrs {
+		prefix += "|" + a.String()
+	}
+	return &DedupHandler{next: h.next.WithAttrs(attrs), interval: h.interval, prefix: prefix, state: h.state}
+}
+
+// WithGroup implements slog.Handler.
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	return &DedupHandler{next: h.next.WithGroup(name), interval: h.interval, prefix: h.prefix + "|" + name + ".", state: h.state}
+}
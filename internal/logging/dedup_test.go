@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewDedupHandler(slog.NewTextHandler(&buf, nil), time.Hour)
+	logger := slog.New(h)
+	refused := errors.New("connection refused")
+
+	for i := 0; i < 3; i++ {
+		logger.Error("scrape failed", "error", refused, "consecutive_failures", i+1)
+	}
+	logger.Error("scrape failed", "error", errors.New("timeout"))
+	logger.With("target", "redis-b:6379").Error("scrape failed", "error", refused)
+	logger.Info("listening")
+	logger.Info("listening")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("want 5 lines (first error, other error, other target, 2 info), got %d:\n%s", len(lines), buf.String())
+	}
+
+	// Once the interval has passed, the next occurrence reports the count.
+	h.state.entries["|ERROR|scrape failed|connection refused"].last = time.Now().Add(-2 * time.Hour)
+	buf.Reset()
+	logger.Error("scrape failed", "error", refused)
+	if !strings.Contains(buf.String(), "repeated=2") {
+		t.Errorf("want repeat count in %q", buf.String())
+	}
+}
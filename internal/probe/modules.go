@@ -0,0 +1,73 @@
+// Package probe defines the modules of the /probe endpoint, which scrapes the
+// Redis instance named in the request, like the blackbox exporter's /probe.
+// A module selects what a probe collects, so that one exporter can serve a
+// heterogeneous fleet.
+package probe
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+// Module is a named probe configuration from the modules file:
+//
+//	# modules.yml
+//	modules:
+//	  minimal:
+//	    collectors: [server, channels]
+//	  orders:
+//	    collectors: [server, channels, patterns]
+//	    redis:
+//	      password_file: /etc/redis/orders-password
+//	      known_patterns: ["orders.*"]
+type Module struct {
+	// Collectors names the scrape subsystems that run, e.g. "channels";
+	// none means all, minus those disabled globally.
+	Collectors []string `yaml:"collectors"`
+	// Redis overrides connection and collector settings like the "redis"
+	// block of a targets file.
+	Redis targets.Overrides `yaml:"redis"`
+}
+
+type modulesFile struct {
+	Modules map[string]Module `yaml:"modules"`
+}
+
+// LoadModules reads and parses a modules file.
+func LoadModules(path string) (map[string]Module, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read modules file: %w", err)
+	}
+	modules, err := ParseModules(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse modules file %s: %w", path, err)
+	}
+	return modules, nil
+}
+
+// ParseModules parses the content of a modules file.
+func ParseModules(raw []byte) (map[string]Module, error) {
+	var f modulesFile
+	if err := yaml.UnmarshalStrict(raw, &f); err != nil {
+		return nil, err
+	}
+	builtin := collector.BuiltinSubsystems()
+	for name, m := range f.Modules {
+		for _, c := range m.Collectors {
+			if !slices.Contains(builtin, c) {
+				return nil, fmt.Errorf("module %s: unknown collector %q (want one of %v)", name, c, builtin)
+			}
+		}
+	}
+	if f.Modules == nil {
+		f.Modules = map[string]Module{}
+	}
+	return f.Modules, nil
+}
@@ -0,0 +1,55 @@
+package probe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/redis-pubsub-exporter/internal/targets"
+)
+
+func TestParseModules(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]Module
+		wantErr string
+	}{
+		{
+			name: "modules",
+			raw: `
+modules:
+  minimal:
+    collectors: [server, channels]
+  orders:
+    redis:
+      known_patterns: ["orders.*"]
+`,
+			want: map[string]Module{
+				"minimal": {Collectors: []string{"server", "channels"}},
+				"orders":  {Redis: targets.Overrides{KnownPatterns: []string{"orders.*"}}},
+			},
+		},
+		{name: "empty", raw: "", want: map[string]Module{}},
+		{name: "unknown collector", raw: "modules:\n  m:\n    collectors: [chanels]\n", wantErr: `unknown collector "chanels"`},
+		{name: "unknown field", raw: "modules:\n  m:\n    colectors: [server]\n", wantErr: "colectors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseModules([]byte(tt.raw))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package sampler
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Connection states exported in sampler_connection_state.
+const (
+	stateIdle       = "idle"       // not running, e.g. on a standby replica or between MONITOR slices
+	stateConnecting = "connecting" // waiting for the subscription confirmation
+	stateConnected  = "connected"  // subscribed and counting messages
+	stateBackoff    = "backoff"    // waiting to reconnect after a failed or broken subscription
+)
+
+var connStates = []string{stateIdle, stateConnecting, stateConnected, stateBackoff}
+
+// Reconnect backoff and the time allowed for a subscription to be confirmed,
+// so that a server that accepts the connection but never answers can't hang
+// the sampler.
+const (
+	defaultMinBackoff       = time.Second
+	defaultMaxBackoff       = 30 * time.Second
+	defaultSubscribeTimeout = 10 * time.Second
+)
+
+func (s *Sampler) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// backoff waits before the next subscription attempt and reports whether ctx
+// is still live afterwards.
+func (s *Sampler) backoff(ctx context.Context) bool {
+	s.setState(stateBackoff)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(s.retry.Next()):
+	}
+	s.mu.Lock()
+	s.reconnects++
+	s.mu.Unlock()
+	return true
+}
+
+// resubscribed handles a subscription confirmation received while consuming.
+// go-redis reconnects a broken subscription by itself, e.g. after a failover,
+// and resubscribes on the new connection; the first confirmation there has
+// Count 1. Messages published in between were missed, so the sampler starts
+// observing afresh as after a reconnect of its own.
+func (s *Sampler) resubscribed(sub *redis.Subscription, now time.Time) {
+	if sub.Count != 1 {
+		return
+	}
+	s.logger.Info("sampler resubscribed after a broken connection", "patterns", s.subscribe)
+	s.stop()
+	s.start(now)
+	s.mu.Lock()
+	s.reconnects++
+	s.mu.Unlock()
+}
@@ -0,0 +1,200 @@
+package sampler
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sampler modes.
+const (
+	ModePSubscribe = "psubscribe" // subscribe to the channel patterns (default)
+	ModeMonitor    = "monitor"    // observe PUBLISH commands through MONITOR
+)
+
+// Monitorer is a client for MONITOR. MONITOR takes over the connection, so
+// each slice gets its own client, which is closed afterwards.
+type Monitorer interface {
+	Monitor(ctx context.Context, ch chan string) *redis.MonitorCmd
+	Close() error
+}
+
+// runMonitor observes PUBLISH commands for MonitorDuration every
+// MonitorInterval until ctx is done. MONITOR makes Redis stream every command
+// it executes to the exporter, which can cost a busy server more than half its
+// throughput; slicing bounds how long that lasts.
+func (s *Sampler) runMonitor(ctx context.Context) {
+	s.logger.Warn("sampler uses MONITOR; Redis throughput drops while a slice runs",
+		"duration", s.monitorDuration, "interval", s.monitorInterval)
+	ticker := time.NewTicker(s.monitorInterval)
+	defer ticker.Stop()
+
+	for {
+		s.monitorSlice(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) monitorSlice(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.monitorDuration)
+	defer cancel()
+
+	s.setState(stateConnecting)
+	client := s.newMonitor()
+	lines := make(chan string, 1024)
+	cmd := client.Monitor(ctx, lines)
+	if err := cmd.Err(); err != nil {
+		_ = client.Close()
+		s.setState(stateIdle)
+		if ctx.Err() == nil {
+			s.logger.Warn("sampler MONITOR failed", "error", err)
+		}
+		return
+	}
+	cmd.Start()
+	start := time.Now()
+	s.start(start)
+
+	published := 0
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case line := <-lines:
+			if s.observeMonitor(line, time.Now()) {
+				published++
+			}
+		}
+	}
+
+	cmd.Stop()
+	_ = client.Close()
+	s.stop()
+	s.setPublishRate(float64(published) / time.Since(start).Seconds())
+
+	// go-redis reads MONITOR output in its own goroutine, which may be
+	// blocked sending a line; drain until it sees the closed connection.
+	go func() {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case <-lines:
+			case <-timeout:
+				return
+			}
+		}
+	}()
+}
+
+// observeMonitor counts a MONITOR line if it is a PUBLISH and reports
+// whether it was.
+func (s *Sampler) observeMonitor(line string, at time.Time) bool {
+	addr, args, ok := parseMonitorLine(line)
+	if !ok || len(args) < 3 {
+		return false
+	}
+	switch strings.ToLower(args[0]) {
+	case "publish", "spublish":
+	default:
+		return false
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr // e.g. a unix socket path
+	}
+	s.observePublisher(host)
+	s.observe(args[1], args[2], at)
+	return true
+}
+
+// parseMonitorLine splits a MONITOR line such as
+//
+//	1700000000.123456 [0 10.0.0.5:51234] "publish" "orders" "{\"id\":1}"
+//
+// into the client address and the unquoted command arguments.
+func parseMonitorLine(line string) (addr string, args []string, ok bool) {
+	// The address may itself contain brackets (IPv6), so the client part
+	// ends at the last ']' before the first argument.
+	quote := strings.IndexByte(line, '"')
+	if quote < 0 {
+		return "", nil, false
+	}
+	open := strings.IndexByte(line, '[')
+	end := strings.LastIndexByte(line[:quote], ']')
+	if open < 0 || end < open {
+		return "", nil, false
+	}
+	client := strings.Fields(line[open+1 : end])
+	if len(client) != 2 {
+		return "", nil, false
+	}
+
+	rest := line[end+1:]
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if rest == "" {
+			return client[1], args, len(args) > 0
+		}
+		arg, n, ok := unquote(rest)
+		if !ok {
+			return "", nil, false
+		}
+		args = append(args, arg)
+		rest = rest[n:]
+	}
+}
+
+// unquote reads one double-quoted MONITOR argument from the start of s,
+// decoding the escapes Redis uses, and returns it with its length in s.
+func unquote(s string) (string, int, bool) {
+	if s == "" || s[0] != '"' {
+		return "", 0, false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), i + 1, true
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, false
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'x':
+				if i+2 >= len(s) {
+					return "", 0, false
+				}
+				v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", 0, false
+				}
+				b.WriteByte(byte(v))
+				i += 2
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,91 @@
+package sampler
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseMonitorLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantAddr string
+		wantArgs []string
+		wantOK   bool
+	}{
+		{
+			name:     "publish",
+			line:     `1700000000.123456 [0 10.0.0.5:51234] "publish" "orders" "{\"id\":1}"`,
+			wantAddr: "10.0.0.5:51234",
+			wantArgs: []string{"publish", "orders", `{"id":1}`},
+			wantOK:   true,
+		},
+		{
+			name:     "escapes",
+			line:     `1700000000.1 [3 [::1]:6000] "PUBLISH" "a b" "x\n\x00\\"`,
+			wantAddr: "[::1]:6000",
+			wantArgs: []string{"PUBLISH", "a b", "x\n\x00\\"},
+			wantOK:   true,
+		},
+		{
+			name:     "lua client",
+			line:     `1700000000.1 [0 lua] "publish" "jobs" "1"`,
+			wantAddr: "lua",
+			wantArgs: []string{"publish", "jobs", "1"},
+			wantOK:   true,
+		},
+		{name: "monitor ack", line: "OK"},
+		{name: "unterminated quote", line: `1700000000.1 [0 10.0.0.5:1] "publish" "orders`},
+		{name: "bad hex escape", line: `1700000000.1 [0 10.0.0.5:1] "publish" "\xZZ"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, args, ok := parseMonitorLine(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("ok: want %v, got %v", tt.wantOK, ok)
+			}
+			if addr != tt.wantAddr || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("want %q %q, got %q %q", tt.wantAddr, tt.wantArgs, addr, args)
+			}
+		})
+	}
+}
+
+func TestObserveMonitor(t *testing.T) {
+	s := New(nil, Options{Mode: ModeMonitor, ChannelCounters: true, Patterns: []string{"orders.*"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	for _, line := range []string{
+		`1700000000.1 [0 10.0.0.5:51234] "publish" "orders.created" "1"`,
+		`1700000000.2 [0 10.0.0.5:51235] "spublish" "orders.created" "2"`,
+		`1700000000.3 [0 10.0.0.6:4000] "get" "orders.created"`,
+		`1700000000.4 [0 10.0.0.7:4000] "publish" "users.login" "3"`,
+	} {
+		s.observeMonitor(line, now)
+	}
+	s.start(now)
+
+	if !s.ObservingSince().IsZero() {
+		t.Error("monitor mode must not report continuous observation")
+	}
+	got := gather(t, s)
+	want := map[string]float64{
+		"redis_pubsub_sampler_messages_total/orders.created":     2,
+		"redis_pubsub_sampler_pattern_messages_total/orders.*":   2,
+		"redis_pubsub_sampler_publisher_messages_total/10.0.0.5": 2,
+		"redis_pubsub_sampler_publisher_messages_total/10.0.0.7": 1,
+		"redis_pubsub_sampler_messages_total/users.login":        1,
+		"redis_pubsub_sampler_active":                            1,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: want %v, got %v", k, v, got[k])
+		}
+	}
+	if _, ok := got["redis_pubsub_sampler_publisher_messages_total/10.0.0.6"]; ok {
+		t.Error("non-PUBLISH commands should not be counted")
+	}
+}
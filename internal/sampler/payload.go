@@ -0,0 +1,70 @@
+package sampler
+
+import (
+	"encoding/json"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Payload classes.
+const (
+	ClassJSON   = "json"   // valid JSON
+	ClassText   = "text"   // UTF-8 without control characters other than whitespace
+	ClassBinary = "binary" // anything else
+)
+
+// maxPayloadTypes caps the distinct type field values counted per pattern;
+// further values are counted as "other".
+const maxPayloadTypes = 50
+
+// unmatchedPattern is the pattern label of messages matching no known pattern.
+const unmatchedPattern = "none"
+
+type payloadKey struct{ pattern, value string }
+
+// classify returns the class of payload and, for JSON objects, the string
+// value of typeField ("" if typeField is empty, missing or not a string).
+func classify(payload, typeField string) (class, typ string) {
+	if json.Valid([]byte(payload)) {
+		if typeField != "" && len(payload) > 0 && payload[0] == '{' {
+			var obj map[string]json.RawMessage
+			if json.Unmarshal([]byte(payload), &obj) == nil {
+				_ = json.Unmarshal(obj[typeField], &typ)
+			}
+		}
+		return ClassJSON, typ
+	}
+	if !utf8.ValidString(payload) {
+		return ClassBinary, ""
+	}
+	for _, r := range payload {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return ClassBinary, ""
+		}
+	}
+	return ClassText, ""
+}
+
+// observePayload counts a payload's class (and type) for each pattern its
+// channel matched. Called with s.mu held.
+func (s *Sampler) observePayload(patterns []string, payload string) {
+	class, typ := classify(payload, s.typeField)
+	if len(patterns) == 0 {
+		patterns = []string{unmatchedPattern}
+	}
+	for _, p := range patterns {
+		s.payloadClasses[payloadKey{p, class}]++
+		if s.typeField == "" || class != ClassJSON {
+			continue
+		}
+		key := payloadKey{p, typ}
+		if _, ok := s.payloadTypes[key]; !ok {
+			if s.typesPerPattern[p] >= maxPayloadTypes {
+				key.value = "other"
+			} else {
+				s.typesPerPattern[p]++
+			}
+		}
+		s.payloadTypes[key]++
+	}
+}
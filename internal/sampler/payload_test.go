@@ -0,0 +1,69 @@
+package sampler
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		typeField string
+		wantClass string
+		wantType  string
+	}{
+		{name: "json object", payload: `{"event_type":"order.created","id":1}`, wantClass: ClassJSON},
+		{name: "json type field", payload: `{"event_type":"order.created","id":1}`, typeField: "event_type", wantClass: ClassJSON, wantType: "order.created"},
+		{name: "json missing field", payload: `{"id":1}`, typeField: "event_type", wantClass: ClassJSON},
+		{name: "json non-string field", payload: `{"event_type":3}`, typeField: "event_type", wantClass: ClassJSON},
+		{name: "json array", payload: `[1,2]`, typeField: "event_type", wantClass: ClassJSON},
+		{name: "json number", payload: `42`, wantClass: ClassJSON},
+		{name: "truncated json", payload: `{"event_type":"order.created"`, typeField: "event_type", wantClass: ClassText},
+		{name: "text", payload: "hello\tworld\n", wantClass: ClassText},
+		{name: "empty", payload: "", wantClass: ClassText},
+		{name: "invalid utf-8", payload: "\xff\xfe", wantClass: ClassBinary},
+		{name: "control characters", payload: "a\x00b", wantClass: ClassBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, typ := classify(tt.payload, tt.typeField)
+			if class != tt.wantClass || typ != tt.wantType {
+				t.Errorf("want (%q, %q), got (%q, %q)", tt.wantClass, tt.wantType, class, typ)
+			}
+		})
+	}
+}
+
+func TestSamplerPayloadCounters(t *testing.T) {
+	s := New(nil, Options{
+		Patterns:  []string{"orders.*"},
+		TypeField: "event_type",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	s.observe("orders.created", `{"event_type":"created"}`, now)
+	s.observe("orders.created", `{"event_type":"created"}`, now)
+	s.observe("orders.deleted", "not json", now)
+	s.observe("users.login", "\x00\x01", now)
+	for i := range maxPayloadTypes + 5 {
+		s.observe("orders.other", `{"event_type":"t`+strconv.Itoa(i)+`"}`, now)
+	}
+
+	got := gather(t, s)
+	want := map[string]float64{
+		"redis_pubsub_sampler_payloads_total/json/orders.*":         maxPayloadTypes + 7,
+		"redis_pubsub_sampler_payloads_total/text/orders.*":         1,
+		"redis_pubsub_sampler_payloads_total/binary/none":           1,
+		"redis_pubsub_sampler_payload_types_total/orders.*/created": 2,
+		"redis_pubsub_sampler_payload_types_total/orders.*/other":   6,
+		"redis_pubsub_sampler_payload_types_total/orders.*/t0":      1,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: want %v, got %v", k, v, got[k])
+		}
+	}
+}
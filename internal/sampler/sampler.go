@@ -0,0 +1,429 @@
+// Package sampler subscribes to channel patterns and counts the messages
+// published on each channel, adding the traffic that PUBSUB commands can't
+// show to the channel metrics.
+package sampler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/backoff"
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+// Subscriber is the subset of the go-redis client API used by the sampler. It
+// should be a client of its own rather than the one used for scraping: a
+// subscription holds a connection for as long as it lasts.
+type Subscriber interface {
+	PSubscribe(ctx context.Context, patterns ...string) *redis.PubSub
+}
+
+// Options configures a Sampler.
+type Options struct {
+	// Mode is ModePSubscribe (default) or ModeMonitor. Monitor mode needs
+	// NewMonitor and samples for MonitorDuration every MonitorInterval.
+	Mode            string
+	NewMonitor      func() Monitorer
+	MonitorDuration time.Duration
+	MonitorInterval time.Duration
+
+	// Subscribe lists the patterns the sampler subscribes to; none means "*".
+	Subscribe []string
+
+	// Patterns are known channel patterns whose messages are also counted
+	// in aggregate, e.g. for low-cardinality publish-rate alerts.
+	Patterns []string
+
+	// ChannelCounters exports a message counter per channel.
+	ChannelCounters bool
+
+	// MaxChannels caps the number of channels tracked individually (0 =
+	// unlimited). Messages on further channels still count per pattern.
+	MaxChannels int
+
+	// ClassifyPayloads counts messages per known pattern and payload class
+	// (json, text, binary). With TypeField set, JSON objects are also
+	// counted by the string value of that field, e.g. "event_type".
+	ClassifyPayloads bool
+	TypeField        string
+
+	// MaxRate is the number of messages processed per second (0 =
+	// unlimited); the rest are dropped and counted.
+	MaxRate int
+}
+
+// Sampler counts messages per channel through a pattern subscription. It
+// implements prometheus.Collector and collector.ActivitySource.
+type Sampler struct {
+	client          Subscriber
+	mode            string
+	newMonitor      func() Monitorer
+	monitorDuration time.Duration
+	monitorInterval time.Duration
+	subscribe       []string
+	patterns        []string
+	channelCounters bool
+	maxChannels     int
+	maxRate         int
+	classify        bool
+	typeField       string
+	logger          *slog.Logger
+
+	retry            backoff.Backoff
+	subscribeTimeout time.Duration
+
+	messages        *prometheus.Desc
+	patternMessages *prometheus.Desc
+	dropped         *prometheus.Desc
+	active          *prometheus.Desc
+	publisherMsgs   *prometheus.Desc
+	publishRate     *prometheus.Desc
+	payloadClass    *prometheus.Desc
+	payloadType     *prometheus.Desc
+	connState       *prometheus.Desc
+	reconnectsDesc  *prometheus.Desc
+	limit           *prometheus.Desc
+
+	mu            sync.Mutex
+	since         time.Time            // start of the current subscription; zero while not subscribed
+	counts        map[string]float64   // messages per channel; kept across resubscriptions
+	patternCounts map[string]float64   // messages per known pattern
+	lastSeen      map[string]time.Time // last message per channel during the current subscription
+	untrackedAt   time.Time            // last message that was dropped or not tracked per channel
+	droppedRate   float64              // messages dropped by MaxRate
+	droppedChan   float64              // messages without a per-channel entry due to MaxChannels
+	window        time.Time            // start of the current one-second rate window
+	windowCount   int                  // messages processed in the current rate window
+	publishers    map[string]float64   // PUBLISH commands per client IP (monitor mode)
+	lastRate      float64              // PUBLISH commands per second in the last MONITOR slice
+	state         string               // connection state, one of connStates
+	reconnects    float64              // subscription attempts after a failed or broken one
+
+	payloadClasses  map[payloadKey]float64 // messages per pattern and payload class
+	payloadTypes    map[payloadKey]float64 // JSON messages per pattern and type field value
+	typesPerPattern map[string]int         // distinct type values counted per pattern
+}
+
+// New returns a Sampler for the given options.
+func New(client Subscriber, opts Options, logger *slog.Logger) *Sampler {
+	if len(opts.Subscribe) == 0 {
+		opts.Subscribe = []string{"*"}
+	}
+	patternCounts := make(map[string]float64, len(opts.Patterns))
+	for _, p := range opts.Patterns {
+		patternCounts[p] = 0 // export zero rather than nothing for idle patterns
+	}
+	return &Sampler{
+		client:          client,
+		mode:            opts.Mode,
+		newMonitor:      opts.NewMonitor,
+		monitorDuration: opts.MonitorDuration,
+		monitorInterval: opts.MonitorInterval,
+		subscribe:       opts.Subscribe,
+		patterns:        opts.Patterns,
+		channelCounters: opts.ChannelCounters,
+		maxChannels:     opts.MaxChannels,
+		maxRate:         opts.MaxRate,
+		classify:        opts.ClassifyPayloads || opts.TypeField != "",
+		typeField:       opts.TypeField,
+		logger:          logger,
+		state:           stateIdle,
+		counts:          make(map[string]float64),
+		patternCounts:   patternCounts,
+		lastSeen:        make(map[string]time.Time),
+		publishers:      make(map[string]float64),
+		payloadClasses:  make(map[payloadKey]float64),
+		payloadTypes:    make(map[payloadKey]float64),
+		typesPerPattern: make(map[string]int),
+
+		retry:            backoff.Backoff{Min: defaultMinBackoff, Max: defaultMaxBackoff},
+		subscribeTimeout: defaultSubscribeTimeout,
+
+		messages: prometheus.NewDesc(
+			collector.Namespace+"_sampler_messages_total",
+			"Number of messages observed per channel by the sampler",
+			[]string{"channel"}, nil,
+		),
+		patternMessages: prometheus.NewDesc(
+			collector.Namespace+"_sampler_pattern_messages_total",
+			"Number of messages observed by the sampler on channels matching a known pattern",
+			[]string{"pattern"}, nil,
+		),
+		dropped: prometheus.NewDesc(
+			collector.Namespace+"_sampler_dropped_messages_total",
+			"Number of sampled messages not fully counted: rate_limit only counts them per pattern, channel_limit only skips the per-channel entry",
+			[]string{"reason"}, nil,
+		),
+		publisherMsgs: prometheus.NewDesc(
+			collector.Namespace+"_sampler_publisher_messages_total",
+			"Number of PUBLISH commands observed per publishing client IP (monitor mode)",
+			[]string{"publisher"}, nil,
+		),
+		publishRate: prometheus.NewDesc(
+			collector.Namespace+"_sampler_monitor_publish_rate",
+			"PUBLISH commands per second observed during the last MONITOR slice",
+			nil, nil,
+		),
+		payloadClass: prometheus.NewDesc(
+			collector.Namespace+"_sampler_payloads_total",
+			"Number of sampled messages per known pattern (\"none\" if no pattern matched) and payload class (json, text, binary)",
+			[]string{"pattern", "class"}, nil,
+		),
+		payloadType: prometheus.NewDesc(
+			collector.Namespace+"_sampler_payload_types_total",
+			"Number of sampled JSON messages per known pattern and value of the configured type field",
+			[]string{"pattern", "type"}, nil,
+		),
+		active: prometheus.NewDesc(
+			collector.Namespace+"_sampler_active",
+			"Whether the sampler is currently subscribed (1) or not (0), e.g. on a standby replica",
+			nil, nil,
+		),
+		connState: prometheus.NewDesc(
+			collector.Namespace+"_sampler_connection_state",
+			"State of the sampler's dedicated Redis connection: 1 for the current state (idle, connecting, connected, backoff), 0 for the others",
+			[]string{"state"}, nil,
+		),
+		reconnectsDesc: prometheus.NewDesc(
+			collector.Namespace+"_sampler_reconnects_total",
+			"Number of times the sampler resubscribed after its subscription failed or broke",
+			nil, nil,
+		),
+		limit: prometheus.NewDesc(
+			collector.Namespace+"_sampler_limit",
+			"Configured sampler limit: max_channels tracked individually or max_rate messages per second (0 = unlimited)",
+			[]string{"limit"}, nil,
+		),
+	}
+}
+
+// Run subscribes to the sampler's patterns and counts messages until ctx is
+// done, resubscribing after errors with jittered exponential backoff. In
+// monitor mode it runs MONITOR slices instead.
+func (s *Sampler) Run(ctx context.Context) {
+	defer s.setState(stateIdle)
+	if s.mode == ModeMonitor {
+		s.runMonitor(ctx)
+		return
+	}
+	for ctx.Err() == nil {
+		if s.consume(ctx) {
+			s.retry.Reset()
+		}
+		if ctx.Err() != nil || !s.backoff(ctx) {
+			return
+		}
+	}
+}
+
+// consume counts messages until the subscription breaks or ctx is done. It
+// reports whether the subscription was confirmed.
+func (s *Sampler) consume(ctx context.Context) bool {
+	s.setState(stateConnecting)
+	confirmCtx, cancel := context.WithTimeout(ctx, s.subscribeTimeout)
+	pubsub := s.client.PSubscribe(confirmCtx, s.subscribe...)
+	defer func() { _ = pubsub.Close() }()
+
+	// Wait for the subscription confirmation so connection errors surface here.
+	_, err := pubsub.Receive(confirmCtx)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("sampler subscribe failed", "patterns", s.subscribe, "error", err)
+		}
+		return false
+	}
+	s.logger.Info("sampling messages", "patterns", s.subscribe)
+	s.start(time.Now())
+	defer s.stop()
+
+	msgs := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case msg, ok := <-msgs:
+			if !ok {
+				s.logger.Warn("sampler subscription closed", "patterns", s.subscribe)
+				return true
+			}
+			switch msg := msg.(type) {
+			case *redis.Message:
+				s.observe(msg.Channel, msg.Payload, time.Now())
+			case *redis.Subscription:
+				s.resubscribed(msg, time.Now())
+			}
+		}
+	}
+}
+
+func (s *Sampler) start(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.since = now
+	s.state = stateConnected
+}
+
+// stop marks the sampler as not subscribed. Last-message times are dropped:
+// messages published in between were not seen, so they can't be trusted.
+func (s *Sampler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.since = time.Time{}
+	s.state = stateIdle
+	s.untrackedAt = time.Time{}
+	clear(s.lastSeen)
+}
+
+// observe counts a message. Pattern counters count every message; one
+// dropped by MaxRate is not classified and gets no per-channel entry.
+func (s *Sampler) observe(channel, payload string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []string
+	for _, p := range s.patterns {
+		if collector.MatchGlob(p, channel) {
+			s.patternCounts[p]++
+			matched = append(matched, p)
+		}
+	}
+
+	if s.maxRate > 0 {
+		if at.Sub(s.window) >= time.Second {
+			s.window, s.windowCount = at, 0
+		}
+		if s.windowCount >= s.maxRate {
+			s.droppedRate++
+			s.untrackedAt = at
+			return
+		}
+		s.windowCount++
+	}
+
+	if s.classify {
+		s.observePayload(matched, payload)
+	}
+
+	_, seen := s.lastSeen[channel]
+	_, counted := s.counts[channel]
+	full := s.maxChannels > 0 && ((!seen && len(s.lastSeen) >= s.maxChannels) ||
+		(s.channelCounters && !counted && len(s.counts) >= s.maxChannels))
+	if full {
+		s.droppedChan++
+		s.untrackedAt = at
+		return
+	}
+	if s.channelCounters {
+		s.counts[channel]++
+	}
+	s.lastSeen[channel] = at
+}
+
+// ObservingSince implements collector.ActivitySource. MONITOR slices miss
+// most messages, so in monitor mode the sampler never claims to observe and
+// channels are not reported as silent.
+func (s *Sampler) ObservingSince() time.Time {
+	if s.mode == ModeMonitor {
+		return time.Time{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.since
+}
+
+// observePublisher counts a PUBLISH by client IP, within MaxChannels.
+func (s *Sampler) observePublisher(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.publishers[ip]; ok || s.maxChannels <= 0 || len(s.publishers) < s.maxChannels {
+		s.publishers[ip]++
+	}
+}
+
+func (s *Sampler) setPublishRate(v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRate = v
+}
+
+// LastMessage implements collector.ActivitySource. While messages are being
+// dropped by the limits, any channel may have received one, so the last drop
+// counts as a message on every channel; that way limits never make channels
+// look silent.
+func (s *Sampler) LastMessage(channel string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := s.lastSeen[channel]
+	if s.untrackedAt.After(last) {
+		return s.untrackedAt
+	}
+	return last
+}
+
+// Describe implements prometheus.Collector.
+func (s *Sampler) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.messages
+	ch <- s.patternMessages
+	ch <- s.dropped
+	ch <- s.active
+	ch <- s.connState
+	ch <- s.reconnectsDesc
+	ch <- s.limit
+	if s.classify {
+		ch <- s.payloadClass
+	}
+	if s.typeField != "" {
+		ch <- s.payloadType
+	}
+	if s.mode == ModeMonitor {
+		ch <- s.publisherMsgs
+		ch <- s.publishRate
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (s *Sampler) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for channel, n := range s.counts {
+		ch <- prometheus.MustNewConstMetric(s.messages, prometheus.CounterValue, n, channel)
+	}
+	for pattern, n := range s.patternCounts {
+		ch <- prometheus.MustNewConstMetric(s.patternMessages, prometheus.CounterValue, n, pattern)
+	}
+	ch <- prometheus.MustNewConstMetric(s.dropped, prometheus.CounterValue, s.droppedRate, "rate_limit")
+	ch <- prometheus.MustNewConstMetric(s.dropped, prometheus.CounterValue, s.droppedChan, "channel_limit")
+	active := 0.0
+	if !s.since.IsZero() {
+		active = 1
+	}
+	ch <- prometheus.MustNewConstMetric(s.active, prometheus.GaugeValue, active)
+	for _, state := range connStates {
+		v := 0.0
+		if state == s.state {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(s.connState, prometheus.GaugeValue, v, state)
+	}
+	ch <- prometheus.MustNewConstMetric(s.reconnectsDesc, prometheus.CounterValue, s.reconnects)
+	ch <- prometheus.MustNewConstMetric(s.limit, prometheus.GaugeValue, float64(s.maxChannels), "max_channels")
+	ch <- prometheus.MustNewConstMetric(s.limit, prometheus.GaugeValue, float64(s.maxRate), "max_rate")
+	for k, n := range s.payloadClasses {
+		ch <- prometheus.MustNewConstMetric(s.payloadClass, prometheus.CounterValue, n, k.pattern, k.value)
+	}
+	for k, n := range s.payloadTypes {
+		ch <- prometheus.MustNewConstMetric(s.payloadType, prometheus.CounterValue, n, k.pattern, k.value)
+	}
+	if s.mode == ModeMonitor {
+		for ip, n := range s.publishers {
+			ch <- prometheus.MustNewConstMetric(s.publisherMsgs, prometheus.CounterValue, n, ip)
+		}
+		ch <- prometheus.MustNewConstMetric(s.publishRate, prometheus.GaugeValue, s.lastRate)
+	}
+}
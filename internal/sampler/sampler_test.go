@@ -0,0 +1,269 @@
+package sampler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/backoff"
+)
+
+// gather returns sample values keyed by metric name and channel label.
+func gather(t *testing.T, c prometheus.Collector) map[string]float64 {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	out := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, lp := range m.GetLabel() {
+				key += "/" + lp.GetValue()
+			}
+			out[key] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+	}
+	return out
+}
+
+func TestSampler(t *testing.T) {
+	s := New(nil, Options{ChannelCounters: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if !s.ObservingSince().IsZero() {
+		t.Fatal("sampler should not observe before subscribing")
+	}
+
+	start := time.Now()
+	s.start(start)
+	s.observe("orders.created", "", start.Add(time.Second))
+	s.observe("orders.created", "", start.Add(2*time.Second))
+	s.observe("users.login", "", start.Add(3*time.Second))
+
+	if got := s.ObservingSince(); !got.Equal(start) {
+		t.Errorf("want observing since %v, got %v", start, got)
+	}
+	if got := s.LastMessage("orders.created"); !got.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("unexpected last message %v", got)
+	}
+
+	want := map[string]float64{
+		"redis_pubsub_sampler_messages_total/orders.created": 2,
+		"redis_pubsub_sampler_messages_total/users.login":    1,
+		"redis_pubsub_sampler_active":                        1,
+	}
+	got := gather(t, s)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: want %v, got %v", k, v, got[k])
+		}
+	}
+
+	// Losing the subscription forgets last-message times but keeps counters.
+	s.stop()
+	if !s.ObservingSince().IsZero() || !s.LastMessage("orders.created").IsZero() {
+		t.Error("stop should reset observation state")
+	}
+	got = gather(t, s)
+	if got["redis_pubsub_sampler_active"] != 0 || got["redis_pubsub_sampler_messages_total/orders.created"] != 2 {
+		t.Errorf("unexpected metrics after stop: %v", got)
+	}
+}
+
+func TestSamplerPatternCounters(t *testing.T) {
+	tests := []struct {
+		name            string
+		channelCounters bool
+		want            map[string]float64
+	}{
+		{
+			name:            "with channel counters",
+			channelCounters: true,
+			want: map[string]float64{
+				"redis_pubsub_sampler_pattern_messages_total/orders.*": 2,
+				"redis_pubsub_sampler_pattern_messages_total/*.login":  1,
+				"redis_pubsub_sampler_pattern_messages_total/jobs:*":   0,
+				"redis_pubsub_sampler_messages_total/orders.created":   1,
+			},
+		},
+		{
+			name: "pattern counters only",
+			want: map[string]float64{
+				"redis_pubsub_sampler_pattern_messages_total/orders.*": 2,
+				"redis_pubsub_sampler_pattern_messages_total/*.login":  1,
+				"redis_pubsub_sampler_pattern_messages_total/jobs:*":   0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, Options{
+				Patterns:        []string{"orders.*", "*.login", "jobs:*"},
+				ChannelCounters: tt.channelCounters,
+			}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			now := time.Now()
+			s.observe("orders.created", "", now)
+			s.observe("orders.deleted", "", now)
+			s.observe("users.login", "", now)
+
+			got := gather(t, s)
+			delete(got, "redis_pubsub_sampler_active")
+			if !tt.channelCounters {
+				for k := range got {
+					if strings.HasPrefix(k, "redis_pubsub_sampler_messages_total") {
+						t.Errorf("unexpected per-channel counter %s", k)
+					}
+				}
+			}
+			for k, v := range tt.want {
+				if g, ok := got[k]; !ok || g != v {
+					t.Errorf("%s: want %v, got %v (present %v)", k, v, g, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestSamplerLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	start := time.Now()
+
+	t.Run("max channels", func(t *testing.T) {
+		s := New(nil, Options{Patterns: []string{"*"}, ChannelCounters: true, MaxChannels: 2}, logger)
+		s.start(start)
+		for _, channel := range []string{"a", "b", "c", "a"} {
+			s.observe(channel, "", start)
+		}
+		got := gather(t, s)
+		if _, ok := got["redis_pubsub_sampler_messages_total/c"]; ok {
+			t.Error("channel beyond the limit should not get a counter")
+		}
+		if got["redis_pubsub_sampler_messages_total/a"] != 2 || got["redis_pubsub_sampler_pattern_messages_total/*"] != 4 {
+			t.Errorf("unexpected counters %v", got)
+		}
+		if got["redis_pubsub_sampler_dropped_messages_total/channel_limit"] != 1 {
+			t.Errorf("want 1 channel_limit drop, got %v", got["redis_pubsub_sampler_dropped_messages_total/channel_limit"])
+		}
+		if !s.LastMessage("c").Equal(start) {
+			t.Error("an untracked channel must not look silent")
+		}
+		if got["redis_pubsub_sampler_limit/max_channels"] != 2 {
+			t.Errorf("want max_channels limit 2, got %v", got["redis_pubsub_sampler_limit/max_channels"])
+		}
+	})
+
+	t.Run("max rate", func(t *testing.T) {
+		s := New(nil, Options{Patterns: []string{"*", "b"}, ChannelCounters: true, ClassifyPayloads: true, MaxRate: 2}, logger)
+		for i := range 5 {
+			s.observe("a", "{}", start.Add(time.Duration(i)*time.Millisecond))
+		}
+		s.observe("a", "{}", start.Add(time.Second))
+
+		got := gather(t, s)
+		if got["redis_pubsub_sampler_messages_total/a"] != 3 {
+			t.Errorf("want 2 messages in the first second and 1 in the next, got %v", got["redis_pubsub_sampler_messages_total/a"])
+		}
+		if got["redis_pubsub_sampler_dropped_messages_total/rate_limit"] != 3 {
+			t.Errorf("want 3 rate_limit drops, got %v", got["redis_pubsub_sampler_dropped_messages_total/rate_limit"])
+		}
+		if got["redis_pubsub_sampler_pattern_messages_total/*"] != 6 || got["redis_pubsub_sampler_pattern_messages_total/b"] != 0 {
+			t.Errorf("pattern counters must include dropped messages, got %v", got)
+		}
+		if got["redis_pubsub_sampler_payloads_total/json/*"] != 3 {
+			t.Errorf("want only processed messages classified, got %v", got["redis_pubsub_sampler_payloads_total/json/*"])
+		}
+		if got["redis_pubsub_sampler_limit/max_rate"] != 2 {
+			t.Errorf("want max_rate limit 2, got %v", got["redis_pubsub_sampler_limit/max_rate"])
+		}
+	})
+}
+
+func TestSamplerReconnect(t *testing.T) {
+	// A server that accepts connections but never answers, like a stuck
+	// Redis: the subscription is never confirmed.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), ContextTimeoutEnabled: true, DisableIdentity: true})
+	defer func() { _ = client.Close() }()
+	s := New(client, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.retry = backoff.Backoff{Min: 10 * time.Millisecond, Max: 20 * time.Millisecond}
+	s.subscribeTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for gather(t, s)["redis_pubsub_sampler_reconnects_total"] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("sampler did not retry the unconfirmed subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := gather(t, s)
+	if got["redis_pubsub_sampler_connection_state/connected"] != 0 || got["redis_pubsub_sampler_active"] != 0 {
+		t.Errorf("sampler should not be connected, got %v", got)
+	}
+
+	cancel()
+	<-done
+	got = gather(t, s)
+	if got["redis_pubsub_sampler_connection_state/idle"] != 1 {
+		t.Errorf("want idle state after Run returns, got %v", got)
+	}
+}
+
+func TestSamplerResubscribed(t *testing.T) {
+	s := New(nil, Options{ChannelCounters: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	start := time.Now()
+	s.start(start)
+	s.observe("a", "", start)
+
+	// Further confirmations of the initial subscription change nothing.
+	s.resubscribed(&redis.Subscription{Kind: "psubscribe", Channel: "b*", Count: 2}, start.Add(time.Second))
+	if !s.ObservingSince().Equal(start) || gather(t, s)["redis_pubsub_sampler_reconnects_total"] != 0 {
+		t.Fatal("a second pattern confirmation should not count as a reconnect")
+	}
+
+	// The first confirmation on a new connection restarts observation.
+	later := start.Add(time.Minute)
+	s.resubscribed(&redis.Subscription{Kind: "psubscribe", Channel: "*", Count: 1}, later)
+	if !s.ObservingSince().Equal(later) {
+		t.Errorf("want observation to restart at %v, got %v", later, s.ObservingSince())
+	}
+	if !s.LastMessage("a").IsZero() {
+		t.Error("last-message times from before the reconnect should be dropped")
+	}
+	got := gather(t, s)
+	if got["redis_pubsub_sampler_reconnects_total"] != 1 || got["redis_pubsub_sampler_messages_total/a"] != 1 {
+		t.Errorf("want 1 reconnect and the message count kept, got %v", got)
+	}
+}
@@ -0,0 +1,215 @@
+package sentinel
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/backoff"
+)
+
+const namespace = "redis_pubsub"
+
+// Sentinel event channels the watcher subscribes to.
+const (
+	eventSwitchMaster = "+switch-master"
+	eventSDown        = "+sdown"
+	eventODown        = "+odown"
+)
+
+// Event is a parsed Sentinel pub/sub event.
+type Event struct {
+	Type         string // channel the event was published on, e.g. "+sdown"
+	InstanceType string // master, slave or sentinel ("master" for +switch-master)
+	MasterName   string // name of the affected master
+}
+
+// Watcher subscribes to Sentinel events and exports failover and
+// down-detection counters. It implements prometheus.Collector.
+type Watcher struct {
+	addrs    []string
+	password string
+	logger   *slog.Logger
+	retry    backoff.Backoff
+
+	failovers  *prometheus.CounterVec
+	sdown      *prometheus.CounterVec
+	odown      *prometheus.CounterVec
+	reconnects prometheus.Counter
+}
+
+// NewWatcher creates a Watcher for the given Sentinel addresses.
+func NewWatcher(addrs []string, password string, logger *slog.Logger) *Watcher {
+	return &Watcher{
+		addrs:    addrs,
+		password: password,
+		logger:   logger,
+		retry:    backoff.Backoff{Min: time.Second, Max: 30 * time.Second},
+
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sentinel_failovers_total",
+			Help:      "Number of +switch-master events observed from Sentinel",
+		}, []string{"master_name"}),
+		sdown: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sentinel_sdown_total",
+			Help:      "Number of +sdown (subjectively down) events observed from Sentinel",
+		}, []string{"master_name", "instance_type"}),
+		odown: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sentinel_odown_total",
+			Help:      "Number of +odown (objectively down) events observed from Sentinel",
+		}, []string{"master_name"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sentinel_reconnects_total",
+			Help:      "Number of times the Sentinel event subscription was re-established after it failed or broke",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (w *Watcher) Describe(ch chan<- *prometheus.Desc) {
+	w.failovers.Describe(ch)
+	w.sdown.Describe(ch)
+	w.odown.Describe(ch)
+	w.reconnects.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (w *Watcher) Collect(ch chan<- prometheus.Metric) {
+	w.failovers.Collect(ch)
+	w.sdown.Collect(ch)
+	w.odown.Collect(ch)
+	w.reconnects.Collect(ch)
+}
+
+// Run subscribes to Sentinel events until ctx is cancelled. Sentinels are
+// tried in order, with jittered exponential backoff in between; events are
+// consumed from a single Sentinel at a time so that each failover is counted
+// once.
+func (w *Watcher) Run(ctx context.Context) {
+	for i := 0; ctx.Err() == nil; i = (i + 1) % len(w.addrs) {
+		if w.watch(ctx, w.addrs[i]) {
+			w.retry.Reset()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.retry.Next()):
+			w.reconnects.Inc()
+		}
+	}
+}
+
+// watch consumes events from one Sentinel until the subscription breaks or
+// ctx is cancelled. It reports whether the subscription was confirmed.
+func (w *Watcher) watch(ctx context.Context, addr string) bool {
+	client := redis.NewSentinelClient(&redis.Options{
+		Addr:        addr,
+		Password:    w.password,
+		DialTimeout: 5 * time.Second,
+	})
+	defer func() { _ = client.Close() }()
+
+	pubsub := client.Subscribe(ctx, eventSwitchMaster, eventSDown, eventODown)
+	defer func() { _ = pubsub.Close() }()
+
+	// Wait for the subscription confirmation so connection errors surface here.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() == nil {
+			w.logger.Warn("sentinel subscribe failed", "sentinel", addr, "error", err)
+		}
+		return false
+	}
+	w.logger.Info("watching sentinel events", "sentinel", addr)
+
+	msgs := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case msg, ok := <-msgs:
+			if !ok {
+				return true
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// go-redis reconnects a broken subscription by itself and
+				// resubscribes; the first confirmation on the new
+				// connection has Count 1.
+				if msg.Count == 1 {
+					w.logger.Info("resubscribed to sentinel events", "sentinel", addr)
+					w.reconnects.Inc()
+				}
+			case *redis.Message:
+				w.handle(msg)
+			}
+		}
+	}
+}
+
+func (w *Watcher) handle(msg *redis.Message) {
+	ev, ok := ParseEvent(msg.Channel, msg.Payload)
+	if !ok {
+		w.logger.Warn("unparseable sentinel event", "channel", msg.Channel, "payload", msg.Payload)
+		return
+	}
+	w.logger.Info("sentinel event", "event", ev.Type, "master_name", ev.MasterName, "instance_type", ev.InstanceType)
+	w.record(ev)
+}
+
+func (w *Watcher) record(ev Event) {
+	switch ev.Type {
+	case eventSwitchMaster:
+		w.failovers.WithLabelValues(ev.MasterName).Inc()
+	case eventSDown:
+		w.sdown.WithLabelValues(ev.MasterName, ev.InstanceType).Inc()
+	case eventODown:
+		w.odown.WithLabelValues(ev.MasterName).Inc()
+	}
+}
+
+// ParseEvent parses a Sentinel event payload.
+//
+// Payload formats:
+//
+//	+switch-master <master-name> <old-ip> <old-port> <new-ip> <new-port>
+//	+sdown master <master-name> <ip> <port>
+//	+sdown slave <ip>:<port> <ip> <port> @ <master-name> <master-ip> <master-port>
+func ParseEvent(channel, payload string) (Event, bool) {
+	fields := strings.Fields(payload)
+
+	switch channel {
+	case eventSwitchMaster:
+		if len(fields) < 1 {
+			return Event{}, false
+		}
+		return Event{Type: channel, InstanceType: "master", MasterName: fields[0]}, true
+
+	case eventSDown, eventODown:
+		if len(fields) < 2 {
+			return Event{}, false
+		}
+		ev := Event{Type: channel, InstanceType: fields[0]}
+		if ev.InstanceType == "master" {
+			ev.MasterName = fields[1]
+			return ev, true
+		}
+		for i, f := range fields {
+			if f == "@" && i+1 < len(fields) {
+				ev.MasterName = fields[i+1]
+				return ev, true
+			}
+		}
+		return Event{}, false
+	}
+
+	return Event{}, false
+}
@@ -0,0 +1,76 @@
+package sentinel
+
+import "testing"
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		payload string
+		want    Event
+		wantOK  bool
+	}{
+		{
+			name:    "switch-master",
+			channel: "+switch-master",
+			payload: "mymaster 10.0.0.1 6379 10.0.0.2 6379",
+			want:    Event{Type: "+switch-master", InstanceType: "master", MasterName: "mymaster"},
+			wantOK:  true,
+		},
+		{
+			name:    "sdown on master",
+			channel: "+sdown",
+			payload: "master mymaster 10.0.0.1 6379",
+			want:    Event{Type: "+sdown", InstanceType: "master", MasterName: "mymaster"},
+			wantOK:  true,
+		},
+		{
+			name:    "sdown on replica",
+			channel: "+sdown",
+			payload: "slave 10.0.0.3:6379 10.0.0.3 6379 @ mymaster 10.0.0.1 6379",
+			want:    Event{Type: "+sdown", InstanceType: "slave", MasterName: "mymaster"},
+			wantOK:  true,
+		},
+		{
+			name:    "odown with quorum suffix",
+			channel: "+odown",
+			payload: "master mymaster 10.0.0.1 6379 #quorum 2/2",
+			want:    Event{Type: "+odown", InstanceType: "master", MasterName: "mymaster"},
+			wantOK:  true,
+		},
+		{
+			name:    "sdown on sentinel",
+			channel: "+sdown",
+			payload: "sentinel 10.0.0.9:26379 10.0.0.9 26379 @ payments 10.0.0.1 6379",
+			want:    Event{Type: "+sdown", InstanceType: "sentinel", MasterName: "payments"},
+			wantOK:  true,
+		},
+		{
+			name:    "replica event without master part",
+			channel: "+sdown",
+			payload: "slave 10.0.0.3:6379 10.0.0.3 6379",
+		},
+		{
+			name:    "empty payload",
+			channel: "+switch-master",
+			payload: "",
+		},
+		{
+			name:    "unknown channel",
+			channel: "+tilt",
+			payload: "#tilt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseEvent(tt.channel, tt.payload)
+			if ok != tt.wantOK {
+				t.Fatalf("ok: want %v, got %v", tt.wantOK, ok)
+			}
+			if got != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
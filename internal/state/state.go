@@ -0,0 +1,117 @@
+// Package state persists small pieces of exporter state, such as cumulative
+// counters, across restarts in a local file or a Redis key.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backend stores one blob of state.
+type Backend interface {
+	// Load returns the saved state, or nil if nothing has been saved yet.
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+}
+
+// File is a Backend that keeps state in a local file.
+type File string
+
+// Load implements Backend.
+func (f File) Load(context.Context) ([]byte, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save implements Backend. The file is replaced atomically so a crash while
+// saving never leaves a truncated file behind.
+func (f File) Save(_ context.Context, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(string(f)), filepath.Base(string(f))+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), string(f))
+}
+
+// KeyValue is the subset of the go-redis client API used by RedisKey.
+type KeyValue interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+}
+
+// RedisKey is a Backend that keeps state in a Redis string key.
+type RedisKey struct {
+	Client KeyValue
+	Key    string
+}
+
+// Load implements Backend.
+func (r RedisKey) Load(ctx context.Context) ([]byte, error) {
+	data, err := r.Client.Get(ctx, r.Key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save implements Backend.
+func (r RedisKey) Save(ctx context.Context, data []byte) error {
+	return r.Client.Set(ctx, r.Key, data, 0).Err()
+}
+
+// Load decodes the saved state into v. It reports false if nothing has been
+// saved yet.
+func Load(ctx context.Context, b Backend, v any) (bool, error) {
+	data, err := b.Load(ctx)
+	if err != nil || data == nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// Save encodes v as JSON and stores it.
+func Save(ctx context.Context, b Backend, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Save(ctx, data)
+}
+
+// Run saves snapshot() every interval until ctx is done. Failures are logged
+// and retried on the next tick; the caller saves a final time on shutdown.
+func Run(ctx context.Context, b Backend, interval time.Duration, snapshot func() any, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		saveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err := Save(saveCtx, b, snapshot()); err != nil {
+			logger.Warn("failed to save state", "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeKeyValue map[string]string
+
+func (f fakeKeyValue) Get(_ context.Context, key string) *redis.StringCmd {
+	v, ok := f[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (f fakeKeyValue) Set(_ context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+	f[key] = string(value.([]byte))
+	return redis.NewStatusResult("OK", nil)
+}
+
+type counters struct {
+	Errors float64 `json:"errors"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend Backend
+	}{
+		{name: "file", backend: File(filepath.Join(t.TempDir(), "state.json"))},
+		{name: "redis key", backend: RedisKey{Client: fakeKeyValue{}, Key: "exporter:state"}},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got counters
+			ok, err := Load(ctx, tt.backend, &got)
+			if err != nil || ok {
+				t.Fatalf("want nothing saved yet, got ok=%v err=%v", ok, err)
+			}
+
+			if err := Save(ctx, tt.backend, counters{Errors: 3}); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			ok, err = Load(ctx, tt.backend, &got)
+			if err != nil || !ok {
+				t.Fatalf("load: ok=%v err=%v", ok, err)
+			}
+			if got.Errors != 3 {
+				t.Errorf("want 3 errors, got %v", got.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// invalidLabelChars matches characters not allowed in Prometheus label names.
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// ConsulSource discovers targets from the healthy instances of a Consul
+// service. Service metadata becomes target labels.
+type ConsulSource struct {
+	Addr    string // Consul HTTP address, e.g. http://127.0.0.1:8500
+	Service string
+	Tag     string // optional tag filter
+	Token   string // optional ACL token
+	Client  *http.Client
+}
+
+// consulEntry is the subset of /v1/health/service/<service> used here.
+type consulEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+// Targets implements Source.
+func (s *ConsulSource) Targets(ctx context.Context) ([]Target, error) {
+	u, err := url.Parse(s.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("consul address: %w", err)
+	}
+	u = u.JoinPath("/v1/health/service", s.Service)
+	q := url.Values{"passing": {"true"}}
+	if s.Tag != "" {
+		q.Set("tag", s.Tag)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Consul-Token", s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul query: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul query: unexpected status %s", resp.Status)
+	}
+
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("consul response: %w", err)
+	}
+
+	targets := make([]Target, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		port := strconv.Itoa(e.Service.Port)
+		if e.Service.Port == 0 {
+			port = DefaultPort
+		}
+
+		labels := map[string]string{"consul_node": e.Node.Node}
+		for k, v := range e.Service.Meta {
+			labels[consulLabelName(k)] = v
+		}
+		targets = append(targets, Target{Addr: net.JoinHostPort(host, port), Labels: labels})
+	}
+	return targets, nil
+}
+
+// consulLabelName converts a service meta key into a valid label name.
+func consulLabelName(key string) string {
+	name := invalidLabelChars.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return strings.ToLower(name)
+}
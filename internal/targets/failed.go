@@ -0,0 +1,65 @@
+package targets
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+// newFailedScraper returns the Scraper of a target whose factory failed,
+// e.g. because its password file is unreadable. It scrapes like any other
+// target but every command fails with err, so the target reports redis_up 0,
+// counts scrape errors and shows err in its status rather than vanishing.
+func newFailedScraper(err error, logger *slog.Logger) Scraper {
+	return collector.New(failedQuerier{err: err}, collector.Options{MaxChannels: 1}, logger)
+}
+
+// failedQuerier is a collector.RedisQuerier whose every command fails.
+type failedQuerier struct {
+	err error
+}
+
+func (q failedQuerier) Ping(context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("", q.err)
+}
+
+func (q failedQuerier) InfoMap(ctx context.Context, _ ...string) *redis.InfoCmd {
+	cmd := redis.NewInfoCmd(ctx)
+	cmd.SetErr(q.err)
+	return cmd
+}
+
+func (q failedQuerier) PubSubChannels(context.Context, string) *redis.StringSliceCmd {
+	return redis.NewStringSliceResult(nil, q.err)
+}
+
+func (q failedQuerier) PubSubChannelsMulti(ctx context.Context, patterns ...string) []*redis.StringSliceCmd {
+	cmds := make([]*redis.StringSliceCmd, len(patterns))
+	for i, p := range patterns {
+		cmds[i] = q.PubSubChannels(ctx, p)
+	}
+	return cmds
+}
+
+func (q failedQuerier) PubSubNumSub(context.Context, ...string) *redis.MapStringIntCmd {
+	return redis.NewMapStringIntCmdResult(nil, q.err)
+}
+
+func (q failedQuerier) PubSubNumPat(context.Context) *redis.IntCmd {
+	return redis.NewIntResult(0, q.err)
+}
+
+func (q failedQuerier) ClientList(context.Context) *redis.StringCmd {
+	return redis.NewStringResult("", q.err)
+}
+
+func (q failedQuerier) HGetAll(context.Context, string) *redis.MapStringStringCmd {
+	return redis.NewMapStringStringResult(nil, q.err)
+}
+
+func (q failedQuerier) ClusterInfo(context.Context) *redis.StringCmd {
+	return redis.NewStringResult("", q.err)
+}
@@ -0,0 +1,87 @@
+package targets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// fileGroup is one entry of a targets file. The format matches Prometheus
+// file_sd, so the same file can be shared with other tooling:
+//
+//	# targets.yml
+//	- targets: ["redis-a:6379", "redis-b:6379"]
+//	  labels:
+//	    env: prod
+//
+// An optional "redis" block overrides connection and collector settings for
+// the targets of the group (the file is then no longer valid file_sd):
+//
+//	# targets.yml
+//	- targets: ["redis-orders:6380"]
+//	  redis:
+//	    password_file: /etc/redis/orders-password
+//	    tls: true
+//	    db: 2
+//	    known_patterns: ["orders.*"]
+type fileGroup struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+	Redis   Overrides         `yaml:"redis"`
+}
+
+// FileSource reads targets from a YAML or JSON file. The file is re-parsed
+// only when its content changes.
+type FileSource struct {
+	Path string
+
+	mu      sync.Mutex
+	raw     []byte
+	targets []Target
+}
+
+// Targets implements Source.
+func (s *FileSource) Targets(context.Context) ([]Target, error) {
+	raw, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read targets file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.targets != nil && bytes.Equal(raw, s.raw) {
+		return s.targets, nil
+	}
+
+	targets, err := ParseFile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse targets file %s: %w", s.Path, err)
+	}
+	s.raw = raw
+	s.targets = targets
+	return targets, nil
+}
+
+// ParseFile parses the content of a targets file.
+func ParseFile(raw []byte) ([]Target, error) {
+	var groups []fileGroup
+	if err := yaml.UnmarshalStrict(raw, &groups); err != nil {
+		return nil, err
+	}
+
+	targets := []Target{}
+	for i, g := range groups {
+		for _, addr := range g.Targets {
+			norm, err := NormalizeAddr(addr)
+			if err != nil {
+				return nil, fmt.Errorf("group %d: %w", i, err)
+			}
+			targets = append(targets, Target{Addr: norm, Labels: g.Labels, Overrides: g.Redis})
+		}
+	}
+	return targets, nil
+}
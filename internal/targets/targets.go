@@ -0,0 +1,320 @@
+// Package targets implements multi-target mode: a dynamic set of Redis
+// instances discovered from a file or Consul, each scraped by its own
+// collector and labelled with the target address and per-target labels.
+package targets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+// DefaultPort is used for targets listed without a port.
+const DefaultPort = "6379"
+
+// Target is a single Redis instance to scrape.
+type Target struct {
+	Addr      string            // host:port
+	Labels    map[string]string // extra labels attached to every metric of this target
+	Overrides Overrides         // per-target settings; zero values inherit the global config
+}
+
+// Overrides holds per-target connection and collector settings for
+// heterogeneous targets. Nil pointers and empty values mean "use the global
+// setting".
+type Overrides struct {
+	Password      string   `yaml:"password"`
+	PasswordFile  string   `yaml:"password_file"` // read when the target is created
+	TLS           *bool    `yaml:"tls"`
+	DB            *int     `yaml:"db"`
+	KnownPatterns []string `yaml:"known_patterns"`
+}
+
+// key identifies a target by address, labels and overrides; a change to any
+// of them re-creates it.
+func (t Target) key() string {
+	var b strings.Builder
+	b.WriteString(t.Addr)
+	for _, k := range slices.Sorted(maps.Keys(t.Labels)) {
+		b.WriteString("|" + k + "=" + t.Labels[k])
+	}
+	o := t.Overrides
+	b.WriteString("|" + o.Password + "|" + o.PasswordFile + "|" + strings.Join(o.KnownPatterns, ","))
+	if o.TLS != nil {
+		fmt.Fprintf(&b, "|tls=%t", *o.TLS)
+	}
+	if o.DB != nil {
+		fmt.Fprintf(&b, "|db=%d", *o.DB)
+	}
+	return b.String()
+}
+
+// Source discovers the current target list.
+type Source interface {
+	Targets(ctx context.Context) ([]Target, error)
+}
+
+// Scraper is the per-target collector created by a Factory.
+type Scraper interface {
+	collector.ContextCollector
+	IsRedisUp() bool
+	Status() collector.Status
+	History() []collector.Snapshot
+}
+
+// Status is the scrape status of one target.
+type Status struct {
+	Addr   string            `json:"addr"`
+	Labels map[string]string `json:"labels,omitempty"`
+	collector.Status
+}
+
+// History is the recent scrape history of one target.
+type History struct {
+	Addr    string               `json:"addr"`
+	Labels  map[string]string    `json:"labels,omitempty"`
+	Scrapes []collector.Snapshot `json:"scrapes"`
+}
+
+// Factory creates a Scraper for a target. The returned func releases its
+// resources (e.g. closes the Redis client) once the target is removed.
+type Factory func(t Target) (Scraper, func(), error)
+
+type entry struct {
+	target  Target
+	scraper Scraper
+	close   func()
+	failed  bool // the factory failed; scraper reports the error and creation is retried
+}
+
+// Manager keeps one Scraper per discovered target, adding and removing them
+// as the Source changes. It implements prometheus.Gatherer: every Gather
+// collects all current targets with their labels attached.
+type Manager struct {
+	source   Source
+	factory  Factory
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu         sync.RWMutex
+	active     map[string]*entry
+	labelNames []string // union of target label names, sorted
+}
+
+// NewManager creates a Manager that re-reads source every interval.
+func NewManager(source Source, factory Factory, interval time.Duration, logger *slog.Logger) *Manager {
+	return &Manager{
+		source:   source,
+		factory:  factory,
+		interval: interval,
+		logger:   logger,
+		active:   make(map[string]*entry),
+	}
+}
+
+// Run syncs targets until ctx is cancelled, then releases all targets.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Sync(ctx); err != nil {
+			m.logger.Error("target discovery failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			m.closeAll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reads the source once and reconciles the active targets. On a
+// discovery error the current targets are kept.
+func (m *Manager) Sync(ctx context.Context) error {
+	discovered, err := m.source.Targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]Target, len(discovered))
+	names := make(map[string]struct{})
+	for _, t := range discovered {
+		want[t.key()] = t
+		for k := range t.Labels {
+			names[k] = struct{}{}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.labelNames = slices.Sorted(maps.Keys(names))
+
+	for key, e := range m.active {
+		if _, ok := want[key]; ok {
+			continue
+		}
+		e.close()
+		delete(m.active, key)
+		m.logger.Info("target removed", "target", e.target.Addr)
+	}
+
+	for key, t := range want {
+		e, ok := m.active[key]
+		if ok && !e.failed {
+			continue
+		}
+		scraper, closeFn, err := m.factory(t)
+		if err != nil {
+			m.logger.Error("failed to create target", "target", t.Addr, "error", err)
+			if !ok {
+				// Keep the target visible as down instead of dropping it.
+				m.active[key] = &entry{target: t, scraper: newFailedScraper(err, m.logger.With("target", t.Addr)), close: func() {}, failed: true}
+			}
+			continue
+		}
+		m.active[key] = &entry{target: t, scraper: scraper, close: closeFn}
+		m.logger.Info("target added", "target", t.Addr, "labels", t.Labels)
+	}
+	return nil
+}
+
+// Gather implements prometheus.Gatherer. Targets are registered into a
+// fresh registry on every call: Prometheus requires all series of a metric to
+// share label names, so each target gets the union of all targets' label
+// names (missing ones empty), and that union may change between scrapes.
+func (m *Manager) Gather() ([]*dto.MetricFamily, error) {
+	return m.GatherContext(context.Background())
+}
+
+// GatherContext is Gather with every target scraped bound to ctx, so a
+// cancelled /metrics request aborts the Redis commands still in flight.
+func (m *Manager) GatherContext(ctx context.Context) ([]*dto.MetricFamily, error) {
+	m.mu.RLock()
+	reg := prometheus.NewRegistry()
+	for _, e := range m.active {
+		wrapped := prometheus.WrapRegistererWith(m.targetLabels(e.target), reg)
+		if err := wrapped.Register(collector.WithContext(ctx, e.scraper)); err != nil {
+			m.logger.Error("failed to register target", "target", e.target.Addr, "error", err)
+		}
+	}
+	m.mu.RUnlock()
+
+	return reg.Gather()
+}
+
+// targetLabels returns the const labels for t over the current label union.
+func (m *Manager) targetLabels(t Target) prometheus.Labels {
+	labels := prometheus.Labels{"target": t.Addr}
+	for _, name := range m.labelNames {
+		labels[name] = t.Labels[name]
+	}
+	return labels
+}
+
+// Targets returns the currently registered targets, sorted by address.
+func (m *Manager) Targets() []Target {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Target, 0, len(m.active))
+	for _, e := range m.active {
+		out = append(out, e.target)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key() < out[j].key() })
+	return out
+}
+
+// Statuses returns the scrape status of every registered target, sorted by
+// address.
+func (m *Manager) Statuses() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Status, 0, len(m.active))
+	for _, e := range m.active {
+		out = append(out, Status{Addr: e.target.Addr, Labels: e.target.Labels, Status: e.scraper.Status()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// Histories returns the recent scrape history of every registered target,
+// sorted by address.
+func (m *Manager) Histories() []History {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]History, 0, len(m.active))
+	for _, e := range m.active {
+		out = append(out, History{Addr: e.target.Addr, Labels: e.target.Labels, Scrapes: e.scraper.History()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// IsRedisUp reports whether at least one target was reachable on its last scrape.
+func (m *Manager) IsRedisUp() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, e := range m.active {
+		if e.scraper.IsRedisUp() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, e := range m.active {
+		e.close()
+		delete(m.active, key)
+	}
+}
+
+// NormalizeAddr trims addr and adds the default Redis port if it has none.
+// IPv6 literals may be given bare ("::1") or bracketed ("[::1]",
+// "[::1]:6379"); the result is always a valid host:port.
+func NormalizeAddr(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", fmt.Errorf("empty target address")
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// No port: a host name or an IP literal.
+		host, port = addr, DefaultPort
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1]
+		}
+	}
+	if host == "" || strings.ContainsAny(host, "[]/ ") {
+		return "", fmt.Errorf("invalid target address %q", addr)
+	}
+	if strings.Contains(host, ":") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", fmt.Errorf("invalid target address %q: %q is not an IPv6 address", addr, host)
+		}
+	}
+	if port == "" {
+		port = DefaultPort
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port in %q", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
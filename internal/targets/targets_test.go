@@ -0,0 +1,403 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/redis-pubsub-exporter/internal/collector"
+)
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name: "yaml with labels",
+			input: `
+- targets: ["redis-a:6379", "redis-b"]
+  labels:
+    env: prod
+- targets: ["10.0.0.5:6380"]
+`,
+			want: []Target{
+				{Addr: "redis-a:6379", Labels: map[string]string{"env": "prod"}},
+				{Addr: "redis-b:6379", Labels: map[string]string{"env": "prod"}},
+				{Addr: "10.0.0.5:6380"},
+			},
+		},
+		{
+			name:  "json file_sd format",
+			input: `[{"targets": ["redis-a:6379"], "labels": {"team": "payments"}}]`,
+			want:  []Target{{Addr: "redis-a:6379", Labels: map[string]string{"team": "payments"}}},
+		},
+		{
+			name: "per-target overrides",
+			input: `
+- targets: ["redis-orders:6380"]
+  redis:
+    password_file: /etc/redis/orders
+    tls: true
+    db: 2
+    known_patterns: ["orders.*"]
+`,
+			want: []Target{{Addr: "redis-orders:6380", Overrides: Overrides{
+				PasswordFile:  "/etc/redis/orders",
+				TLS:           boolPtr(true),
+				DB:            intPtr(2),
+				KnownPatterns: []string{"orders.*"},
+			}}},
+		},
+		{
+			name:    "unknown override is rejected",
+			input:   `[{"targets": ["a"], "redis": {"passwd": "x"}}]`,
+			wantErr: true,
+		},
+		{
+			name:  "ipv6 without port",
+			input: `[{"targets": ["[::1]"]}]`,
+			want:  []Target{{Addr: "[::1]:6379"}},
+		},
+		{
+			name:  "empty list",
+			input: `[]`,
+			want:  []Target{},
+		},
+		{
+			name:    "unknown field is rejected",
+			input:   `[{"targets": ["a"], "lables": {"x": "y"}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "empty address is rejected",
+			input:   `[{"targets": [""]}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFile([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTargets(t, got, tt.want)
+		})
+	}
+}
+
+func TestNormalizeAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{addr: "redis", want: "redis:6379"},
+		{addr: " redis:6380 ", want: "redis:6380"},
+		{addr: "10.0.0.5", want: "10.0.0.5:6379"},
+		{addr: "::1", want: "[::1]:6379"},
+		{addr: "[::1]", want: "[::1]:6379"},
+		{addr: "[2001:db8::1]:6380", want: "[2001:db8::1]:6380"},
+		{addr: "fe80::1%eth0", want: "[fe80::1%eth0]:6379"},
+		{addr: "redis:", want: "redis:6379"},
+		{addr: "", wantErr: true},
+		{addr: "[::1", wantErr: true},
+		{addr: "redis:6379:1", wantErr: true},
+		{addr: "redis:0", wantErr: true},
+		{addr: "redis:http", wantErr: true},
+		{addr: "[redis:6379]:6380", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := NormalizeAddr(tt.addr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileSourceRereadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.yml")
+	writeFile(t, path, `[{"targets": ["redis-a"]}]`)
+	src := &FileSource{Path: path}
+
+	got, err := src.Targets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertTargets(t, got, []Target{{Addr: "redis-a:6379"}})
+
+	writeFile(t, path, `[{"targets": ["redis-a", "redis-b"]}]`)
+	got, err = src.Targets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertTargets(t, got, []Target{{Addr: "redis-a:6379"}, {Addr: "redis-b:6379"}})
+
+	writeFile(t, path, `not: [valid`)
+	if _, err := src.Targets(context.Background()); err == nil {
+		t.Error("expected error for invalid file")
+	}
+}
+
+func TestConsulSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/redis" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("passing") != "true" || r.URL.Query().Get("tag") != "pubsub" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			t.Errorf("missing consul token")
+		}
+		_, _ = io.WriteString(w, `[
+			{"Node": {"Node": "node-1", "Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 6379, "Meta": {"redis-role": "primary"}}},
+			{"Node": {"Node": "node-2", "Address": "10.0.0.2"}, "Service": {"Address": "10.1.0.2", "Port": 6380}}
+		]`)
+	}))
+	defer srv.Close()
+
+	src := &ConsulSource{Addr: srv.URL, Service: "redis", Tag: "pubsub", Token: "secret"}
+	got, err := src.Targets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertTargets(t, got, []Target{
+		{Addr: "10.0.0.1:6379", Labels: map[string]string{"consul_node": "node-1", "redis_role": "primary"}},
+		{Addr: "10.1.0.2:6380", Labels: map[string]string{"consul_node": "node-2"}},
+	})
+}
+
+// staticSource returns a fixed, mutable target list.
+type staticSource struct{ targets []Target }
+
+func (s *staticSource) Targets(context.Context) ([]Target, error) { return s.targets, nil }
+
+// fakeScraper emits one gauge so registration can be observed.
+type fakeScraper struct {
+	desc *prometheus.Desc
+	up   bool
+}
+
+func (f *fakeScraper) Describe(ch chan<- *prometheus.Desc) { ch <- f.desc }
+func (f *fakeScraper) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue, 1)
+}
+func (f *fakeScraper) CollectContext(_ context.Context, ch chan<- prometheus.Metric) {
+	f.Collect(ch)
+}
+func (f *fakeScraper) IsRedisUp() bool { return f.up }
+func (f *fakeScraper) Status() collector.Status {
+	return collector.Status{Ready: f.up}
+}
+func (f *fakeScraper) History() []collector.Snapshot {
+	return []collector.Snapshot{{Up: f.up}}
+}
+
+func TestManagerSync(t *testing.T) {
+	src := &staticSource{targets: []Target{
+		{Addr: "redis-a:6379", Labels: map[string]string{"env": "prod"}},
+		{Addr: "redis-b:6379"},
+	}}
+	closed := make(map[string]bool)
+	factory := func(tg Target) (Scraper, func(), error) {
+		s := &fakeScraper{
+			desc: prometheus.NewDesc("redis_pubsub_exporter_redis_up", "test", nil, nil),
+			up:   tg.Addr == "redis-b:6379",
+		}
+		return s, func() { closed[tg.Addr] = true }, nil
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewManager(src, factory, time.Minute, logger)
+
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := countSeries(t, m); got != 2 {
+		t.Fatalf("want 2 series, got %d", got)
+	}
+	if !m.IsRedisUp() {
+		t.Error("manager should be up while one target is up")
+	}
+	if got := m.Statuses(); len(got) != 2 || got[0].Addr != "redis-a:6379" || got[0].Ready || !got[1].Ready {
+		t.Errorf("unexpected statuses %+v", got)
+	}
+	if got := m.Histories(); len(got) != 2 || got[1].Addr != "redis-b:6379" || !got[1].Scrapes[0].Up {
+		t.Errorf("unexpected histories %+v", got)
+	}
+
+	src.targets = src.targets[:1]
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := countSeries(t, m); got != 1 {
+		t.Fatalf("want 1 series after removal, got %d", got)
+	}
+	if !closed["redis-b:6379"] {
+		t.Error("removed target should be closed")
+	}
+	if m.IsRedisUp() {
+		t.Error("manager should be down when no target is up")
+	}
+	if got := m.Targets(); len(got) != 1 || got[0].Addr != "redis-a:6379" {
+		t.Errorf("unexpected targets %v", got)
+	}
+
+	// A new label name widens the label set of all targets.
+	src.targets = append(src.targets, Target{Addr: "redis-c:6379", Labels: map[string]string{"team": "payments"}})
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := countSeries(t, m); got != 2 {
+		t.Fatalf("want 2 series after relabel, got %d", got)
+	}
+	if closed["redis-a:6379"] {
+		t.Error("kept target must not be closed on relabel")
+	}
+}
+
+func TestManagerFailedTarget(t *testing.T) {
+	src := &staticSource{targets: []Target{{Addr: "redis-a:6379"}, {Addr: "redis-b:6379"}}}
+	broken := true
+	factory := func(tg Target) (Scraper, func(), error) {
+		if tg.Addr == "redis-b:6379" && broken {
+			return nil, nil, errors.New("read password file: permission denied")
+		}
+		return &fakeScraper{desc: prometheus.NewDesc("redis_pubsub_exporter_redis_up", "Whether Redis is reachable (1=up, 0=down)", nil, nil), up: true}, func() {}, nil
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewManager(src, factory, time.Minute, logger)
+
+	for i := 0; i < 2; i++ {
+		if err := m.Sync(context.Background()); err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+	}
+	got := gatherByTarget(t, m)
+	if got["redis_pubsub_exporter_redis_up"]["redis-a:6379"] != 1 || got["redis_pubsub_exporter_redis_up"]["redis-b:6379"] != 0 {
+		t.Errorf("want redis_up 1 for redis-a and 0 for redis-b, got %v", got["redis_pubsub_exporter_redis_up"])
+	}
+	if got["redis_pubsub_exporter_scrape_errors_total"]["redis-b:6379"] != 1 {
+		t.Errorf("failed target should count scrape errors, got %v", got["redis_pubsub_exporter_scrape_errors_total"])
+	}
+	if _, ok := got["redis_pubsub_exporter_scrape_duration_seconds"]["redis-b:6379"]; !ok {
+		t.Error("failed target should report its scrape duration")
+	}
+	if st := m.Statuses(); len(st) != 2 || st[1].LastError != "PING: read password file: permission denied" {
+		t.Errorf("failed target should report its error, got %+v", st)
+	}
+
+	// Creation is retried on the next sync.
+	broken = false
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := gatherByTarget(t, m)["redis_pubsub_exporter_redis_up"]["redis-b:6379"]; got != 1 {
+		t.Errorf("recovered target should be up, got %v", got)
+	}
+}
+
+// gatherByTarget returns the gauge and counter values per metric name and
+// target label.
+func gatherByTarget(t *testing.T, g prometheus.Gatherer) map[string]map[string]float64 {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	out := make(map[string]map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var target string
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "target" {
+					target = lp.GetValue()
+				}
+			}
+			if out[mf.GetName()] == nil {
+				out[mf.GetName()] = make(map[string]float64)
+			}
+			out[mf.GetName()][target] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+	}
+	return out
+}
+
+func countSeries(t *testing.T, g prometheus.Gatherer) int {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	n := 0
+	for _, mf := range families {
+		n += len(mf.GetMetric())
+	}
+	return n
+}
+
+func assertTargets(t *testing.T, got, want []Target) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("want %d targets, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i].Addr != want[i].Addr {
+			t.Errorf("target %d addr: want %q, got %q", i, want[i].Addr, got[i].Addr)
+		}
+		if len(got[i].Labels) != len(want[i].Labels) {
+			t.Errorf("target %d labels: want %v, got %v", i, want[i].Labels, got[i].Labels)
+			continue
+		}
+		for k, v := range want[i].Labels {
+			if got[i].Labels[k] != v {
+				t.Errorf("target %d label %s: want %q, got %q", i, k, v, got[i].Labels[k])
+			}
+		}
+		if got[i].key() != want[i].key() {
+			t.Errorf("target %d overrides: want %+v, got %+v", i, want[i].Overrides, got[i].Overrides)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+func intPtr(i int) *int    { return &i }
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package tlsfiles loads the Redis client certificate, key and CA bundle
+// from files and reloads them whenever they change, so a certificate rotated
+// by cert-manager is picked up without a restart.
+package tlsfiles
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Files names the PEM files to load. Cert and Key go together; each part is
+// optional.
+type Files struct {
+	Cert string
+	Key  string
+	CA   string
+}
+
+// Source holds the TLS material last loaded from Files.
+type Source struct {
+	files  Files
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	raw  [][]byte // content last loaded, in cert, key, CA order
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// New returns a Source for files. Call Load before using it.
+func New(files Files, logger *slog.Logger) *Source {
+	return &Source{files: files, logger: logger}
+}
+
+// Load reads the files and replaces the TLS material if they changed. It
+// reports whether they did; invalid material keeps the previous one.
+func (s *Source) Load() (bool, error) {
+	raw := make([][]byte, 0, 3)
+	for _, path := range []string{s.files.Cert, s.files.Key, s.files.CA} {
+		var data []byte
+		if path != "" {
+			var err error
+			if data, err = os.ReadFile(path); err != nil {
+				return false, fmt.Errorf("read TLS file: %w", err)
+			}
+		}
+		raw = append(raw, data)
+	}
+
+	s.mu.Lock()
+	unchanged := s.raw != nil && slices.EqualFunc(raw, s.raw, bytes.Equal)
+	s.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if s.files.Cert != "" {
+		c, err := tls.X509KeyPair(raw[0], raw[1])
+		if err != nil {
+			return false, fmt.Errorf("TLS client certificate: %w", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if s.files.CA != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw[2]) {
+			return false, errors.New("TLS CA: no certificates found")
+		}
+	}
+
+	s.mu.Lock()
+	s.raw, s.cert, s.pool = raw, cert, pool
+	s.mu.Unlock()
+	s.logger.Info("redis TLS material loaded", "cert", s.files.Cert, "ca", s.files.CA)
+	return true, nil
+}
+
+// TLSConfig returns base extended with the current TLS material.
+func (s *Source) TLSConfig(base *tls.Config) *tls.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg := base.Clone()
+	if s.cert != nil {
+		cfg.Certificates = []tls.Certificate{*s.cert}
+	}
+	if s.pool != nil {
+		cfg.RootCAs = s.pool
+	}
+	return cfg
+}
+
+// Run watches the directories of the files, which also catches the symlink
+// swap Kubernetes uses to update Secret volumes, and reloads on every change
+// until ctx is done. onChange is called after new material was loaded, e.g.
+// to close connections set up with the old one. Invalid files keep the
+// previous material.
+func (s *Source) Run(ctx context.Context, onChange func()) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		s.logger.Error("TLS file watch failed", "error", err)
+		return
+	}
+	defer func() { _ = fw.Close() }()
+	for _, path := range []string{s.files.Cert, s.files.Key, s.files.CA} {
+		if path == "" || slices.Contains(fw.WatchList(), filepath.Dir(path)) {
+			continue
+		}
+		if err := fw.Add(filepath.Dir(path)); err != nil {
+			s.logger.Error("TLS file watch failed", "file", path, "error", err)
+			return
+		}
+	}
+
+	// A rotation rewrites the certificate and key in quick succession; reload
+	// once both are written.
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warn("TLS file watch error", "error", err)
+		case _, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			settle = time.After(100 * time.Millisecond)
+		case <-settle:
+			settle = nil
+			changed, err := s.Load()
+			if err != nil {
+				s.logger.Error("TLS file reload failed, keeping previous material", "error", err)
+				continue
+			}
+			if changed && onChange != nil {
+				onChange()
+			}
+		}
+	}
+}
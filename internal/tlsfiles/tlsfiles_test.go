@@ -0,0 +1,163 @@
+package tlsfiles
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a PEM certificate and key with the given common name.
+func selfSigned(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commonName returns the subject of the client certificate in cfg.
+func commonName(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("want 1 client certificate, got %d", len(cfg.Certificates))
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Subject.CommonName
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := Files{
+		Cert: filepath.Join(dir, "tls.crt"),
+		Key:  filepath.Join(dir, "tls.key"),
+		CA:   filepath.Join(dir, "ca.crt"),
+	}
+	certA, keyA := selfSigned(t, "a")
+	certB, keyB := selfSigned(t, "b")
+	writeFile(t, files.Cert, certA)
+	writeFile(t, files.Key, keyA)
+	writeFile(t, files.CA, certA)
+
+	s := New(files, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	tests := []struct {
+		name        string
+		cert, key   []byte
+		wantChanged bool
+		wantErr     bool
+		wantCN      string
+	}{
+		{name: "initial", cert: certA, key: keyA, wantChanged: true, wantCN: "a"},
+		{name: "unchanged", cert: certA, key: keyA, wantCN: "a"},
+		{name: "rotated", cert: certB, key: keyB, wantChanged: true, wantCN: "b"},
+		{name: "mismatched key keeps previous", cert: certA, key: keyB, wantErr: true, wantCN: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFile(t, files.Cert, tt.cert)
+			writeFile(t, files.Key, tt.key)
+			changed, err := s.Load()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("want changed %v, got %v", tt.wantChanged, changed)
+			}
+			base := &tls.Config{ServerName: "redis"}
+			cfg := s.TLSConfig(base)
+			if got := commonName(t, cfg); got != tt.wantCN {
+				t.Errorf("want certificate %q, got %q", tt.wantCN, got)
+			}
+			if cfg.RootCAs == nil || cfg.ServerName != "redis" {
+				t.Error("want CA pool and base settings kept")
+			}
+			if base.Certificates != nil {
+				t.Error("base config must not be modified")
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	s := New(Files{CA: filepath.Join(t.TempDir(), "missing.crt")}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := s.Load(); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestRunReload(t *testing.T) {
+	dir := t.TempDir()
+	files := Files{Cert: filepath.Join(dir, "tls.crt"), Key: filepath.Join(dir, "tls.key")}
+	certA, keyA := selfSigned(t, "a")
+	writeFile(t, files.Cert, certA)
+	writeFile(t, files.Key, keyA)
+
+	s := New(files, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := s.Load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 1)
+	go s.Run(ctx, func() { changes <- struct{}{} })
+	time.Sleep(50 * time.Millisecond) // let the watch start
+
+	// Rotate the way cert-manager does: write elsewhere, rename.
+	certB, keyB := selfSigned(t, "b")
+	for path, data := range map[string][]byte{files.Cert: certB, files.Key: keyB} {
+		writeFile(t, path+".tmp", data)
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("onChange not called after rotation")
+	}
+	if got := commonName(t, s.TLSConfig(&tls.Config{})); got != "b" {
+		t.Errorf("want rotated certificate, got %q", got)
+	}
+}
@@ -0,0 +1,307 @@
+// Package vault fetches Redis credentials (password and optional TLS
+// material) from HashiCorp Vault and keeps them fresh.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret keys holding TLS material.
+const (
+	KeyTLSCert = "tls_cert"
+	KeyTLSKey  = "tls_key"
+	KeyTLSCA   = "tls_ca"
+)
+
+// DefaultKubernetesTokenPath is the projected service account token.
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Config configures the Vault client.
+type Config struct {
+	Addr            string        // Vault address, e.g. https://vault:8200
+	Path            string        // secret path, e.g. secret/data/redis (KV v2) or secret/redis (KV v1)
+	Token           string        // static token; ignored when KubernetesRole is set
+	KubernetesRole  string        // role for the kubernetes auth method
+	KubernetesMount string        // auth mount path, default "kubernetes"
+	JWTPath         string        // service account token path, default DefaultKubernetesTokenPath
+	PasswordKey     string        // secret key holding the Redis password, default "password"
+	RefreshInterval time.Duration // re-read interval when the secret has no lease
+	HTTPClient      *http.Client
+}
+
+// Credentials is the material read from the secret.
+type Credentials struct {
+	Password string
+	TLSCert  []byte // PEM client certificate
+	TLSKey   []byte // PEM client key
+	TLSCA    []byte // PEM CA bundle
+}
+
+// Client reads credentials from Vault and re-reads them before the lease
+// runs out.
+type Client struct {
+	cfg    Config
+	logger *slog.Logger
+
+	mu          sync.RWMutex
+	creds       Credentials
+	lease       time.Duration
+	token       string
+	tokenExpiry time.Time // zero for non-expiring tokens
+}
+
+// New creates a Client. Call Load before use.
+func New(cfg Config, logger *slog.Logger) *Client {
+	if cfg.KubernetesMount == "" {
+		cfg.KubernetesMount = "kubernetes"
+	}
+	if cfg.JWTPath == "" {
+		cfg.JWTPath = DefaultKubernetesTokenPath
+	}
+	if cfg.PasswordKey == "" {
+		cfg.PasswordKey = "password"
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{cfg: cfg, logger: logger, token: cfg.Token}
+}
+
+// Load reads the secret once. It fails if the secret is missing or its TLS
+// material does not parse.
+func (c *Client) Load(ctx context.Context) error {
+	data, lease, err := c.read(ctx)
+	if err != nil {
+		return err
+	}
+	creds := Credentials{
+		Password: data[c.cfg.PasswordKey],
+		TLSCert:  []byte(data[KeyTLSCert]),
+		TLSKey:   []byte(data[KeyTLSKey]),
+		TLSCA:    []byte(data[KeyTLSCA]),
+	}
+	if _, err := buildTLSConfig(&tls.Config{}, creds); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	changed := c.creds.Password != creds.Password ||
+		!bytes.Equal(c.creds.TLSCert, creds.TLSCert) ||
+		!bytes.Equal(c.creds.TLSKey, creds.TLSKey) ||
+		!bytes.Equal(c.creds.TLSCA, creds.TLSCA)
+	c.creds = creds
+	c.lease = lease
+	c.mu.Unlock()
+
+	if changed {
+		c.logger.Info("redis credentials loaded from vault", "path", c.cfg.Path, "lease", lease)
+	}
+	return nil
+}
+
+// Run re-reads the secret at two thirds of its lease (or every
+// RefreshInterval) until ctx is cancelled. Failures keep the last
+// known credentials and are retried after a short delay.
+func (c *Client) Run(ctx context.Context) {
+	for {
+		wait := c.nextRefresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if err := c.Load(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error("vault refresh failed, keeping previous credentials", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(30 * time.Second):
+			}
+		}
+	}
+}
+
+func (c *Client) nextRefresh() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lease > 0 {
+		return c.lease * 2 / 3
+	}
+	return c.cfg.RefreshInterval
+}
+
+// Credentials returns the current credentials.
+func (c *Client) Credentials() Credentials {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.creds
+}
+
+// Password returns the current Redis password.
+func (c *Client) Password() string {
+	return c.Credentials().Password
+}
+
+// TLSConfig returns base extended with the current TLS material. If the
+// secret carries no TLS material, base is returned unchanged.
+func (c *Client) TLSConfig(base *tls.Config) *tls.Config {
+	cfg, err := buildTLSConfig(base, c.Credentials())
+	if err != nil {
+		// Validated in Load; only reachable if base is unusable.
+		c.logger.Error("invalid TLS material from vault", "error", err)
+		return base
+	}
+	return cfg
+}
+
+func buildTLSConfig(base *tls.Config, creds Credentials) (*tls.Config, error) {
+	if len(creds.TLSCert) == 0 && len(creds.TLSKey) == 0 && len(creds.TLSCA) == 0 {
+		return base, nil
+	}
+	cfg := base.Clone()
+	if len(creds.TLSCert) > 0 || len(creds.TLSKey) > 0 {
+		cert, err := tls.X509KeyPair(creds.TLSCert, creds.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("vault TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if len(creds.TLSCA) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(creds.TLSCA) {
+			return nil, errors.New("vault TLS CA: no certificates found")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// secretResponse is the subset of a Vault read response used here.
+type secretResponse struct {
+	LeaseDuration int                    `json:"lease_duration"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// read fetches the secret, logging in first if needed. KV v2 responses
+// (data nested under "data") are unwrapped.
+func (c *Client) read(ctx context.Context) (map[string]string, time.Duration, error) {
+	token, err := c.authToken(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var resp secretResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(c.cfg.Path, "/"), token, nil, &resp); err != nil {
+		return nil, 0, fmt.Errorf("read vault secret %s: %w", c.cfg.Path, err)
+	}
+
+	data := resp.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, v2 := data["metadata"]; v2 {
+			data = inner
+		}
+	}
+
+	out := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		}
+	}
+	return out, time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+// authToken returns a valid token, logging in via the kubernetes auth method
+// when configured and the previous token has expired.
+func (c *Client) authToken(ctx context.Context) (string, error) {
+	if c.cfg.KubernetesRole == "" {
+		if c.token == "" {
+			return "", errors.New("no vault token configured")
+		}
+		return c.token, nil
+	}
+
+	c.mu.RLock()
+	token, expiry := c.token, c.tokenExpiry
+	c.mu.RUnlock()
+	if token != "" && (expiry.IsZero() || time.Until(expiry) > 30*time.Second) {
+		return token, nil
+	}
+
+	jwt, err := os.ReadFile(c.cfg.JWTPath)
+	if err != nil {
+		return "", fmt.Errorf("read service account token: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{
+		"role": c.cfg.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var resp secretResponse
+	path := "/v1/auth/" + strings.Trim(c.cfg.KubernetesMount, "/") + "/login"
+	if err := c.do(ctx, http.MethodPost, path, "", body, &resp); err != nil {
+		return "", fmt.Errorf("vault kubernetes login: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", errors.New("vault kubernetes login: no client token in response")
+	}
+
+	c.mu.Lock()
+	c.token = resp.Auth.ClientToken
+	c.tokenExpiry = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		c.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	}
+	c.mu.Unlock()
+	return resp.Auth.ClientToken, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path, token string, body []byte, out interface{}) error {
+	u, err := url.JoinPath(c.cfg.Addr, path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
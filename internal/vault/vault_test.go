@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantPass  string
+		wantLease time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "kv v1",
+			body:      `{"lease_duration": 3600, "data": {"password": "s3cret"}}`,
+			wantPass:  "s3cret",
+			wantLease: time.Hour,
+		},
+		{
+			name:     "kv v2",
+			body:     `{"data": {"data": {"password": "v2pass"}, "metadata": {"version": 3}}}`,
+			wantPass: "v2pass",
+		},
+		{
+			name:    "invalid tls material",
+			body:    `{"data": {"password": "x", "tls_cert": "not a cert", "tls_key": "nope"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid ca",
+			body:    `{"data": {"tls_ca": "garbage"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/secret/redis" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.Header.Get("X-Vault-Token") != "root" {
+					http.Error(w, "permission denied", http.StatusForbidden)
+					return
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c := New(Config{Addr: srv.URL, Path: "secret/redis", Token: "root"}, testLogger())
+			err := c.Load(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.Password(); got != tt.wantPass {
+				t.Errorf("password: want %q, got %q", tt.wantPass, got)
+			}
+			if got := c.nextRefresh(); tt.wantLease > 0 && got != tt.wantLease*2/3 {
+				t.Errorf("refresh: want %s, got %s", tt.wantLease*2/3, got)
+			}
+		})
+	}
+}
+
+func TestLoadKubernetesAuth(t *testing.T) {
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("sa-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logins := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			logins++
+			var req map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode login: %v", err)
+			}
+			if req["role"] != "exporter" || req["jwt"] != "sa-jwt" {
+				t.Errorf("unexpected login request %v", req)
+			}
+			_, _ = io.WriteString(w, `{"auth": {"client_token": "k8s-token", "lease_duration": 3600}}`)
+		case "/v1/secret/data/redis":
+			if r.Header.Get("X-Vault-Token") != "k8s-token" {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, `{"data": {"data": {"password": "fromk8s"}, "metadata": {}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{
+		Addr:           srv.URL,
+		Path:           "secret/data/redis",
+		KubernetesRole: "exporter",
+		JWTPath:        jwtPath,
+	}, testLogger())
+
+	for i := 0; i < 2; i++ {
+		if err := c.Load(context.Background()); err != nil {
+			t.Fatalf("load %d: %v", i, err)
+		}
+	}
+	if got := c.Password(); got != "fromk8s" {
+		t.Errorf("password: want %q, got %q", "fromk8s", got)
+	}
+	if logins != 1 {
+		t.Errorf("token should be reused until expiry, got %d logins", logins)
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}